@@ -0,0 +1,388 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// assertKey is the annotation listing the assertions an object must
+// satisfy. In picoschema assertions are written under the "(assert)" key
+// of an object, as a single expression or a list:
+//
+//	schema:
+//	  startDate: string
+//	  endDate?: string
+//	  (assert): endDate >= startDate
+//
+// An expression compares property paths, such as "address.zip", and
+// literals ('text', "text", numbers, true, false and null) with the
+// operators ==, !=, <, <=, > and >=, and combines comparisons with
+// &&, || and !. Strings are compared lexically, which orders ISO 8601
+// dates correctly. An assertion that refers to a missing property is
+// not evaluated.
+const assertKey = "x-assert"
+
+// An assertion is a compiled assertion expression.
+type assertion struct {
+	src  string
+	root exprNode
+}
+
+// A compiledAssertion is the result of compiling an assertion.
+type compiledAssertion struct {
+	a   *assertion
+	err error
+}
+
+// assertionCache caches compiled assertions by source text.
+var assertionCache = &boundedCache[compiledAssertion]{
+	max: 1024,
+	compute: func(src string) compiledAssertion {
+		a, err := parseAssertion(src)
+		return compiledAssertion{a, err}
+	},
+}
+
+// compileAssertion returns the compiled assertion expression src.
+func compileAssertion(src string) (*assertion, error) {
+	c := assertionCache.get(src)
+	return c.a, c.err
+}
+
+// parseAssertion parses the assertion expression src.
+func parseAssertion(src string) (*assertion, error) {
+	p := &exprParser{src: src}
+	if err := p.tokenize(); err != nil {
+		return nil, fmt.Errorf("assertion %q: %w", src, err)
+	}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("assertion %q: %w", src, err)
+	}
+	return &assertion{src: src, root: root}, nil
+}
+
+// errMissing reports that an assertion refers to a missing property.
+var errMissing = errors.New("missing property")
+
+// eval evaluates the assertion against obj. It reports ok=false
+// if the assertion could not be evaluated because a property is missing.
+func (a *assertion) eval(obj map[string]any) (result, ok bool, err error) {
+	v, err := a.root.eval(obj)
+	if errors.Is(err, errMissing) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	b, isBool := v.(bool)
+	if !isBool {
+		return false, false, fmt.Errorf("assertion %q evaluated to %v, not a boolean", a.src, v)
+	}
+	return b, true, nil
+}
+
+// assertionSources returns the assertion expressions in the value of
+// an assert annotation, which is a string or a list of strings.
+func assertionSources(v any) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case []any:
+		srcs := make([]string, 0, len(v))
+		for _, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("assertion %v of type %[1]T is not a string", e)
+			}
+			srcs = append(srcs, s)
+		}
+		return srcs, nil
+	case []string:
+		return v, nil
+	}
+	return nil, fmt.Errorf("assertions %v of type %[1]T are not a string or list", v)
+}
+
+// An exprNode is a node of an assertion's syntax tree.
+type exprNode interface {
+	eval(obj map[string]any) (any, error)
+}
+
+type (
+	literalNode struct{ val any }
+	pathNode    struct{ path []string }
+	notNode     struct{ x exprNode }
+	binaryNode  struct {
+		op   string
+		x, y exprNode
+	}
+)
+
+func (n literalNode) eval(map[string]any) (any, error) { return n.val, nil }
+
+func (n pathNode) eval(obj map[string]any) (any, error) {
+	var v any = obj
+	for _, name := range n.path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, errMissing
+		}
+		if v, ok = m[name]; !ok {
+			return nil, errMissing
+		}
+	}
+	return v, nil
+}
+
+func (n notNode) eval(obj map[string]any) (any, error) {
+	v, err := n.x.eval(obj)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operand of ! is %v, not a boolean", v)
+	}
+	return !b, nil
+}
+
+func (n binaryNode) eval(obj map[string]any) (any, error) {
+	x, err := n.x.eval(obj)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		xb, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operand of %s is %v, not a boolean", n.op, x)
+		}
+		if xb == (n.op == "||") {
+			return xb, nil
+		}
+		y, err := n.y.eval(obj)
+		if err != nil {
+			return nil, err
+		}
+		yb, ok := y.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operand of %s is %v, not a boolean", n.op, y)
+		}
+		return yb, nil
+	}
+	y, err := n.y.eval(obj)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return jsonEqual(x, y), nil
+	case "!=":
+		return !jsonEqual(x, y), nil
+	}
+	c, err := compareValues(x, y)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default: // ">="
+		return c >= 0, nil
+	}
+}
+
+// compareValues orders two numbers or two strings.
+func compareValues(x, y any) (int, error) {
+	if fx, ok := toFloat(x); ok {
+		if fy, ok := toFloat(y); ok {
+			switch {
+			case fx < fy:
+				return -1, nil
+			case fx > fy:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if sx, ok := x.(string); ok {
+		if sy, ok := y.(string); ok {
+			return strings.Compare(sx, sy), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %v and %v", x, y)
+}
+
+// A token is a lexical token of an assertion.
+type token struct {
+	kind byte // 'i' identifier or path, 'n' number, 's' string, 'o' operator or parenthesis
+	text string
+}
+
+// exprParser is a recursive-descent parser for assertions.
+type exprParser struct {
+	src  string
+	toks []token
+	pos  int
+}
+
+func (p *exprParser) tokenize() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case c == '\'' || c == '"':
+			j := strings.IndexByte(s[i+1:], s[i])
+			if j < 0 {
+				return errors.New("unterminated string")
+			}
+			p.toks = append(p.toks, token{'s', s[i+1 : i+1+j]})
+			i += j + 2
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			j := i + 1
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.' || s[j] == 'e' || s[j] == 'E') {
+				j++
+			}
+			p.toks = append(p.toks, token{'n', s[i:j]})
+			i = j
+		case c == '_' || unicode.IsLetter(c):
+			j := i + size
+			for j < len(s) {
+				d, n := utf8.DecodeRuneInString(s[j:])
+				if d != '_' && d != '.' && d != '-' && (d < '0' || d > '9') && !unicode.IsLetter(d) {
+					break
+				}
+				j += n
+			}
+			p.toks = append(p.toks, token{'i', s[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return fmt.Errorf("unexpected character %q", c)
+			}
+			p.toks = append(p.toks, token{'o', op})
+			i += len(op)
+		}
+	}
+	return nil
+}
+
+// accept consumes the next token if it is the operator op.
+func (p *exprParser) accept(op string) bool {
+	if p.pos < len(p.toks) && p.toks[p.pos].kind == 'o' && p.toks[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	x, err := p.parseAnd()
+	for err == nil && p.accept("||") {
+		var y exprNode
+		y, err = p.parseAnd()
+		x = binaryNode{"||", x, y}
+	}
+	return x, err
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	x, err := p.parseComparison()
+	for err == nil && p.accept("&&") {
+		var y exprNode
+		y, err = p.parseComparison()
+		x = binaryNode{"&&", x, y}
+	}
+	return x, err
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			y, err := p.parseUnary()
+			return binaryNode{op, x, y}, err
+		}
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.accept("!") {
+		x, err := p.parseUnary()
+		return notNode{x}, err
+	}
+	if p.accept("(") {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, errors.New("missing )")
+		}
+		return x, nil
+	}
+	if p.pos >= len(p.toks) {
+		return nil, errors.New("unexpected end of expression")
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	switch t.kind {
+	case 's':
+		return literalNode{t.text}, nil
+	case 'n':
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", t.text)
+		}
+		return literalNode{f}, nil
+	case 'i':
+		switch t.text {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		case "null":
+			return literalNode{nil}, nil
+		}
+		return pathNode{strings.Split(t.text, ".")}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
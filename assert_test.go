@@ -0,0 +1,80 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+)
+
+func TestAssertionEval(t *testing.T) {
+	obj := map[string]any{
+		"start": "2024-01-01",
+		"end":   "2024-02-01",
+		"min":   1,
+		"max":   10.5,
+		"kind":  "range",
+		"addr":  map[string]any{"country": "US", "zip": "02139"},
+		"größe": 3,
+	}
+	for _, test := range []struct {
+		src             string
+		want, evaluated bool
+	}{
+		{"end >= start", true, true},
+		{"end < start", false, true},
+		{"min <= max && kind == 'range'", true, true},
+		{"!(min > max) || missing == 1", true, true},
+		{"addr.country != \"US\" || addr.zip != null", true, true},
+		{"max == 10.5", true, true},
+		{"min < -1", false, true},
+		{"missing > min", false, false},
+		{"größe > min && kind != 'ünits'", true, true},
+	} {
+		a, err := compileAssertion(test.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, evaluated, err := a.eval(obj)
+		if err != nil {
+			t.Fatalf("%q: %v", test.src, err)
+		}
+		if got != test.want || evaluated != test.evaluated {
+			t.Errorf("%q: got (%t, %t), want (%t, %t)", test.src, got, evaluated, test.want, test.evaluated)
+		}
+	}
+}
+
+func TestAssertionSyntaxErrors(t *testing.T) {
+	for _, src := range []string{"", "a >", "a == 'b", "(a < b", "a < b c", "a # b", "a < b\u00a7"} {
+		if _, err := compileAssertion(src); err == nil {
+			t.Errorf("%q: got nil error", src)
+		}
+	}
+}
+
+func TestCheckAssertions(t *testing.T) {
+	s := mustSchema(t, `
+startDate: string
+endDate?: string
+(assert): endDate >= startDate
+`)
+	if vs := Check(map[string]any{"startDate": "2024-05-01"}, s); len(vs) != 0 {
+		t.Errorf("got violations with endDate missing: %v", vs)
+	}
+	vs := Check(map[string]any{"startDate": "2024-05-01", "endDate": "2024-04-01"}, s)
+	if len(vs) != 1 || vs[0].Keyword != assertKey {
+		t.Errorf("got %v, want one assertion violation", vs)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bench provides representative picoschema fixtures and helpers
+// for benchmarking the conversion of picoschema to JSON Schema.
+//
+// The benchmarks are named by fixture, so that the output of
+//
+//	go test -run '^$' -bench . -count 10
+//
+// from two versions can be compared with benchstat. A downstream
+// package can run them from its own tests, as in
+//
+//	func BenchmarkPicoschema(b *testing.B) { bench.Run(b) }
+//
+// and guard the allocations of the conversion with CheckAllocs.
+package bench
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jumonapp/picoschema"
+	"gopkg.in/yaml.v3"
+)
+
+// A Fixture is a picoschema document to benchmark.
+type Fixture struct {
+	// Name names the benchmarks of the fixture.
+	Name string
+	// Source is the picoschema YAML.
+	Source string
+	// MaxAllocs is the budget of allocations for one conversion of the
+	// decoded Source with ToJSONSchema and no options, with headroom
+	// over what the conversion takes, that CheckAllocs enforces.
+	MaxAllocs float64
+}
+
+// The fixtures, in increasing size.
+var (
+	// Small is an object of a few scalar properties, as in a tool call.
+	Small = Fixture{Name: "small", Source: small, MaxAllocs: 60}
+	// Medium is a document of nested objects, arrays, enums, wildcards
+	// and $defs, as in a typical API payload.
+	Medium = Fixture{Name: "medium", Source: medium, MaxAllocs: 700}
+	// Huge is a generated document of hundreds of $defs and thousands of
+	// properties, as in the registries of large services.
+	Huge = Fixture{Name: "huge", Source: huge(200, 25), MaxAllocs: 60000}
+)
+
+// Fixtures returns the fixtures, in increasing size.
+func Fixtures() []Fixture {
+	return []Fixture{Small, Medium, Huge}
+}
+
+// Value returns the decoded YAML of f, as ToJSONSchema takes it.
+func (f Fixture) Value() (any, error) {
+	var v any
+	if err := yaml.Unmarshal([]byte(f.Source), &v); err != nil {
+		return nil, fmt.Errorf("bench: fixture %s: %w", f.Name, err)
+	}
+	return v, nil
+}
+
+// Convert benchmarks converting the decoded f with ToJSONSchema and
+// opts, the hot path of services that convert at run time. It reports
+// allocations.
+func Convert(b *testing.B, f Fixture, opts ...picoschema.Option) {
+	v, err := f.Value()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(f.Source)))
+	b.ResetTimer()
+	for range b.N {
+		if _, err := picoschema.ToJSONSchema(v, opts...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Parse benchmarks converting f from its YAML source with ParseYAML
+// and opts, which includes decoding the YAML. It reports allocations.
+func Parse(b *testing.B, f Fixture, opts ...picoschema.Option) {
+	data := []byte(f.Source)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for range b.N {
+		if _, err := picoschema.ParseYAML(data, opts...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Run runs the benchmarks Convert and Parse of each fixture with opts,
+// as sub-benchmarks named "Convert/small", "Parse/huge" and so on.
+func Run(b *testing.B, opts ...picoschema.Option) {
+	for _, bm := range []struct {
+		name string
+		fn   func(*testing.B, Fixture, ...picoschema.Option)
+	}{
+		{"Convert", Convert},
+		{"Parse", Parse},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for _, f := range Fixtures() {
+				b.Run(f.Name, func(b *testing.B) { bm.fn(b, f, opts...) })
+			}
+		})
+	}
+}
+
+// AllocsPerConversion returns the average number of allocations of
+// converting the decoded f with ToJSONSchema and opts.
+func AllocsPerConversion(f Fixture, opts ...picoschema.Option) (float64, error) {
+	v, err := f.Value()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := picoschema.ToJSONSchema(v, opts...); err != nil {
+		return 0, fmt.Errorf("bench: fixture %s: %w", f.Name, err)
+	}
+	return testing.AllocsPerRun(10, func() {
+		picoschema.ToJSONSchema(v, opts...)
+	}), nil
+}
+
+// CheckAllocs fails tb if converting f with no options takes more
+// allocations than f.MaxAllocs, guarding the conversion against
+// regressions. Allocation counts do not vary with the machine, unlike
+// times, so they can be checked in ordinary test runs.
+func CheckAllocs(tb testing.TB, f Fixture) {
+	tb.Helper()
+	if testing.Short() && f.Name == Huge.Name {
+		tb.Skipf("fixture %s in short mode", f.Name)
+	}
+	got, err := AllocsPerConversion(f)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if got > f.MaxAllocs {
+		tb.Errorf("converting fixture %s takes %.0f allocations, over its budget of %.0f", f.Name, got, f.MaxAllocs)
+	}
+}
+
+const small = `
+(description): A request to look up the weather.
+city: string, the name of the city
+country?: string, the ISO 3166 country code
+days?(integer, minimum=1, maximum=14): the number of days to forecast
+units?(enum): [metric, imperial]
+`
+
+const medium = `
+$defs:
+  money:
+    amount(number, minimum=0): the amount
+    currency(string, pattern=^[A-Z]{3}$): the ISO 4217 currency code
+  address:
+    street: string
+    city: string
+    postalCode?: string
+    country(string, pattern=^[A-Z]{2}$):
+(description): An order placed in the store.
+id(string, readOnly): the order ID
+status(enum): [pending, paid, shipped, delivered, cancelled]
+createdAt(string, format=date-time):
+customer(object):
+  id: string
+  name: string
+  email?(string, format=email):
+  phone?: string?
+  addresses(array): address
+items(array, minItems=1):
+  sku: string
+  name: string
+  quantity(integer, minimum=1):
+  price: money
+  options?(*): string
+  tags?(array): string
+shipping?:
+  method(enum): [standard, express, pickup]
+  address: address
+  cost: money
+  trackingNumber?: string?
+payment(oneOf, discriminator=kind):
+  - kind(const): card
+    last4(string, pattern=^[0-9]{4}$):
+    brand: string
+  - kind(const): transfer
+    iban: string
+  - kind(const): wallet
+    provider(enum): [apple, google]
+    token: string
+discount?: money
+total: money
+notes?: string, notes from the customer
+metadata?(*): any
+(/^x-/): string
+`
+
+// huge returns a document of defs $defs of props properties each,
+// in which each definition refers to the one before it, and whose root
+// has a property of each definition.
+func huge(defs, props int) string {
+	var sb strings.Builder
+	sb.WriteString("$defs:\n")
+	for d := range defs {
+		fmt.Fprintf(&sb, "  entity%d:\n", d)
+		for p := range props {
+			switch p % 5 {
+			case 0:
+				fmt.Fprintf(&sb, "    field%d: string, field %d of entity %d\n", p, p, d)
+			case 1:
+				fmt.Fprintf(&sb, "    field%d?(integer, minimum=0, maximum=%d):\n", p, 100+p)
+			case 2:
+				fmt.Fprintf(&sb, "    field%d?(enum): [a%d, b%d, c%d]\n", p, p, p, p)
+			case 3:
+				fmt.Fprintf(&sb, "    field%d(array): number\n", p)
+			case 4:
+				if d > 0 {
+					fmt.Fprintf(&sb, "    field%d?: entity%d\n", p, d-1)
+				} else {
+					fmt.Fprintf(&sb, "    field%d?: boolean?\n", p)
+				}
+			}
+		}
+	}
+	for d := range defs {
+		fmt.Fprintf(&sb, "root%d: entity%d\n", d, d)
+	}
+	return sb.String()
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bench
+
+import (
+	"testing"
+
+	"github.com/jumonapp/picoschema"
+)
+
+func BenchmarkPicoschema(b *testing.B) { Run(b) }
+
+func TestAllocs(t *testing.T) {
+	for _, f := range Fixtures() {
+		t.Run(f.Name, func(t *testing.T) { CheckAllocs(t, f) })
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	for _, f := range Fixtures() {
+		v, err := f.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := picoschema.ToJSONSchema(v)
+		if err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		if s.Type != "object" {
+			t.Errorf("%s: type %q, want object", f.Name, s.Type)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// Default limits of a Bundler.
+const (
+	DefaultMaxRefs        = 1000
+	DefaultMaxBundleBytes = 16 << 20
+)
+
+// ErrLimitExceeded is returned, wrapped, when bundling or inlining
+// exceeds a Bundler's limits.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Bundle bundles s with the default Bundler and resolver res.
+//
+// The code generators, GenerateGo, GenerateProto, GeneratePydantic,
+// GenerateTypeScript and GenerateZod, follow only refs that point to
+// their schema or into its $defs, so a schema that refers to others by
+// name should be bundled first.
+func Bundle(s *jsonschema.Schema, res SchemaResolver) (*jsonschema.Schema, error) {
+	b := Bundler{Resolver: res}
+	return b.Bundle(s)
+}
+
+// Inline inlines the refs of s with the default Bundler and resolver res.
+func Inline(s *jsonschema.Schema, res SchemaResolver) (*jsonschema.Schema, error) {
+	b := Bundler{Resolver: res}
+	return b.Inline(s)
+}
+
+// A Bundler makes self-contained schemas by resolving external refs.
+// Its limits protect services that bundle untrusted schemas from
+// schemas whose refs amplify into huge documents.
+type Bundler struct {
+	// Resolver resolves external refs. A ref is looked up by its
+	// target, the part before any "#": a URL, resolved against the URL
+	// of the schema containing it, or a name such as "customer" or
+	// "billing.Invoice". A name with a file extension, such as
+	// "customer.json", is also looked up without the extension.
+	Resolver SchemaResolver
+
+	// MaxRefs limits the number of distinct external schemas resolved.
+	// Zero means DefaultMaxRefs.
+	MaxRefs int
+
+	// MaxBytes limits the total size, in bytes of JSON, of the resolved
+	// schemas and, for Inline, of the copies made. Zero means
+	// DefaultMaxBundleBytes.
+	MaxBytes int64
+
+	// RecordSources says whether each resolved schema records its
+	// target in an x-source annotation, as does each copy of part of it
+	// made by Inline, so that the properties of the result can be traced
+	// to their origin with Sources.
+	RecordSources bool
+}
+
+// Bundle returns a copy of s in which every external schema that s
+// refers to, directly or indirectly, is copied into $defs, and every
+// external ref is rewritten to point there. s is not modified.
+func (b *Bundler) Bundle(s *jsonschema.Schema) (*jsonschema.Schema, error) {
+	st, err := b.bundle(s)
+	if err != nil {
+		recordError("bundle", err)
+		return nil, err
+	}
+	return st.root, nil
+}
+
+// Inline returns a copy of s in which every external ref is replaced
+// by a copy of the schema it refers to. Inline fails if the external
+// schemas refer to each other in a cycle, which only Bundle can
+// represent. s is not modified.
+func (b *Bundler) Inline(s *jsonschema.Schema) (*jsonschema.Schema, error) {
+	st, err := b.bundle(s)
+	if err == nil {
+		err = st.inlineAll()
+	}
+	if err != nil {
+		recordError("bundle", err)
+		return nil, err
+	}
+	return st.root, nil
+}
+
+// inlineAll inlines the refs into bundled schemas and removes them.
+func (st *bundleState) inlineAll() error {
+	// Detach the bundled schemas, so that only the parts referred to
+	// are copied.
+	for _, name := range st.defs {
+		st.bundled[name] = st.root.Definitions[name]
+		delete(st.root.Definitions, name)
+	}
+	if len(st.root.Definitions) == 0 {
+		st.root.Definitions = nil
+	}
+	return st.inline(st.root, nil)
+}
+
+type bundleState struct {
+	b     *Bundler
+	root  *jsonschema.Schema
+	defs  map[string]string // resolved target to $defs name
+	bytes int64
+
+	// bundled holds the bundled schemas by $defs name while inlining.
+	bundled map[string]*jsonschema.Schema
+}
+
+func (b *Bundler) bundle(s *jsonschema.Schema) (*bundleState, error) {
+	if b.Resolver == nil {
+		return nil, errors.New("picoschema: Bundler has no Resolver")
+	}
+	st := &bundleState{
+		b:       b,
+		root:    cloneSchema(s),
+		defs:    make(map[string]string),
+		bundled: make(map[string]*jsonschema.Schema),
+	}
+	if err := st.rewrite(st.root, nil, ""); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// rewrite bundles the external refs within s, whose document has the
+// URL base (or nil) and is stored in $defs under self ("" for the root),
+// and rewrites all its refs to point into the root.
+func (st *bundleState) rewrite(s *jsonschema.Schema, base *url.URL, self string) error {
+	var err error
+	walkSchema(s, "", func(n *jsonschema.Schema, _ string) bool {
+		if err != nil {
+			return false
+		}
+		if n.Ref == "" {
+			return true
+		}
+		target, frag, _ := strings.Cut(n.Ref, "#")
+		if target == "" {
+			if self != "" {
+				n.Ref = "#/$defs/" + escapePointer(self) + frag
+			}
+			return true
+		}
+		key := target
+		var docURL *url.URL
+		if u, perr := url.Parse(target); perr == nil {
+			if base != nil {
+				u = base.ResolveReference(u)
+			}
+			if u.IsAbs() {
+				docURL, key = u, u.String()
+			}
+		}
+		var name string
+		if name, err = st.add(key, docURL); err != nil {
+			return false
+		}
+		n.Ref = "#/$defs/" + escapePointer(name) + frag
+		return true
+	})
+	return err
+}
+
+// add resolves the external schema key, stores it in $defs if it is
+// not there already, and returns its name in $defs.
+func (st *bundleState) add(key string, docURL *url.URL) (string, error) {
+	if name, ok := st.defs[key]; ok {
+		return name, nil
+	}
+	if max := cmp.Or(st.b.MaxRefs, DefaultMaxRefs); len(st.defs) >= max {
+		return "", fmt.Errorf("picoschema: bundling %s: %w: more than %d refs", key, ErrLimitExceeded, max)
+	}
+	s, err := st.b.Resolver.Resolve(key)
+	if errors.Is(err, ErrSchemaNotFound) && docURL == nil {
+		if name, ok := refTarget(key); ok && name != key {
+			s, err = st.b.Resolver.Resolve(name)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("picoschema: bundling %s: %w", key, err)
+	}
+	c := cloneSchema(s)
+	if err := st.count(c, key); err != nil {
+		return "", err
+	}
+	if st.b.RecordSources {
+		setSource(c, key)
+	}
+	name := st.defName(key, docURL)
+	st.defs[key] = name
+	if st.root.Definitions == nil {
+		st.root.Definitions = make(jsonschema.Definitions)
+	}
+	st.root.Definitions[name] = c
+	return name, st.rewrite(c, docURL, name)
+}
+
+// count adds the size of s to the bytes used and checks the limit.
+func (st *bundleState) count(s *jsonschema.Schema, what string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("picoschema: bundling %s: %w", what, err)
+	}
+	st.bytes += int64(len(data))
+	if max := cmp.Or(st.b.MaxBytes, DefaultMaxBundleBytes); st.bytes > max {
+		return fmt.Errorf("picoschema: bundling %s: %w: more than %d bytes", what, ErrLimitExceeded, max)
+	}
+	return nil
+}
+
+// defName chooses an unused $defs name for the schema key.
+func (st *bundleState) defName(key string, docURL *url.URL) string {
+	base := key
+	if docURL != nil {
+		base = path.Base(docURL.Path)
+		base = strings.TrimSuffix(base, path.Ext(base))
+		if base == "" || base == "." || base == "/" {
+			base = docURL.Hostname()
+		}
+	} else if name, ok := refTarget(key); ok {
+		base = name
+	}
+	name := base
+	for i := 2; ; i++ {
+		if _, taken := st.root.Definitions[name]; !taken {
+			return name
+		}
+		name = base + "_" + strconv.Itoa(i)
+	}
+}
+
+// inline replaces refs into bundled $defs within s by copies of their
+// targets. stack holds the refs being inlined.
+func (st *bundleState) inline(s *jsonschema.Schema, stack []string) error {
+	// The target may itself be a ref, so repeat until s is not.
+	for {
+		name, ptr, ok := st.bundledRef(s.Ref)
+		if !ok {
+			break
+		}
+		ref := s.Ref
+		if slices.Contains(stack, ref) {
+			return fmt.Errorf("picoschema: cannot inline cyclic refs: %s", strings.Join(append(stack, ref), " -> "))
+		}
+		target, ok := resolvePointer(st.bundled[name], ptr)
+		if !ok {
+			return fmt.Errorf("picoschema: ref %q does not resolve", s.Ref)
+		}
+		c := cloneSchema(target)
+		if err := st.count(c, ref); err != nil {
+			return err
+		}
+		if src, ok := st.bundled[name].Extras[sourceKey].(string); ok && st.b.RecordSources {
+			setSource(c, src)
+		}
+		replaceRef(s, c)
+		stack = append(slices.Clip(stack), ref)
+	}
+	var err error
+	forEachSubschema(s, func(sub *jsonschema.Schema, _ string) {
+		if err == nil {
+			err = st.inline(sub, stack)
+		}
+	})
+	return err
+}
+
+// bundledRef reports whether ref points into a bundled schema, and if
+// so returns the schema's $defs name and the pointer within it.
+func (st *bundleState) bundledRef(ref string) (name, ptr string, ok bool) {
+	rest, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok {
+		return "", "", false
+	}
+	first, ptr, _ := strings.Cut(rest, "/")
+	if ptr != "" {
+		ptr = "/" + ptr
+	}
+	name = unescapePointer(first)
+	_, ok = st.bundled[name]
+	return name, ptr, ok
+}
+
+// replaceRef replaces the ref node s by target. Annotations beside the
+// ref are kept; other keywords beside it are kept alongside target
+// under allOf.
+func replaceRef(s, target *jsonschema.Schema) {
+	s.Ref = ""
+	extra := slices.DeleteFunc(schemaKeywords(s), func(k string) bool {
+		return k == "title" || k == "description"
+	})
+	if len(extra) > 0 {
+		s.AllOf = append(s.AllOf, target)
+		return
+	}
+	title, desc := s.Title, s.Description
+	*s = *target
+	if title != "" {
+		s.Title = title
+	}
+	if desc != "" {
+		s.Description = desc
+	}
+}
+
+// resolvePointer returns the subschema of root at the JSON Pointer ptr.
+func resolvePointer(root *jsonschema.Schema, ptr string) (*jsonschema.Schema, bool) {
+	if ptr == "" {
+		return root, true
+	}
+	toks := strings.Split(strings.TrimPrefix(ptr, "/"), "/")
+	for i := range toks {
+		toks[i] = unescapePointer(toks[i])
+	}
+	cur := root
+	for len(toks) > 0 && cur != nil {
+		kw := toks[0]
+		toks = toks[1:]
+		next := func() (string, bool) {
+			if len(toks) == 0 {
+				return "", false
+			}
+			t := toks[0]
+			toks = toks[1:]
+			return t, true
+		}
+		index := func(list []*jsonschema.Schema) *jsonschema.Schema {
+			t, ok := next()
+			i, err := strconv.Atoi(t)
+			if !ok || err != nil || i < 0 || i >= len(list) {
+				return nil
+			}
+			return list[i]
+		}
+		named := func(m map[string]*jsonschema.Schema) *jsonschema.Schema {
+			t, ok := next()
+			if !ok {
+				return nil
+			}
+			return m[t]
+		}
+		switch kw {
+		case "properties":
+			t, ok := next()
+			if !ok || cur.Properties == nil {
+				return nil, false
+			}
+			cur, _ = cur.Properties.Get(t)
+		case "$defs", "definitions":
+			cur = named(cur.Definitions)
+		case "patternProperties":
+			cur = named(cur.PatternProperties)
+		case "dependentSchemas":
+			cur = named(cur.DependentSchemas)
+		case "allOf":
+			cur = index(cur.AllOf)
+		case "anyOf":
+			cur = index(cur.AnyOf)
+		case "oneOf":
+			cur = index(cur.OneOf)
+		case "prefixItems":
+			cur = index(cur.PrefixItems)
+		case "items":
+			cur = cur.Items
+		case "additionalProperties":
+			cur = cur.AdditionalProperties
+		case "not":
+			cur = cur.Not
+		case "if":
+			cur = cur.If
+		case "then":
+			cur = cur.Then
+		case "else":
+			cur = cur.Else
+		case "contains":
+			cur = cur.Contains
+		case "propertyNames":
+			cur = cur.PropertyNames
+		case "contentSchema":
+			cur = cur.ContentSchema
+		default:
+			return nil, false
+		}
+	}
+	return cur, cur != nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// refObject returns an object schema whose properties are refs.
+func refObject(props ...string) *jsonschema.Schema {
+	s := &jsonschema.Schema{Type: "object", Properties: orderedmap.New[string, *jsonschema.Schema]()}
+	for i := 0; i+1 < len(props); i += 2 {
+		s.Properties.Set(props[i], &jsonschema.Schema{Ref: props[i+1]})
+	}
+	return s
+}
+
+func schemaJSON(t *testing.T, s *jsonschema.Schema) any {
+	t.Helper()
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func bundleRegistry(t *testing.T) *Registry {
+	t.Helper()
+	var r Registry
+	for name, s := range map[string]*jsonschema.Schema{
+		"country": {Type: "string", Pattern: "^[A-Z]{2}$"},
+		"address": refObject("country", "country.json", "self", "#"),
+		"node":    refObject("next", "node"),
+	} {
+		if err := r.Register(name, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &r
+}
+
+func TestBundle(t *testing.T) {
+	r := bundleRegistry(t)
+	s := refObject("home", "address", "code", "address#/properties/country", "id", "#/properties/code")
+	got, err := Bundle(s, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"home": map[string]any{"$ref": "#/$defs/address"},
+			"code": map[string]any{"$ref": "#/$defs/address/properties/country"},
+			"id":   map[string]any{"$ref": "#/properties/code"},
+		},
+		"$defs": map[string]any{
+			"address": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"country": map[string]any{"$ref": "#/$defs/country"},
+					"self":    map[string]any{"$ref": "#/$defs/address"},
+				},
+			},
+			"country": map[string]any{"type": "string", "pattern": "^[A-Z]{2}$"},
+		},
+	}
+	if diff := cmp.Diff(want, schemaJSON(t, got)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if s.Definitions != nil {
+		t.Error("Bundle modified its argument")
+	}
+
+	if _, err := Bundle(refObject("x", "missing"), r); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("got %v, want ErrSchemaNotFound", err)
+	}
+}
+
+func TestInline(t *testing.T) {
+	r := bundleRegistry(t)
+	s := refObject("code", "address#/properties/country")
+	s.Properties.Set("country", &jsonschema.Schema{Ref: "country", Description: "where"})
+	got, err := Inline(s, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	country := map[string]any{"type": "string", "pattern": "^[A-Z]{2}$"}
+	want := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"code":    country,
+			"country": map[string]any{"type": "string", "pattern": "^[A-Z]{2}$", "description": "where"},
+		},
+	}
+	if diff := cmp.Diff(want, schemaJSON(t, got)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if _, err := Inline(refObject("n", "node"), r); err == nil {
+		t.Error("Inline of cyclic refs succeeded")
+	}
+}
+
+func TestBundlerLimits(t *testing.T) {
+	// Each level refers to the one below twice, so inlining level n
+	// makes 2^n copies of level 0.
+	var r Registry
+	r.Register("level0", &jsonschema.Schema{Type: "string", Description: "a fairly long description of the leaf"})
+	for i := 1; i <= 30; i++ {
+		below := fmt.Sprintf("level%d", i-1)
+		r.Register(fmt.Sprintf("level%d", i), refObject("a", below, "b", below))
+	}
+	top := &jsonschema.Schema{Ref: "level30"}
+
+	if _, err := Bundle(top, &r); err != nil {
+		t.Errorf("Bundle: %v", err)
+	}
+	b := Bundler{Resolver: &r, MaxBytes: 1 << 16}
+	if _, err := b.Inline(top); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Inline: got %v, want ErrLimitExceeded", err)
+	}
+	b = Bundler{Resolver: &r, MaxRefs: 10}
+	if _, err := b.Bundle(top); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Bundle with MaxRefs: got %v, want ErrLimitExceeded", err)
+	}
+	b = Bundler{Resolver: &r, MaxBytes: 1 << 10}
+	if _, err := b.Bundle(top); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Bundle with MaxBytes: got %v, want ErrLimitExceeded", err)
+	}
+	b = Bundler{Resolver: &r, MaxBytes: 1 << 20}
+	if _, err := b.Inline(&jsonschema.Schema{Ref: "level3"}); err != nil {
+		t.Errorf("Inline of small schema: %v", err)
+	}
+}
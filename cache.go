@@ -0,0 +1,78 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"regexp"
+	"sync"
+)
+
+// A boundedCache memoizes a function of strings, such as the
+// compilation of patterns, holding at most max results so that a cache
+// of values taken from schemas or model output cannot grow without
+// bound. When it is full, an arbitrary entry is evicted.
+type boundedCache[V any] struct {
+	max     int
+	compute func(string) V
+
+	mu sync.Mutex
+	m  map[string]V
+}
+
+// get returns the result for key, computing it if it is not cached.
+func (c *boundedCache[V]) get(key string) V {
+	c.mu.Lock()
+	v, ok := c.m[key]
+	c.mu.Unlock()
+	if ok {
+		return v
+	}
+	v = c.compute(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = make(map[string]V)
+	}
+	if len(c.m) >= c.max {
+		for k := range c.m {
+			delete(c.m, k)
+			break
+		}
+	}
+	c.m[key] = v
+	return v
+}
+
+// A compiledPattern is the result of compiling a regular expression.
+type compiledPattern struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// patternCache caches the regular expressions of pattern and
+// patternProperties keywords for the validator.
+var patternCache = &boundedCache[compiledPattern]{
+	max: 1024,
+	compute: func(p string) compiledPattern {
+		re, err := regexp.Compile(p)
+		return compiledPattern{re, err}
+	},
+}
+
+// compilePattern returns the compiled regular expression p.
+func compilePattern(p string) (*regexp.Regexp, error) {
+	c := patternCache.get(p)
+	return c.re, c.err
+}
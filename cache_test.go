@@ -0,0 +1,42 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestBoundedCache(t *testing.T) {
+	calls := 0
+	c := &boundedCache[int]{max: 3, compute: func(s string) int {
+		calls++
+		n, _ := strconv.Atoi(s)
+		return n
+	}}
+	for i := range 10 {
+		if got := c.get(strconv.Itoa(i)); got != i {
+			t.Errorf("get(%d) = %d", i, got)
+		}
+		if len(c.m) > 3 {
+			t.Fatalf("cache holds %d entries, want at most 3", len(c.m))
+		}
+	}
+	calls = 0
+	c.get("9")
+	if calls != 0 {
+		t.Error("recent entry recomputed")
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A CatalogEntry describes one schema file found by BuildCatalog.
+type CatalogEntry struct {
+	Name        string `json:"name"`
+	Version     string `json:"version,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	Path        string `json:"path"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// catalogFormats maps file extensions to the importer used to read them.
+var catalogFormats = map[string]string{
+	".yaml": "picoschema",
+	".yml":  "picoschema",
+	".json": "jsonschema",
+}
+
+// BuildCatalog walks fsys and returns an entry for every schema file,
+// sorted by path. Files ending in .yaml or .yml are read as picoschema
+// and files ending in .json as JSON Schema; other files are ignored.
+// The name of an entry is the file's path without its extension.
+// A version may be given by ending the file name with "@version",
+// as in "billing/invoice@2.yaml".
+func BuildCatalog(fsys fs.FS) ([]CatalogEntry, error) {
+	var entries []CatalogEntry
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := path.Ext(p)
+		format, ok := catalogFormats[ext]
+		if !ok {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		s, err := Import(format, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		fp, err := Fingerprint(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		name, version, _ := strings.Cut(strings.TrimSuffix(p, ext), "@")
+		entries = append(entries, CatalogEntry{
+			Name:        name,
+			Version:     version,
+			Fingerprint: fp,
+			Path:        p,
+			Title:       s.Title,
+			Description: s.Description,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Fingerprint returns a hex-encoded SHA-256 digest of the canonical
+// JSON encoding of s. Schemas that differ only in object key order or
+// in the order of their required lists have the same fingerprint.
+func Fingerprint(s *jsonschema.Schema) (string, error) {
+	data, err := canonicalJSON(s)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJSON returns a stable JSON encoding of s.
+func canonicalJSON(s *jsonschema.Schema) ([]byte, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var a any
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+	sortRequired(a)
+	// Marshaling a map sorts its keys.
+	return json.Marshal(a)
+}
+
+// sortRequired sorts the "required" lists of the decoded JSON schema a
+// and of its subschemas, but not lists of that name in instance data,
+// such as defaults and examples.
+func sortRequired(a any) {
+	forEachValueSubschema(a, "", func(sub any, _, _ string) error {
+		if m, ok := sub.(map[string]any); ok {
+			if req, ok := m["required"].([]any); ok {
+				slices.SortFunc(req, func(x, y any) int {
+					return strings.Compare(fmt.Sprint(x), fmt.Sprint(y))
+				})
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestBuildCatalog(t *testing.T) {
+	fsys := fstest.MapFS{
+		"billing/invoice@2.yaml": {Data: []byte("id: string\ntotal: number")},
+		"user.json":              {Data: []byte(`{"title": "User", "description": "a user", "type": "object"}`)},
+		"README.md":              {Data: []byte("# schemas")},
+	}
+	got, err := BuildCatalog(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CatalogEntry{
+		{Name: "billing/invoice", Version: "2", Path: "billing/invoice@2.yaml"},
+		{Name: "user", Path: "user.json", Title: "User", Description: "a user"},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(CatalogEntry{}, "Fingerprint")); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	for _, e := range got {
+		if len(e.Fingerprint) != 64 {
+			t.Errorf("%s: bad fingerprint %q", e.Path, e.Fingerprint)
+		}
+	}
+}
+
+func TestFingerprintStable(t *testing.T) {
+	val := map[string]any{"a": "string", "b": "string", "c": "string", "d": "string"}
+	var first string
+	for i := 0; i < 10; i++ {
+		s, err := ToJSONSchema(val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 1 {
+			// The order of required lists does not matter.
+			slices.Reverse(s.Required)
+		}
+		fp, err := Fingerprint(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = fp
+		} else if fp != first {
+			t.Fatalf("fingerprint changed: %s != %s", fp, first)
+		}
+	}
+}
+
+func TestFingerprintInstanceData(t *testing.T) {
+	// Lists named required in defaults are data, whose order matters.
+	fp := func(required ...any) string {
+		s := mustSchema(t, "form: any")
+		s.Properties.Value("form").Default = map[string]any{"required": required}
+		got, err := Fingerprint(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	if fp("a", "b") == fp("b", "a") {
+		t.Error("defaults that differ in the order of a list have the same fingerprint")
+	}
+}
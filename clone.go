@@ -0,0 +1,144 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"slices"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// Clone returns a deep copy of s: a copy that shares no subschemas,
+// properties, keyword values or extension keywords with s, so that
+// either may be modified without affecting the other. The order of
+// the properties is kept. Schemas returned by this package may be
+// shared, as by a Registry or an HTTPCache, and must be cloned before
+// they are modified.
+func Clone(s *jsonschema.Schema) *jsonschema.Schema {
+	return cloneSchema(s)
+}
+
+// cloneSchema implements Clone.
+func cloneSchema(s *jsonschema.Schema) *jsonschema.Schema {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.Definitions = cloneSchemaMap(s.Definitions)
+	c.AllOf = cloneSchemaList(s.AllOf)
+	c.AnyOf = cloneSchemaList(s.AnyOf)
+	c.OneOf = cloneSchemaList(s.OneOf)
+	c.Not = cloneSchema(s.Not)
+	c.If = cloneSchema(s.If)
+	c.Then = cloneSchema(s.Then)
+	c.Else = cloneSchema(s.Else)
+	c.DependentSchemas = cloneSchemaMap(s.DependentSchemas)
+	c.PrefixItems = cloneSchemaList(s.PrefixItems)
+	c.Items = cloneSchema(s.Items)
+	c.Contains = cloneSchema(s.Contains)
+	if s.Properties != nil {
+		c.Properties = orderedmap.New[string, *jsonschema.Schema]()
+		for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+			c.Properties.Set(p.Key, cloneSchema(p.Value))
+		}
+	}
+	c.PatternProperties = cloneSchemaMap(s.PatternProperties)
+	c.AdditionalProperties = cloneSchema(s.AdditionalProperties)
+	c.PropertyNames = cloneSchema(s.PropertyNames)
+	c.ContentSchema = cloneSchema(s.ContentSchema)
+	c.Enum = cloneValues(s.Enum)
+	c.Const = copyValue(s.Const)
+	c.Default = copyValue(s.Default)
+	c.Examples = cloneValues(s.Examples)
+	c.Required = slices.Clone(s.Required)
+	if s.DependentRequired != nil {
+		c.DependentRequired = make(map[string][]string, len(s.DependentRequired))
+		for k, req := range s.DependentRequired {
+			c.DependentRequired[k] = slices.Clone(req)
+		}
+	}
+	for _, p := range []**uint64{&c.MaxLength, &c.MinLength, &c.MaxItems, &c.MinItems,
+		&c.MaxContains, &c.MinContains, &c.MaxProperties, &c.MinProperties} {
+		if *p != nil {
+			n := **p
+			*p = &n
+		}
+	}
+	if s.Extras != nil {
+		c.Extras = make(map[string]any, len(s.Extras))
+		for k, v := range s.Extras {
+			c.Extras[k] = copyValue(v)
+		}
+	}
+	return &c
+}
+
+func cloneValues(vs []any) []any {
+	if vs == nil {
+		return nil
+	}
+	ret := make([]any, len(vs))
+	for i, v := range vs {
+		ret[i] = copyValue(v)
+	}
+	return ret
+}
+
+func cloneSchemaList(ss []*jsonschema.Schema) []*jsonschema.Schema {
+	if ss == nil {
+		return nil
+	}
+	ret := make([]*jsonschema.Schema, len(ss))
+	for i, s := range ss {
+		ret[i] = cloneSchema(s)
+	}
+	return ret
+}
+
+func cloneSchemaMap[M ~map[string]*jsonschema.Schema](m M) M {
+	if m == nil {
+		return nil
+	}
+	ret := make(M, len(m))
+	for k, s := range m {
+		ret[k] = cloneSchema(s)
+	}
+	return ret
+}
+
+// copyValue returns a deep copy of v, a value decoded from JSON or YAML
+// or a keyword value of a schema.
+func copyValue(v any) any {
+	switch v := v.(type) {
+	case *jsonschema.Schema:
+		return cloneSchema(v)
+	case []string:
+		return slices.Clone(v)
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for k, e := range v {
+			c[k] = copyValue(e)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, e := range v {
+			c[i] = copyValue(e)
+		}
+		return c
+	}
+	return v
+}
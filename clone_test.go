@@ -0,0 +1,191 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// TestConcurrentUse runs the package's functions on a shared schema
+// from several goroutines. Run it with -race.
+func TestConcurrentUse(t *testing.T) {
+	shared := mustSchema(t, `
+name: string, the name
+age?(unit=years, audience=internal): integer
+role(enum): [admin, user]
+tags(array): string
+address?:
+  street: string
+  zip?: string
+`)
+	before := schemaJSON(t, shared)
+	var reg Registry
+	instance := map[string]any{"name": "a", "role": "user", "tags": []any{"x"}}
+	openai, _ := LookupProfile("openai")
+
+	ops := []func(i int) error{
+		func(int) error { _, err := Fingerprint(shared); return err },
+		func(int) error { _, err := FromJSONSchema(shared); return err },
+		func(int) error { return Validate(instance, shared) },
+		func(int) error { Coerce(instance, shared); return nil },
+		func(int) error { _, _, err := AdaptToProfile(shared, openai); return err },
+		func(int) error { _, _, err := DowngradeToDraft07(shared); return err },
+		func(int) error { _, err := RenderPrompt(shared); return err },
+		func(int) error { _, err := Sample(shared); return err },
+		func(int) error { _, err := EstimateTokens(shared, ApproxTokenizer); return err },
+		func(int) error { Lint(shared); View(shared, "internal"); Localize(shared, "fr"); return nil },
+		func(int) error { Project(shared, []string{"name"}, nil); ScrubSecrets(shared); return nil },
+		func(int) error { Clone(shared).Properties.Set("extra", &jsonschema.Schema{}); return nil },
+		func(i int) error { return reg.Register(fmt.Sprint("s", i), shared) },
+		func(i int) error {
+			RegisterProfile(&Profile{Name: fmt.Sprint("concurrent", i%4), UnsupportedKeywords: []string{"enum"}})
+			p, _ := LookupProfile(fmt.Sprint("concurrent", i%4))
+			_, err := EstimateBytes(shared, p)
+			return err
+		},
+		func(i int) error {
+			RegisterScalar(fmt.Sprint("concurrent", i%4), &jsonschema.Schema{Type: "string", Pattern: "^concurrent$"})
+			_, err := ParseYAMLString(fmt.Sprintf("a: concurrent%d\nb: customer", i%4), WithResolver(SchemaResolverFunc(func(string) (*jsonschema.Schema, error) {
+				return shared, nil
+			})))
+			return err
+		},
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 8*len(ops))
+	for i := range 8 {
+		for _, op := range ops {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := op(i); err != nil {
+					errs <- err
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if after := schemaJSON(t, shared); fmt.Sprint(after) != fmt.Sprint(before) {
+		t.Errorf("shared schema was modified:\nbefore: %v\nafter:  %v", before, after)
+	}
+}
+
+func TestClone(t *testing.T) {
+	s := mustSchema(t, `
+name: string
+tags(array): string
+address?:
+  street: string
+`)
+	s.Required = append(s.Required[:0:0], "name", "tags")
+	s.Extras = map[string]any{"x-owner": "billing"}
+	want := schemaJSON(t, s)
+
+	c := Clone(s)
+	if diff := cmp.Diff(want, schemaJSON(t, c)); diff != "" {
+		t.Errorf("clone differs (-want, +got):\n%s", diff)
+	}
+	c.Properties.Value("address").Properties.Set("city", &jsonschema.Schema{Type: "string"})
+	c.Properties.Value("tags").Items.Type = "integer"
+	c.Required[0] = "changed"
+	c.Extras["x-owner"] = "search"
+	c.Properties.Delete("name")
+	if diff := cmp.Diff(want, schemaJSON(t, s)); diff != "" {
+		t.Errorf("modifying the clone changed the original (-want, +got):\n%s", diff)
+	}
+	if Clone(nil) != nil {
+		t.Error("Clone(nil) is not nil")
+	}
+}
+
+func TestCloneDeep(t *testing.T) {
+	n := uint64(3)
+	sub := func(typ string) *jsonschema.Schema { return &jsonschema.Schema{Type: typ} }
+	s := &jsonschema.Schema{
+		Definitions:       jsonschema.Definitions{"d": sub("string")},
+		AllOf:             []*jsonschema.Schema{sub("object")},
+		AnyOf:             []*jsonschema.Schema{sub("string")},
+		OneOf:             []*jsonschema.Schema{sub("string")},
+		Not:               sub("null"),
+		If:                sub("object"),
+		Then:              sub("object"),
+		Else:              sub("object"),
+		DependentSchemas:  map[string]*jsonschema.Schema{"a": sub("object")},
+		PrefixItems:       []*jsonschema.Schema{sub("string")},
+		Items:             sub("string"),
+		Contains:          sub("string"),
+		Properties:        orderedmap.New[string, *jsonschema.Schema](),
+		PatternProperties: map[string]*jsonschema.Schema{"^x": sub("string")},
+		PropertyNames:     sub("string"),
+		ContentSchema:     sub("string"),
+		Enum:              []any{map[string]any{"k": []any{1}}},
+		Const:             map[string]any{"k": "v"},
+		Default:           []any{map[string]any{"k": "v"}},
+		Examples:          []any{map[string]any{"k": "v"}},
+		MaxLength:         &n,
+		MinItems:          &n,
+		Required:          []string{"a"},
+		DependentRequired: map[string][]string{"a": {"b"}},
+		Extras:            map[string]any{"x-meta": map[string]any{"tags": []any{"t"}}},
+	}
+	s.Properties.Set("z", sub("string"))
+	s.Properties.Set("a", sub("integer"))
+	s.Properties.Set("m", sub("number"))
+	want := schemaJSON(t, s)
+
+	c := Clone(s)
+	if diff := cmp.Diff(want, schemaJSON(t, c)); diff != "" {
+		t.Fatalf("clone differs (-want, +got):\n%s", diff)
+	}
+	var keys []string
+	for p := c.Properties.Oldest(); p != nil; p = p.Next() {
+		keys = append(keys, p.Key)
+	}
+	if !cmp.Equal(keys, []string{"z", "a", "m"}) {
+		t.Errorf("clone has properties in order %v, want [z a m]", keys)
+	}
+
+	c.Definitions["d"].Type = "integer"
+	for _, sub := range []*jsonschema.Schema{c.AllOf[0], c.AnyOf[0], c.OneOf[0], c.Not, c.If, c.Then, c.Else,
+		c.DependentSchemas["a"], c.PrefixItems[0], c.Items, c.Contains, c.Properties.Value("z"),
+		c.PatternProperties["^x"], c.PropertyNames, c.ContentSchema} {
+		sub.Type = "boolean"
+	}
+	c.Enum[0].(map[string]any)["k"].([]any)[0] = 2
+	c.Const.(map[string]any)["k"] = "changed"
+	c.Default.([]any)[0].(map[string]any)["k"] = "changed"
+	c.Examples[0].(map[string]any)["k"] = "changed"
+	*c.MaxLength = 9
+	*c.MinItems = 9
+	c.DependentRequired["a"][0] = "changed"
+	c.Extras["x-meta"].(map[string]any)["tags"].([]any)[0] = "changed"
+	if diff := cmp.Diff(want, schemaJSON(t, s)); diff != "" {
+		t.Errorf("modifying the clone changed the original (-want, +got):\n%s", diff)
+	}
+
+	if b := Clone(jsonschema.FalseSchema); schemaJSON(t, b) != false {
+		t.Errorf("Clone(FalseSchema) = %v, want false", schemaJSON(t, b))
+	}
+}
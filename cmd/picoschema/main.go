@@ -0,0 +1,286 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Picoschema converts picoschema YAML to JSON Schema, or back.
+//
+// Usage:
+//
+//	picoschema [-from format] [-pretty] [-o file] [file]
+//	picoschema [-from format] [-pretty] -o dir dir
+//	picoschema verify file...
+//
+// It reads the named file, or standard input if there is none, and
+// writes the result to standard output or to the file given by -o.
+// Properties are written in the order of their names, so that the
+// same input always gives the same output.
+//
+// The -from flag names the format of the input: picoschema, the
+// default, or an import format registered with the picoschema package,
+// such as jsonschema, sample or typescript. Input in an import format
+// is converted to picoschema YAML, and what cannot be written in
+// picoschema is reported on standard error, naming the JSON Pointer of
+// each part that is embedded as JSON Schema or dropped.
+//
+// Given a directory, it converts every file in the directory tree with
+// an extension of the input format (.yaml or .yml for picoschema, .json
+// for jsonschema and sample, .ts for typescript, and the name of the
+// format otherwise), writing each result to the same relative path
+// under the directory given by -o, with the extension of the output
+// format. It converts as many files as it can and reports
+// every failure.
+//
+// The verify subcommand checks that each named picoschema file
+// round-trips: that converting it to JSON Schema, back to picoschema
+// and to JSON Schema again gives the same schema. It reports what is
+// lost or changed on the way, and exits with
+// a non-zero status if any file does not round-trip, so that it can
+// serve as a pre-commit hook.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+	"github.com/jumonapp/picoschema"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// A format is an input format of the command.
+type format struct {
+	exts   []string // extensions of input files, in directory mode
+	outExt string   // extension of output files, in directory mode
+	// convert converts data, returning the output
+	// and notes on what the conversion lost.
+	convert func(data []byte, pretty bool) ([]byte, []string, error)
+}
+
+// importExts maps import formats to the extensions of their input
+// files, for those whose extension is not their name.
+var importExts = map[string][]string{
+	"jsonschema": {".json"},
+	"sample":     {".json"},
+	"typescript": {".ts"},
+}
+
+// lookupFormat returns the format named by the -from flag value name:
+// picoschema, or a format registered with picoschema.RegisterImporter.
+func lookupFormat(name string) (format, bool) {
+	if name == "picoschema" {
+		return format{[]string{".yaml", ".yml"}, ".json", fromPicoschema}, true
+	}
+	if _, ok := picoschema.LookupImporter(name); !ok {
+		return format{}, false
+	}
+	exts, ok := importExts[name]
+	if !ok {
+		exts = []string{"." + name}
+	}
+	return format{exts, ".yaml", toPicoschema(name)}, true
+}
+
+// formatNames returns the values of the -from flag, sorted.
+func formatNames() []string {
+	names := picoschema.Importers()
+	if !slices.Contains(names, "picoschema") {
+		names = append(names, "picoschema")
+		slices.Sort(names)
+	}
+	return names
+}
+
+// run runs the command with the arguments args.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) > 0 && args[0] == "verify" {
+		return verify(args[1:], stdout, stderr)
+	}
+	fset := flag.NewFlagSet("picoschema", flag.ContinueOnError)
+	fset.SetOutput(stderr)
+	from := fset.String("from", "picoschema", "the `format` of the input: "+strings.Join(formatNames(), ", "))
+	pretty := fset.Bool("pretty", false, "indent the JSON output")
+	out := fset.String("o", "", "write the output to `file` instead of standard output")
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "usage: picoschema [-from format] [-pretty] [-o file] [file]\n")
+		fmt.Fprintf(fset.Output(), "       picoschema [-from format] [-pretty] -o dir dir\n")
+		fmt.Fprintf(fset.Output(), "       picoschema verify file...\n")
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() > 1 {
+		fset.Usage()
+		return fmt.Errorf("picoschema: too many arguments")
+	}
+	f, ok := lookupFormat(*from)
+	if !ok {
+		return fmt.Errorf("picoschema: unknown -from format %q, want one of %s", *from, strings.Join(formatNames(), ", "))
+	}
+
+	if fset.NArg() == 1 {
+		if info, err := os.Stat(fset.Arg(0)); err == nil && info.IsDir() {
+			if *out == "" {
+				return fmt.Errorf("picoschema: -o is required to convert directory %s", fset.Arg(0))
+			}
+			return convertDir(f, fset.Arg(0), *out, *pretty, stderr)
+		}
+	}
+
+	var data []byte
+	var err error
+	if fset.NArg() == 1 {
+		data, err = os.ReadFile(fset.Arg(0))
+	} else {
+		data, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		return err
+	}
+	res, notes, err := f.convert(data, *pretty)
+	name := fset.Arg(0)
+	if name == "" {
+		name = "<stdin>"
+	}
+	for _, n := range notes {
+		fmt.Fprintf(stderr, "%s: %s\n", name, n)
+	}
+	if err != nil {
+		if fset.NArg() == 1 {
+			return fmt.Errorf("%s: %w", fset.Arg(0), err)
+		}
+		return err
+	}
+	if *out != "" {
+		return os.WriteFile(*out, res, 0o644)
+	}
+	_, err = stdout.Write(res)
+	return err
+}
+
+// convertDir converts the files in the tree rooted at dir
+// that have an extension of f, writing the results under outDir.
+func convertDir(f format, dir, outDir string, pretty bool, stderr io.Writer) error {
+	var errs []error
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := filepath.Ext(path)
+		if d.IsDir() || !slices.Contains(f.exts, ext) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		res, notes, err := f.convert(data, pretty)
+		for _, n := range notes {
+			fmt.Fprintf(stderr, "%s: %s\n", path, n)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(outDir, strings.TrimSuffix(rel, ext)+f.outExt)
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, res, 0o644); err != nil {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// fromPicoschema converts picoschema YAML to JSON Schema.
+func fromPicoschema(data []byte, pretty bool) ([]byte, []string, error) {
+	s, err := picoschema.ParseYAML(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var js []byte
+	if pretty {
+		js, err = json.MarshalIndent(s, "", "  ")
+	} else {
+		js, err = json.Marshal(s)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(js, '\n'), nil, nil
+}
+
+// toPicoschema returns a function that converts input
+// in the import format name to picoschema YAML.
+func toPicoschema(name string) func([]byte, bool) ([]byte, []string, error) {
+	return func(data []byte, _ bool) ([]byte, []string, error) {
+		s, err := picoschema.Import(name, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return fromJSONSchema(s)
+	}
+}
+
+// fromJSONSchema converts s to picoschema YAML.
+func fromJSONSchema(s *jsonschema.Schema) ([]byte, []string, error) {
+	v, report, err := picoschema.FromJSONSchemaReport(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	var notes []string
+	for _, p := range report.Embedded {
+		notes = append(notes, "embedded as JSON Schema: "+pointer(p))
+	}
+	for _, p := range report.Dropped {
+		notes = append(notes, "dropped: "+pointer(p))
+	}
+	res, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, notes, err
+	}
+	return res, notes, nil
+}
+
+// pointer returns the JSON Pointer p, writing the root as "/".
+func pointer(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return p
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/invopop/jsonschema"
+	"github.com/jumonapp/picoschema"
+)
+
+func TestRun(t *testing.T) {
+	const src = "name?: string, the name\n"
+	const want = `{"properties":{"name":{"type":"string","description":"the name"}},"additionalProperties":false,"type":"object"}` + "\n"
+
+	var stdout bytes.Buffer
+	if err := run(nil, strings.NewReader(src), &stdout, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != want {
+		t.Errorf("stdin to stdout: got %s, want %s", got, want)
+	}
+
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "in.yaml"), filepath.Join(dir, "out.json")
+	if err := os.WriteFile(in, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if err := run([]string{"-pretty", "-o", out, in}, strings.NewReader(""), &stdout, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(got, []byte("\n  \"type\": \"object\"\n}\n")) || stdout.Len() != 0 {
+		t.Errorf("file to pretty file: got %q and stdout %q", got, stdout.String())
+	}
+
+	if err := os.WriteFile(in, []byte("name: strng\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = run([]string{in}, strings.NewReader(""), &stdout, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "in.yaml: picoschema: line 1, column 7") {
+		t.Errorf("got error %v, want one naming the file and position", err)
+	}
+}
+
+func TestRunDeterministic(t *testing.T) {
+	const src = "zip: string\ncity: string\nstreet: {number: integer, name: string}\ncountry?: string\nbuilding: string\n"
+	const want = `{"properties":{"building":{"type":"string"},"city":{"type":"string"},"country":{"type":"string"},` +
+		`"street":{"properties":{"name":{"type":"string"},"number":{"type":"integer"}},"additionalProperties":false,"type":"object","required":["name","number"]},` +
+		`"zip":{"type":"string"}},"additionalProperties":false,"type":"object","required":["building","city","street","zip"]}` + "\n"
+	for range 20 {
+		var stdout bytes.Buffer
+		if err := run(nil, strings.NewReader(src), &stdout, io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if got := stdout.String(); got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestRunFromJSONSchema(t *testing.T) {
+	const src = `{"type": "object", "title": "Person", "required": ["name"],
+		"properties": {"name": {"type": "string", "description": "the name"}}}`
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"-from", "jsonschema"}, strings.NewReader(src), &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "(*): any\nname: string, the name\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := stderr.String(), "<stdin>: dropped: /title\n"; got != want {
+		t.Errorf("got notes %q, want %q", got, want)
+	}
+
+	stdout.Reset()
+	const ts = "interface Tag { label: string }\ninterface Post { tags: Tag[] }\n"
+	if err := run([]string{"-from", "typescript"}, strings.NewReader(ts), &stdout, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "$defs:\n    Tag:\n        label: string\ntags(array): Tag\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	stdout.Reset()
+	if err := run([]string{"-from", "sample"}, strings.NewReader(`{"id": 1}`), &stdout, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "id: integer\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	err := run([]string{"-from", "xml"}, strings.NewReader(src), &stdout, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "picoschema, sample, typescript") {
+		t.Errorf("got error %v, want one listing the formats", err)
+	}
+}
+
+func TestRunFromRegisteredImporter(t *testing.T) {
+	picoschema.RegisterImporter("cmdtest", picoschema.ImporterFunc(func(data []byte) (*jsonschema.Schema, error) {
+		return picoschema.ToJSONSchema(map[string]any{string(data): "string"})
+	}))
+	var stdout bytes.Buffer
+	if err := run([]string{"-from", "cmdtest"}, strings.NewReader("code"), &stdout, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "code: string\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRunDirectory(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		"a.json":        `{"type": "object", "properties": {"id": {"type": "integer"}}, "required": ["id"], "additionalProperties": false}`,
+		"sub/b.json":    `{"type": "object", "properties": {"tag": {"type": "string"}}, "additionalProperties": false}`,
+		"sub/bad.json":  `{"type": `,
+		"sub/notes.txt": "not a schema",
+	}
+	for name, data := range files {
+		path := filepath.Join(in, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := run([]string{"-from", "jsonschema", "-o", out, in}, strings.NewReader(""), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "bad.json") {
+		t.Errorf("got error %v, want one naming bad.json", err)
+	}
+	for name, want := range map[string]string{
+		"a.yaml":     "id: integer\n",
+		"sub/b.yaml": "tag?: string\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "sub", "notes.yaml")); err == nil {
+		t.Error("converted a file with another extension")
+	}
+
+	if err := run([]string{in}, strings.NewReader(""), io.Discard, io.Discard); err == nil {
+		t.Error("directory converted without -o")
+	}
+}
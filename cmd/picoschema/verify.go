@@ -0,0 +1,192 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+	"github.com/jumonapp/picoschema"
+	"gopkg.in/yaml.v3"
+)
+
+// verify runs the verify subcommand with the arguments args.
+// It checks that each named picoschema file round-trips: that
+// converting it to JSON Schema, back to picoschema, and to JSON
+// Schema again gives the same schema, with nothing dropped on the way.
+// Parts written back as JSON Schema, with the parenthetical type
+// jsonschema, are not problems as long as the schema is the same.
+func verify(args []string, stdout, stderr io.Writer) error {
+	fset := flag.NewFlagSet("picoschema verify", flag.ContinueOnError)
+	fset.SetOutput(stderr)
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "usage: picoschema verify file...\n")
+	}
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() == 0 {
+		fset.Usage()
+		return fmt.Errorf("picoschema: no files to verify")
+	}
+	var errs []error
+	for _, name := range fset.Args() {
+		problems, err := verifyFile(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		for _, p := range problems {
+			fmt.Fprintf(stderr, "%s: %s\n", name, p)
+		}
+		if len(problems) > 0 {
+			errs = append(errs, fmt.Errorf("%s: does not round-trip", name))
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: ok\n", name)
+	}
+	return errors.Join(errs...)
+}
+
+// verifyFile round-trips the picoschema file name and returns
+// the problems it finds.
+func verifyFile(name string) ([]string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	first, err := picoschema.ParseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	v, report, err := picoschema.FromJSONSchemaReport(first)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, p := range report.Dropped {
+		problems = append(problems, "lost: "+pointer(p))
+	}
+	back, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	second, err := picoschema.ParseYAML(back)
+	if err != nil {
+		return nil, fmt.Errorf("converting back: %w", err)
+	}
+	if p, ok, err := firstDifference(first, second); err != nil {
+		return nil, err
+	} else if ok {
+		problems = append(problems, "changed: "+pointer(p))
+	}
+	return problems, nil
+}
+
+// firstDifference reports whether schemas a and b differ beyond
+// the order of object keys and required lists, and if so returns the
+// JSON Pointer of the first difference.
+func firstDifference(a, b *jsonschema.Schema) (string, bool, error) {
+	fa, err := picoschema.Fingerprint(a)
+	if err != nil {
+		return "", false, err
+	}
+	fb, err := picoschema.Fingerprint(b)
+	if err != nil {
+		return "", false, err
+	}
+	if fa == fb {
+		return "", false, nil
+	}
+	da, err := decode(a)
+	if err != nil {
+		return "", false, err
+	}
+	db, err := decode(b)
+	if err != nil {
+		return "", false, err
+	}
+	return diffPath(da, db, ""), true, nil
+}
+
+// decode returns the decoded JSON encoding of s.
+func decode(s *jsonschema.Schema) (any, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
+
+// diffPath returns the JSON Pointer, relative to path, of the first
+// place where the decoded JSON values a and b differ.
+func diffPath(a, b any, path string) string {
+	ma, aok := a.(map[string]any)
+	mb, bok := b.(map[string]any)
+	if !aok || !bok {
+		return path
+	}
+	var keys []string
+	for k := range ma {
+		keys = append(keys, k)
+	}
+	for k := range mb {
+		if _, ok := ma[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		va, vb := ma[k], mb[k]
+		if k == "required" {
+			va, vb = sortedList(va), sortedList(vb)
+		}
+		if !reflect.DeepEqual(va, vb) {
+			return diffPath(va, vb, path+"/"+pointerEscaper.Replace(k))
+		}
+	}
+	return path
+}
+
+// pointerEscaper escapes a JSON Pointer reference token.
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// sortedList returns a sorted copy of v if it is a list of strings.
+func sortedList(v any) any {
+	list, ok := v.([]any)
+	if !ok {
+		return v
+	}
+	strs := make([]string, 0, len(list))
+	for _, e := range list {
+		s, ok := e.(string)
+		if !ok {
+			return v
+		}
+		strs = append(strs, s)
+	}
+	slices.Sort(strs)
+	return strs
+}
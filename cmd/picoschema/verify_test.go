@@ -0,0 +1,69 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	good, embedded, broken := filepath.Join(dir, "good.yaml"), filepath.Join(dir, "embedded.yaml"), filepath.Join(dir, "broken.yaml")
+	for name, src := range map[string]string{
+		good:     "name: string, the name\ntags?(array, labels): string\nn?(integer, minimum=0):\n",
+		embedded: "name: string\nscore(jsonschema): {type: number, title: Score}\n",
+		broken:   "name: strng\n",
+	} {
+		if err := os.WriteFile(name, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"verify", good, embedded}, strings.NewReader(""), &stdout, &stderr); err != nil {
+		t.Fatalf("verify %s %s: %v", good, embedded, err)
+	}
+	if got, want := stdout.String(), good+": ok\n"+embedded+": ok\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if stderr.Len() > 0 {
+		t.Errorf("got problems %q, want none", stderr.String())
+	}
+
+	stdout.Reset()
+	err := run([]string{"verify", good, broken}, strings.NewReader(""), &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "broken.yaml: picoschema:") {
+		t.Errorf("got error %v, want one naming broken.yaml", err)
+	}
+	if got, want := stdout.String(), good+": ok\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if err := run([]string{"verify"}, strings.NewReader(""), &stdout, &stderr); err == nil {
+		t.Error("verify with no files succeeded")
+	}
+}
+
+func TestDiffPath(t *testing.T) {
+	a := map[string]any{"properties": map[string]any{"a/b": map[string]any{"type": "string"}}, "required": []any{"x", "y"}}
+	b := map[string]any{"properties": map[string]any{"a/b": map[string]any{"type": "integer"}}, "required": []any{"y", "x"}}
+	if got, want := diffPath(a, b, ""), "/properties/a~1b/type"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// enumAliasesKey is the annotation mapping canonical enum values to
+// lists of aliases. In picoschema an enum member with aliases is
+// written as a single-key map, as in
+//
+//	country(enum): [{US: [USA, United States]}, CA]
+const enumAliasesKey = "x-enumAliases"
+
+// normalizeKey is the annotation listing the string normalizations
+// Coerce applies to a value, in order. In picoschema they are written
+// as flags, as in "email(trim, lowercase): string". The normalizations
+// are trim, lowercase, uppercase and collapse-whitespace.
+const normalizeKey = "x-normalize"
+
+// Coerce coerces instance with the default Coercer.
+func Coerce(instance any, s *jsonschema.Schema) any {
+	var c Coercer
+	return c.Coerce(instance, s)
+}
+
+// CoerceAndCheck coerces instance with the default Coercer
+// and validates the result against s.
+func CoerceAndCheck(instance any, s *jsonschema.Schema) (any, []Violation) {
+	var c Coercer
+	return c.CoerceAndCheck(instance, s)
+}
+
+// A Coercer repairs near misses in model output that can be fixed
+// mechanically, so that the output is more likely to validate.
+// The zero Coercer applies the string normalizations declared in the
+// schema and replaces enum aliases with their canonical values.
+type Coercer struct {
+	// DisableEnumAliases turns off the replacement of enum aliases.
+	DisableEnumAliases bool
+
+	// FoldEnumCase matches strings against enum members and aliases
+	// ignoring case and surrounding whitespace, replacing them with
+	// the canonical member.
+	FoldEnumCase bool
+
+	// ConvertUnits converts strings such as "2s" or "1.5 kg" in numeric
+	// properties with a unit annotation to numbers in that unit.
+	ConvertUnits bool
+}
+
+// Coerce returns a copy of instance, a document decoded from JSON or
+// YAML, normalized according to the annotations of s.
+func (c *Coercer) Coerce(instance any, s *jsonschema.Schema) any {
+	return c.coerce(copyValue(instance), s)
+}
+
+// CoerceAndCheck coerces instance and validates the result against s.
+func (c *Coercer) CoerceAndCheck(instance any, s *jsonschema.Schema) (any, []Violation) {
+	v := c.Coerce(instance, s)
+	return v, Check(v, s)
+}
+
+func (c *Coercer) coerce(v any, s *jsonschema.Schema) any {
+	if s == nil {
+		return v
+	}
+	if _, ok := boolSchema(s); ok {
+		return v
+	}
+	v = normalizeString(v, s)
+	if unit, ok := s.Extras[unitKey].(string); ok && c.ConvertUnits {
+		v = coerceUnit(v, unit, s.Type)
+	}
+	v = c.coerceEnum(v, s)
+	for _, sub := range s.AllOf {
+		v = c.coerce(v, sub)
+	}
+	switch inst := v.(type) {
+	case map[string]any:
+		for k, e := range inst {
+			inst[k] = c.coerce(e, propertySchema(s, k))
+		}
+	case []any:
+		for i, e := range inst {
+			inst[i] = c.coerce(e, itemSchema(s, i))
+		}
+	}
+	return v
+}
+
+// coerceEnum replaces v with its canonical enum value,
+// if it is an alias or differs from a member only in case.
+func (c *Coercer) coerceEnum(v any, s *jsonschema.Schema) any {
+	if s.Enum == nil || slicesContainsEqual(s.Enum, v) {
+		return v
+	}
+	if c.FoldEnumCase {
+		if str, ok := v.(string); ok {
+			for _, m := range s.Enum {
+				if ms, ok := m.(string); ok && foldEqual(str, ms) {
+					return m
+				}
+			}
+		}
+	}
+	if c.DisableEnumAliases {
+		return v
+	}
+	if canon, ok := findEnumAlias(s, v, c.FoldEnumCase); ok {
+		return canon
+	}
+	return v
+}
+
+// normalizeString applies the normalizations declared by s to v,
+// if it is a string.
+func normalizeString(v any, s *jsonschema.Schema) any {
+	str, ok := v.(string)
+	if !ok {
+		return v
+	}
+	ns, _ := s.Extras[normalizeKey].([]any)
+	for _, n := range ns {
+		switch n {
+		case "trim":
+			str = strings.TrimSpace(str)
+		case "lowercase":
+			str = strings.ToLower(str)
+		case "uppercase":
+			str = strings.ToUpper(str)
+		case "collapse-whitespace":
+			str = strings.Join(strings.Fields(str), " ")
+		}
+	}
+	return str
+}
+
+// enumAliasOf returns the canonical enum value of which v is an alias.
+func enumAliasOf(s *jsonschema.Schema, v any) (string, bool) {
+	return findEnumAlias(s, v, false)
+}
+
+// findEnumAlias returns the canonical enum value of which v is an alias,
+// comparing strings with foldEqual if fold is set.
+func findEnumAlias(s *jsonschema.Schema, v any, fold bool) (string, bool) {
+	aliases, _ := s.Extras[enumAliasesKey].(map[string]any)
+	str, isStr := v.(string)
+	for _, canon := range sortedKeys(aliases) {
+		as, _ := aliases[canon].([]any)
+		for _, a := range as {
+			if jsonEqual(a, v) {
+				return canon, true
+			}
+			if astr, ok := a.(string); ok && fold && isStr && foldEqual(str, astr) {
+				return canon, true
+			}
+		}
+	}
+	return "", false
+}
+
+// foldEqual reports whether s and t are equal ignoring case
+// and surrounding whitespace.
+func foldEqual(s, t string) bool {
+	return strings.EqualFold(strings.TrimSpace(s), strings.TrimSpace(t))
+}
+
+// propertySchema returns the schema that applies to property k of an
+// object validated by s, or nil if there is none.
+func propertySchema(s *jsonschema.Schema, k string) *jsonschema.Schema {
+	if s.Properties != nil {
+		if ps, ok := s.Properties.Get(k); ok {
+			return ps
+		}
+	}
+	for pat, ps := range s.PatternProperties {
+		if re, err := compilePattern(pat); err == nil && re.MatchString(k) {
+			return ps
+		}
+	}
+	return s.AdditionalProperties
+}
+
+// itemSchema returns the schema that applies to item i of an array
+// validated by s, or nil if there is none.
+func itemSchema(s *jsonschema.Schema, i int) *jsonschema.Schema {
+	if i < len(s.PrefixItems) {
+		return s.PrefixItems[i]
+	}
+	return s.Items
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCoerceEnumAliases(t *testing.T) {
+	s := mustSchema(t, `
+country(enum): [{US: [USA, United States]}, CA]
+visited(array):
+  country(enum): [{US: [USA]}, {CA: [Canada]}]
+`)
+	in := map[string]any{
+		"country": "United States",
+		"visited": []any{map[string]any{"country": "Canada"}, map[string]any{"country": "US"}},
+	}
+	if vs := Check(in, s); len(vs) != 2 {
+		t.Errorf("got %d violations before coercion, want 2: %v", len(vs), vs)
+	}
+	got, vs := CoerceAndCheck(in, s)
+	if len(vs) != 0 {
+		t.Errorf("got violations after coercion: %v", vs)
+	}
+	want := map[string]any{
+		"country": "US",
+		"visited": []any{map[string]any{"country": "CA"}, map[string]any{"country": "US"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if in["country"] != "United States" {
+		t.Error("Coerce modified its input")
+	}
+}
+
+func TestCoerceFoldEnumCase(t *testing.T) {
+	s := mustSchema(t, `
+country(enum): [{US: [USA]}, CA]
+`)
+	for _, test := range []struct {
+		c    Coercer
+		in   string
+		want string
+	}{
+		{Coercer{}, " ca ", " ca "},
+		{Coercer{FoldEnumCase: true}, " ca ", "CA"},
+		{Coercer{FoldEnumCase: true}, "usa", "US"},
+		{Coercer{FoldEnumCase: true, DisableEnumAliases: true}, "usa", "usa"},
+		{Coercer{DisableEnumAliases: true}, "USA", "USA"},
+	} {
+		got := test.c.Coerce(map[string]any{"country": test.in}, s).(map[string]any)["country"]
+		if got != test.want {
+			t.Errorf("%+v.Coerce(%q) = %q, want %q", test.c, test.in, got, test.want)
+		}
+	}
+}
+
+func TestCoerceNormalize(t *testing.T) {
+	s := mustSchema(t, `
+email(trim, lowercase): string
+title(collapse-whitespace, the title): string
+code?(object, uppercase, the code):
+  value: string
+`)
+	got := Coerce(map[string]any{
+		"email": "  Ann@Example.COM ",
+		"title": "  a   long\n title ",
+		"code":  map[string]any{"value": "keep Me"},
+	}, s)
+	want := map[string]any{
+		"email": "ann@example.com",
+		"title": "a long title",
+		"code":  map[string]any{"value": "keep Me"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	title, _ := s.Properties.Get("title")
+	if title.Description != "the title" {
+		t.Errorf("got description %q, want %q", title.Description, "the title")
+	}
+}
@@ -0,0 +1,430 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// Intersect returns the most restrictive schema common to a and b: one
+// that accepts exactly the values both accept. Keywords are combined
+// where they can be, as by taking the larger minimum, the common enum
+// values and the properties of both objects, with properties that one
+// object does not allow removed from the other. Keywords whose values
+// cannot be combined, such as two different patterns, are kept side by
+// side, b's under allOf. The annotations of a, such as its description,
+// take precedence over those of b.
+//
+// Intersect fails if the schemas have no values in common, as when
+// their types differ or one requires a property the other does not
+// allow. Neither a nor b is modified.
+func Intersect(a, b *jsonschema.Schema) (*jsonschema.Schema, error) {
+	return intersect(a, b, "")
+}
+
+// Subtract returns a copy of a without what b declares, as when a
+// request schema is derived from a response schema by removing the
+// fields the server fills in. Each property of b is removed from a,
+// unless both are objects with properties of their own, in which case
+// b's is subtracted from a's. A name in b's required list that b has no
+// property for is made optional in a. Other constraints of b, such as
+// maxLength or pattern, are removed from a where a has them with the
+// same value; the type and annotations of a are kept, as are its
+// additionalProperties. Neither a nor b is modified.
+func Subtract(a, b *jsonschema.Schema) *jsonschema.Schema {
+	c := cloneSchema(a)
+	subtract(c, b)
+	return c
+}
+
+// errNoMerge reports that two values of a keyword cannot be combined.
+var errNoMerge = errors.New("no merge")
+
+// objectKeywords and arrayKeywords are the keywords that
+// depend on each other, and so are intersected together.
+var (
+	objectKeywords = []string{"properties", "patternProperties", "additionalProperties"}
+	arrayKeywords  = []string{"prefixItems", "items"}
+)
+
+// intersect intersects a and b, which are at path.
+func intersect(a, b *jsonschema.Schema, path string) (*jsonschema.Schema, error) {
+	if a == nil {
+		return cloneSchema(b), nil
+	}
+	if b == nil {
+		return cloneSchema(a), nil
+	}
+	if v, ok := boolSchema(a); ok {
+		if v {
+			return cloneSchema(b), nil
+		}
+		return jsonschema.FalseSchema, nil
+	}
+	if v, ok := boolSchema(b); ok {
+		if v {
+			return cloneSchema(a), nil
+		}
+		return jsonschema.FalseSchema, nil
+	}
+	c, bc := cloneSchema(a), cloneSchema(b)
+	rc, rb := reflect.ValueOf(c).Elem(), reflect.ValueOf(bc).Elem()
+	var conflicts []string
+	for i := 0; i < rc.NumField(); i++ {
+		name, _, _ := strings.Cut(rc.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || slices.Contains(objectKeywords, name) || slices.Contains(arrayKeywords, name) {
+			continue
+		}
+		fc, fb := rc.Field(i), rb.Field(i)
+		if fb.IsZero() {
+			continue
+		}
+		if fc.IsZero() || reflect.DeepEqual(fc.Interface(), fb.Interface()) {
+			fc.Set(fb)
+			continue
+		}
+		err := intersectKeyword(c, bc, name)
+		if errors.Is(err, errNoMerge) {
+			conflicts = append(conflicts, name)
+		} else if err != nil {
+			return nil, intersectError(path, err)
+		}
+	}
+	for k, v := range bc.Extras {
+		if _, ok := c.Extras[k]; !ok {
+			setExtra(c, k, v)
+		}
+	}
+
+	if err := intersectObjects(c, a, b, path); errors.Is(err, errNoMerge) {
+		conflicts = append(conflicts, objectKeywords...)
+	} else if err != nil {
+		return nil, err
+	}
+	if err := intersectArrays(c, a, b, path); errors.Is(err, errNoMerge) {
+		conflicts = append(conflicts, arrayKeywords...)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(conflicts) > 0 {
+		// b's values of the keywords that could not be
+		// combined apply alongside those of a.
+		extra := &jsonschema.Schema{}
+		re := reflect.ValueOf(extra).Elem()
+		for i := 0; i < re.NumField(); i++ {
+			name, _, _ := strings.Cut(re.Type().Field(i).Tag.Get("json"), ",")
+			if slices.Contains(conflicts, name) {
+				re.Field(i).Set(rb.Field(i))
+			}
+		}
+		c.AllOf = append(c.AllOf, extra)
+	}
+	if err := checkBounds(c); err != nil {
+		return nil, intersectError(path, err)
+	}
+	return c, nil
+}
+
+// intersectError returns err, from intersecting the schemas at path.
+func intersectError(path string, err error) error {
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Errorf("picoschema: intersecting %s: %w", path, err)
+}
+
+// intersectKeyword combines b's value of the keyword name into c,
+// which has a different value for it. It returns errNoMerge if the
+// values cannot be combined.
+func intersectKeyword(c, b *jsonschema.Schema, name string) error {
+	switch name {
+	case "type":
+		switch {
+		case c.Type == "integer" && b.Type == "number":
+		case c.Type == "number" && b.Type == "integer":
+			c.Type = "integer"
+		default:
+			return fmt.Errorf("types %s and %s have no values in common", c.Type, b.Type)
+		}
+	case "enum":
+		c.Enum = slices.DeleteFunc(c.Enum, func(v any) bool {
+			return !slices.ContainsFunc(b.Enum, func(w any) bool { return jsonText(v) == jsonText(w) })
+		})
+		if len(c.Enum) == 0 {
+			return errors.New("enums have no values in common")
+		}
+	case "const":
+		if jsonText(c.Const) != jsonText(b.Const) {
+			return fmt.Errorf("consts %s and %s differ", jsonText(c.Const), jsonText(b.Const))
+		}
+	case "minimum", "exclusiveMinimum":
+		return mergeNumber(c, b, name, 1)
+	case "maximum", "exclusiveMaximum":
+		return mergeNumber(c, b, name, -1)
+	case "minLength", "minItems", "minProperties", "minContains":
+		mergeCount(c, b, name, 1)
+	case "maxLength", "maxItems", "maxProperties", "maxContains":
+		mergeCount(c, b, name, -1)
+	case "required":
+		for _, r := range b.Required {
+			if !slices.Contains(c.Required, r) {
+				c.Required = append(c.Required, r)
+			}
+		}
+	case "allOf":
+		c.AllOf = append(c.AllOf, b.AllOf...)
+	case "$defs":
+		for k, s := range b.Definitions {
+			if old, ok := c.Definitions[k]; ok && !reflect.DeepEqual(old, s) {
+				return fmt.Errorf("definitions of %q differ", k)
+			}
+			c.Definitions[k] = s
+		}
+	default:
+		if slices.Contains(annotationKeywords, name) {
+			return nil
+		}
+		return errNoMerge
+	}
+	return nil
+}
+
+// mergeNumber sets the numeric keyword name of c to the larger of its
+// values in c and b if sign is 1, or to the smaller if sign is -1.
+func mergeNumber(c, b *jsonschema.Schema, name string, sign int) error {
+	fc := reflect.ValueOf(c).Elem().FieldByIndex(keywordField(name))
+	fb := reflect.ValueOf(b).Elem().FieldByIndex(keywordField(name))
+	x, err1 := fc.Interface().(json.Number).Float64()
+	y, err2 := fb.Interface().(json.Number).Float64()
+	if err1 != nil || err2 != nil {
+		return errNoMerge
+	}
+	if (y > x) == (sign > 0) {
+		fc.Set(fb)
+	}
+	return nil
+}
+
+// mergeCount is like mergeNumber for the count keyword name.
+func mergeCount(c, b *jsonschema.Schema, name string, sign int) {
+	fc := reflect.ValueOf(c).Elem().FieldByIndex(keywordField(name))
+	fb := reflect.ValueOf(b).Elem().FieldByIndex(keywordField(name))
+	x, y := *fc.Interface().(*uint64), *fb.Interface().(*uint64)
+	if (y > x) == (sign > 0) {
+		fc.Set(fb)
+	}
+}
+
+// keywordField returns the index of the Schema field of the keyword name.
+func keywordField(name string) []int {
+	t := reflect.TypeFor[jsonschema.Schema]()
+	for i := 0; i < t.NumField(); i++ {
+		if k, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); k == name {
+			return []int{i}
+		}
+	}
+	panic("picoschema: no schema field for keyword " + name)
+}
+
+// checkBounds reports a lower bound of s above its upper bound.
+func checkBounds(s *jsonschema.Schema) error {
+	if s.Minimum != "" && s.Maximum != "" {
+		lo, err1 := s.Minimum.Float64()
+		hi, err2 := s.Maximum.Float64()
+		if err1 == nil && err2 == nil && lo > hi {
+			return fmt.Errorf("minimum %s is above maximum %s", s.Minimum, s.Maximum)
+		}
+	}
+	for _, pair := range [][2]string{{"minLength", "maxLength"}, {"minItems", "maxItems"}, {"minProperties", "maxProperties"}} {
+		lo := reflect.ValueOf(s).Elem().FieldByIndex(keywordField(pair[0])).Interface().(*uint64)
+		hi := reflect.ValueOf(s).Elem().FieldByIndex(keywordField(pair[1])).Interface().(*uint64)
+		if lo != nil && hi != nil && *lo > *hi {
+			return fmt.Errorf("%s %d is above %s %d", pair[0], *lo, pair[1], *hi)
+		}
+	}
+	return nil
+}
+
+// intersectObjects sets the object keywords of c, at path, to the
+// intersection of those of a and b. It returns errNoMerge if they
+// cannot be combined.
+func intersectObjects(c, a, b *jsonschema.Schema, path string) error {
+	if a.Properties == nil && b.Properties == nil && a.PatternProperties == nil && b.PatternProperties == nil &&
+		a.AdditionalProperties == nil && b.AdditionalProperties == nil {
+		return nil
+	}
+	if !reflect.DeepEqual(a.PatternProperties, b.PatternProperties) {
+		return errNoMerge
+	}
+	var patterns []*regexp.Regexp
+	for p := range a.PatternProperties {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return errNoMerge
+		}
+		patterns = append(patterns, re)
+	}
+	// other returns the schema that s applies to the property name,
+	// which it does not declare, other than by patternProperties.
+	other := func(s *jsonschema.Schema, name string) *jsonschema.Schema {
+		if slices.ContainsFunc(patterns, func(re *regexp.Regexp) bool { return re.MatchString(name) }) {
+			return nil
+		}
+		return s.AdditionalProperties
+	}
+	var names []string
+	for _, s := range []*jsonschema.Schema{a, b} {
+		if s.Properties != nil {
+			for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+				if !slices.Contains(names, p.Key) {
+					names = append(names, p.Key)
+				}
+			}
+		}
+	}
+	props := orderedmap.New[string, *jsonschema.Schema]()
+	for _, name := range names {
+		pa, pb := declaredProperty(a, name), declaredProperty(b, name)
+		if pa == nil {
+			pa = other(a, name)
+		}
+		if pb == nil {
+			pb = other(b, name)
+		}
+		ppath := path + "/properties/" + escapePointer(name)
+		if isFalse(pa) || isFalse(pb) {
+			// One object does not allow the property.
+			if slices.Contains(c.Required, name) {
+				return intersectError(path, fmt.Errorf("property %q is required by one schema but not allowed by the other", name))
+			}
+			continue
+		}
+		p, err := intersect(pa, pb, ppath)
+		if err != nil {
+			return err
+		}
+		props.Set(name, p)
+	}
+	if props.Len() > 0 {
+		c.Properties = props
+	} else {
+		c.Properties = nil
+	}
+	ap, err := intersect(a.AdditionalProperties, b.AdditionalProperties, path+"/additionalProperties")
+	if err != nil {
+		return err
+	}
+	c.AdditionalProperties = ap
+	c.PatternProperties = cloneSchemaMap(a.PatternProperties)
+	return nil
+}
+
+// isFalse reports whether s is the schema false.
+func isFalse(s *jsonschema.Schema) bool {
+	v, ok := boolSchema(s)
+	return ok && !v
+}
+
+// declaredProperty returns the schema s declares for the property name, or nil.
+func declaredProperty(s *jsonschema.Schema, name string) *jsonschema.Schema {
+	if s.Properties == nil {
+		return nil
+	}
+	return s.Properties.Value(name)
+}
+
+// intersectArrays sets the array keywords of c, at path, to the
+// intersection of those of a and b. It returns errNoMerge if they
+// cannot be combined.
+func intersectArrays(c, a, b *jsonschema.Schema, path string) error {
+	if len(a.PrefixItems) != len(b.PrefixItems) {
+		return errNoMerge
+	}
+	for i := range a.PrefixItems {
+		p, err := intersect(a.PrefixItems[i], b.PrefixItems[i], fmt.Sprintf("%s/prefixItems/%d", path, i))
+		if err != nil {
+			return err
+		}
+		c.PrefixItems[i] = p
+	}
+	items, err := intersect(a.Items, b.Items, path+"/items")
+	if err != nil {
+		return err
+	}
+	c.Items = items
+	return nil
+}
+
+// structuralKeywords are the keywords that Subtract
+// handles other than by removing equal values.
+var structuralKeywords = []string{"type", "properties", "required", "additionalProperties", "items", "$defs"}
+
+// subtract removes what b declares from c.
+func subtract(c, b *jsonschema.Schema) {
+	if c == nil || b == nil {
+		return
+	}
+	if _, ok := boolSchema(c); ok {
+		return
+	}
+	if b.Properties != nil && c.Properties != nil {
+		for p := b.Properties.Oldest(); p != nil; p = p.Next() {
+			pc, ok := c.Properties.Get(p.Key)
+			if !ok {
+				continue
+			}
+			if pc.Properties != nil && p.Value.Properties != nil {
+				subtract(pc, p.Value)
+				continue
+			}
+			c.Properties.Delete(p.Key)
+			c.Required = slices.DeleteFunc(c.Required, func(r string) bool { return r == p.Key })
+		}
+	}
+	for _, r := range b.Required {
+		if declaredProperty(b, r) == nil {
+			c.Required = slices.DeleteFunc(c.Required, func(name string) bool { return name == r })
+		}
+	}
+	if len(c.Required) == 0 {
+		c.Required = nil
+	}
+	if c.Items != nil && b.Items != nil {
+		subtract(c.Items, b.Items)
+	}
+	rc, rb := reflect.ValueOf(c).Elem(), reflect.ValueOf(b).Elem()
+	for i := 0; i < rc.NumField(); i++ {
+		name, _, _ := strings.Cut(rc.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || slices.Contains(structuralKeywords, name) || slices.Contains(annotationKeywords, name) {
+			continue
+		}
+		if fb := rb.Field(i); !fb.IsZero() && reflect.DeepEqual(rc.Field(i).Interface(), fb.Interface()) {
+			rc.Field(i).SetZero()
+		}
+	}
+	if v, ok := b.Extras[assertKey]; ok && reflect.DeepEqual(c.Extras[assertKey], v) {
+		deleteExtra(c, assertKey)
+	}
+}
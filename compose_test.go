@@ -0,0 +1,286 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+)
+
+func TestIntersect(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		a, b    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "properties of both",
+			a: `
+id: string
+name?: string
+(*): any
+`,
+			b: `
+name: string, the display name
+email?: string
+(*): any
+`,
+			want: `
+id: string
+name: string, the display name
+email?: string
+(*): any
+`,
+		},
+		{
+			name: "closed object drops property",
+			a: `
+id: string
+note?: string
+(*): any
+`,
+			b: `
+id: string
+`,
+			want: `
+id: string
+`,
+		},
+		{
+			name: "bounds and types",
+			a: `
+age(number, minimum=0, maximum=150):
+name(string, maxLength=100):
+`,
+			b: `
+age(integer, minimum=18):
+name(string, minLength=1, maxLength=50):
+`,
+			want: `
+age(integer, minimum=18, maximum=150):
+name(string, minLength=1, maxLength=50):
+`,
+		},
+		{
+			name: "common enum values",
+			a: `
+status(enum): [open, closed, archived]
+`,
+			b: `
+status(enum): [closed, archived, deleted]
+`,
+			want: `
+status(enum): [closed, archived]
+`,
+		},
+		{
+			name:    "different types",
+			a:       `id: string`,
+			b:       `id: integer`,
+			wantErr: "intersecting /properties/id: types string and integer have no values in common",
+		},
+		{
+			name:    "disjoint enums",
+			a:       `status(enum): [open]`,
+			b:       `status(enum): [closed]`,
+			wantErr: "intersecting /properties/status: enums have no values in common",
+		},
+		{
+			name:    "empty range",
+			a:       `age(integer, minimum=18):`,
+			b:       `age(integer, maximum=10):`,
+			wantErr: "intersecting /properties/age: minimum 18 is above maximum 10",
+		},
+		{
+			name: "required property not allowed",
+			a: `
+id: string
+note: string
+`,
+			b:       `id: string`,
+			wantErr: `intersecting /: property "note" is required by one schema but not allowed by the other`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := mustSchema(t, test.a), mustSchema(t, test.b)
+			before, err := ConvertSchema(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Intersect(a, b)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got error %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotJSON, err := ConvertSchema(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := ConvertSchema(mustSchema(t, test.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, gotJSON); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+			after, err := ConvertSchema(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(before, after); diff != "" {
+				t.Errorf("Intersect modified its input (-before, +after):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIntersectBoolean(t *testing.T) {
+	s := mustSchema(t, `id: string`)
+	got, err := Intersect(jsonschema.TrueSchema, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(schemaJSON(t, s), schemaJSON(t, got)); diff != "" {
+		t.Errorf("true: mismatch (-want, +got):\n%s", diff)
+	}
+	got, err = Intersect(s, jsonschema.FalseSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := boolSchema(got); !ok || v {
+		t.Errorf("false: got %v, want false", schemaJSON(t, got))
+	}
+}
+
+func TestIntersectConflicts(t *testing.T) {
+	a := &jsonschema.Schema{Type: "string", Pattern: "^[A-Z]+$"}
+	b := &jsonschema.Schema{Type: "string", Pattern: "^.{3}$", Format: "code"}
+	got, err := Intersect(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &jsonschema.Schema{
+		Type:    "string",
+		Pattern: "^[A-Z]+$",
+		Format:  "code",
+		AllOf:   []*jsonschema.Schema{{Pattern: "^.{3}$"}},
+	}
+	if diff := cmp.Diff(schemaJSON(t, want), schemaJSON(t, got)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "server fields",
+			a: `
+id: string
+createdAt: string
+name: string
+email?: string
+`,
+			b: `
+id: string
+createdAt: string
+`,
+			want: `
+name: string
+email?: string
+`,
+		},
+		{
+			name: "nested",
+			a: `
+id: string
+profile(object):
+  bio: string
+  updatedAt: string
+`,
+			b: `
+profile(object):
+  updatedAt: string
+`,
+			want: `
+id: string
+profile(object):
+  bio: string
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := mustSchema(t, test.a), mustSchema(t, test.b)
+			before, err := ConvertSchema(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Subtract(a, b)
+			gotJSON, err := ConvertSchema(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := ConvertSchema(mustSchema(t, test.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, gotJSON); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+			after, err := ConvertSchema(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(before, after); diff != "" {
+				t.Errorf("Subtract modified its input (-before, +after):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSubtractConstraints(t *testing.T) {
+	a := mustSchema(t, `
+id: string
+name(string, maxLength=50, pattern=^[a-z]+$):
+`)
+	// A name that b requires without declaring it is made optional.
+	got := Subtract(a, &jsonschema.Schema{Required: []string{"id"}})
+	want := mustSchema(t, `
+id?: string
+name(string, maxLength=50, pattern=^[a-z]+$):
+`)
+	if diff := cmp.Diff(schemaJSON(t, want), schemaJSON(t, got)); diff != "" {
+		t.Errorf("required: mismatch (-want, +got):\n%s", diff)
+	}
+
+	maxLength := uint64(50)
+	got = Subtract(a.Properties.Value("name"), &jsonschema.Schema{Type: "string", MaxLength: &maxLength})
+	want = &jsonschema.Schema{Type: "string", Pattern: "^[a-z]+$"}
+	if diff := cmp.Diff(schemaJSON(t, want), schemaJSON(t, got)); diff != "" {
+		t.Errorf("constraints: mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileName is the name of the file FindConfig looks for.
+const ConfigFileName = ".picoschema.yaml"
+
+// Config is the contents of a .picoschema.yaml file.
+// It holds the lint policy for the schemas in a directory tree.
+//
+// A typical file looks like:
+//
+//	rules:
+//	  missing-description: off
+//	  untyped-property: error
+//	naming: camelCase
+//	maxDepth: 6
+//	maxBytes: 16384
+//	profiles: [openai]
+type Config struct {
+	// Rules maps rule IDs to a severity: off, info, warning or error.
+	Rules map[string]string `yaml:"rules"`
+	// Naming is the property naming convention; see Linter.Naming.
+	Naming   string   `yaml:"naming"`
+	MaxDepth int      `yaml:"maxDepth"`
+	MaxBytes int      `yaml:"maxBytes"`
+	Profiles []string `yaml:"profiles"`
+}
+
+// ParseConfig parses the contents of a config file.
+func ParseConfig(data []byte) (*Config, error) {
+	var c Config
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("picoschema: config: %w", err)
+	}
+	return &c, nil
+}
+
+// LoadConfig reads and parses the config file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// FindConfig looks for a config file in dir and then in each of its
+// parent directories, and loads the first one found.
+// If there is none, it returns an empty Config.
+func FindConfig(dir string) (*Config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		c, err := LoadConfig(filepath.Join(dir, ConfigFileName))
+		if !errors.Is(err, fs.ErrNotExist) {
+			return c, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return &Config{}, nil
+		}
+		dir = parent
+	}
+}
+
+// Linter returns a Linter enforcing the policy in c.
+func (c *Config) Linter() (*Linter, error) {
+	l := &Linter{
+		Naming:   c.Naming,
+		MaxDepth: c.MaxDepth,
+		MaxBytes: c.MaxBytes,
+	}
+	if c.Naming != "" {
+		if _, ok := namingConventions[c.Naming]; !ok {
+			return nil, fmt.Errorf("picoschema: config: unknown naming convention %q", c.Naming)
+		}
+	}
+	if len(c.Rules) > 0 {
+		l.Severity = make(map[string]Severity, len(c.Rules))
+	}
+	for id, name := range c.Rules {
+		if !slices.Contains(LintRules(), id) {
+			return nil, fmt.Errorf("picoschema: config: unknown lint rule %q", id)
+		}
+		sev := slices.Index(severityNames, name)
+		if sev < 0 {
+			return nil, fmt.Errorf("picoschema: config: rule %q has severity %q, want one of %q", id, name, severityNames)
+		}
+		l.Severity[id] = Severity(sev)
+	}
+	for _, name := range c.Profiles {
+		p, ok := LookupProfile(name)
+		if !ok {
+			return nil, fmt.Errorf("picoschema: config: unknown provider profile %q", name)
+		}
+		l.Profiles = append(l.Profiles, p)
+	}
+	return l, nil
+}
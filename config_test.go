@@ -0,0 +1,85 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestConfigLinter(t *testing.T) {
+	dir := t.TempDir()
+	config := `
+rules:
+  missing-description: off
+naming: camelCase
+maxDepth: 2
+profiles: [gemini]
+`
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "prompts", "extract")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := FindConfig(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := c.Linter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := l.Lint(mustSchema(t, `
+user_name: string
+address:
+  city:
+    name: string
+`))
+	want := []Diagnostic{
+		{Rule: "provider-keyword", Severity: SeverityError, Path: ""},
+		{Rule: "provider-keyword", Severity: SeverityError, Path: "/properties/address"},
+		{Rule: "provider-keyword", Severity: SeverityError, Path: "/properties/address/properties/city"},
+		{Rule: "max-depth", Severity: SeverityError, Path: "/properties/address/properties/city/properties/name"},
+		{Rule: "property-naming", Severity: SeverityWarning, Path: "/properties/user_name"},
+	}
+	less := func(a, b Diagnostic) bool { return a.Path+a.Rule < b.Path+b.Rule }
+	if diff := cmp.Diff(want, got, cmpopts.SortSlices(less), cmpopts.IgnoreFields(Diagnostic{}, "Message")); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, src := range []string{
+		"rules: {no-such-rule: error}",
+		"rules: {missing-description: loud}",
+		"naming: SHOUTING",
+		"profiles: [no-such-provider]",
+	} {
+		c, err := ParseConfig([]byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Linter(); err == nil {
+			t.Errorf("%q: got nil error", src)
+		}
+	}
+}
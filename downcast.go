@@ -0,0 +1,193 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A SchemaChange records one change made to a schema to adapt it to a
+// provider or to an older dialect of JSON Schema, so that callers can
+// decide whether the adapted schema is still acceptable.
+type SchemaChange struct {
+	// Path is the JSON Pointer of the changed keyword or subschema
+	// in the original schema.
+	Path string `json:"path"`
+	// Action is "removed", "changed" or "added".
+	Action string `json:"action"`
+	// Lossy reports whether the change alters which instances are
+	// valid, as opposed to changing only annotations or spelling.
+	Lossy  bool   `json:"lossy"`
+	Reason string `json:"reason"`
+}
+
+func (c SchemaChange) String() string {
+	p := c.Path
+	if p == "" {
+		p = "/"
+	}
+	lossy := ""
+	if c.Lossy {
+		lossy = " (lossy)"
+	}
+	return fmt.Sprintf("%s %s%s: %s", c.Action, p, lossy, c.Reason)
+}
+
+// annotationKeywords are the keywords that do not affect validation.
+var annotationKeywords = []string{
+	"title", "description", "default", "examples", "deprecated",
+	"readOnly", "writeOnly", "$comment", "$id", "$schema", "$anchor",
+}
+
+// isAnnotation reports whether removing the keyword k leaves the set
+// of valid instances unchanged.
+func isAnnotation(k string) bool {
+	return slices.Contains(annotationKeywords, k) || strings.HasPrefix(k, "x-")
+}
+
+// sortChanges sorts changes by path and action.
+func sortChanges(changes []SchemaChange) {
+	slices.SortStableFunc(changes, func(a, b SchemaChange) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Action, b.Action))
+	})
+}
+
+// AdaptToProfile returns the decoded JSON encoding of s with the
+// keywords p does not support removed and its descriptions shortened
+// to p's limits, and a report of the changes. If s is over p's
+// MaxSchemaBytes even without descriptions, the error wraps
+// ErrLimitExceeded.
+func AdaptToProfile(s *jsonschema.Schema, p *Profile) (any, []SchemaChange, error) {
+	v, err := schemaToValue(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := adaptValue(v, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	sortChanges(changes)
+	return v, changes, nil
+}
+
+// adaptValue adapts v, a decoded schema, to p as AdaptToProfile does.
+func adaptValue(v any, p *Profile) ([]SchemaChange, error) {
+	changes := stripKeywordsValue(v, p)
+	limited, err := limitDescriptions(v, p)
+	return append(changes, limited...), err
+}
+
+// limitDescriptions shortens the descriptions in v, a decoded schema,
+// to p's limits.
+func limitDescriptions(v any, p *Profile) ([]SchemaChange, error) {
+	var changes []SchemaChange
+	if p.MaxDescriptionBytes > 0 {
+		changes = append(changes, truncateDescriptions(v, p.MaxDescriptionBytes)...)
+	}
+	if p.MaxSchemaBytes > 0 {
+		fit, err := fitDescriptions(v, p.MaxSchemaBytes, "bytes", func(text string) int { return len(text) })
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, fit...)
+	}
+	return changes, nil
+}
+
+// draft07Removed are the keywords of JSON Schema 2020-12 that draft-07
+// has no equivalent for.
+var draft07Removed = []string{
+	"$anchor", "$dynamicRef", "$dynamicAnchor", "$vocabulary",
+	"unevaluatedProperties", "unevaluatedItems", "minContains", "maxContains",
+}
+
+// DowngradeToDraft07 returns the decoded JSON encoding of s rewritten
+// in JSON Schema draft-07, for consumers that predate 2020-12, and a
+// report of the changes. $defs become definitions, prefixItems become
+// an items array, dependentRequired and dependentSchemas become
+// dependencies, and keywords draft-07 lacks, such as
+// unevaluatedProperties, are removed.
+func DowngradeToDraft07(s *jsonschema.Schema) (any, []SchemaChange, error) {
+	v, err := schemaToValue(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	var changes []SchemaChange
+	var ops []func()
+	change := func(path, action string, lossy bool, reason string) {
+		changes = append(changes, SchemaChange{Path: path, Action: action, Lossy: lossy, Reason: reason})
+	}
+	// Record the changes on the unmodified schema, so that their paths
+	// are those of the original, and make them afterwards.
+	forEachValueSubschema(v, "", func(sub any, path string, _ string) error {
+		m, ok := sub.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for _, k := range sortedKeys(m) {
+			kpath := path + "/" + escapePointer(k)
+			switch {
+			case slices.Contains(draft07Removed, k):
+				change(kpath, "removed", !isAnnotation(k), "not in draft-07")
+				ops = append(ops, func() { delete(m, k) })
+			case k == "$defs":
+				change(kpath, "changed", false, "renamed to definitions")
+				ops = append(ops, func() { m["definitions"] = m["$defs"]; delete(m, "$defs") })
+			case k == "$ref":
+				if ref, _ := m[k].(string); strings.HasPrefix(ref, "#/$defs/") {
+					change(kpath, "changed", false, "rewritten to point into definitions")
+					ops = append(ops, func() { m[k] = "#/definitions/" + strings.TrimPrefix(ref, "#/$defs/") })
+				}
+			case k == "$schema":
+				change(kpath, "changed", false, "set to draft-07")
+				ops = append(ops, func() { m[k] = "http://json-schema.org/draft-07/schema#" })
+			case k == "prefixItems":
+				change(kpath, "changed", false, "rewritten as an items array")
+				ops = append(ops, func() {
+					if items, ok := m["items"]; ok {
+						m["additionalItems"] = items
+					}
+					m["items"] = m["prefixItems"]
+					delete(m, "prefixItems")
+				})
+			case k == "dependentRequired" || k == "dependentSchemas":
+				change(kpath, "changed", false, "merged into dependencies")
+				ops = append(ops, func() {
+					deps, _ := m["dependencies"].(map[string]any)
+					if deps == nil {
+						deps = make(map[string]any)
+					}
+					dm, _ := m[k].(map[string]any)
+					for name, d := range dm {
+						deps[name] = d
+					}
+					m["dependencies"] = deps
+					delete(m, k)
+				})
+			}
+		}
+		return nil
+	})
+	for _, op := range ops {
+		op()
+	}
+	sortChanges(changes)
+	return v, changes, nil
+}
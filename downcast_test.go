@@ -0,0 +1,99 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAdaptToProfile(t *testing.T) {
+	s, err := Import("jsonschema", []byte(`{
+		"type": "object",
+		"properties": {
+			"n": {"type": "integer", "multipleOf": 2, "description": "even"},
+			"m": {"type": "object", "additionalProperties": false, "patternProperties": {"^x": {}}}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := LookupProfile("gemini")
+	_, changes, err := AdaptToProfile(s, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SchemaChange{
+		{Path: "/properties/m/additionalProperties", Action: "removed", Lossy: true, Reason: "not supported by gemini"},
+		{Path: "/properties/m/patternProperties", Action: "removed", Lossy: true, Reason: "not supported by gemini"},
+		{Path: "/properties/n/multipleOf", Action: "removed", Lossy: true, Reason: "not supported by gemini"},
+	}
+	if diff := cmp.Diff(want, changes); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestDowngradeToDraft07(t *testing.T) {
+	s, err := Import("jsonschema", []byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"pair": {"type": "array", "prefixItems": [{"type": "string"}, {"type": "integer"}], "items": false},
+			"home": {"$ref": "#/$defs/address"}
+		},
+		"dependentRequired": {"a": ["b"]},
+		"contains": {"type": "integer"},
+		"minContains": 2,
+		"$defs": {"address": {"type": "string"}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, changes, err := DowngradeToDraft07(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type":    "object",
+		"properties": map[string]any{
+			"pair": map[string]any{
+				"type":            "array",
+				"items":           []any{map[string]any{"type": "string"}, map[string]any{"type": "integer"}},
+				"additionalItems": false,
+			},
+			"home": map[string]any{"$ref": "#/definitions/address"},
+		},
+		"dependencies": map[string]any{"a": []any{"b"}},
+		"contains":     map[string]any{"type": "integer"},
+		"definitions":  map[string]any{"address": map[string]any{"type": "string"}},
+	}
+	if diff := cmp.Diff(want, v); diff != "" {
+		t.Errorf("schema mismatch (-want, +got):\n%s", diff)
+	}
+	var lossy []string
+	for _, c := range changes {
+		if c.Lossy {
+			lossy = append(lossy, c.Path)
+		}
+	}
+	if diff := cmp.Diff([]string{"/minContains"}, lossy); diff != "" {
+		t.Errorf("lossy changes mismatch (-want, +got):\n%s", diff)
+	}
+	if len(changes) != 6 {
+		t.Errorf("got %d changes, want 6: %v", len(changes), changes)
+	}
+}
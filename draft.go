@@ -0,0 +1,138 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A Draft is a version of JSON Schema that converted picoschema can
+// be written in, for validators that accept only some dialects.
+type Draft int
+
+const (
+	// Draft202012 is JSON Schema 2020-12, the default.
+	Draft202012 Draft = iota
+	// Draft201909 is JSON Schema 2019-09, which writes tuples
+	// with an items array rather than prefixItems.
+	Draft201909
+	// Draft07 is JSON Schema draft-07, which also puts definitions
+	// under definitions rather than $defs, and writes a nullable
+	// scalar type as a type array, such as ["string", "null"],
+	// rather than with anyOf.
+	Draft07
+)
+
+// Schema URIs of the drafts.
+const (
+	Draft202012SchemaURI = "https://json-schema.org/draft/2020-12/schema"
+	Draft201909SchemaURI = "https://json-schema.org/draft/2019-09/schema"
+)
+
+// schemaURI returns the URI that identifies d in $schema.
+func (d Draft) schemaURI() string {
+	switch d {
+	case Draft201909:
+		return Draft201909SchemaURI
+	case Draft07:
+		return Draft07SchemaURI
+	}
+	return Draft202012SchemaURI
+}
+
+// WithDraft writes the result in the JSON Schema draft d, and sets its
+// $schema keyword to the URI of d, unless a later WithSchemaURI sets
+// another. By default the result is written in 2020-12 without $schema.
+//
+// Keywords that d lacks are written in extension keywords of the
+// result, such as "definitions", so that they marshal as d expects.
+// The functions of this package that read schemas, such as Check,
+// expect 2020-12, so the result is meant to be marshaled.
+func WithDraft(d Draft) Option {
+	return func(o *options) {
+		o.draft = d
+		o.schemaURI = d.schemaURI()
+	}
+}
+
+// writeDraft rewrites s, converted or read as JSON Schema, in the draft d.
+func writeDraft(s *jsonschema.Schema, d Draft) {
+	if d == Draft202012 {
+		return
+	}
+	var nodes []*jsonschema.Schema
+	walkSchema(s, "", func(sub *jsonschema.Schema, _ string) bool {
+		nodes = append(nodes, sub)
+		return true
+	})
+	// Rewrite subschemas before the schemas containing them, which
+	// may move them out of reach of walkSchema.
+	for i := len(nodes) - 1; i >= 0; i-- {
+		n := nodes[i]
+		if _, ok := boolSchema(n); ok {
+			continue
+		}
+		if len(n.PrefixItems) > 0 {
+			setExtra(n, "items", n.PrefixItems)
+			if n.Items != nil {
+				setExtra(n, "additionalItems", n.Items)
+			}
+			n.PrefixItems, n.Items = nil, nil
+		}
+		if d != Draft07 {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(n.Ref, "#/$defs/"); ok {
+			n.Ref = "#/definitions/" + rest
+		}
+		writeNullableType(n)
+	}
+	if d == Draft07 && s.Definitions != nil {
+		setExtra(s, "definitions", s.Definitions)
+		s.Definitions = nil
+	}
+}
+
+// writeNullableType rewrites s, if it is a nullable scalar type written
+// with anyOf, as in picoschema "string?", with a type array instead.
+func writeNullableType(s *jsonschema.Schema) {
+	if len(s.AnyOf) != 2 || s.AnyOf[1].Type != "null" || len(schemaKeywords(s.AnyOf[1])) != 1 {
+		return
+	}
+	for _, k := range schemaKeywords(s) {
+		if k != "anyOf" && k != "description" {
+			return
+		}
+	}
+	alt := s.AnyOf[0]
+	if _, ok := boolSchema(alt); ok || !slices.Contains(builtinScalars, alt.Type) || alt.Type == "any" || alt.Type == "null" {
+		return
+	}
+	for _, k := range schemaKeywords(alt) {
+		if compositions[k] != nil || k == "$ref" || k == "enum" || k == "const" {
+			return
+		}
+	}
+	desc := s.Description
+	*s = *cloneSchema(alt)
+	setExtra(s, "type", []any{s.Type, "null"})
+	s.Type = ""
+	if desc != "" {
+		s.Description = desc
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithDraft(t *testing.T) {
+	src := `
+$defs:
+  point(tuple): [number, number]
+middle?: string?, their middle name
+at: point
+`
+	defs := map[string]any{
+		"point": map[string]any{
+			"type": "array", "minItems": float64(2),
+			"prefixItems": []any{map[string]any{"type": "number"}, map[string]any{"type": "number"}},
+			"items":       false,
+		},
+	}
+	props := map[string]any{
+		"middle": map[string]any{
+			"description": "their middle name",
+			"anyOf":       []any{map[string]any{"type": "string"}, map[string]any{"type": "null"}},
+		},
+		"at": map[string]any{"$ref": "#/$defs/point"},
+	}
+	object := func(uri string, props, defs map[string]any, defsKey string) map[string]any {
+		m := map[string]any{
+			"type": "object", "additionalProperties": false, "required": []any{"at"},
+			"properties": props,
+			defsKey:      defs,
+		}
+		if uri != "" {
+			m["$schema"] = uri
+		}
+		return m
+	}
+	draft2019Defs := map[string]any{
+		"point": map[string]any{
+			"type": "array", "minItems": float64(2),
+			"items":           []any{map[string]any{"type": "number"}, map[string]any{"type": "number"}},
+			"additionalItems": false,
+		},
+	}
+	for _, test := range []struct {
+		name string
+		opts []Option
+		want map[string]any
+	}{
+		{"default", nil, object("", props, defs, "$defs")},
+		{"2020-12", []Option{WithDraft(Draft202012)}, object(Draft202012SchemaURI, props, defs, "$defs")},
+		{"2019-09", []Option{WithDraft(Draft201909)}, object(Draft201909SchemaURI, props, draft2019Defs, "$defs")},
+		{"draft-07", []Option{WithDraft(Draft07)}, object(Draft07SchemaURI, map[string]any{
+			"middle": map[string]any{"description": "their middle name", "type": []any{"string", "null"}},
+			"at":     map[string]any{"$ref": "#/definitions/point"},
+		}, draft2019Defs, "definitions")},
+		{"draft-07 with another URI", []Option{WithDraft(Draft07), WithSchemaURI("urn:custom")}, object("urn:custom", map[string]any{
+			"middle": map[string]any{"description": "their middle name", "type": []any{"string", "null"}},
+			"at":     map[string]any{"$ref": "#/definitions/point"},
+		}, draft2019Defs, "definitions")},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ParseYAMLString(src, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, schemaJSON(t, s)); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithDraftJSONSchema(t *testing.T) {
+	s, err := ParseYAMLString(`
+type: object
+$defs:
+  name: {type: string}
+properties:
+  name: {$ref: "#/$defs/name"}
+  middle: {anyOf: [{type: string}, {type: "null"}]}
+`, WithDraft(Draft07))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"$schema":     Draft07SchemaURI,
+		"type":        "object",
+		"definitions": map[string]any{"name": map[string]any{"type": "string"}},
+		"properties": map[string]any{
+			"name":   map[string]any{"$ref": "#/definitions/name"},
+			"middle": map[string]any{"type": []any{"string", "null"}},
+		},
+	}
+	if diff := cmp.Diff(want, schemaJSON(t, s)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// An Embedder computes an embedding vector for a text,
+// typically by calling an embedding model.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// An EmbedderFunc adapts a function to the Embedder interface.
+type EmbedderFunc func(ctx context.Context, text string) ([]float32, error)
+
+// Embed calls f.
+func (f EmbedderFunc) Embed(ctx context.Context, text string) ([]float32, error) {
+	return f(ctx, text)
+}
+
+// CanonicalText returns a normalized text serialization of s for
+// computing embeddings. Each line describes one schema location, as in
+//
+//	customer.email: string, required - the customer's email address
+//
+// Lines are sorted by path, so that property order does not matter;
+// descriptions are lower-cased with whitespace collapsed; enum members
+// are sorted; and extension keywords are omitted. Schemas that differ
+// only in those respects have the same canonical text.
+func CanonicalText(s *jsonschema.Schema) string {
+	var lines []string
+	canonicalLines(s, "", true, &lines)
+	slices.Sort(lines)
+	return strings.Join(lines, "\n")
+}
+
+func canonicalLines(s *jsonschema.Schema, path string, required bool, lines *[]string) {
+	if s == nil {
+		return
+	}
+	var parts []string
+	if t := s.Type; t != "" {
+		parts = append(parts, t)
+	} else {
+		parts = append(parts, "any")
+	}
+	if path != "" && required {
+		parts = append(parts, "required")
+	}
+	if s.Enum != nil {
+		es := strings.Split(enumList(s.Enum, "|"), "|")
+		slices.Sort(es)
+		parts = append(parts, "one of "+strings.Join(es, " "))
+	}
+	if s.Format != "" {
+		parts = append(parts, "format "+s.Format)
+	}
+	line := path
+	if line == "" {
+		line = "$"
+	}
+	line += ": " + strings.Join(parts, ", ")
+	if d := strings.Join(strings.Fields(strings.ToLower(s.Description)), " "); d != "" {
+		line += " - " + d
+	}
+	*lines = append(*lines, line)
+
+	if s.Properties != nil {
+		for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+			canonicalLines(p.Value, joinPath(path, p.Key), slices.Contains(s.Required, p.Key), lines)
+		}
+	}
+	if s.Items != nil {
+		canonicalLines(s.Items, path+"[]", true, lines)
+	}
+	for i, alts := range [][]*jsonschema.Schema{s.AllOf, s.AnyOf, s.OneOf} {
+		kw := []string{"allOf", "anyOf", "oneOf"}[i]
+		for j, sub := range alts {
+			canonicalLines(sub, fmt.Sprintf("%s<%s%d>", path, kw, j), true, lines)
+		}
+	}
+}
+
+// SchemaEmbedding returns the embedding of the canonical text of s.
+func SchemaEmbedding(ctx context.Context, e Embedder, s *jsonschema.Schema) ([]float32, error) {
+	v, err := e.Embed(ctx, CanonicalText(s))
+	if err != nil {
+		return nil, fmt.Errorf("picoschema: embedding schema: %w", err)
+	}
+	return v, nil
+}
+
+// CosineSimilarity returns the cosine similarity of a and b, between -1
+// and 1. It returns 0 if the vectors differ in length or either is zero.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// A Similarity is the similarity of a named schema to a query.
+type Similarity struct {
+	Name  string
+	Score float64
+}
+
+// MostSimilar ranks the named embeddings by their cosine similarity to
+// query and returns those scoring at least threshold, most similar first.
+// It is meant for finding existing schemas similar to a new one.
+func MostSimilar(query []float32, embeddings map[string][]float32, threshold float64) []Similarity {
+	var out []Similarity
+	for _, name := range sortedKeys(embeddings) {
+		if score := CosineSimilarity(query, embeddings[name]); score >= threshold {
+			out = append(out, Similarity{Name: name, Score: score})
+		}
+	}
+	slices.SortStableFunc(out, func(a, b Similarity) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	return out
+}
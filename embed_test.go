@@ -0,0 +1,81 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalText(t *testing.T) {
+	a := mustSchema(t, `
+name: string, The   Customer's name
+status(enum): [b, a]
+address:
+  city?: string
+`)
+	b := mustSchema(t, `
+address:
+  city?: string
+status(enum): [a, b]
+name: string, the customer's NAME
+`)
+	want := `$: object
+address.city: string
+address: object, required
+name: string, required - the customer's name
+status: any, required, one of "a" "b"`
+	if got := CanonicalText(a); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if CanonicalText(a) != CanonicalText(b) {
+		t.Errorf("equivalent schemas have different canonical text:\n%s\n\n%s", CanonicalText(a), CanonicalText(b))
+	}
+}
+
+// wordEmbedder embeds text as a bag of hashed words.
+var wordEmbedder = EmbedderFunc(func(_ context.Context, text string) ([]float32, error) {
+	v := make([]float32, 64)
+	for _, w := range strings.FieldsFunc(text, func(r rune) bool { return !('a' <= r && r <= 'z') }) {
+		h := fnv.New32a()
+		h.Write([]byte(w))
+		v[h.Sum32()%64]++
+	}
+	return v, nil
+})
+
+func TestMostSimilar(t *testing.T) {
+	ctx := context.Background()
+	embed := func(src string) []float32 {
+		v, err := SchemaEmbedding(ctx, wordEmbedder, mustSchema(t, src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	existing := map[string][]float32{
+		"customer": embed("customerId: string\nemail: string\nfullName: string"),
+		"invoice":  embed("invoiceId: string\namount: number\ncurrency: string\ndueDate: string"),
+	}
+	got := MostSimilar(embed("customerId: string\nemail?: string\nname: string"), existing, 0.85)
+	if len(got) != 1 || got[0].Name != "customer" {
+		t.Errorf("got %v, want only customer", got)
+	}
+	if s := CosineSimilarity([]float32{1, 0}, []float32{1, 0}); s != 1 {
+		t.Errorf("got self-similarity %v", s)
+	}
+}
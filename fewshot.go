@@ -0,0 +1,83 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// FewShotPrompt returns a few-shot prompt section for s made with
+// the default Generator.
+func FewShotPrompt(s *jsonschema.Schema, n int) (string, error) {
+	var g Generator
+	return g.FewShotPrompt(s, n)
+}
+
+// FewShotPrompt returns a prompt section, ready to insert into any
+// provider's prompt, that presents s and n generated documents valid
+// against it, each in a fenced JSON block:
+//
+//	Respond with JSON that conforms to this schema:
+//
+//	```json
+//	{ ...schema... }
+//	```
+//
+//	Example 1:
+//
+//	```json
+//	{ ...document... }
+//	```
+//
+// Extension keywords (x-...) are omitted from the schema shown.
+func (g *Generator) FewShotPrompt(s *jsonschema.Schema, n int) (string, error) {
+	examples, err := g.GenerateN(s, n)
+	if err != nil {
+		return "", err
+	}
+	c := cloneSchema(s)
+	walkSchema(c, "", func(s *jsonschema.Schema, _ string) bool {
+		s.Extras = nil
+		return true
+	})
+	var b strings.Builder
+	b.WriteString("Respond with JSON that conforms to this schema:\n\n")
+	if err := writeJSONBlock(&b, c); err != nil {
+		return "", err
+	}
+	for i, e := range examples {
+		fmt.Fprintf(&b, "\nExample %d:\n\n", i+1)
+		if err := writeJSONBlock(&b, e); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// writeJSONBlock writes v to b as indented JSON in a fenced code block.
+func writeJSONBlock(b *strings.Builder, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("picoschema: %w", err)
+	}
+	b.WriteString("```json\n")
+	b.Write(data)
+	b.WriteString("\n```\n")
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFewShotPrompt(t *testing.T) {
+	s := mustSchema(t, `
+color(enum, the color): [red, green]
+size(unit=cm): integer
+`)
+	got, err := FewShotPrompt(s, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "Respond with JSON that conforms to this schema:\n\n```json\n{") {
+		t.Errorf("unexpected start:\n%s", got)
+	}
+	if strings.Contains(got, "x-unit") {
+		t.Errorf("extension keyword in prompt:\n%s", got)
+	}
+	blocks := strings.Split(got, "```json\n")[1:]
+	if len(blocks) != 3 {
+		t.Fatalf("got %d JSON blocks, want 3:\n%s", len(blocks), got)
+	}
+	for i, b := range blocks[1:] {
+		if !strings.Contains(got, "Example "+string(rune('1'+i))+":") {
+			t.Errorf("missing label for example %d", i+1)
+		}
+		body, _, _ := strings.Cut(b, "\n```")
+		var v any
+		if err := json.Unmarshal([]byte(body), &v); err != nil {
+			t.Fatalf("example %d: %v", i+1, err)
+		}
+		if vs := Check(v, s); len(vs) != 0 {
+			t.Errorf("example %d is invalid: %v", i+1, vs)
+		}
+	}
+}
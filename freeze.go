@@ -0,0 +1,564 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// The frozen form of schemas is a compact binary encoding that thaws
+// much faster than picoschema YAML or JSON Schema parses, so that
+// services can convert their schemas at build time and load thousands
+// of them at startup. It starts with frozenMagic, a version byte and
+// the fingerprint of the Schema type it was written for, followed by
+// the number of schemas and, for each, its name and encoding.
+//
+// A schema is encoded as a tag, frozenNil, frozenTrue, frozenFalse or
+// frozenObject, and for an object the keywords that are set, each as
+// its Schema field number plus one and its value, followed by a zero.
+// Counts, lengths and field numbers are unsigned varints, and maps are
+// written in key order, except properties, which keep their order.
+const (
+	frozenMagic   = "pico"
+	frozenVersion = 1
+)
+
+const (
+	frozenNil byte = iota
+	frozenTrue
+	frozenFalse
+	frozenObject
+)
+
+// Tags of values, such as those of enum, const and extension keywords.
+const (
+	valueNull byte = iota
+	valueFalse
+	valueTrue
+	valueInt
+	valueFloat
+	valueString
+	valueNumber
+	valueList
+	valueMap
+	valueJSON // the JSON encoding of a value of another type
+)
+
+// frozenLayout fingerprints the fields of the Schema type, so that
+// schemas frozen with a different version of it are not misread.
+var frozenLayout = func() uint32 {
+	h := fnv.New32a()
+	t := reflect.TypeFor[jsonschema.Schema]()
+	for i := 0; i < t.NumField(); i++ {
+		fmt.Fprintf(h, "%s %s %s;", t.Field(i).Name, t.Field(i).Type, t.Field(i).Tag)
+	}
+	return h.Sum32()
+}()
+
+// errFrozen is returned, wrapped, for data that is not a valid frozen form.
+var errFrozen = errors.New("picoschema: invalid frozen schemas")
+
+// Freeze returns the frozen form of s.
+func Freeze(s *jsonschema.Schema) ([]byte, error) {
+	return FreezeAll(map[string]*jsonschema.Schema{"": s})
+}
+
+// Thaw decodes a schema frozen by Freeze.
+func Thaw(data []byte) (*jsonschema.Schema, error) {
+	all, err := ThawAll(data)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := all[""]
+	if !ok || len(all) != 1 {
+		return nil, fmt.Errorf("%w: not a single schema", errFrozen)
+	}
+	return s, nil
+}
+
+// FreezeAll returns the frozen form of the named schemas,
+// such as those of a Registry.
+func FreezeAll(schemas map[string]*jsonschema.Schema) ([]byte, error) {
+	f := &freezer{buf: append([]byte(frozenMagic), frozenVersion)}
+	f.buf = binary.BigEndian.AppendUint32(f.buf, frozenLayout)
+	f.uvarint(uint64(len(schemas)))
+	for _, name := range sortedKeys(schemas) {
+		f.string(name)
+		if err := f.schema(schemas[name]); err != nil {
+			return nil, fmt.Errorf("picoschema: freezing %q: %w", name, err)
+		}
+	}
+	return f.buf, nil
+}
+
+// ThawAll decodes the named schemas frozen by FreezeAll.
+func ThawAll(data []byte) (map[string]*jsonschema.Schema, error) {
+	header := len(frozenMagic) + 5
+	if len(data) < header || string(data[:len(frozenMagic)]) != frozenMagic {
+		return nil, fmt.Errorf("%w: bad header", errFrozen)
+	}
+	if v := data[len(frozenMagic)]; v != frozenVersion {
+		return nil, fmt.Errorf("%w: version %d, want %d", errFrozen, v, frozenVersion)
+	}
+	if binary.BigEndian.Uint32(data[len(frozenMagic)+1:]) != frozenLayout {
+		return nil, fmt.Errorf("%w: frozen with a different version of the jsonschema package", errFrozen)
+	}
+	t := &thawer{data: data[header:]}
+	n := t.count()
+	schemas := make(map[string]*jsonschema.Schema, n)
+	for i := 0; i < n && t.err == nil; i++ {
+		name := t.string()
+		schemas[name] = t.schema()
+	}
+	if t.err == nil && len(t.data) > 0 {
+		t.fail("trailing data")
+	}
+	if t.err != nil {
+		return nil, t.err
+	}
+	return schemas, nil
+}
+
+// A freezer appends the frozen form of schemas to buf.
+type freezer struct {
+	buf []byte
+}
+
+func (f *freezer) uvarint(n uint64) {
+	f.buf = binary.AppendUvarint(f.buf, n)
+}
+
+func (f *freezer) string(s string) {
+	f.uvarint(uint64(len(s)))
+	f.buf = append(f.buf, s...)
+}
+
+func (f *freezer) strings(list []string) {
+	f.uvarint(uint64(len(list)))
+	for _, s := range list {
+		f.string(s)
+	}
+}
+
+func (f *freezer) schema(s *jsonschema.Schema) error {
+	if s == nil {
+		f.buf = append(f.buf, frozenNil)
+		return nil
+	}
+	if b, ok := boolSchema(s); ok {
+		if b {
+			f.buf = append(f.buf, frozenTrue)
+		} else {
+			f.buf = append(f.buf, frozenFalse)
+		}
+		return nil
+	}
+	f.buf = append(f.buf, frozenObject)
+	rs := reflect.ValueOf(s).Elem()
+	for i := 0; i < rs.NumField(); i++ {
+		field := rs.Field(i)
+		if !rs.Type().Field(i).IsExported() || field.IsZero() {
+			continue
+		}
+		f.uvarint(uint64(i + 1))
+		var err error
+		if field.Type() == anyType {
+			err = f.value(field.Interface())
+		} else {
+			err = f.field(field.Interface())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	f.uvarint(0)
+	return nil
+}
+
+// field appends the value v of a Schema field.
+func (f *freezer) field(v any) error {
+	switch v := v.(type) {
+	case string:
+		f.string(v)
+	case jsonschema.ID:
+		f.string(string(v))
+	case json.Number:
+		f.string(string(v))
+	case bool:
+		// Only true is written, and its presence says so.
+	case *uint64:
+		f.uvarint(*v)
+	case []string:
+		f.strings(v)
+	case *jsonschema.Schema:
+		return f.schema(v)
+	case []*jsonschema.Schema:
+		f.uvarint(uint64(len(v)))
+		for _, s := range v {
+			if err := f.schema(s); err != nil {
+				return err
+			}
+		}
+	case jsonschema.Definitions:
+		return f.schemaMap(v)
+	case map[string]*jsonschema.Schema:
+		return f.schemaMap(v)
+	case *orderedmap.OrderedMap[string, *jsonschema.Schema]:
+		f.uvarint(uint64(v.Len()))
+		for p := v.Oldest(); p != nil; p = p.Next() {
+			f.string(p.Key)
+			if err := f.schema(p.Value); err != nil {
+				return err
+			}
+		}
+	case map[string][]string:
+		f.uvarint(uint64(len(v)))
+		for _, k := range sortedKeys(v) {
+			f.string(k)
+			f.strings(v[k])
+		}
+	case []any:
+		f.uvarint(uint64(len(v)))
+		for _, e := range v {
+			if err := f.value(e); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		return f.valueMap(v)
+	default:
+		return fmt.Errorf("unsupported field type %T", v)
+	}
+	return nil
+}
+
+func (f *freezer) schemaMap(m map[string]*jsonschema.Schema) error {
+	f.uvarint(uint64(len(m)))
+	for _, k := range sortedKeys(m) {
+		f.string(k)
+		if err := f.schema(m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *freezer) valueMap(m map[string]any) error {
+	f.uvarint(uint64(len(m)))
+	for _, k := range sortedKeys(m) {
+		f.string(k)
+		if err := f.value(m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value appends v, a value as decoded from YAML or JSON. Values of
+// other types are written as their JSON encoding, which thaws as it
+// would decode.
+func (f *freezer) value(v any) error {
+	switch v := v.(type) {
+	case nil:
+		f.buf = append(f.buf, valueNull)
+	case bool:
+		if v {
+			f.buf = append(f.buf, valueTrue)
+		} else {
+			f.buf = append(f.buf, valueFalse)
+		}
+	case int:
+		f.buf = append(f.buf, valueInt)
+		f.buf = binary.AppendVarint(f.buf, int64(v))
+	case float64:
+		f.buf = append(f.buf, valueFloat)
+		f.buf = binary.LittleEndian.AppendUint64(f.buf, math.Float64bits(v))
+	case string:
+		f.buf = append(f.buf, valueString)
+		f.string(v)
+	case json.Number:
+		f.buf = append(f.buf, valueNumber)
+		f.string(string(v))
+	case []any:
+		f.buf = append(f.buf, valueList)
+		f.uvarint(uint64(len(v)))
+		for _, e := range v {
+			if err := f.value(e); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		f.buf = append(f.buf, valueMap)
+		return f.valueMap(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		f.buf = append(f.buf, valueJSON)
+		f.string(string(data))
+	}
+	return nil
+}
+
+// A thawer decodes the frozen form of schemas from data. After the
+// first error, which it records in err, it decodes zero values.
+type thawer struct {
+	data []byte
+	err  error
+}
+
+func (t *thawer) fail(msg string) {
+	if t.err == nil {
+		t.err = fmt.Errorf("%w: %s", errFrozen, msg)
+	}
+	t.data = nil
+}
+
+func (t *thawer) byte() byte {
+	if len(t.data) == 0 {
+		t.fail("unexpected end of data")
+		return 0
+	}
+	b := t.data[0]
+	t.data = t.data[1:]
+	return b
+}
+
+func (t *thawer) uvarint() uint64 {
+	n, size := binary.Uvarint(t.data)
+	if size <= 0 {
+		t.fail("bad varint")
+		return 0
+	}
+	t.data = t.data[size:]
+	return n
+}
+
+// count decodes the number of elements of a list or map,
+// each of which takes at least one byte.
+func (t *thawer) count() int {
+	n := t.uvarint()
+	if n > uint64(len(t.data)) {
+		t.fail("count exceeds data")
+		return 0
+	}
+	return int(n)
+}
+
+func (t *thawer) string() string {
+	n := t.count()
+	s := string(t.data[:n])
+	t.data = t.data[n:]
+	return s
+}
+
+func (t *thawer) strings() []string {
+	n := t.count()
+	list := make([]string, n)
+	for i := range list {
+		list[i] = t.string()
+	}
+	return list
+}
+
+func (t *thawer) schema() *jsonschema.Schema {
+	switch t.byte() {
+	case frozenNil:
+		return nil
+	case frozenTrue:
+		return cloneSchema(jsonschema.TrueSchema)
+	case frozenFalse:
+		return cloneSchema(jsonschema.FalseSchema)
+	case frozenObject:
+	default:
+		t.fail("bad schema tag")
+		return nil
+	}
+	s := &jsonschema.Schema{}
+	rs := reflect.ValueOf(s).Elem()
+	for t.err == nil {
+		i := t.uvarint()
+		if i == 0 {
+			break
+		}
+		if i > uint64(rs.NumField()) || !rs.Type().Field(int(i-1)).IsExported() {
+			t.fail("bad field number")
+			break
+		}
+		field := rs.Field(int(i - 1))
+		if v := t.field(field.Type()); v != nil && t.err == nil {
+			field.Set(reflect.ValueOf(v))
+		}
+	}
+	return s
+}
+
+// Types of Schema fields.
+var (
+	idType         = reflect.TypeFor[jsonschema.ID]()
+	jsonNumberType = reflect.TypeFor[json.Number]()
+	schemaType     = reflect.TypeFor[*jsonschema.Schema]()
+	schemaListType = reflect.TypeFor[[]*jsonschema.Schema]()
+	defsType       = reflect.TypeFor[jsonschema.Definitions]()
+	schemaMapType  = reflect.TypeFor[map[string]*jsonschema.Schema]()
+	propertiesType = reflect.TypeFor[*orderedmap.OrderedMap[string, *jsonschema.Schema]]()
+	dependentType  = reflect.TypeFor[map[string][]string]()
+	valueListType  = reflect.TypeFor[[]any]()
+	valueMapType   = reflect.TypeFor[map[string]any]()
+	anyType        = reflect.TypeFor[any]()
+)
+
+// field decodes the value of a Schema field of type typ. It returns
+// nil for a value that is to be left zero.
+func (t *thawer) field(typ reflect.Type) any {
+	switch typ {
+	case reflect.TypeFor[string]():
+		return t.string()
+	case idType:
+		return jsonschema.ID(t.string())
+	case jsonNumberType:
+		return json.Number(t.string())
+	case reflect.TypeFor[bool]():
+		return true
+	case reflect.TypeFor[*uint64]():
+		n := t.uvarint()
+		return &n
+	case reflect.TypeFor[[]string]():
+		return t.strings()
+	case schemaType:
+		if s := t.schema(); s != nil {
+			return s
+		}
+		return nil
+	case schemaListType:
+		list := make([]*jsonschema.Schema, t.count())
+		for i := range list {
+			list[i] = t.schema()
+		}
+		return list
+	case defsType:
+		return jsonschema.Definitions(t.schemaMap())
+	case schemaMapType:
+		return t.schemaMap()
+	case propertiesType:
+		n := t.count()
+		props := orderedmap.New[string, *jsonschema.Schema](n)
+		for i := 0; i < n && t.err == nil; i++ {
+			k := t.string()
+			props.Set(k, t.schema())
+		}
+		return props
+	case dependentType:
+		n := t.count()
+		m := make(map[string][]string, n)
+		for i := 0; i < n && t.err == nil; i++ {
+			k := t.string()
+			m[k] = t.strings()
+		}
+		return m
+	case valueListType:
+		return t.list()
+	case valueMapType:
+		return t.valueMap()
+	case anyType:
+		if v := t.value(); v != nil {
+			return v
+		}
+		return nil
+	}
+	t.fail("unsupported field type " + typ.String())
+	return nil
+}
+
+func (t *thawer) schemaMap() map[string]*jsonschema.Schema {
+	n := t.count()
+	m := make(map[string]*jsonschema.Schema, n)
+	for i := 0; i < n && t.err == nil; i++ {
+		k := t.string()
+		m[k] = t.schema()
+	}
+	return m
+}
+
+func (t *thawer) list() []any {
+	list := make([]any, t.count())
+	for i := range list {
+		list[i] = t.value()
+	}
+	return list
+}
+
+func (t *thawer) valueMap() map[string]any {
+	n := t.count()
+	m := make(map[string]any, n)
+	for i := 0; i < n && t.err == nil; i++ {
+		k := t.string()
+		m[k] = t.value()
+	}
+	return m
+}
+
+func (t *thawer) value() any {
+	switch t.byte() {
+	case valueNull:
+		return nil
+	case valueFalse:
+		return false
+	case valueTrue:
+		return true
+	case valueInt:
+		n, size := binary.Varint(t.data)
+		if size <= 0 {
+			t.fail("bad varint")
+			return nil
+		}
+		t.data = t.data[size:]
+		return int(n)
+	case valueFloat:
+		if len(t.data) < 8 {
+			t.fail("unexpected end of data")
+			return nil
+		}
+		f := math.Float64frombits(binary.LittleEndian.Uint64(t.data))
+		t.data = t.data[8:]
+		return f
+	case valueString:
+		return t.string()
+	case valueNumber:
+		return json.Number(t.string())
+	case valueList:
+		return t.list()
+	case valueMap:
+		return t.valueMap()
+	case valueJSON:
+		var v any
+		if err := json.Unmarshal([]byte(t.string()), &v); err != nil {
+			t.fail("bad JSON value")
+		}
+		return v
+	}
+	t.fail("bad value tag")
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+)
+
+func TestFreezeRoundTrip(t *testing.T) {
+	s := mustSchema(t, `
+$defs:
+  money:
+    amount: number
+    currency(string, pattern=^[A-Z]{3}$):
+id(string, readOnly, example=o-1):
+total: money
+status(enum): [open, {closed: no longer accepting changes}]
+kind(const): order
+retries?(integer, default=3, minimum=0):
+point(tuple): [number, number]
+labels(*): string
+(/^x-/): any
+`)
+	one := uint64(1)
+	s.Properties.Set("extra", &jsonschema.Schema{
+		ID:                "urn:extra",
+		Const:             []any{1.5, json.Number("2"), nil, map[string]any{"a": true}},
+		DependentRequired: map[string][]string{"a": {"b", "c"}},
+		DependentSchemas:  map[string]*jsonschema.Schema{"a": {MinProperties: &one}},
+		Not:               jsonschema.FalseSchema,
+		Items:             jsonschema.TrueSchema,
+		Extras:            map[string]any{"x-unit": "ms", "x-list": []string{"a"}},
+	})
+	data, err := Freeze(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Thaw(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(schemaJSON(t, s), schemaJSON(t, got)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	// Values keep their Go types, as decoded from YAML.
+	if d := got.Properties.Value("retries").Default; d != 3 {
+		t.Errorf("default is %#v, want int 3", d)
+	}
+	if c := got.Properties.Value("extra").Const.([]any); c[1] != json.Number("2") {
+		t.Errorf("const member is %#v, want json.Number", c[1])
+	}
+	// Properties keep their order.
+	var keys []string
+	for p := got.Properties.Oldest(); p != nil; p = p.Next() {
+		keys = append(keys, p.Key)
+	}
+	var wantKeys []string
+	for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+		wantKeys = append(wantKeys, p.Key)
+	}
+	if diff := cmp.Diff(wantKeys, keys); diff != "" {
+		t.Errorf("property order mismatch (-want, +got):\n%s", diff)
+	}
+	// Boolean schemas are copies, not the shared true and false.
+	if not := got.Properties.Value("extra").Not; not == jsonschema.FalseSchema {
+		t.Error("thawed false schema is jsonschema.FalseSchema")
+	}
+}
+
+func TestFreezeAll(t *testing.T) {
+	schemas := map[string]*jsonschema.Schema{
+		"customer": mustSchema(t, "name: string"),
+		"flag":     jsonschema.TrueSchema,
+		"empty":    nil,
+	}
+	data, err := FreezeAll(schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ThawAll(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(schemas) {
+		t.Fatalf("got %d schemas, want %d", len(got), len(schemas))
+	}
+	for name, s := range schemas {
+		if diff := cmp.Diff(schemaJSON(t, s), schemaJSON(t, got[name])); diff != "" {
+			t.Errorf("%s: mismatch (-want, +got):\n%s", name, diff)
+		}
+	}
+	if _, err := Thaw(data); err == nil {
+		t.Error("Thaw of several schemas succeeded")
+	}
+}
+
+func TestThawInvalid(t *testing.T) {
+	data, err := Freeze(mustSchema(t, "name: string\ntags?(array): string\nstatus(enum): [a, 1]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every truncation fails cleanly.
+	for i := range data {
+		if _, err := Thaw(data[:i]); !errors.Is(err, errFrozen) {
+			t.Fatalf("Thaw of %d of %d bytes: got %v, want an invalid frozen form", i, len(data), err)
+		}
+	}
+	bad := append([]byte(nil), data...)
+	bad[len(frozenMagic)+1] ^= 0xff
+	if _, err := Thaw(bad); !errors.Is(err, errFrozen) {
+		t.Errorf("Thaw with another layout: got %v, want an invalid frozen form", err)
+	}
+	if _, err := Thaw(append(data, 0)); !errors.Is(err, errFrozen) {
+		t.Errorf("Thaw with trailing data: got %v, want an invalid frozen form", err)
+	}
+}
@@ -3,14 +3,16 @@ module github.com/jumonapp/picoschema
 go 1.22.6
 
 require (
+	github.com/google/go-cmp v0.6.0
 	github.com/invopop/jsonschema v0.12.0
+	github.com/santhosh-tekuri/jsonschema/v6 v6.0.2
 	github.com/wk8/go-ordered-map/v2 v2.1.8
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/bahlo/generic-list-go v0.2.0 // indirect
 	github.com/buger/jsonparser v1.1.1 // indirect
-	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
@@ -0,0 +1,499 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	gotoken "go/token"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/invopop/jsonschema"
+)
+
+// GenerateGo returns the source of a Go file of package pkg declaring
+// the type typeName, whose values encode with encoding/json as the JSON
+// values that s accepts, so that output conforming to a schema, such as
+// a model's response, can be decoded into typed Go values.
+//
+// An object with properties becomes a struct, whose fields have json
+// tags naming the properties and are sorted by name. A property that is
+// not required has the omitempty option, and is a pointer unless it is
+// a slice or map; a nullable one, as in picoschema "string?", is a
+// pointer too. An object nested in a property or an array is declared
+// as a type named after typeName and the property, as OrderCustomer is
+// for the property customer of Order. Each schema under $defs of s is
+// declared under its name, made an exported identifier. A description
+// becomes a doc comment and a jsonschema_description tag, as FromGoType
+// reads.
+//
+// Integers become int64, numbers float64, strings of the format
+// date-time time.Time, and maps map[string]T. Enums and consts become
+// the type of their values, with the values listed in the doc comment.
+// Unions and other schemas with no Go equivalent become any, and the
+// additional properties of a struct are not kept, nor are constraints
+// on which of its properties are present, as the exclusion groups of
+// picoschema write.
+//
+// Like the other generators, it follows only local refs; see Bundle.
+func GenerateGo(s *jsonschema.Schema, pkg, typeName string) ([]byte, error) {
+	if !gotoken.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("picoschema: package name %q is not an identifier", pkg)
+	}
+	if !gotoken.IsIdentifier(typeName) || !gotoken.IsExported(typeName) {
+		return nil, fmt.Errorf("picoschema: type name %q is not an exported identifier", typeName)
+	}
+	g := &goGenerator{root: typeName, defs: make(map[string]string), taken: map[string]bool{typeName: true}}
+	defs := sortedKeys(s.Definitions)
+	for _, name := range defs {
+		g.defs[name] = g.uniqueName(goName(name))
+	}
+	if err := g.declare(typeName, s); err != nil {
+		return nil, err
+	}
+	for _, name := range defs {
+		if err := g.declare(g.defs[name], s.Definitions[name]); err != nil {
+			return nil, fmt.Errorf("picoschema: $defs %q: %w", name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by picoschema. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if g.usesTime {
+		buf.WriteString("import \"time\"\n\n")
+	}
+	for _, d := range g.decls {
+		buf.WriteString(d)
+		buf.WriteString("\n")
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("picoschema: formatting generated Go: %w", err)
+	}
+	return src, nil
+}
+
+// A goGenerator writes Go type declarations of schemas.
+type goGenerator struct {
+	root     string
+	defs     map[string]string // Go names of $defs
+	taken    map[string]bool   // declared type names
+	decls    []string
+	usesTime bool
+}
+
+// uniqueName returns name, or name followed by a number if a type
+// of that name is already declared, and reserves it.
+func (g *goGenerator) uniqueName(name string) string {
+	unique := name
+	for i := 2; g.taken[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	g.taken[unique] = true
+	return unique
+}
+
+// declare declares the type name of s.
+func (g *goGenerator) declare(name string, s *jsonschema.Schema) error {
+	// Declare the types nested in s after it.
+	i := len(g.decls)
+	g.decls = append(g.decls, "")
+	var sb strings.Builder
+	writeGoDoc(&sb, name+" is", s, "")
+	if goIsStruct(s) {
+		body, err := g.structBody(name, s)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sb, "type %s struct {\n%s}\n", name, body)
+	} else {
+		t, err := g.typ(name, s)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sb, "type %s %s\n", name, t)
+	}
+	g.decls[i] = sb.String()
+	return nil
+}
+
+// goIsStruct reports whether s becomes a struct: an object with properties.
+func goIsStruct(s *jsonschema.Schema) bool {
+	if _, ok := boolSchema(s); ok {
+		return false
+	}
+	s = withoutPresence(s)
+	return s.Properties != nil && s.Properties.Len() > 0 && s.Ref == "" &&
+		s.AnyOf == nil && s.OneOf == nil && s.AllOf == nil && (s.Type == "" || s.Type == "object")
+}
+
+// structBody returns the fields of the struct type name of s.
+// withoutPresence returns s without the subschemas that only constrain
+// which of its properties are present, such as those of the exclusion
+// groups of picoschema, which the generated types do not express.
+func withoutPresence(s *jsonschema.Schema) *jsonschema.Schema {
+	if _, ok := boolSchema(s); ok || s == nil {
+		return s
+	}
+	allOf := slices.DeleteFunc(slices.Clone(s.AllOf), isPresenceOnly)
+	dropNot := s.Not != nil && isPresenceOnly(s.Not)
+	dropAnyOf := s.AnyOf != nil && !slices.ContainsFunc(s.AnyOf, isNotPresenceOnly)
+	dropOneOf := s.OneOf != nil && !slices.ContainsFunc(s.OneOf, isNotPresenceOnly)
+	if len(allOf) == len(s.AllOf) && !dropNot && !dropAnyOf && !dropOneOf {
+		return s
+	}
+	c := *s
+	c.AllOf = allOf
+	if len(allOf) == 0 {
+		c.AllOf = nil
+	}
+	if dropNot {
+		c.Not = nil
+	}
+	if dropAnyOf {
+		c.AnyOf = nil
+	}
+	if dropOneOf {
+		c.OneOf = nil
+	}
+	return &c
+}
+
+// isPresenceOnly reports whether s only constrains which properties are
+// present: its only keywords are required, and not, anyOf, oneOf and
+// allOf of such schemas.
+func isPresenceOnly(s *jsonschema.Schema) bool {
+	if _, ok := boolSchema(s); ok || s == nil {
+		return false
+	}
+	kws := schemaKeywords(s)
+	if len(kws) == 0 {
+		return false
+	}
+	for _, k := range kws {
+		switch k {
+		case "required":
+		case "not":
+			if !isPresenceOnly(s.Not) {
+				return false
+			}
+		case "anyOf", "oneOf", "allOf":
+			if slices.ContainsFunc(*compositions[k](s), isNotPresenceOnly) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isNotPresenceOnly is the negation of isPresenceOnly.
+func isNotPresenceOnly(s *jsonschema.Schema) bool { return !isPresenceOnly(s) }
+
+func (g *goGenerator) structBody(name string, s *jsonschema.Schema) (string, error) {
+	var sb strings.Builder
+	var props []string
+	for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+		props = append(props, p.Key)
+	}
+	slices.Sort(props)
+	fields := make(map[string]bool)
+	for _, prop := range props {
+		ps := s.Properties.Value(prop)
+		field := goName(prop)
+		for i := 2; fields[field]; i++ {
+			field = goName(prop) + strconv.Itoa(i)
+		}
+		fields[field] = true
+
+		t, err := g.typ(name+field, ps)
+		if err != nil {
+			return "", fmt.Errorf("property %q: %w", prop, err)
+		}
+		required := slices.Contains(s.Required, prop)
+		if !required && !strings.HasPrefix(t, "*") && !strings.HasPrefix(t, "[]") && !strings.HasPrefix(t, "map[") && t != "any" {
+			t = "*" + t
+		}
+		tag := prop
+		if !required {
+			tag += ",omitempty"
+		}
+		tags := "json:" + strconv.Quote(tag)
+		if desc := schemaDescription(ps); desc != "" && !strings.Contains(desc, "`") {
+			tags += " jsonschema_description:" + strconv.Quote(desc)
+		}
+		writeGoDoc(&sb, field+" is", ps, "\t")
+		fmt.Fprintf(&sb, "\t%s %s `%s`\n", field, t, tags)
+	}
+	return sb.String(), nil
+}
+
+// typ returns the Go type of s. An object with properties is declared
+// as a struct named name.
+func (g *goGenerator) typ(name string, s *jsonschema.Schema) (string, error) {
+	if _, ok := boolSchema(s); ok {
+		return "any", nil
+	}
+	s = withoutPresence(s)
+	if s.Ref != "" {
+		t, err := g.ref(s.Ref)
+		if err != nil {
+			return "", err
+		}
+		if s.AnyOf == nil && s.OneOf == nil && s.AllOf == nil {
+			return t, nil
+		}
+		return "any", nil
+	}
+	if alt, ok := nullableAlternative(s); ok {
+		t, err := g.typ(name, alt)
+		if err != nil || strings.HasPrefix(t, "*") || t == "any" {
+			return t, err
+		}
+		return "*" + t, nil
+	}
+	if len(s.AllOf) == 1 && s.AnyOf == nil && s.OneOf == nil && len(schemaKeywords(s)) == 1 {
+		return g.typ(name, s.AllOf[0])
+	}
+	if s.AnyOf != nil || s.OneOf != nil || s.AllOf != nil {
+		return "any", nil
+	}
+	if s.Const != nil {
+		return goValueType([]any{s.Const}), nil
+	}
+	if s.Enum != nil {
+		return goValueType(s.Enum), nil
+	}
+	if goIsStruct(s) {
+		name = g.uniqueName(name)
+		if err := g.declare(name, s); err != nil {
+			return "", err
+		}
+		return name, nil
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			g.usesTime = true
+			return "time.Time", nil
+		}
+		return "string", nil
+	case "integer":
+		return "int64", nil
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		items := s.Items
+		if len(s.PrefixItems) > 0 {
+			// Go has no tuples.
+			items = nil
+		}
+		if items == nil {
+			return "[]any", nil
+		}
+		t, err := g.typ(name+"Item", items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + t, nil
+	case "object":
+		if s.AdditionalProperties == nil {
+			return "map[string]any", nil
+		}
+		if b, ok := boolSchema(s.AdditionalProperties); ok && !b {
+			return "struct{}", nil
+		}
+		t, err := g.typ(name+"Value", s.AdditionalProperties)
+		if err != nil {
+			return "", err
+		}
+		return "map[string]" + t, nil
+	}
+	return "any", nil
+}
+
+// nullableAlternative returns the alternative to null of s,
+// if s is a nullable schema as picoschema writes it.
+func nullableAlternative(s *jsonschema.Schema) (*jsonschema.Schema, bool) {
+	if len(s.AnyOf) != 2 || s.AnyOf[1].Type != "null" || len(schemaKeywords(s.AnyOf[1])) != 1 {
+		return nil, false
+	}
+	for _, k := range schemaKeywords(s) {
+		if k != "anyOf" && !isAnnotation(k) {
+			return nil, false
+		}
+	}
+	return s.AnyOf[0], true
+}
+
+// goValueType returns the Go type of the JSON values vals.
+func goValueType(vals []any) string {
+	var types []string
+	for _, v := range vals {
+		var t string
+		switch v := v.(type) {
+		case string:
+			t = "string"
+		case bool:
+			t = "bool"
+		case int, int64, uint64:
+			t = "int64"
+		case float64:
+			t = "float64"
+			if v == float64(int64(v)) {
+				t = "int64"
+			}
+		default:
+			return "any"
+		}
+		if !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 2 && slices.Contains(types, "int64") && slices.Contains(types, "float64") {
+		return "float64"
+	}
+	if len(types) != 1 {
+		return "any"
+	}
+	return types[0]
+}
+
+// ref returns the Go type that ref refers to.
+func (g *goGenerator) ref(ref string) (string, error) {
+	if ref == "#" {
+		return "*" + g.root, nil
+	}
+	if rest, ok := strings.CutPrefix(ref, "#/$defs/"); ok {
+		if name, ok := g.defs[unescapePointer(rest)]; ok {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("$ref %q is not to the schema or one of its $defs", ref)
+}
+
+// schemaDescription returns the description of s, or of the
+// alternative to null of a nullable s.
+func schemaDescription(s *jsonschema.Schema) string {
+	if _, ok := boolSchema(s); ok {
+		return ""
+	}
+	if s.Description == "" {
+		if alt, ok := nullableAlternative(s); ok {
+			return alt.Description
+		}
+	}
+	return s.Description
+}
+
+// writeGoDoc writes the doc comment of s, beginning with subject, such
+// as "Order is", indented by indent: its description, the values of an
+// enum or const, and whether it is deprecated.
+func writeGoDoc(sb *strings.Builder, subject string, s *jsonschema.Schema, indent string) {
+	if _, ok := boolSchema(s); ok {
+		return
+	}
+	var paras []string
+	if desc := schemaDescription(s); desc != "" {
+		paras = append(paras, subject+" "+desc)
+	}
+	vals := s.Enum
+	if s.Const != nil {
+		vals = []any{s.Const}
+	}
+	if alt, ok := nullableAlternative(s); ok && vals == nil {
+		vals = alt.Enum
+	}
+	if len(vals) > 0 {
+		var lits []string
+		for _, v := range vals {
+			lits = append(lits, jsonText(v))
+		}
+		paras = append(paras, "One of "+strings.Join(lits, ", ")+".")
+	}
+	if s.Deprecated {
+		paras = append(paras, "Deprecated: do not use.")
+	}
+	for i, p := range paras {
+		if i > 0 {
+			sb.WriteString(indent + "//\n")
+		}
+		for _, l := range strings.Split(p, "\n") {
+			fmt.Fprintf(sb, "%s// %s\n", indent, strings.TrimRight(l, " "))
+		}
+	}
+}
+
+// goInitialisms are the words written in upper case in Go names.
+var goInitialisms = []string{"API", "HTML", "HTTP", "HTTPS", "ID", "JSON", "SQL", "URI", "URL", "UUID", "XML"}
+
+// goName returns name, a property or $defs name, as an exported Go
+// identifier: its letters and digits in Pascal case, with common
+// initialisms in upper case, as "user_id" becomes "UserID" and
+// "createdAt" becomes "CreatedAt".
+func goName(name string) string {
+	var sb strings.Builder
+	for _, w := range nameWords(name) {
+		if up := strings.ToUpper(w); slices.Contains(goInitialisms, up) {
+			sb.WriteString(up)
+			continue
+		}
+		rs := []rune(w)
+		sb.WriteRune(unicode.ToUpper(rs[0]))
+		sb.WriteString(string(rs[1:]))
+	}
+	id := sb.String()
+	if id == "" || !unicode.IsLetter([]rune(id)[0]) {
+		id = "X" + id
+	}
+	return id
+}
+
+// nameWords splits name into words at characters other than letters
+// and digits and at changes of case, as "createdAt" and "created-at"
+// have the words "created" and "At" or "at".
+func nameWords(name string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	rs := []rune(name)
+	for i, r := range rs {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && len(word) > 0 && (unicode.IsLower(word[len(word)-1]) ||
+			i+1 < len(rs) && unicode.IsLower(rs[i+1])):
+			// A new word, as in "createdAt" or the "Parser" of "JSONParser".
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+	return words
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerateGo(t *testing.T) {
+	s := mustSchema(t, `
+$defs:
+  money:
+    amount: number
+    currency: string
+order_id: string, the order ID
+status(enum): [open, closed]
+createdAt(string, format=date-time):
+note?: string?
+total: money
+discount?: money
+customer(object):
+  name: string
+  email?: string
+lines(array):
+  sku: string
+  quantity(integer):
+tags?(array): string
+labels?(*): string
+payment(oneOf):
+  - {card: string}
+  - {iban: string}
+legacy?(string, deprecated):
+`)
+	got, err := GenerateGo(s, "orders", "Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "// Code generated by picoschema. DO NOT EDIT.\n" + `
+package orders
+
+import "time"
+
+type Order struct {
+	CreatedAt time.Time         ` + "`json:\"createdAt\"`" + `
+	Customer  OrderCustomer     ` + "`json:\"customer\"`" + `
+	Discount  *Money            ` + "`json:\"discount,omitempty\"`" + `
+	Labels    map[string]string ` + "`json:\"labels,omitempty\"`" + `
+	// Deprecated: do not use.
+	Legacy *string          ` + "`json:\"legacy,omitempty\"`" + `
+	Lines  []OrderLinesItem ` + "`json:\"lines\"`" + `
+	Note   *string          ` + "`json:\"note,omitempty\"`" + `
+	// OrderID is the order ID
+	OrderID string ` + "`json:\"order_id\" jsonschema_description:\"the order ID\"`" + `
+	Payment any    ` + "`json:\"payment\"`" + `
+	// One of "open", "closed".
+	Status string   ` + "`json:\"status\"`" + `
+	Tags   []string ` + "`json:\"tags,omitempty\"`" + `
+	Total  Money    ` + "`json:\"total\"`" + `
+}
+
+type OrderCustomer struct {
+	Email *string ` + "`json:\"email,omitempty\"`" + `
+	Name  string  ` + "`json:\"name\"`" + `
+}
+
+type OrderLinesItem struct {
+	Quantity int64  ` + "`json:\"quantity\"`" + `
+	Sku      string ` + "`json:\"sku\"`" + `
+}
+
+type Money struct {
+	Amount   float64 ` + "`json:\"amount\"`" + `
+	Currency string  ` + "`json:\"currency\"`" + `
+}
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestGenerateGoErrors(t *testing.T) {
+	s := mustSchema(t, "a: string")
+	if _, err := GenerateGo(s, "orders", "order"); err == nil {
+		t.Error("unexported type name accepted")
+	}
+	if _, err := GenerateGo(s, "my-orders", "Order"); err == nil {
+		t.Error("invalid package name accepted")
+	}
+	s.Properties.Value("a").Ref = "customer"
+	if _, err := GenerateGo(s, "orders", "Order"); err == nil || !strings.Contains(err.Error(), `$ref "customer"`) {
+		t.Errorf("external ref: got %v", err)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for name, want := range map[string]string{
+		"id":           "ID",
+		"user_id":      "UserID",
+		"createdAt":    "CreatedAt",
+		"JSONParser":   "JSONParser",
+		"api-url":      "APIURL",
+		"tree node":    "TreeNode",
+		"2fa":          "X2fa",
+		"content-type": "ContentType",
+	} {
+		if got := goName(name); got != want {
+			t.Errorf("goName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGenerateGroupedSchema(t *testing.T) {
+	// The exclusion groups only constrain which properties are present,
+	// which the generated types leave to validation.
+	s := mustSchema(t, `
+(oneOfGroup): [email, phone]
+(atMostOneGroup): [nickname, alias]
+email?: string
+phone?: string
+nickname?: string
+alias?: string
+`)
+	generate := map[string]func() (string, error){
+		"go": func() (string, error) {
+			b, err := GenerateGo(s, "p", "Contact")
+			return string(b), err
+		},
+		"proto": func() (string, error) {
+			b, err := GenerateProto(s, "p", "Contact")
+			return string(b), err
+		},
+		"pydantic": func() (string, error) {
+			b, err := GeneratePydantic(s, "Contact")
+			return string(b), err
+		},
+		"typescript": func() (string, error) {
+			return GenerateTypeScript(s, TypeScriptOptions{RootName: "Contact"})
+		},
+		"zod": func() (string, error) {
+			return GenerateZod(s, ZodOptions{RootName: "Contact"})
+		},
+	}
+	for lang, want := range map[string]string{
+		"go":         "\tEmail    *string `json:\"email,omitempty\"`\n",
+		"proto":      "  optional string email = 2;\n",
+		"pydantic":   "    email: Optional[str] = None\n",
+		"typescript": "interface Contact {\n  alias?: string;\n  email?: string;\n",
+		"zod":        "const Contact = z.object({\n  alias: z.string().optional(),\n  email: z.string().optional(),\n",
+	} {
+		got, err := generate[lang]()
+		if err != nil {
+			t.Errorf("%s: %v", lang, err)
+			continue
+		}
+		if !strings.Contains(got, want) {
+			t.Errorf("%s: output does not contain %q:\n%s", lang, want, got)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"cmp"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// FromGoType returns picoschema describing the JSON encoding of v,
+// which is usually the zero value of a struct type, such as
+// FromGoType(Request{}). Fields are named, promoted from embedded
+// structs and omitted as by encoding/json; fields with the omitempty
+// option, pointer fields and fields of embedded struct pointers are
+// optional. A field's jsonschema_description tag, if present,
+// becomes its description.
+//
+// Parts picoschema cannot express directly, such as slices of slices,
+// are written as JSON Schema, as FromJSONSchema does.
+// FromGoType fails if the type is recursive.
+func FromGoType(v any) (any, error) {
+	if v == nil {
+		return nil, fmt.Errorf("picoschema: FromGoType of nil")
+	}
+	t := reflect.TypeOf(v)
+	s, err := goTypeSchema(t, nil)
+	if err != nil {
+		return nil, err
+	}
+	val, report, err := FromJSONSchemaReport(s)
+	if err != nil {
+		return nil, err
+	}
+	if !report.Lossless() {
+		return nil, fmt.Errorf("picoschema: type %s: cannot express %s", t, strings.Join(report.Dropped, ", "))
+	}
+	return val, nil
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	numberType        = reflect.TypeFor[json.Number]()
+	marshalerType     = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// goTypeSchema returns the schema of the JSON encoding of values of
+// type t. stack holds the struct types being converted.
+func goTypeSchema(t reflect.Type, stack []reflect.Type) (*jsonschema.Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &jsonschema.Schema{Type: "string"}, nil
+	case rawMessageType:
+		return &jsonschema.Schema{}, nil
+	case numberType:
+		return &jsonschema.Schema{Type: "number"}, nil
+	}
+	pt := reflect.PointerTo(t)
+	switch {
+	case t.Implements(marshalerType) || pt.Implements(marshalerType):
+		return &jsonschema.Schema{}, nil
+	case t.Implements(textMarshalerType) || pt.Implements(textMarshalerType):
+		return &jsonschema.Schema{Type: "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &jsonschema.Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &jsonschema.Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &jsonschema.Schema{Type: "number"}, nil
+	case reflect.String:
+		return &jsonschema.Schema{Type: "string"}, nil
+	case reflect.Interface:
+		return &jsonschema.Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes []byte as base64.
+			return &jsonschema.Schema{Type: "string"}, nil
+		}
+		items, err := goTypeSchema(t.Elem(), stack)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonschema.Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !t.Key().Implements(textMarshalerType) {
+				return nil, fmt.Errorf("picoschema: map key type %s is not supported", t.Key())
+			}
+		}
+		vs, err := goTypeSchema(t.Elem(), stack)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonschema.Schema{
+			Type:                 "object",
+			Properties:           orderedmap.New[string, *jsonschema.Schema](),
+			AdditionalProperties: vs,
+		}, nil
+	case reflect.Struct:
+		if slices.Contains(stack, t) {
+			return nil, fmt.Errorf("picoschema: recursive type %s cannot be expressed", t)
+		}
+		s := &jsonschema.Schema{
+			Type:                 "object",
+			Properties:           orderedmap.New[string, *jsonschema.Schema](),
+			AdditionalProperties: jsonschema.FalseSchema,
+		}
+		if err := addStructFields(s, t, append(slices.Clip(stack), t)); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("picoschema: type %s is not supported", t)
+}
+
+// addStructFields adds the fields of the struct type t to the object
+// schema s, following the rules of encoding/json.
+func addStructFields(s *jsonschema.Schema, t reflect.Type, stack []reflect.Type) error {
+	for _, f := range jsonFields(t) {
+		var fs *jsonschema.Schema
+		if slices.Contains(f.opts, "string") {
+			fs = &jsonschema.Schema{Type: "string"}
+		} else {
+			var err error
+			if fs, err = goTypeSchema(f.Type, stack); err != nil {
+				return fmt.Errorf("picoschema: field %s.%s: %w", t, f.Name, err)
+			}
+		}
+		fs.Description = f.Tag.Get("jsonschema_description")
+		s.Properties.Set(f.name, fs)
+		if !f.optional && f.Type.Kind() != reflect.Pointer && !slices.Contains(f.opts, "omitempty") {
+			s.Required = append(s.Required, f.name)
+		}
+	}
+	return nil
+}
+
+// A jsonField is a field of a struct as encoding/json encodes it.
+type jsonField struct {
+	reflect.StructField
+	name     string   // the JSON name
+	tagged   bool     // whether the name comes from the tag
+	opts     []string // the options of the tag
+	index    []int    // the index sequence from the outer struct
+	optional bool     // whether the field is reached through an embedded pointer
+}
+
+// jsonFields returns the fields of the struct type t that encoding/json
+// encodes, in the order it encodes them. As there, the fields of an
+// embedded struct without a name in its tag are promoted, while one
+// with a name is a field of its own; of the fields with the same name,
+// the least nested wins, then the tagged one, and if that leaves
+// several, none is encoded.
+func jsonFields(t reflect.Type) []jsonField {
+	type embedded struct {
+		typ      reflect.Type
+		index    []int
+		optional bool
+	}
+	var fields []jsonField
+	next := []embedded{{typ: t}}
+	visited := make(map[reflect.Type]bool)
+	for len(next) > 0 {
+		current := next
+		next = nil
+		// count counts the structs of each type at this depth, whose
+		// fields therefore conflict.
+		count := make(map[reflect.Type]int)
+		for _, e := range current {
+			count[e.typ]++
+		}
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+			for i := range e.typ.NumField() {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				index := append(slices.Clip(e.index), i)
+				optional := e.optional
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{ft, index, optional || sf.Type.Kind() == reflect.Pointer})
+					continue
+				}
+				f := jsonField{sf, name, name != "", strings.Split(opts, ","), index, optional}
+				if !f.tagged {
+					f.name = sf.Name
+				}
+				fields = append(fields, f)
+				if count[e.typ] > 1 {
+					// The same struct at the same depth conflicts
+					// with itself.
+					fields = append(fields, f)
+				}
+			}
+		}
+	}
+
+	slices.SortStableFunc(fields, func(a, b jsonField) int {
+		return cmp.Or(
+			strings.Compare(a.name, b.name),
+			cmp.Compare(len(a.index), len(b.index)),
+			compareBool(b.tagged, a.tagged),
+			slices.Compare(a.index, b.index),
+		)
+	})
+	var ret []jsonField
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		if f, ok := dominantField(fields[i:j]); ok {
+			ret = append(ret, f)
+		}
+		i = j
+	}
+	slices.SortFunc(ret, func(a, b jsonField) int { return slices.Compare(a.index, b.index) })
+	return ret
+}
+
+// dominantField returns the field that encoding/json encodes among
+// fields, which have the same name and are sorted by depth and then
+// with tagged fields first.
+func dominantField(fields []jsonField) (jsonField, bool) {
+	if len(fields) > 1 && len(fields[0].index) == len(fields[1].index) && fields[0].tagged == fields[1].tagged {
+		return jsonField{}, false
+	}
+	return fields[0], true
+}
+
+// compareBool orders false before true.
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
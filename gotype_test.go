@@ -0,0 +1,141 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testAudit struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Author    string    `json:"author,omitempty"`
+}
+
+type testOrder struct {
+	testAudit
+	ID       int64          `json:"id" jsonschema_description:"order number"`
+	Customer *testCustomer  `json:"customer"`
+	Lines    []testLine     `json:"lines"`
+	Tags     []string       `json:"tags,omitempty"`
+	Meta     map[string]any `json:"meta,omitempty"`
+	Counts   map[string]int `json:"counts"`
+	Price    float64        `json:"price,string"`
+	Data     []byte         `json:"data"`
+	Secret   string         `json:"-"`
+	Note     string
+	internal string
+}
+
+type testCustomer struct {
+	Name string `json:"name"`
+}
+
+type testLine struct {
+	SKU string `json:"sku"`
+	Qty int    `json:"qty"`
+}
+
+type testTree struct {
+	Children []testTree `json:"children"`
+}
+
+type testA struct {
+	Name string
+	X    int `json:"x"`
+}
+
+type testB struct {
+	Name string
+	Y    int `json:"y"`
+}
+
+type testEmbedding struct {
+	testAudit `json:"audit"`
+	testA
+	testB
+	*testLine
+	X string `json:"x"`
+}
+
+func TestFromGoTypeEmbedded(t *testing.T) {
+	got, err := FromGoType(testEmbedding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tagged testAudit is a field of its own; the names of testA
+	// and testB conflict at the same depth and are dropped; the outer
+	// x shadows testA's; testLine's fields are optional, as it may be nil.
+	want := map[string]any{
+		"audit": map[string]any{
+			"createdAt": "string",
+			"author?":   "string",
+		},
+		"x":    "string",
+		"y":    "integer",
+		"sku?": "string",
+		"qty?": "integer",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestFromGoType(t *testing.T) {
+	got, err := FromGoType(testOrder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"createdAt": "string",
+		"author?":   "string",
+		"id":        "integer, order number",
+		"customer?": map[string]any{"name": "string"},
+		"lines(array)": map[string]any{
+			"sku": "string",
+			"qty": "integer",
+		},
+		"tags?(array)": "string",
+		"meta?":        map[string]any{"(*)": "any"},
+		"counts":       map[string]any{"(*)": "integer"},
+		"price":        "string",
+		"data":         "string",
+		"Note":         "string",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if _, err := ToJSONSchema(got); err != nil {
+		t.Errorf("converting result: %v", err)
+	}
+
+	got, err = FromGoType(struct {
+		Grid [][]int `json:"grid"`
+	}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]any{"grid(array(array))": "integer"}, got); diff != "" {
+		t.Errorf("nested array mismatch (-want, +got):\n%s", diff)
+	}
+
+	for _, v := range []any{testTree{}, map[[2]int]string{}, make(chan int)} {
+		if _, err := FromGoType(v); err == nil {
+			t.Errorf("FromGoType(%T) succeeded", v)
+		}
+	}
+}
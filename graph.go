@@ -0,0 +1,150 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A DependencyGraph records which registered schemas refer to which.
+type DependencyGraph struct {
+	// Edges maps each schema name to the sorted names of the
+	// registered schemas it refers to.
+	Edges map[string][]string
+	// Missing maps schema names to the sorted refs they contain
+	// that name no registered schema. Local refs such as "#/$defs/x"
+	// are not included.
+	Missing map[string][]string
+	// Order lists every schema name after the schemas it refers to,
+	// so that building in this order builds dependencies first.
+	// The members of a cycle appear together, in sorted order.
+	Order []string
+	// Cycles lists the groups of schemas that refer to each other
+	// directly or indirectly, each sorted by name.
+	Cycles [][]string
+}
+
+// DependencyGraph returns the graph of references between the schemas
+// in r. A schema refers to another through a $ref anywhere within it,
+// including one under allOf that extends the other schema. A ref names a
+// registered schema by its name, optionally followed by a file
+// extension such as ".json" and a "#" fragment, as in "customer",
+// "customer.json" or "./customer.yaml#/properties/id". An unqualified
+// name refers first to the schema of that name in the referring
+// schema's namespace.
+func (r *Registry) DependencyGraph() *DependencyGraph {
+	g := &DependencyGraph{
+		Edges:   make(map[string][]string),
+		Missing: make(map[string][]string),
+	}
+	names := r.Names()
+	for _, name := range names {
+		s, _ := r.Lookup(name)
+		var deps, missing []string
+		for _, ref := range schemaRefs(s) {
+			target, ok := refTarget(ref)
+			if !ok {
+				continue
+			}
+			if dep, found := r.lookupRef(name, target); found {
+				deps = append(deps, dep)
+			} else {
+				missing = append(missing, ref)
+			}
+		}
+		slices.Sort(deps)
+		g.Edges[name] = slices.Compact(deps)
+		if len(missing) > 0 {
+			slices.Sort(missing)
+			g.Missing[name] = slices.Compact(missing)
+		}
+	}
+	g.order(names)
+	return g
+}
+
+// order computes Order and Cycles with Tarjan's algorithm, which emits
+// each strongly connected component after every component it reaches.
+func (g *DependencyGraph) order(names []string) {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var visit func(n string)
+	visit = func(n string) {
+		index[n] = len(index)
+		low[n] = index[n]
+		stack = append(stack, n)
+		onStack[n] = true
+		for _, m := range g.Edges[n] {
+			if _, seen := index[m]; !seen {
+				visit(m)
+				low[n] = min(low[n], low[m])
+			} else if onStack[m] {
+				low[n] = min(low[n], index[m])
+			}
+		}
+		if low[n] != index[n] {
+			return
+		}
+		i := slices.Index(stack, n)
+		comp := slices.Clone(stack[i:])
+		stack = stack[:i]
+		for _, m := range comp {
+			onStack[m] = false
+		}
+		slices.Sort(comp)
+		if len(comp) > 1 || slices.Contains(g.Edges[n], n) {
+			g.Cycles = append(g.Cycles, comp)
+		}
+		g.Order = append(g.Order, comp...)
+	}
+	for _, n := range names {
+		if _, seen := index[n]; !seen {
+			visit(n)
+		}
+	}
+}
+
+// schemaRefs returns the $ref values in s and its subschemas.
+func schemaRefs(s *jsonschema.Schema) []string {
+	var refs []string
+	walkSchema(s, "", func(s *jsonschema.Schema, _ string) bool {
+		if s.Ref != "" {
+			refs = append(refs, s.Ref)
+		}
+		return true
+	})
+	return refs
+}
+
+// refTarget returns the schema name a ref refers to,
+// or false if it is a local ref within the same schema.
+func refTarget(ref string) (string, bool) {
+	target, _, _ := strings.Cut(ref, "#")
+	if target == "" {
+		return "", false
+	}
+	target = strings.TrimPrefix(target, "./")
+	switch path.Ext(target) {
+	case ".json", ".yaml", ".yml":
+		target = strings.TrimSuffix(target, path.Ext(target))
+	}
+	return target, true
+}
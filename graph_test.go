@@ -0,0 +1,64 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	ref := func(r string) *jsonschema.Schema { return &jsonschema.Schema{Ref: r} }
+	obj := func(props ...string) *jsonschema.Schema {
+		s := &jsonschema.Schema{Type: "object", Properties: orderedmap.New[string, *jsonschema.Schema]()}
+		for i := 0; i+1 < len(props); i += 2 {
+			s.Properties.Set(props[i], ref(props[i+1]))
+		}
+		return s
+	}
+	var r Registry
+	for name, s := range map[string]*jsonschema.Schema{
+		"address":  obj("self", "#"),
+		"customer": obj("home", "address.json", "orders", "order"),
+		"order":    obj("buyer", "./customer.yaml#/properties/id", "shipTo", "address"),
+		"invoice":  {AllOf: []*jsonschema.Schema{ref("order"), ref("https://example.com/tax.json")}},
+		"loop":     obj("next", "loop"),
+	} {
+		if err := r.Register(name, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g := r.DependencyGraph()
+	want := &DependencyGraph{
+		Edges: map[string][]string{
+			"address":  nil,
+			"customer": {"address", "order"},
+			"invoice":  {"order"},
+			"loop":     {"loop"},
+			"order":    {"address", "customer"},
+		},
+		Missing: map[string][]string{
+			"invoice": {"https://example.com/tax.json"},
+		},
+		Order:  []string{"address", "customer", "order", "invoice", "loop"},
+		Cycles: [][]string{{"customer", "order"}, {"loop"}},
+	}
+	if diff := cmp.Diff(want, g); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// descriptionsKey is the annotation mapping locales to descriptions.
+// In the expanded form of picoschema a description may be written
+// as a map keyed by locale:
+//
+//	type: object
+//	description:
+//	  en: A customer order
+//	  ja: 顧客の注文
+//
+// The description of the schema is then the one for defaultLocale, if
+// present, or else the one for the first locale in sorted order; Localize
+// selects another.
+const descriptionsKey = "x-descriptions"
+
+// defaultLocale is the locale whose description is used
+// when no locale has been selected.
+const defaultLocale = "en"
+
+// setLocalizedDescription records the descriptions in v, a map from
+// locale to description, in s.
+func setLocalizedDescription(s *jsonschema.Schema, v map[string]any) error {
+	if len(v) == 0 {
+		return fmt.Errorf("picoschema: empty localized description")
+	}
+	for loc, d := range v {
+		if _, ok := d.(string); !ok {
+			return fmt.Errorf("picoschema: found type %T for description in locale %q, want %T", d, loc, "")
+		}
+	}
+	loc, ok := matchLocale(v, defaultLocale)
+	if !ok {
+		loc = sortedKeys(v)[0]
+	}
+	s.Description = v[loc].(string)
+	setExtra(s, descriptionsKey, v)
+	return nil
+}
+
+// Localize returns a copy of s in which every schema with localized
+// descriptions has the description for locale. A locale such as "pt-BR"
+// falls back to its language, "pt", if there is no exact match; schemas
+// with no description in the locale keep their default description.
+// The descriptions for the other locales are preserved in the
+// x-descriptions annotation. s is not modified.
+func Localize(s *jsonschema.Schema, locale string) *jsonschema.Schema {
+	c := cloneSchema(s)
+	walkSchema(c, "", func(s *jsonschema.Schema, _ string) bool {
+		ds, ok := s.Extras[descriptionsKey].(map[string]any)
+		if !ok {
+			return true
+		}
+		loc, ok := matchLocale(ds, locale)
+		if !ok {
+			return true
+		}
+		s.Description, _ = ds[loc].(string)
+		rest := make(map[string]any, len(ds)-1)
+		for k, d := range ds {
+			if k != loc {
+				rest[k] = d
+			}
+		}
+		if len(rest) == 0 {
+			deleteExtra(s, descriptionsKey)
+		} else {
+			setExtra(s, descriptionsKey, rest)
+		}
+		return true
+	})
+	return c
+}
+
+// matchLocale returns the key of ds that best matches locale:
+// the locale itself, compared case-insensitively, or else its language.
+func matchLocale(ds map[string]any, locale string) (string, bool) {
+	lang, _, _ := strings.Cut(locale, "-")
+	var langMatch string
+	for _, k := range sortedKeys(ds) {
+		if strings.EqualFold(k, locale) {
+			return k, true
+		}
+		if langMatch == "" && strings.EqualFold(k, lang) {
+			langMatch = k
+		}
+	}
+	return langMatch, langMatch != ""
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLocalize(t *testing.T) {
+	s := mustSchema(t, `
+type: object
+description: {en: An order, ja: 注文, pt: Um pedido}
+properties:
+  id:
+    type: string
+    description: {en: Order ID}
+  note:
+    type: string
+    description: free text
+`)
+	for _, test := range []struct {
+		locale       string
+		desc, idDesc string
+		rest         map[string]any
+	}{
+		{"ja", "注文", "Order ID", map[string]any{"en": "An order", "pt": "Um pedido"}},
+		{"pt-BR", "Um pedido", "Order ID", map[string]any{"en": "An order", "ja": "注文"}},
+		{"EN", "An order", "Order ID", map[string]any{"ja": "注文", "pt": "Um pedido"}},
+		{"fr", "An order", "Order ID", map[string]any{"en": "An order", "ja": "注文", "pt": "Um pedido"}},
+	} {
+		got := Localize(s, test.locale)
+		if got.Description != test.desc {
+			t.Errorf("%s: got description %q, want %q", test.locale, got.Description, test.desc)
+		}
+		if diff := cmp.Diff(test.rest, got.Extras[descriptionsKey]); diff != "" {
+			t.Errorf("%s: x-descriptions mismatch (-want, +got):\n%s", test.locale, diff)
+		}
+		if id := got.Properties.Value("id"); id.Description != test.idDesc {
+			t.Errorf("%s: got id description %q, want %q", test.locale, id.Description, test.idDesc)
+		}
+		if note := got.Properties.Value("note"); note.Description != "free text" {
+			t.Errorf("%s: got note description %q", test.locale, note.Description)
+		}
+	}
+	if s.Description != "An order" || len(s.Extras[descriptionsKey].(map[string]any)) != 3 {
+		t.Error("Localize modified its argument")
+	}
+}
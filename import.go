@@ -0,0 +1,296 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// An Importer converts schema source in some input format,
+// such as picoschema YAML or sample data, into a JSON Schema.
+type Importer interface {
+	Import(data []byte) (*jsonschema.Schema, error)
+}
+
+// ImporterFunc adapts an ordinary function to the Importer interface.
+type ImporterFunc func(data []byte) (*jsonschema.Schema, error)
+
+// Import calls f(data).
+func (f ImporterFunc) Import(data []byte) (*jsonschema.Schema, error) {
+	return f(data)
+}
+
+var (
+	importersMu sync.RWMutex
+	importers   = map[string]Importer{
+		"picoschema": ImporterFunc(importPicoschema),
+		"jsonschema": ImporterFunc(importJSONSchema),
+		"sample":     ImporterFunc(importSample),
+		"typescript": ImporterFunc(importTypeScript),
+	}
+)
+
+// RegisterImporter makes an Importer available under the given name.
+// It panics if name is already registered or imp is nil.
+func RegisterImporter(name string, imp Importer) {
+	importersMu.Lock()
+	defer importersMu.Unlock()
+	if imp == nil {
+		panic("picoschema: RegisterImporter importer is nil")
+	}
+	if _, dup := importers[name]; dup {
+		panic("picoschema: RegisterImporter called twice for importer " + name)
+	}
+	importers[name] = imp
+}
+
+// LookupImporter returns the Importer registered under name.
+func LookupImporter(name string) (Importer, bool) {
+	importersMu.RLock()
+	defer importersMu.RUnlock()
+	imp, ok := importers[name]
+	return imp, ok
+}
+
+// Importers returns the sorted names of the registered importers.
+func Importers() []string {
+	importersMu.RLock()
+	defer importersMu.RUnlock()
+	names := make([]string, 0, len(importers))
+	for name := range importers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Import converts data using the importer registered under format.
+func Import(format string, data []byte) (*jsonschema.Schema, error) {
+	imp, ok := LookupImporter(format)
+	if !ok {
+		return nil, fmt.Errorf("picoschema: unknown import format %q, want one of %q", format, Importers())
+	}
+	start := time.Now()
+	s, err := imp.Import(data)
+	recordConversion(format, start)
+	recordError("import", err)
+	return s, err
+}
+
+// importPicoschema decodes data as YAML and converts the result.
+func importPicoschema(data []byte) (*jsonschema.Schema, error) {
+	return parseYAML(data, nil)
+}
+
+// importJSONSchema decodes data as a JSON Schema document, converting
+// draft-07 definitions and tuples as the jsonschema parenthetical does,
+// and keeping keywords it does not know as extension keywords.
+// Properties keep the order of the document.
+func importJSONSchema(data []byte) (*jsonschema.Schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	v, err := decodeOrdered(dec)
+	if err == nil {
+		_, err = dec.Token()
+		if err == io.EOF {
+			err = nil
+		} else if err == nil {
+			err = errors.New("invalid character after top-level value")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("picoschema: %w", err)
+	}
+	orders := make(map[string][]string)
+	s, err := schemaValue(unorderedValue(v, "", orders), true)
+	if err != nil {
+		return nil, err
+	}
+	walkSchema(s, "", func(sub *jsonschema.Schema, path string) bool {
+		if names, ok := orders[path]; ok && sub.Properties != nil {
+			props := orderedmap.New[string, *jsonschema.Schema]()
+			for _, name := range names {
+				props.Set(name, sub.Properties.Value(name))
+			}
+			sub.Properties = props
+		}
+		return true
+	})
+	return s, nil
+}
+
+// decodeOrdered decodes the next JSON value of dec, with its objects
+// as ordered maps that keep the order of their keys.
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		m := orderedmap.New[string, any]()
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(k.(string), v)
+		}
+		_, err := dec.Token()
+		return m, err
+	case json.Delim('['):
+		l := []any{}
+		for dec.More() {
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, v)
+		}
+		_, err := dec.Token()
+		return l, err
+	}
+	return tok, nil
+}
+
+// unorderedValue returns v, a value decoded by decodeOrdered, with its
+// objects as maps, as json.Unmarshal decodes them. If v is a schema at
+// path, it records in orders the order of the properties of v and its
+// subschemas, by the JSON Pointers they have once definitions and
+// draft-07 tuples are converted; a nil orders marks a value that is
+// not a schema.
+func unorderedValue(v any, path string, orders map[string][]string) any {
+	switch v := v.(type) {
+	case []any:
+		ret := make([]any, len(v))
+		for i, e := range v {
+			ret[i] = unorderedValue(e, "", nil)
+		}
+		return ret
+	case *orderedmap.OrderedMap[string, any]:
+		ret := make(map[string]any, v.Len())
+		_, tuple := v.Value("items").([]any)
+		for p := v.Oldest(); p != nil; p = p.Next() {
+			k := p.Key
+			list, isList := p.Value.([]any)
+			subs, isMap := p.Value.(*orderedmap.OrderedMap[string, any])
+			switch {
+			case orders == nil:
+				ret[k] = unorderedValue(p.Value, "", nil)
+			case k == "items" && tuple:
+				ret[k] = unorderedList(list, path+"/prefixItems", orders)
+			case k == "additionalItems" && tuple:
+				ret[k] = unorderedValue(p.Value, path+"/items", orders)
+			case slices.Contains(schemaKeywordsSingle, k):
+				ret[k] = unorderedValue(p.Value, path+"/"+k, orders)
+			case slices.Contains(schemaKeywordsList, k) && isList:
+				ret[k] = unorderedList(list, path+"/"+k, orders)
+			case (k == "definitions" || slices.Contains(schemaKeywordsMap, k)) && isMap:
+				name := k
+				if k == "definitions" {
+					name = "$defs"
+				}
+				m := make(map[string]any, subs.Len())
+				var keys []string
+				for q := subs.Oldest(); q != nil; q = q.Next() {
+					keys = append(keys, q.Key)
+					m[q.Key] = unorderedValue(q.Value, path+"/"+name+"/"+escapePointer(q.Key), orders)
+				}
+				ret[k] = m
+				if k == "properties" {
+					orders[path] = keys
+				}
+			default:
+				ret[k] = unorderedValue(p.Value, "", nil)
+			}
+		}
+		return ret
+	}
+	return v
+}
+
+// unorderedList is like unorderedValue for list, a list of schemas
+// whose JSON Pointers are path followed by their indexes.
+func unorderedList(list []any, path string, orders map[string][]string) []any {
+	ret := make([]any, len(list))
+	for i, e := range list {
+		ret[i] = unorderedValue(e, path+"/"+strconv.Itoa(i), orders)
+	}
+	return ret
+}
+
+// importSample infers a schema from a sample JSON document.
+func importSample(data []byte) (*jsonschema.Schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var val any
+	if err := dec.Decode(&val); err != nil {
+		return nil, fmt.Errorf("picoschema: %w", err)
+	}
+	return inferSchema(val), nil
+}
+
+// inferSchema returns the narrowest schema describing val,
+// which must be the result of decoding JSON with UseNumber.
+// Every property present in the sample is required, and
+// array items are inferred from the first element.
+func inferSchema(val any) *jsonschema.Schema {
+	switch val := val.(type) {
+	case nil:
+		return &jsonschema.Schema{Type: "null"}
+	case bool:
+		return &jsonschema.Schema{Type: "boolean"}
+	case string:
+		return &jsonschema.Schema{Type: "string"}
+	case json.Number:
+		if _, err := val.Int64(); err == nil {
+			return &jsonschema.Schema{Type: "integer"}
+		}
+		return &jsonschema.Schema{Type: "number"}
+	case []any:
+		ret := &jsonschema.Schema{Type: "array"}
+		if len(val) > 0 {
+			ret.Items = inferSchema(val[0])
+		}
+		return ret
+	case map[string]any:
+		ret := &jsonschema.Schema{
+			Type:                 "object",
+			Properties:           orderedmap.New[string, *jsonschema.Schema](),
+			AdditionalProperties: jsonschema.FalseSchema,
+		}
+		for _, k := range sortedKeys(val) {
+			ret.Properties.Set(k, inferSchema(val[k]))
+			ret.Required = append(ret.Required, k)
+		}
+		return ret
+	default:
+		return &jsonschema.Schema{}
+	}
+}
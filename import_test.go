@@ -0,0 +1,136 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+)
+
+func TestImport(t *testing.T) {
+	for _, test := range []struct {
+		format string
+		data   string
+		want   any
+	}{
+		{
+			format: "picoschema",
+			data:   "name: string, the name",
+			want: map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties":           map[string]any{"name": map[string]any{"type": "string", "description": "the name"}},
+				"required":             []any{"name"},
+			},
+		},
+		{
+			format: "jsonschema",
+			data:   `{"type": "array", "items": {"type": "integer"}}`,
+			want:   map[string]any{"type": "array", "items": map[string]any{"type": "integer"}},
+		},
+		{
+			format: "sample",
+			data:   `{"n": 1, "x": 1.5, "tags": ["a"], "ok": true}`,
+			want: map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"n":    map[string]any{"type": "integer"},
+					"x":    map[string]any{"type": "number"},
+					"ok":   map[string]any{"type": "boolean"},
+					"tags": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				},
+				"required": []any{"n", "ok", "tags", "x"},
+			},
+		},
+	} {
+		t.Run(test.format, func(t *testing.T) {
+			s, err := Import(test.format, []byte(test.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ConvertSchema(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImportJSONSchemaDraft07(t *testing.T) {
+	s, err := Import("jsonschema", []byte(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"definitions": {"point": {"type": "array", "items": [{"type": "number"}, {"type": "number"}], "additionalItems": false}},
+		"type": "object",
+		"properties": {
+			"z": {"$ref": "#/definitions/point", "x-unit": "m"},
+			"a": {"type": "string", "deprecatedBy": "b"}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+		names = append(names, p.Key)
+	}
+	if diff := cmp.Diff([]string{"z", "a"}, names); diff != "" {
+		t.Errorf("property order mismatch (-want, +got):\n%s", diff)
+	}
+	got, err := ConvertSchema(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"$defs": map[string]any{"point": map[string]any{
+			"type":        "array",
+			"prefixItems": []any{map[string]any{"type": "number"}, map[string]any{"type": "number"}},
+			"items":       false,
+		}},
+		"type": "object",
+		"properties": map[string]any{
+			"z": map[string]any{"$ref": "#/$defs/point", "x-unit": "m"},
+			"a": map[string]any{"type": "string", "deprecatedBy": "b"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if _, err := Import("jsonschema", []byte(`{"type": "string"} {}`)); err == nil {
+		t.Error("got nil error for trailing data")
+	}
+}
+
+func TestRegisterImporter(t *testing.T) {
+	RegisterImporter("test-empty", ImporterFunc(func([]byte) (*jsonschema.Schema, error) {
+		return &jsonschema.Schema{Type: "null"}, nil
+	}))
+	s, err := Import("test-empty", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Type != "null" {
+		t.Errorf("got type %q, want %q", s.Type, "null")
+	}
+	if _, err := Import("no-such-format", nil); err == nil {
+		t.Error("got nil error for unknown format")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+)
+
+// An Interner makes structurally identical schemas share one instance,
+// so that fragments repeated within and across schemas, such as an
+// address or a money amount, are held in memory once. Two schemas are
+// identical if their JSON encodings are, including the order of their
+// properties but not that of their required properties.
+//
+// Interned schemas are shared and must never be modified; use Clone
+// to get a copy that may be. An Interner keeps every schema it has
+// interned, so it should live as long as the schemas it serves, such
+// as those of a Registry. The zero Interner is empty and ready to use.
+// An Interner is safe for concurrent use.
+type Interner struct {
+	mu      sync.Mutex
+	schemas map[string]*jsonschema.Schema // by JSON encoding
+}
+
+// Intern returns a schema equal to s in which every subschema, and the
+// schema itself, is the instance in in of its structure. It adds the
+// subschemas of s that in does not yet hold. It does not modify s.
+func (in *Interner) Intern(s *jsonschema.Schema) (*jsonschema.Schema, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return in.intern(cloneSchema(s))
+}
+
+// Len returns the number of distinct schemas in in.
+func (in *Interner) Len() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return len(in.schemas)
+}
+
+// intern interns s, which it may modify, bottom up.
+func (in *Interner) intern(s *jsonschema.Schema) (*jsonschema.Schema, error) {
+	var err error
+	replaceSubschemas(s, func(sub *jsonschema.Schema) *jsonschema.Schema {
+		if err != nil {
+			return sub
+		}
+		var i *jsonschema.Schema
+		i, err = in.intern(sub)
+		if err != nil {
+			return sub
+		}
+		return i
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The order of required names does not matter.
+	k := *s
+	k.Required = slices.Clone(s.Required)
+	slices.Sort(k.Required)
+	data, err := json.Marshal(&k)
+	if err != nil {
+		return nil, err
+	}
+	key := string(data)
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if i, ok := in.schemas[key]; ok {
+		return i, nil
+	}
+	if in.schemas == nil {
+		in.schemas = make(map[string]*jsonschema.Schema)
+	}
+	in.schemas[key] = s
+	return s, nil
+}
+
+// replaceSubschemas replaces each immediate subschema sub of s
+// with fn(sub).
+func replaceSubschemas(s *jsonschema.Schema, fn func(sub *jsonschema.Schema) *jsonschema.Schema) {
+	one := func(sub **jsonschema.Schema) {
+		if *sub != nil {
+			*sub = fn(*sub)
+		}
+	}
+	list := func(subs []*jsonschema.Schema) {
+		for i := range subs {
+			one(&subs[i])
+		}
+	}
+	named := func(subs map[string]*jsonschema.Schema) {
+		for k, sub := range subs {
+			if sub != nil {
+				subs[k] = fn(sub)
+			}
+		}
+	}
+
+	named(s.Definitions)
+	list(s.AllOf)
+	list(s.AnyOf)
+	list(s.OneOf)
+	one(&s.Not)
+	one(&s.If)
+	one(&s.Then)
+	one(&s.Else)
+	named(s.DependentSchemas)
+	list(s.PrefixItems)
+	one(&s.Items)
+	one(&s.Contains)
+	if s.Properties != nil {
+		for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+			one(&p.Value)
+		}
+	}
+	named(s.PatternProperties)
+	one(&s.AdditionalProperties)
+	one(&s.PropertyNames)
+	one(&s.ContentSchema)
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+)
+
+func TestIntern(t *testing.T) {
+	// Import JSON Schema, which keeps the order of properties.
+	const address = `{"type": "object", "properties": {"street": {"type": "string"}, "city": {"type": "string"}}, "required": ["street", "city"], "additionalProperties": false}`
+	imp := func(src string) *jsonschema.Schema {
+		t.Helper()
+		s, err := Import("jsonschema", []byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	order := imp(`{"type": "object", "properties": {"billing": ` + address + `, "shipping": ` + address + `, "note": {"type": "string"}}, "additionalProperties": false}`)
+	customer := imp(`{"type": "object", "properties": {"home": ` + address + `, "name": {"type": "string"}}}`)
+	want := schemaJSON(t, order)
+
+	var in Interner
+	got, err := in.Intern(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, schemaJSON(t, got)); diff != "" {
+		t.Errorf("interned schema differs (-want, +got):\n%s", diff)
+	}
+	if got == order || got.Properties.Value("billing") == order.Properties.Value("billing") {
+		t.Error("Intern did not copy its argument")
+	}
+	billing, shipping := got.Properties.Value("billing"), got.Properties.Value("shipping")
+	if billing != shipping {
+		t.Error("identical properties billing and shipping are distinct instances")
+	}
+	if billing.Properties.Value("street") != got.Properties.Value("note") {
+		t.Error("identical string schemas are distinct instances")
+	}
+	// The order, its address, a string schema and the false
+	// schema of additionalProperties.
+	if n := in.Len(); n != 4 {
+		t.Errorf("Len() = %d, want 4", n)
+	}
+
+	r := Registry{Interner: &in}
+	if err := r.Register("customer", customer); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := r.Lookup("customer")
+	if c.Properties.Value("home") != billing {
+		t.Error("registered schema does not share the interned address")
+	}
+	again, _ := in.Intern(order)
+	if again != got {
+		t.Error("interning an identical schema again gave a new instance")
+	}
+	if s, err := in.Intern(nil); s != nil || err != nil {
+		t.Errorf("Intern(nil) = %v, %v, want nil, nil", s, err)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A LazySchema is a schema that is converted when it is first needed,
+// so that services holding many schemas pay only for those they use.
+type LazySchema interface {
+	// Schema returns the converted schema. It converts once, and
+	// returns the same schema, or the same error, on every call.
+	Schema() (*jsonschema.Schema, error)
+}
+
+// A LazyObject is a LazySchema of picoschema. If the picoschema is an
+// object, its properties can also be converted one at a time with
+// Property, without converting the rest. A LazyObject is safe for
+// concurrent use.
+type LazyObject struct {
+	val  any
+	opts []Option
+
+	once   sync.Once
+	done   atomic.Bool // once has run
+	schema *jsonschema.Schema
+	err    error
+
+	mu    sync.Mutex
+	props map[string]*lazyProperty
+}
+
+type lazyProperty struct {
+	once   sync.Once
+	schema *jsonschema.Schema
+	err    error
+}
+
+// Lazy returns a LazyObject that converts val, as ToJSONSchema does
+// with opts, when it is first needed. It does not inspect val until then.
+func Lazy(val any, opts ...Option) *LazyObject {
+	return &LazyObject{val: val, opts: opts}
+}
+
+// Schema converts the whole of the picoschema.
+func (l *LazyObject) Schema() (*jsonschema.Schema, error) {
+	l.once.Do(func() {
+		l.schema, l.err = ToJSONSchema(l.val, l.opts...)
+		l.done.Store(true)
+	})
+	return l.schema, l.err
+}
+
+// Property returns the schema of the property name of the object,
+// converting only it and the $defs it may use. If the whole object has
+// already been converted, it returns the property of that schema.
+// Refs within the property, such as those to $defs with WithNamedRefs,
+// are relative to the whole object.
+func (l *LazyObject) Property(name string) (*jsonschema.Schema, error) {
+	l.mu.Lock()
+	if l.props == nil {
+		l.props = make(map[string]*lazyProperty)
+	}
+	lp, ok := l.props[name]
+	if !ok {
+		lp = new(lazyProperty)
+		l.props[name] = lp
+	}
+	l.mu.Unlock()
+	lp.once.Do(func() {
+		lp.schema, lp.err = l.convertProperty(name)
+	})
+	return lp.schema, lp.err
+}
+
+func (l *LazyObject) convertProperty(name string) (*jsonschema.Schema, error) {
+	if s := l.converted(); s != nil {
+		return objectProperty(s, name)
+	}
+	m, ok := l.val.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("picoschema: lazy schema of %T is not an object", l.val)
+	}
+	if isJSONSchemaRoot(m) {
+		// JSON Schema is converted whole.
+		s, err := l.Schema()
+		if err != nil {
+			return nil, err
+		}
+		return objectProperty(s, name)
+	}
+	if name == "" {
+		return nil, noProperty(name)
+	}
+	sub := make(map[string]any)
+	if defs, ok := m[defsKey]; ok {
+		sub[defsKey] = defs
+	}
+	for k, v := range m {
+		if keyProperty(k) == name {
+			sub[k] = v
+			break
+		}
+	}
+	if _, ok := sub[defsKey]; len(sub) == 0 || (ok && len(sub) == 1) {
+		return nil, noProperty(name)
+	}
+	s, err := ToJSONSchema(sub, l.opts...)
+	if err != nil {
+		return nil, err
+	}
+	return objectProperty(s, name)
+}
+
+// converted returns the whole converted schema, or nil if Schema
+// has not converted it.
+func (l *LazyObject) converted() *jsonschema.Schema {
+	if !l.done.Load() {
+		return nil
+	}
+	return l.schema
+}
+
+// keyProperty returns the name of the property declared by the
+// picoschema key k, or "" if k declares none, as "(*)" does not.
+func keyProperty(k string) string {
+	name, _, _ := strings.Cut(k, "(")
+	return strings.TrimSuffix(name, "?")
+}
+
+// isJSONSchemaRoot reports whether the root m is written as JSON
+// Schema rather than picoschema, as toJSONSchema decides.
+func isJSONSchemaRoot(m map[string]any) bool {
+	switch m["type"] {
+	case "string", "boolean", "null", "number", "integer", "object", "array":
+		return true
+	}
+	_, ok := m["properties"].(map[string]any)
+	return ok
+}
+
+func objectProperty(s *jsonschema.Schema, name string) (*jsonschema.Schema, error) {
+	if s != nil && s.Properties != nil {
+		if prop, ok := s.Properties.Get(name); ok {
+			return prop, nil
+		}
+	}
+	return nil, noProperty(name)
+}
+
+func noProperty(name string) error {
+	return fmt.Errorf("picoschema: lazy schema has no property %q", name)
+}
+
+// RegisterLazy adds l to r under name, to be converted and copied into
+// r when the name is first looked up, as by Lookup, Resolve or Find.
+// Until then Names lists the name but r holds no schema for it. Unless r
+// is Immutable, it replaces any schema previously registered under that
+// name. If r is Immutable and already holds the name, l is converted at
+// once and registered as by Register.
+//
+// If l fails to convert, Lookup reports the name as absent and
+// Resolve returns the error.
+func (r *Registry) RegisterLazy(name string, l LazySchema) error {
+	if name == "" {
+		return errors.New("picoschema: RegisterLazy with empty name")
+	}
+	if l == nil {
+		return fmt.Errorf("picoschema: RegisterLazy %q with nil schema", name)
+	}
+	r.mu.Lock()
+	_, registered := r.schemas[name]
+	_, pending := r.lazy[name]
+	if r.Immutable && (registered || pending) {
+		r.mu.Unlock()
+		s, err := lazySchema(name, l)
+		if err != nil {
+			return err
+		}
+		return r.Register(name, s)
+	}
+	defer r.mu.Unlock()
+	delete(r.schemas, name)
+	delete(r.sizes, name)
+	if r.lazy == nil {
+		r.lazy = make(map[string]*lazyEntry)
+	}
+	r.lazy[name] = &lazyEntry{l}
+	return nil
+}
+
+// A lazyEntry is a schema registered with RegisterLazy and not yet
+// converted. Entries are compared by address, since a LazySchema
+// need not be comparable.
+type lazyEntry struct {
+	l LazySchema
+}
+
+// lookup returns the schema registered under name, converting it first
+// if it was registered with RegisterLazy.
+func (r *Registry) lookup(name string) (*jsonschema.Schema, bool, error) {
+	r.mu.RLock()
+	s, ok := r.schemas[name]
+	e := r.lazy[name]
+	r.mu.RUnlock()
+	if ok || e == nil {
+		return s, ok, nil
+	}
+	s, err := lazySchema(name, e.l)
+	if err != nil {
+		return nil, false, err
+	}
+	c, err := r.copyIn(s)
+	if err != nil {
+		return nil, false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lazy[name] == e {
+		// Another Register or RegisterLazy may have replaced e
+		// meanwhile, and then c is returned but not kept.
+		if r.schemas == nil {
+			r.schemas = make(map[string]*jsonschema.Schema)
+		}
+		r.schemas[name] = c
+		r.setSize(name, c)
+		delete(r.lazy, name)
+	} else if s, ok := r.schemas[name]; ok {
+		return s, true, nil
+	}
+	return c, true, nil
+}
+
+// lazySchema converts l, registered under name.
+func lazySchema(name string, l LazySchema) (*jsonschema.Schema, error) {
+	s, err := l.Schema()
+	if err != nil {
+		return nil, fmt.Errorf("picoschema: converting %q: %w", name, err)
+	}
+	if s == nil {
+		return nil, fmt.Errorf("picoschema: converting %q: nil schema", name)
+	}
+	return s, nil
+}
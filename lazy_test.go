@@ -0,0 +1,160 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLazyProperty(t *testing.T) {
+	var val any
+	if err := yaml.Unmarshal([]byte(`
+$defs:
+  money:
+    amount: number
+    currency: string
+id: string, the order ID
+total?: money
+broken: bogus
+`), &val); err != nil {
+		t.Fatal(err)
+	}
+	l := Lazy(val)
+	// Properties convert without the broken one, as they do in the whole
+	// schema, with refs to $defs relative to it.
+	whole := mustSchema(t, `
+$defs:
+  money:
+    amount: number
+    currency: string
+id: string, the order ID
+total?: money
+`)
+	for _, name := range []string{"id", "total"} {
+		got, err := l.Property(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if diff := cmp.Diff(schemaJSON(t, whole.Properties.Value(name)), schemaJSON(t, got)); diff != "" {
+			t.Errorf("%s: mismatch (-want, +got):\n%s", name, diff)
+		}
+	}
+	if _, err := l.Property("missing"); err == nil || !strings.Contains(err.Error(), `no property "missing"`) {
+		t.Errorf("Property of a missing property: got %v", err)
+	}
+	if _, err := l.Property("broken"); err == nil {
+		t.Error("Property of a broken property succeeded")
+	}
+	if _, err := l.Schema(); err == nil {
+		t.Error("Schema with a broken property succeeded")
+	}
+
+	// After Schema, properties come from the whole schema.
+	l = Lazy(map[string]any{"id": "string"})
+	s, err := l.Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := l.Property("id"); err != nil || got != s.Properties.Value("id") {
+		t.Errorf("Property after Schema: got %v, %v, want the property of the schema", got, err)
+	}
+
+	if _, err := Lazy("string").Property("id"); err == nil {
+		t.Error("Property of a scalar succeeded")
+	}
+}
+
+// countingSchema is a LazySchema that counts its conversions.
+type countingSchema struct {
+	src string
+	n   int
+}
+
+func (c *countingSchema) Schema() (*jsonschema.Schema, error) {
+	c.n++
+	return ParseYAMLString(c.src)
+}
+
+func TestRegistryRegisterLazy(t *testing.T) {
+	var r Registry
+	customer := &countingSchema{src: "name: string"}
+	order := &countingSchema{src: "id: string\ncustomer: customer"}
+	broken := &countingSchema{src: "id: bogus"}
+	for name, l := range map[string]*countingSchema{"customer": customer, "order": order, "broken": broken} {
+		if err := r.RegisterLazy(name, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if diff := cmp.Diff([]string{"broken", "customer", "order"}, r.Names()); diff != "" {
+		t.Errorf("Names mismatch (-want, +got):\n%s", diff)
+	}
+	if customer.n+order.n+broken.n != 0 {
+		t.Fatal("RegisterLazy converted a schema")
+	}
+
+	s, ok := r.Lookup("customer")
+	if !ok || s.Properties.Value("name") == nil {
+		t.Fatalf("Lookup: got %v, %v", s, ok)
+	}
+	if again, _ := r.Lookup("customer"); again != s || customer.n != 1 {
+		t.Errorf("second Lookup converted again, %d conversions", customer.n)
+	}
+	if order.n != 0 {
+		t.Error("Lookup of customer converted order")
+	}
+
+	if _, ok := r.Lookup("broken"); ok {
+		t.Error("Lookup of a schema failing to convert succeeded")
+	}
+	if _, err := r.Resolve("broken"); err == nil || !strings.HasPrefix(err.Error(), `picoschema: converting "broken": `) {
+		t.Errorf("Resolve of a schema failing to convert: got %v", err)
+	}
+
+	// Register replaces a pending schema.
+	if err := r.Register("order", mustSchema(t, "id: integer")); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := r.Lookup("order"); s.Properties.Value("id").Type != "integer" || order.n != 0 {
+		t.Errorf("Register did not replace the pending schema")
+	}
+}
+
+func TestRegistryRegisterLazyImmutable(t *testing.T) {
+	r := Registry{Immutable: true}
+	if err := r.RegisterLazy("customer", Lazy(map[string]any{"name": "string"})); err != nil {
+		t.Fatal(err)
+	}
+	// The same content is accepted, and different content refused,
+	// whether the pending schema was converted or not.
+	if err := r.Register("customer", mustSchema(t, "name: string")); err != nil {
+		t.Errorf("Register of the same content: %v", err)
+	}
+	var conflict *ConflictError
+	if err := r.RegisterLazy("customer", Lazy(map[string]any{"name": "integer"})); !errors.As(err, &conflict) {
+		t.Errorf("RegisterLazy of different content: got %v, want a *ConflictError", err)
+	}
+	if err := r.RegisterLazy("order", Lazy(map[string]any{"id": "string"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("order", mustSchema(t, "id: integer")); !errors.As(err, &conflict) {
+		t.Errorf("Register over a pending schema: got %v, want a *ConflictError", err)
+	}
+}
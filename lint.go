@@ -0,0 +1,382 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// Severity is the importance of a lint diagnostic.
+type Severity int
+
+const (
+	// SeverityOff disables a rule.
+	SeverityOff Severity = iota
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+)
+
+var severityNames = []string{"off", "info", "warning", "error"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// A Diagnostic is a problem reported by the linter.
+type Diagnostic struct {
+	Rule     string
+	Severity Severity
+	Path     string // JSON Pointer of the offending schema
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	p := d.Path
+	if p == "" {
+		p = "/"
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", p, d.Severity, d.Message, d.Rule)
+}
+
+// lintDisableKey is the annotation listing the rules to suppress for
+// a schema and all of its subschemas. Its value is a rule ID, a list
+// of rule IDs, or "all". In picoschema it is set by the lintDisable
+// attribute, as in "code(string, lintDisable=property-naming)".
+const lintDisableKey = "x-lint-disable"
+
+// A lintNode is a schema visited by the linter.
+type lintNode struct {
+	schema   *jsonschema.Schema
+	path     string
+	property string // property name, if the schema is a property of an object
+	depth    int    // number of schemas enclosing this one
+	root     *jsonschema.Schema
+}
+
+// A lintRule checks a single schema.
+type lintRule struct {
+	id       string
+	severity Severity // default severity
+	check    func(l *Linter, n lintNode, report func(format string, args ...any))
+}
+
+// lintRules are the built-in rules, in the order they are run.
+var lintRules = []lintRule{
+	{
+		id:       "missing-description",
+		severity: SeverityInfo,
+		check: func(l *Linter, n lintNode, report func(string, ...any)) {
+			if n.property != "" && n.schema.Description == "" {
+				report("property %q has no description", n.property)
+			}
+		},
+	},
+	{
+		id:       "untyped-property",
+		severity: SeverityWarning,
+		check: func(l *Linter, n lintNode, report func(string, ...any)) {
+			s := n.schema
+			if n.property == "" || s.Type != "" || s.Enum != nil || s.Const != nil || s.Ref != "" ||
+				s.AllOf != nil || s.AnyOf != nil || s.OneOf != nil {
+				return
+			}
+			if _, ok := boolSchema(s); ok {
+				return
+			}
+			report("property %q accepts any value", n.property)
+		},
+	},
+	{
+		id:       "duplicate-enum",
+		severity: SeverityWarning,
+		check: func(l *Linter, n lintNode, report func(string, ...any)) {
+			e := n.schema.Enum
+			for i := range e {
+				if slicesContainsEqual(e[:i], e[i]) {
+					report("enum value %v is listed more than once", e[i])
+				}
+			}
+		},
+	},
+	{
+		id:       "mixed-enum",
+		severity: SeverityWarning,
+		check: func(l *Linter, n lintNode, report func(string, ...any)) {
+			if kinds := enumKinds(n.schema.Enum); len(kinds) > 1 {
+				report("enum mixes %s values", strings.Join(kinds, " and "))
+			}
+		},
+	},
+	{
+		id:       "enum-type-names",
+		severity: SeverityWarning,
+		check: func(l *Linter, n lintNode, report func(string, ...any)) {
+			// A list of type names, as in "id: [string, integer]",
+			// is an enum of the names unless WithTypeUnions is on.
+			// An enum written as one, with the parenthetical type
+			// enum or in JSON Schema, means the names, which only
+			// the trace tells apart.
+			if l.Trace != nil && !l.isBareList(n.path) {
+				return
+			}
+			var local map[string]bool
+			for name := range n.root.Definitions {
+				if local == nil {
+					local = make(map[string]bool)
+				}
+				local[name] = true
+			}
+			p := &parser{local: local}
+			var names []string
+			for _, v := range n.schema.Enum {
+				if v == nil {
+					// Added to the enum of an optional property.
+					continue
+				}
+				name, ok := v.(string)
+				if !ok || !p.isTypeName(strings.TrimSuffix(name, "?")) {
+					return
+				}
+				names = append(names, name)
+			}
+			if len(names) < 2 {
+				return
+			}
+			list := "[" + strings.Join(names, ", ") + "]"
+			report("enum %s lists type names; for a union of the types, write (anyOf): %s or convert with WithTypeUnions", list, list)
+		},
+	},
+	{
+		id:       "unknown-required",
+		severity: SeverityError,
+		check: func(l *Linter, n lintNode, report func(string, ...any)) {
+			s := n.schema
+			if s.Properties == nil {
+				return
+			}
+			if b, ok := boolSchema(s.AdditionalProperties); !ok || b {
+				return
+			}
+			for _, name := range s.Required {
+				if _, ok := s.Properties.Get(name); !ok {
+					report("required property %q is not defined", name)
+				}
+			}
+		},
+	},
+	{
+		id:       "property-naming",
+		severity: SeverityWarning,
+		check: func(l *Linter, n lintNode, report func(string, ...any)) {
+			if n.property == "" || l.Naming == "" {
+				return
+			}
+			if re, ok := namingConventions[l.Naming]; ok && !re.MatchString(n.property) {
+				report("property %q is not %s", n.property, l.Naming)
+			}
+		},
+	},
+	{
+		id:       "max-depth",
+		severity: SeverityError,
+		check: func(l *Linter, n lintNode, report func(string, ...any)) {
+			// Report only the first schema past the limit on each branch.
+			if l.MaxDepth > 0 && n.depth == l.MaxDepth+1 {
+				report("schema is nested more than %d levels deep", l.MaxDepth)
+			}
+		},
+	},
+	{
+		id:       "max-size",
+		severity: SeverityError,
+		check: func(l *Linter, n lintNode, report func(string, ...any)) {
+			if l.MaxBytes <= 0 || n.depth != 0 {
+				return
+			}
+			if data, err := json.Marshal(n.schema); err == nil && len(data) > l.MaxBytes {
+				report("schema is %d bytes of JSON, want at most %d", len(data), l.MaxBytes)
+			}
+		},
+	},
+	{
+		id:       "provider-keyword",
+		severity: SeverityError,
+		check: func(l *Linter, n lintNode, report func(string, ...any)) {
+			for _, p := range l.Profiles {
+				for _, kw := range schemaKeywords(n.schema) {
+					if !p.Supports(kw) {
+						report("keyword %q is not supported by %s", kw, p.Name)
+					}
+				}
+			}
+		},
+	},
+}
+
+// namingConventions maps the names accepted by Linter.Naming to the
+// pattern property names must match.
+var namingConventions = map[string]*regexp.Regexp{
+	"camelCase":  regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`),
+	"PascalCase": regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`),
+	"snake_case": regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`),
+	"kebab-case": regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`),
+}
+
+// LintRules returns the IDs of the built-in lint rules.
+func LintRules() []string {
+	ids := make([]string, len(lintRules))
+	for i, r := range lintRules {
+		ids[i] = r.id
+	}
+	return ids
+}
+
+// A Linter reports likely mistakes and style problems in schemas.
+// The zero Linter runs every rule at its default severity.
+type Linter struct {
+	// Severity overrides the default severity of rules, keyed by rule ID.
+	// Rules set to SeverityOff are not run.
+	Severity map[string]Severity
+
+	// Naming is the convention property names must follow:
+	// one of camelCase, PascalCase, snake_case or kebab-case.
+	// If empty, property names are not checked.
+	Naming string
+
+	// MaxDepth limits how deeply schemas may be nested, if positive.
+	MaxDepth int
+
+	// MaxBytes limits the size of the JSON encoding of the schema, if positive.
+	MaxBytes int
+
+	// Profiles lists the providers whose unsupported keywords are reported.
+	Profiles []*Profile
+
+	// Trace is the trace of the conversion of the schema from
+	// picoschema, as ToJSONSchemaTrace returns it, which tells how the
+	// schema was written. With it, the enum-type-names rule reports
+	// only enums written as bare lists; without it, every enum whose
+	// values are all type names.
+	Trace []TraceStep
+}
+
+// Lint checks s with the default Linter.
+func Lint(s *jsonschema.Schema) []Diagnostic {
+	var l Linter
+	return l.Lint(s)
+}
+
+// Lint checks s and its subschemas and returns the diagnostics found.
+// A schema annotated with x-lint-disable suppresses the listed rules
+// for itself and all of its subschemas.
+func (l *Linter) Lint(s *jsonschema.Schema) []Diagnostic {
+	var diags []Diagnostic
+	var visit func(n lintNode, disabled map[string]bool)
+	visit = func(n lintNode, disabled map[string]bool) {
+		if _, ok := boolSchema(n.schema); ok {
+			return
+		}
+		disabled = withDisabledRules(disabled, n.schema)
+		if !disabled["all"] {
+			for _, r := range lintRules {
+				sev := l.severity(r)
+				if sev == SeverityOff || disabled[r.id] {
+					continue
+				}
+				r.check(l, n, func(format string, args ...any) {
+					diags = append(diags, Diagnostic{
+						Rule:     r.id,
+						Severity: sev,
+						Path:     n.path,
+						Message:  fmt.Sprintf(format, args...),
+					})
+				})
+			}
+		}
+		forEachSubschema(n.schema, func(sub *jsonschema.Schema, ptr string) {
+			child := lintNode{schema: sub, path: n.path + ptr, depth: n.depth + 1, root: n.root}
+			if name, ok := strings.CutPrefix(ptr, "/properties/"); ok {
+				child.property = unescapePointer(name)
+			}
+			visit(child, disabled)
+		})
+	}
+	if s != nil {
+		visit(lintNode{schema: s, root: s}, nil)
+	}
+	return diags
+}
+
+// isBareList reports whether l.Trace shows that the schema at path was
+// written as a bare list, as in "id: [string, integer]", which is read
+// as an enum.
+func (l *Linter) isBareList(path string) bool {
+	for _, st := range l.Trace {
+		if st.Path == path && strings.HasSuffix(st.Interpretation, " of enum") {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Linter) severity(r lintRule) Severity {
+	if sev, ok := l.Severity[r.id]; ok {
+		return sev
+	}
+	return r.severity
+}
+
+// withDisabledRules returns disabled extended by the rules listed in
+// the x-lint-disable annotation of s. It does not modify disabled.
+func withDisabledRules(disabled map[string]bool, s *jsonschema.Schema) map[string]bool {
+	var ids []string
+	switch v := s.Extras[lintDisableKey].(type) {
+	case string:
+		ids = []string{v}
+	case []any:
+		for _, id := range v {
+			if id, ok := id.(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	case []string:
+		ids = v
+	}
+	if len(ids) == 0 {
+		return disabled
+	}
+	ret := make(map[string]bool, len(disabled)+len(ids))
+	for id := range disabled {
+		ret[id] = true
+	}
+	for _, id := range ids {
+		ret[strings.TrimSpace(id)] = true
+	}
+	return ret
+}
+
+// unescapePointer reverses escapePointer.
+func unescapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+}
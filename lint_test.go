@@ -0,0 +1,165 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/invopop/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLint(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		src    string
+		linter Linter
+		want   []Diagnostic
+	}{
+		{
+			name: "defaults",
+			src: `
+name: string
+extra: any, anything at all
+color(enum, the color): [RED, RED]
+size?(enum, the size): [1, 2, XL]
+`,
+			want: []Diagnostic{
+				{Rule: "duplicate-enum", Severity: SeverityWarning, Path: "/properties/color"},
+				{Rule: "mixed-enum", Severity: SeverityWarning, Path: "/properties/size"},
+				{Rule: "untyped-property", Severity: SeverityWarning, Path: "/properties/extra"},
+				{Rule: "missing-description", Severity: SeverityInfo, Path: "/properties/name"},
+			},
+		},
+		{
+			name: "enum of type names",
+			src: `
+$defs:
+  money: number
+id: [string, integer]
+code?(enum): [string, "null"]
+ref: [money, $self]
+kind: [string, other]
+size: [string]
+`,
+			linter: Linter{Severity: map[string]Severity{"missing-description": SeverityOff}},
+			// An explicit enum means the names; $defs names and $self
+			// are type names too.
+			want: []Diagnostic{
+				{Rule: "enum-type-names", Severity: SeverityWarning, Path: "/properties/id"},
+				{Rule: "enum-type-names", Severity: SeverityWarning, Path: "/properties/ref"},
+			},
+		},
+		{
+			name:   "severity override",
+			src:    `name: string`,
+			linter: Linter{Severity: map[string]Severity{"missing-description": SeverityError}},
+			want: []Diagnostic{
+				{Rule: "missing-description", Severity: SeverityError, Path: "/properties/name"},
+			},
+		},
+		{
+			name:   "rule off",
+			src:    `name: string`,
+			linter: Linter{Severity: map[string]Severity{"missing-description": SeverityOff}},
+		},
+		{
+			name: "suppression",
+			src: `
+type: object
+properties:
+  legacy:
+    type: object
+    x-lint-disable: [missing-description]
+    properties:
+      a: {type: string}
+      b: {}
+  other:
+    x-lint-disable: all
+`,
+			want: []Diagnostic{
+				{Rule: "untyped-property", Severity: SeverityWarning, Path: "/properties/legacy/properties/b"},
+			},
+		},
+		{
+			name: "suppression in picoschema",
+			src: `
+legacy(object, lintDisable=missing-description):
+  a: string
+  b: any
+note(string, lintDisable=all):
+ok: string
+`,
+			want: []Diagnostic{
+				{Rule: "untyped-property", Severity: SeverityWarning, Path: "/properties/legacy/properties/b"},
+				{Rule: "missing-description", Severity: SeverityInfo, Path: "/properties/ok"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, steps := mustSchemaTrace(t, test.src)
+			test.linter.Trace = steps
+			got := test.linter.Lint(s)
+			less := func(a, b Diagnostic) bool { return a.Path+a.Rule < b.Path+b.Rule }
+			if diff := cmp.Diff(test.want, got, cmpopts.SortSlices(less), cmpopts.IgnoreFields(Diagnostic{}, "Message")); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// mustSchemaTrace converts the picoschema src with its trace.
+func mustSchemaTrace(t *testing.T, src string) (*jsonschema.Schema, []TraceStep) {
+	t.Helper()
+	var val any
+	if err := yaml.Unmarshal([]byte(src), &val); err != nil {
+		t.Fatal(err)
+	}
+	s, steps, err := ToJSONSchemaTrace(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, steps
+}
+
+func TestLintEnumTypeNamesWithoutTrace(t *testing.T) {
+	s := mustSchema(t, "$defs:\n  money: number\nid: [string, integer]\ncode?(enum): [string, \"null\"]\nref: [money, $self]\nkind: [string, other]\n")
+	var got []string
+	for _, d := range Lint(s) {
+		if d.Rule == "enum-type-names" {
+			got = append(got, d.Path)
+		}
+	}
+	// Without the trace, an explicit enum of type names is reported too.
+	want := []string{"/properties/code", "/properties/id", "/properties/ref"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestLintEnumTypeNamesMessage(t *testing.T) {
+	s, steps := mustSchemaTrace(t, "id: [string, integer]")
+	l := Linter{Severity: map[string]Severity{"missing-description": SeverityOff}, Trace: steps}
+	diags := l.Lint(s)
+	if len(diags) != 1 {
+		t.Fatalf("got %v, want one diagnostic", diags)
+	}
+	want := "enum [string, integer] lists type names; for a union of the types, write (anyOf): [string, integer] or convert with WithTypeUnions"
+	if diags[0].Message != want {
+		t.Errorf("message %q, want %q", diags[0].Message, want)
+	}
+}
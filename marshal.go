@@ -0,0 +1,209 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invopop/jsonschema"
+)
+
+// MarshalInstance returns a canonical JSON encoding of data, an
+// instance of s, so that instances that differ only in the order of
+// their keys or in how they write the same numbers and times encode
+// the same. It gives stable keys for caching model responses and
+// stable text for diffing them.
+//
+// data is first encoded with encoding/json, so it may be a Go value of
+// any type that can be, as well as a document decoded from JSON or
+// YAML. The encoding is compact and does not escape HTML characters.
+// The keys of an object come in the order of the properties of its
+// schema, which for schemas converted from picoschema is the order of
+// their names whatever the order of the source, followed by the keys
+// the schema does not declare, sorted. Numbers are written in their
+// shortest form, as 1.5 for 1.50 and 100 for 1e2, and those of integer
+// schemas without a fraction or an exponent. Strings of the format
+// date-time are written in UTC in RFC 3339, as 2024-05-01T10:00:00Z for
+// 2024-05-01T12:00:00+02:00, unless they do not parse as one.
+//
+// The schema of a value is found by following local refs and taking
+// the alternative to null of nullable schemas; values under other
+// unions are written with no schema.
+func MarshalInstance(data any, s *jsonschema.Schema) ([]byte, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("picoschema: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("picoschema: %w", err)
+	}
+	m := &instanceMarshaler{root: s}
+	m.value(v, s)
+	return m.buf.Bytes(), nil
+}
+
+// An instanceMarshaler writes the canonical encoding of instances.
+type instanceMarshaler struct {
+	root *jsonschema.Schema // for resolving refs
+	buf  bytes.Buffer
+}
+
+// value writes v, a document decoded with numbers as json.Number,
+// as an instance of s, which may be nil.
+func (m *instanceMarshaler) value(v any, s *jsonschema.Schema) {
+	s = m.resolve(s)
+	switch v := v.(type) {
+	case nil:
+		m.buf.WriteString("null")
+	case bool:
+		m.buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		m.buf.WriteString(canonicalNumber(v, s != nil && s.Type == "integer"))
+	case string:
+		if s != nil {
+			v = canonicalTime(v, s.Format)
+		}
+		m.string(v)
+	case []any:
+		m.buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				m.buf.WriteByte(',')
+			}
+			var es *jsonschema.Schema
+			if s != nil {
+				es = itemSchema(s, i)
+			}
+			m.value(e, es)
+		}
+		m.buf.WriteByte(']')
+	case map[string]any:
+		m.buf.WriteByte('{')
+		for i, k := range instanceKeys(v, s) {
+			if i > 0 {
+				m.buf.WriteByte(',')
+			}
+			m.string(k)
+			m.buf.WriteByte(':')
+			var ps *jsonschema.Schema
+			if s != nil {
+				ps = propertySchema(s, k)
+			}
+			m.value(v[k], ps)
+		}
+		m.buf.WriteByte('}')
+	}
+}
+
+// string writes str as a JSON string.
+func (m *instanceMarshaler) string(str string) {
+	enc := json.NewEncoder(&m.buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(str)
+	// Encode ends the value with a newline.
+	m.buf.Truncate(m.buf.Len() - 1)
+}
+
+// resolve returns the schema that describes the values of s: the
+// target of its ref, the alternative to null if it is nullable, or
+// the schema it wraps in allOf, repeatedly. It returns nil for
+// boolean schemas and refs that do not resolve.
+func (m *instanceMarshaler) resolve(s *jsonschema.Schema) *jsonschema.Schema {
+	// The limit stops cycles of refs.
+	for range 32 {
+		if _, ok := boolSchema(s); ok || s == nil {
+			return nil
+		}
+		switch {
+		case s.Ref != "":
+			ptr, ok := strings.CutPrefix(s.Ref, "#")
+			if !ok || m.root == nil {
+				return nil
+			}
+			if s, ok = resolvePointer(m.root, ptr); !ok {
+				return nil
+			}
+		case len(s.AllOf) == 1 && len(schemaKeywords(s)) == 1:
+			s = s.AllOf[0]
+		default:
+			alt, ok := nullableAlternative(s)
+			if !ok {
+				return s
+			}
+			s = alt
+		}
+	}
+	return nil
+}
+
+// instanceKeys returns the keys of obj in the order of the properties
+// of s, followed by the others, sorted.
+func instanceKeys(obj map[string]any, s *jsonschema.Schema) []string {
+	keys := make([]string, 0, len(obj))
+	if s != nil && s.Properties != nil {
+		for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+			if _, ok := obj[p.Key]; ok {
+				keys = append(keys, p.Key)
+			}
+		}
+	}
+	declared := len(keys)
+	for k := range obj {
+		if s == nil || s.Properties == nil || s.Properties.Value(k) == nil {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys[declared:])
+	return keys
+}
+
+// canonicalNumber returns the shortest text of n, written without a
+// fraction or an exponent if integer is set and n is an integer.
+func canonicalNumber(n json.Number, integer bool) string {
+	if i, err := n.Int64(); err == nil {
+		return strconv.FormatInt(i, 10)
+	}
+	f, err := n.Float64()
+	if err != nil || !strings.ContainsAny(n.String(), ".eE") {
+		// Out of range of float64, or an integer out of range of
+		// int64, which a float64 would round.
+		return n.String()
+	}
+	if integer && f == math.Trunc(f) {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return jsonText(f)
+}
+
+// canonicalTime returns str in UTC if the format is date-time
+// and str parses as one.
+func canonicalTime(str, format string) string {
+	if format == "date-time" {
+		if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return str
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/invopop/jsonschema"
+)
+
+func TestMarshalInstance(t *testing.T) {
+	var s jsonschema.Schema
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string"},
+			"at": {"type": "string", "format": "date-time"},
+			"count": {"type": "integer"},
+			"price": {"type": "number"},
+			"next": {"anyOf": [{"$ref": "#"}, {"type": "null"}]},
+			"lines": {"type": "array", "items": {"$ref": "#/$defs/line"}}
+		},
+		"$defs": {
+			"line": {"type": "object", "properties": {"sku": {"type": "string"}, "qty": {"type": "integer"}}}
+		}
+	}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", `{}`, `{}`},
+		{"schema order", `{"price": 2, "name": "a"}`, `{"name":"a","price":2}`},
+		{"undeclared keys last, sorted", `{"z": 1, "name": "a", "b": {"y": 1, "x": 2}}`, `{"name":"a","b":{"x":2,"y":1},"z":1}`},
+		{"numbers", `{"count": 3.0, "price": 1.50}`, `{"count":3,"price":1.5}`},
+		{"exponents", `{"count": 1e3, "price": 2.5e-3}`, `{"count":1000,"price":0.0025}`},
+		{"large integer", `{"count": 12345678901234567890}`, `{"count":12345678901234567890}`},
+		{"large integer with exponent", `{"count": 1.5e20}`, `{"count":150000000000000000000}`},
+		{"date-time in UTC", `{"at": "2024-05-01T12:00:00.500+02:00"}`, `{"at":"2024-05-01T10:00:00.5Z"}`},
+		{"not a date-time", `{"at": "yesterday", "name": "2024-05-01T12:00:00+02:00"}`, `{"name":"2024-05-01T12:00:00+02:00","at":"yesterday"}`},
+		{"no HTML escaping", `{"name": "<a&b>"}`, `{"name":"<a&b>"}`},
+		{"recursive ref", `{"next": {"price": 1.0, "name": "b", "next": null}}`, `{"next":{"name":"b","price":1,"next":null}}`},
+		{"defs ref", `{"lines": [{"qty": 2.0, "sku": "x"}]}`, `{"lines":[{"sku":"x","qty":2}]}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dec := json.NewDecoder(strings.NewReader(tc.in))
+			dec.UseNumber()
+			var in any
+			if err := dec.Decode(&in); err != nil {
+				t.Fatal(err)
+			}
+			got, err := MarshalInstance(in, &s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMarshalInstanceGoValue(t *testing.T) {
+	type order struct {
+		Price float64   `json:"price"`
+		At    time.Time `json:"at"`
+	}
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	var s *jsonschema.Schema
+	// Each conversion of the schema gives the same encoding.
+	for range 10 {
+		s = mustSchema(t, "price: number\nat(string, format=date-time):\nid?: string")
+		got, err := MarshalInstance(order{Price: 9.5, At: at}, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `{"at":"2024-05-01T10:00:00Z","price":9.5}`; string(got) != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+	if _, err := MarshalInstance(func() {}, s); err == nil {
+		t.Error("function marshaled")
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// MemoryStats estimates the memory a Registry retains for its schemas.
+type MemoryStats struct {
+	// Total is the sum of the Bytes of the schemas.
+	Total int64
+	// Schemas are the registered schemas, sorted by name.
+	Schemas []SchemaMemory
+}
+
+// SchemaMemory estimates the memory retained for one registered schema.
+type SchemaMemory struct {
+	Name string
+	// Bytes estimates the heap memory of the schema, including its
+	// subschemas, strings, maps and values. Subschemas that an Interner
+	// shares with other schemas are counted in each, so with an
+	// Interner the Total overstates the memory retained.
+	Bytes int64
+	// Pending reports that the schema was registered with RegisterLazy
+	// and has not been converted, so that it retains no schema yet.
+	Pending bool
+}
+
+// MemoryStats estimates the memory r retains for its schemas, to help
+// plan the capacity of services that load whole catalogs. The size of
+// each schema is estimated when it is registered, so MemoryStats is
+// cheap enough to export as a metric. The estimate follows the layout
+// of Go values on 64-bit platforms, without allocator overhead.
+func (r *Registry) MemoryStats() MemoryStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var st MemoryStats
+	for name, size := range r.sizes {
+		st.Schemas = append(st.Schemas, SchemaMemory{Name: name, Bytes: size})
+		st.Total += size
+	}
+	for name := range r.lazy {
+		st.Schemas = append(st.Schemas, SchemaMemory{Name: name, Pending: true})
+	}
+	slices.SortFunc(st.Schemas, func(a, b SchemaMemory) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return st
+}
+
+// retainedBytes estimates the heap memory reachable from s.
+func retainedBytes(s *jsonschema.Schema) int64 {
+	m := &memSizer{seen: make(map[uintptr]bool)}
+	return m.pointer(reflect.ValueOf(s))
+}
+
+// A memSizer estimates the heap memory of values, counting each
+// pointer target once.
+type memSizer struct {
+	seen map[uintptr]bool
+}
+
+// Estimated overhead of each map entry beyond its key and value,
+// for the hash table's buckets and spare capacity.
+const mapEntryOverhead = 16
+
+// pointer returns the size of the target of the pointer p,
+// unless it was counted before, and of what it refers to.
+func (m *memSizer) pointer(p reflect.Value) int64 {
+	if p.IsNil() || m.seen[p.Pointer()] {
+		return 0
+	}
+	m.seen[p.Pointer()] = true
+	return int64(p.Type().Elem().Size()) + m.indirect(p.Elem())
+}
+
+// indirect returns the size of the memory v refers to, not counting v
+// itself, which is part of the value containing it.
+func (m *memSizer) indirect(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Pointer:
+		return m.pointer(v)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		e := v.Elem()
+		n := m.indirect(e)
+		if e.Kind() != reflect.Pointer && e.Kind() != reflect.Map {
+			// Non-pointer values are boxed.
+			n += int64(e.Type().Size())
+		}
+		return n
+	case reflect.Slice:
+		if v.IsNil() || m.seen[v.Pointer()] {
+			return 0
+		}
+		m.seen[v.Pointer()] = true
+		n := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := range v.Len() {
+			n += m.indirect(v.Index(i))
+		}
+		return n
+	case reflect.Map:
+		if v.IsNil() || m.seen[v.Pointer()] {
+			return 0
+		}
+		m.seen[v.Pointer()] = true
+		entry := int64(v.Type().Key().Size()+v.Type().Elem().Size()) + mapEntryOverhead
+		n := int64(v.Len()) * entry
+		for it := v.MapRange(); it.Next(); {
+			n += m.indirect(it.Key()) + m.indirect(it.Value())
+		}
+		return n
+	case reflect.Struct:
+		var n int64
+		for i := range v.NumField() {
+			n += m.indirect(v.Field(i))
+		}
+		return n
+	case reflect.Array:
+		var n int64
+		for i := range v.Len() {
+			n += m.indirect(v.Index(i))
+		}
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryMemoryStats(t *testing.T) {
+	var r Registry
+	if err := r.Register("small", mustSchema(t, "id: string")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("large", mustSchema(t, "id: string\nname: string, "+strings.Repeat("x", 1000)+"\ntags(array): string")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RegisterLazy("later", Lazy(map[string]any{"id": "string"})); err != nil {
+		t.Fatal(err)
+	}
+	st := r.MemoryStats()
+	if len(st.Schemas) != 3 {
+		t.Fatalf("got %d schemas, want 3", len(st.Schemas))
+	}
+	large, later, small := st.Schemas[0], st.Schemas[1], st.Schemas[2]
+	if large.Name != "large" || later.Name != "later" || small.Name != "small" {
+		t.Fatalf("schemas not sorted by name: %+v", st.Schemas)
+	}
+	if small.Bytes <= 0 || large.Bytes < small.Bytes+1000 {
+		t.Errorf("sizes small %d, large %d: want large over small by at least its description", small.Bytes, large.Bytes)
+	}
+	if !later.Pending || later.Bytes != 0 {
+		t.Errorf("lazy schema: got %+v, want pending", later)
+	}
+	if st.Total != small.Bytes+large.Bytes {
+		t.Errorf("Total %d, want %d", st.Total, small.Bytes+large.Bytes)
+	}
+
+	r.Lookup("later")
+	if later := r.MemoryStats().Schemas[1]; later.Pending || later.Bytes != small.Bytes {
+		t.Errorf("converted lazy schema: got %+v, want the size %d of the same schema", later, small.Bytes)
+	}
+}
+
+func TestRetainedBytesShared(t *testing.T) {
+	s := mustSchema(t, "a: {x: string, y: string}\nb: {x: string, y: string}")
+	distinct := retainedBytes(s)
+	s.Properties.Set("b", s.Properties.Value("a"))
+	if shared := retainedBytes(s); shared >= distinct {
+		t.Errorf("shared subschema counted twice: %d bytes, %d when distinct", shared, distinct)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// ErrConflict is returned, wrapped, by Merge with FailOnConflict.
+var ErrConflict = errors.New("conflict")
+
+// A ConflictHandler decides what becomes of a schema that both schemas
+// given to Merge declare, at the JSON Pointer path, in ways that cannot
+// be combined, as when a property is a string on one side and an
+// integer on the other. It returns the schema to use in their place,
+// or an error to make Merge fail. left and right are copies, which the
+// handler may return or modify.
+type ConflictHandler func(path string, left, right *jsonschema.Schema) (*jsonschema.Schema, error)
+
+// Conflict handlers for Merge.
+var (
+	// PreferLeft keeps the schema of the first argument of Merge.
+	PreferLeft ConflictHandler = func(_ string, left, _ *jsonschema.Schema) (*jsonschema.Schema, error) {
+		return left, nil
+	}
+	// PreferRight keeps the schema of the second argument of Merge.
+	PreferRight ConflictHandler = func(_ string, _, right *jsonschema.Schema) (*jsonschema.Schema, error) {
+		return right, nil
+	}
+	// FailOnConflict makes Merge fail with an error wrapping
+	// ErrConflict that names the sources of the schemas, if recorded.
+	FailOnConflict ConflictHandler = func(path string, left, right *jsonschema.Schema) (*jsonschema.Schema, error) {
+		if path == "" {
+			path = "/"
+		}
+		err := fmt.Errorf("picoschema: merging %s: %w", path, ErrConflict)
+		ls, _ := left.Extras[sourceKey].(string)
+		rs, _ := right.Extras[sourceKey].(string)
+		if ls != "" || rs != "" {
+			err = fmt.Errorf("%w between %s and %s", err, sourceOrUnknown(ls), sourceOrUnknown(rs))
+		}
+		return nil, err
+	}
+)
+
+// sourceOrUnknown returns src, or "an unknown source" if it is empty.
+func sourceOrUnknown(src string) string {
+	if src == "" {
+		return "an unknown source"
+	}
+	return src
+}
+
+// Merge returns the union of the object schemas a and b: one with the
+// properties of both, as when a schema extends another. A property
+// that both declare, differently, is merged in turn if it is an object
+// on both sides, and otherwise decided by onConflict, as are two
+// schemas that differ in keywords other than their properties, required
+// lists and annotations. The required lists are combined, and the
+// annotations of a, such as its description, take precedence over
+// those of b. A nil onConflict means FailOnConflict, so that no schema
+// is silently preferred. Neither a nor b is modified.
+func Merge(a, b *jsonschema.Schema, onConflict ConflictHandler) (*jsonschema.Schema, error) {
+	if onConflict == nil {
+		onConflict = FailOnConflict
+	}
+	return merge(a, b, "", onConflict)
+}
+
+// mergedKeywords are the keywords that Merge combines
+// rather than requiring them to be equal.
+var mergedKeywords = []string{"properties", "required"}
+
+// merge merges a and b, which are at path.
+func merge(a, b *jsonschema.Schema, path string, onConflict ConflictHandler) (*jsonschema.Schema, error) {
+	if a == nil {
+		return cloneSchema(b), nil
+	}
+	if b == nil {
+		return cloneSchema(a), nil
+	}
+	ja, err1 := canonicalJSON(a)
+	jb, err2 := canonicalJSON(b)
+	if err1 == nil && err2 == nil && bytes.Equal(ja, jb) {
+		return cloneSchema(a), nil
+	}
+	if !mergeable(a, b) {
+		return onConflict(path, cloneSchema(a), cloneSchema(b))
+	}
+	c, bc := cloneSchema(a), cloneSchema(b)
+	rc, rb := reflect.ValueOf(c).Elem(), reflect.ValueOf(bc).Elem()
+	for i := 0; i < rc.NumField(); i++ {
+		name, _, _ := strings.Cut(rc.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || slices.Contains(mergedKeywords, name) {
+			continue
+		}
+		if rc.Field(i).IsZero() && !rb.Field(i).IsZero() {
+			rc.Field(i).Set(rb.Field(i))
+		}
+	}
+	for k, v := range bc.Extras {
+		if _, ok := c.Extras[k]; !ok {
+			setExtra(c, k, v)
+		}
+	}
+	for p := bc.Properties.Oldest(); p != nil; p = p.Next() {
+		old, ok := c.Properties.Get(p.Key)
+		if !ok {
+			c.Properties.Set(p.Key, p.Value)
+			continue
+		}
+		m, err := merge(old, p.Value, path+"/properties/"+escapePointer(p.Key), onConflict)
+		if err != nil {
+			return nil, err
+		}
+		c.Properties.Set(p.Key, m)
+	}
+	for _, r := range b.Required {
+		if !slices.Contains(c.Required, r) {
+			c.Required = append(c.Required, r)
+		}
+	}
+	return c, nil
+}
+
+// mergeable reports whether a and b are objects with properties
+// that agree on every keyword other than their properties, required
+// lists and annotations.
+func mergeable(a, b *jsonschema.Schema) bool {
+	if _, ok := boolSchema(a); ok {
+		return false
+	}
+	if _, ok := boolSchema(b); ok {
+		return false
+	}
+	if a.Properties == nil || b.Properties == nil {
+		return false
+	}
+	ra, rb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for i := 0; i < ra.NumField(); i++ {
+		name, _, _ := strings.Cut(ra.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || slices.Contains(mergedKeywords, name) || isAnnotation(name) {
+			continue
+		}
+		fa, fb := ra.Field(i), rb.Field(i)
+		if !fa.IsZero() && !fb.IsZero() && !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+)
+
+func TestMerge(t *testing.T) {
+	base := `
+id: string
+status(enum): [open, closed]
+owner(object):
+  name: string
+`
+	ext := `
+status: string
+owner(object):
+  email?: string
+createdAt?: string
+`
+	widen := func(path string, left, right *jsonschema.Schema) (*jsonschema.Schema, error) {
+		return &jsonschema.Schema{AnyOf: []*jsonschema.Schema{left, right}}, nil
+	}
+	for _, test := range []struct {
+		name       string
+		onConflict ConflictHandler
+		want       string
+		wantErr    string
+	}{
+		{
+			name:       "prefer left",
+			onConflict: PreferLeft,
+			want: `
+id: string
+status(enum): [open, closed]
+owner(object):
+  name: string
+  email?: string
+createdAt?: string
+`,
+		},
+		{
+			name:       "prefer right",
+			onConflict: PreferRight,
+			want: `
+id: string
+status: string
+owner(object):
+  name: string
+  email?: string
+createdAt?: string
+`,
+		},
+		{
+			name:       "custom",
+			onConflict: widen,
+			want: `
+id: string
+status(anyOf): [[open, closed], string]
+owner(object):
+  name: string
+  email?: string
+createdAt?: string
+`,
+		},
+		{
+			name:    "default",
+			wantErr: "picoschema: merging /properties/status: conflict",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := mustSchema(t, base), mustSchema(t, ext)
+			before, err := ConvertSchema(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Merge(a, b, test.onConflict)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr || !errors.Is(err, ErrConflict) {
+					t.Fatalf("got error %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotJSON, err := ConvertSchema(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := ConvertSchema(mustSchema(t, test.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, gotJSON); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+			after, err := ConvertSchema(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(before, after); diff != "" {
+				t.Errorf("Merge modified its input (-before, +after):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMergeConflictSources(t *testing.T) {
+	a := mustSchema(t, "id: string")
+	b := mustSchema(t, "id: integer")
+	setSource(a.Properties.Value("id"), "orders.yaml")
+	_, err := Merge(a, b, FailOnConflict)
+	want := "picoschema: merging /properties/id: conflict between orders.yaml and an unknown source"
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+
+	// Objects that disagree on more than their properties conflict as a whole.
+	a = mustSchema(t, "id: string\n(*): any")
+	if _, err := Merge(a, b, nil); err == nil || err.Error() != "picoschema: merging /: conflict" {
+		t.Errorf("got error %v, want a conflict at the root", err)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements from the package, for export to a
+// monitoring system such as Prometheus. Its methods are called
+// synchronously, possibly from several goroutines at once, and
+// should return quickly.
+type Metrics interface {
+	// Conversion records a conversion of schema source in format
+	// ("picoschema", or an importer name) that took elapsed,
+	// whether or not it succeeded.
+	Conversion(format string, elapsed time.Duration)
+
+	// Error records a failure of the operation op ("import", "convert",
+	// "resolve" or "bundle"). The kind is one of "not_found", "resolve"
+	// (a failure to fetch a schema), "limit" (a size limit was
+	// exceeded), "signature" or "invalid" (anything else, usually an
+	// error in the schema source).
+	Error(op, kind string)
+
+	// CacheLookup records a lookup in the named cache ("http" for an
+	// HTTPCache) and whether it found a usable entry.
+	CacheLookup(cache string, hit bool)
+}
+
+var (
+	metricsMu sync.RWMutex
+	metrics   Metrics
+)
+
+// SetMetrics makes m receive the package's measurements from now on,
+// replacing any Metrics set before. SetMetrics(nil) stops measurement.
+func SetMetrics(m Metrics) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	metrics = m
+}
+
+func currentMetrics() Metrics {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	return metrics
+}
+
+// recordConversion records a conversion of format that began at start.
+func recordConversion(format string, start time.Time) {
+	if m := currentMetrics(); m != nil {
+		m.Conversion(format, time.Since(start))
+	}
+}
+
+// recordError records err, if it is not nil, as a failure of op.
+func recordError(op string, err error) {
+	if err == nil {
+		return
+	}
+	if m := currentMetrics(); m != nil {
+		m.Error(op, errorKind(err))
+	}
+}
+
+// recordCacheLookup records a lookup in the named cache.
+func recordCacheLookup(cache string, hit bool) {
+	if m := currentMetrics(); m != nil {
+		m.CacheLookup(cache, hit)
+	}
+}
+
+// errorKind classifies err for Metrics.Error.
+func errorKind(err error) string {
+	var re *ResolveError
+	switch {
+	case errors.Is(err, ErrSchemaNotFound):
+		return "not_found"
+	case errors.Is(err, ErrLimitExceeded), errors.Is(err, ErrResponseTooLarge):
+		return "limit"
+	case errors.Is(err, ErrBadSignature):
+		return "signature"
+	case errors.As(err, &re):
+		return "resolve"
+	}
+	return "invalid"
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testMetrics struct {
+	mu          sync.Mutex
+	conversions map[string]int
+	errors      map[string]int
+	cache       map[bool]int
+}
+
+func (m *testMetrics) Conversion(format string, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.conversions[format]++
+}
+
+func (m *testMetrics) Error(op, kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[op+"/"+kind]++
+}
+
+func (m *testMetrics) CacheLookup(cache string, hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache[hit]++
+}
+
+func TestMetrics(t *testing.T) {
+	m := &testMetrics{conversions: map[string]int{}, errors: map[string]int{}, cache: map[bool]int{}}
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/a.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"type": "string"}`))
+	}))
+	defer srv.Close()
+
+	ToJSONSchema(map[string]any{"name": "string"})
+	ToJSONSchema(map[string]any{"name": "nosuchtype"})
+	Import("picoschema", []byte("name: string"))
+	Import("jsonschema", []byte("{"))
+	r := &HTTPResolver{Client: srv.Client(), Cache: &HTTPCache{}, TTL: time.Hour}
+	r.Resolve(srv.URL + "/a.json")
+	r.Resolve(srv.URL + "/a.json")
+	r.Resolve(srv.URL + "/b.json")
+	b := Bundler{Resolver: r, MaxRefs: 1}
+	b.Bundle(refObject("a", srv.URL+"/a.json", "b", srv.URL+"/b.json"))
+
+	if diff := cmp.Diff(map[string]int{"picoschema": 3, "jsonschema": 2}, m.conversions); diff != "" {
+		t.Errorf("conversions mismatch (-want, +got):\n%s", diff)
+	}
+	wantErrors := map[string]int{
+		"convert/invalid":   1,
+		"import/invalid":    1,
+		"resolve/not_found": 1,
+		"bundle/limit":      1,
+	}
+	if diff := cmp.Diff(wantErrors, m.errors); diff != "" {
+		t.Errorf("errors mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[bool]int{true: 2, false: 2}, m.cache); diff != "" {
+		t.Errorf("cache lookups mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// Versioning annotations. In picoschema they are written as attributes,
+// as in "email?(sinceVersion=2): string" or "fullName(renamedFrom=name): string".
+const (
+	sinceVersionKey     = "x-sinceVersion"
+	removedInVersionKey = "x-removedInVersion"
+	renamedFromKey      = "x-renamedFrom"
+)
+
+// A StepKind is the kind of a migration step.
+type StepKind string
+
+const (
+	// StepRename moves the value of a property to a new name.
+	StepRename StepKind = "rename"
+	// StepBackfill adds a property that documents written
+	// against the old schema lack.
+	StepBackfill StepKind = "backfill"
+	// StepDrop removes a property that the new schema no longer has.
+	StepDrop StepKind = "drop"
+	// StepCoerce converts the value of a property whose type changed.
+	StepCoerce StepKind = "coerce"
+)
+
+// A MigrationStep is one change to make to stored documents.
+// Paths are dot-separated property names, looking through arrays
+// as Project does.
+type MigrationStep struct {
+	Kind StepKind
+	// Path is the property's path in the new schema,
+	// or in the old schema for StepDrop.
+	Path string
+	// From is the property's old path, for StepRename.
+	From string
+	// Default is the value to backfill, if the new schema declares one.
+	Default any
+	// Type is the property's type in the new schema,
+	// for StepBackfill and StepCoerce.
+	Type string
+	// Version is the schema version that introduced or removed the
+	// property, taken from its sinceVersion or removedInVersion annotation.
+	Version string
+}
+
+// A Migration is a plan for upgrading documents from one version
+// of a schema to another.
+type Migration struct {
+	Steps []MigrationStep
+}
+
+// MigrationPlan compares two versions of a schema and returns the steps
+// needed to upgrade documents valid under prev to documents valid under next.
+// A property of next annotated with renamedFrom is matched with the old
+// property of that name. Added properties are backfilled when they are
+// required or declare a default; removed properties are dropped; and
+// properties whose type changed are coerced.
+func MigrationPlan(prev, next *jsonschema.Schema) *Migration {
+	m := &Migration{}
+	m.plan(prev, next, "")
+	return m
+}
+
+func (m *Migration) plan(prev, next *jsonschema.Schema, path string) {
+	if prev == nil || next == nil {
+		return
+	}
+	if prev.Items != nil && next.Items != nil {
+		m.plan(prev.Items, next.Items, path)
+	}
+	if prev.Properties == nil || next.Properties == nil {
+		return
+	}
+	matched := make(map[string]bool)
+	for p := next.Properties.Oldest(); p != nil; p = p.Next() {
+		np := joinPath(path, p.Key)
+		from := p.Key
+		if r, ok := p.Value.Extras[renamedFromKey].(string); ok {
+			from = r
+		}
+		if prevProp, ok := prev.Properties.Get(from); ok {
+			matched[from] = true
+			if from != p.Key {
+				m.Steps = append(m.Steps, MigrationStep{Kind: StepRename, Path: np, From: joinPath(path, from)})
+			}
+			if prevProp.Type != "" && p.Value.Type != "" && prevProp.Type != p.Value.Type {
+				m.Steps = append(m.Steps, MigrationStep{Kind: StepCoerce, Path: np, Type: p.Value.Type})
+			}
+			m.plan(prevProp, p.Value, np)
+			continue
+		}
+		if slices.Contains(next.Required, p.Key) || p.Value.Default != nil {
+			v, _ := p.Value.Extras[sinceVersionKey].(string)
+			m.Steps = append(m.Steps, MigrationStep{Kind: StepBackfill, Path: np, Default: p.Value.Default, Type: p.Value.Type, Version: v})
+		}
+	}
+	for p := prev.Properties.Oldest(); p != nil; p = p.Next() {
+		if matched[p.Key] {
+			continue
+		}
+		v, _ := p.Value.Extras[removedInVersionKey].(string)
+		m.Steps = append(m.Steps, MigrationStep{Kind: StepDrop, Path: joinPath(path, p.Key), Version: v})
+	}
+}
+
+// String renders the plan as a skeleton to be filled in by hand,
+// one step per line in the order of their paths. Defaults are written
+// as JSON.
+func (m *Migration) String() string {
+	steps := slices.Clone(m.Steps)
+	slices.SortStableFunc(steps, func(a, b MigrationStep) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	var b strings.Builder
+	for _, s := range steps {
+		switch s.Kind {
+		case StepRename:
+			fmt.Fprintf(&b, "rename %s -> %s", s.From, s.Path)
+		case StepBackfill:
+			fmt.Fprintf(&b, "backfill %s", s.Path)
+			if s.Default != nil {
+				fmt.Fprintf(&b, " = %s", jsonText(s.Default))
+			} else {
+				b.WriteString(" = TODO")
+			}
+		case StepCoerce:
+			fmt.Fprintf(&b, "coerce %s to %s", s.Path, s.Type)
+		default:
+			fmt.Fprintf(&b, "%s %s", s.Kind, s.Path)
+		}
+		if s.Version != "" {
+			fmt.Fprintf(&b, " (version %s)", s.Version)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// joinPath appends a property name to a dot-separated path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// Migrate applies the steps of m to a copy of data, a document decoded
+// from JSON or YAML, and returns the copy.
+// A backfilled property that declares no default is set to the zero
+// value of its type. Migrate fails if a value cannot be coerced.
+func Migrate(data any, m *Migration) (any, error) {
+	data = copyValue(data)
+	for _, s := range m.Steps {
+		var err error
+		switch s.Kind {
+		case StepRename:
+			from := s.From[strings.LastIndexByte(s.From, '.')+1:]
+			err = applyAtPath(data, s.Path, func(obj map[string]any, key string) error {
+				if v, ok := obj[from]; ok {
+					delete(obj, from)
+					obj[key] = v
+				}
+				return nil
+			})
+		case StepBackfill:
+			err = applyAtPath(data, s.Path, func(obj map[string]any, key string) error {
+				if _, ok := obj[key]; !ok {
+					if s.Default != nil {
+						obj[key] = copyValue(s.Default)
+					} else {
+						obj[key] = zeroValue(s.Type)
+					}
+				}
+				return nil
+			})
+		case StepDrop:
+			err = applyAtPath(data, s.Path, func(obj map[string]any, key string) error {
+				delete(obj, key)
+				return nil
+			})
+		case StepCoerce:
+			err = applyAtPath(data, s.Path, func(obj map[string]any, key string) error {
+				v, ok := obj[key]
+				if !ok || v == nil {
+					return nil
+				}
+				c, err := coerceType(v, s.Type)
+				if err != nil {
+					return err
+				}
+				obj[key] = c
+				return nil
+			})
+		default:
+			err = fmt.Errorf("unknown step kind %q", s.Kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("picoschema: migrate %s: %w", s.Path, err)
+		}
+	}
+	return data, nil
+}
+
+// applyAtPath calls fn with each object in v holding the property
+// at the dot-separated path, and the property's name. Arrays along
+// the way are looked through. Objects missing an intermediate
+// property are skipped.
+func applyAtPath(v any, path string, fn func(obj map[string]any, key string) error) error {
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			if err := applyAtPath(e, path, fn); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		first, rest, ok := strings.Cut(path, ".")
+		if !ok {
+			return fn(v, first)
+		}
+		if sub, ok := v[first]; ok {
+			return applyAtPath(sub, rest, fn)
+		}
+	}
+	return nil
+}
+
+// zeroValue returns the zero value of a JSON Schema type.
+func zeroValue(typ string) any {
+	switch typ {
+	case "string":
+		return ""
+	case "number", "integer":
+		return 0
+	case "boolean":
+		return false
+	case "array":
+		return []any{}
+	case "object":
+		return map[string]any{}
+	}
+	return nil
+}
+
+// coerceType converts the scalar v to the JSON Schema type typ.
+func coerceType(v any, typ string) (any, error) {
+	if hasType(v, typ) {
+		return v, nil
+	}
+	switch typ {
+	case "string":
+		switch v.(type) {
+		case map[string]any, []any:
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case "number", "integer":
+		f, ok := toFloat(v)
+		if s, isStr := v.(string); isStr {
+			var err error
+			f, err = strconv.ParseFloat(strings.TrimSpace(s), 64)
+			ok = err == nil
+		}
+		if b, isBool := v.(bool); isBool {
+			f, ok = 0, true
+			if b {
+				f = 1
+			}
+		}
+		if ok && (typ == "number" || f == math.Trunc(f)) {
+			if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+				return int64(f), nil
+			}
+			return f, nil
+		}
+	case "boolean":
+		if s, ok := v.(string); ok {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b, nil
+			}
+		}
+		if f, ok := toFloat(v); ok {
+			return f != 0, nil
+		}
+	case "array":
+		return []any{v}, nil
+	}
+	return nil, fmt.Errorf("cannot convert %v of type %s to %s", v, jsonType(v), typ)
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestMigrationPlan(t *testing.T) {
+	old := mustSchema(t, `
+name: string
+fax?(removedInVersion=3): string
+items(array):
+  sku: string
+`)
+	next := mustSchema(t, `
+fullName(renamedFrom=name): string
+email(sinceVersion=3): string
+nickname?: string
+tags?(array, default=["a"]): string
+items(array):
+  sku: string
+  qty: integer
+`)
+	m := MigrationPlan(old, next)
+	want := []MigrationStep{
+		{Kind: StepRename, Path: "fullName", From: "name"},
+		{Kind: StepBackfill, Path: "email", Type: "string", Version: "3"},
+		{Kind: StepBackfill, Path: "items.qty", Type: "integer"},
+		{Kind: StepBackfill, Path: "tags", Type: "array", Default: []any{"a"}},
+		{Kind: StepDrop, Path: "fax", Version: "3"},
+	}
+	less := func(a, b MigrationStep) bool { return string(a.Kind)+a.Path < string(b.Kind)+b.Path }
+	if diff := cmp.Diff(want, m.Steps, cmpopts.SortSlices(less)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	wantText := `backfill email = TODO (version 3)
+drop fax (version 3)
+rename name -> fullName
+backfill items.qty = TODO
+backfill tags = ["a"]
+`
+	if got := m.String(); got != wantText {
+		t.Errorf("String() = %q, want %q", got, wantText)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	old := mustSchema(t, `
+name: string
+age: string
+fax?: string
+items(array):
+  sku: string
+`)
+	next := mustSchema(t, `
+fullName(renamedFrom=name): string
+age: integer
+active: boolean
+items(array):
+  sku: string
+  qty: integer
+`)
+	data := map[string]any{
+		"name":  "Ann",
+		"age":   "42",
+		"fax":   "555",
+		"items": []any{map[string]any{"sku": "a"}, map[string]any{"sku": "b", "qty": 2}},
+	}
+	got, err := Migrate(data, MigrationPlan(old, next))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"fullName": "Ann",
+		"age":      int64(42),
+		"active":   false,
+		"items":    []any{map[string]any{"sku": "a", "qty": 0}, map[string]any{"sku": "b", "qty": 2}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if _, ok := data["name"]; !ok {
+		t.Error("Migrate modified its input")
+	}
+
+	if _, err := Migrate(map[string]any{"name": "Ann", "age": "old"}, MigrationPlan(old, next)); err == nil {
+		t.Error("got nil error for uncoercible value")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A MutationCase is a mutated instance together with the verdict
+// of Check on it.
+type MutationCase struct {
+	// Name describes the mutation, as in "/age: replace with null".
+	Name     string
+	Instance any
+	// Valid reports whether Instance is valid against the schema.
+	Valid bool
+	// Violations are the violations Check reports for Instance.
+	Violations []Violation
+}
+
+// Mutations returns a battery of mutations of instance, a document
+// decoded from JSON or YAML, each with its expected verdict under s.
+// Every value in the instance is replaced with null and with a value of
+// each other JSON type; properties are removed and added; arrays are
+// shortened, emptied and extended; strings and numbers are nudged;
+// and enum values are swapped for the other members. Some mutations
+// leave the instance valid, and their cases say so.
+//
+// The cases serve to test other validators, such as a provider's,
+// against Check; see Disagreements.
+func Mutations(instance any, s *jsonschema.Schema) []MutationCase {
+	var cases []MutationCase
+	mutateValue(instance, s, "", func(name string, replace func(root any) any) {
+		inst := replace(copyValue(instance))
+		vs := Check(inst, s)
+		cases = append(cases, MutationCase{Name: name, Instance: inst, Valid: len(vs) == 0, Violations: vs})
+	})
+	return cases
+}
+
+// Disagreements returns the cases on which valid, another validator's
+// verdict, differs from the verdict of Check.
+func Disagreements(cases []MutationCase, valid func(instance any) bool) []MutationCase {
+	var out []MutationCase
+	for _, c := range cases {
+		if valid(c.Instance) != c.Valid {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// mutateValue calls emit for each mutation of v, the value at ipath
+// validated by s. The replace function passed to emit applies the
+// mutation to a copy of the root instance and returns the new root.
+func mutateValue(v any, s *jsonschema.Schema, ipath string, emit func(name string, replace func(root any) any)) {
+	label := ipath
+	if label == "" {
+		label = "/"
+	}
+	set := func(what string, nv any) {
+		emit(label+": "+what, func(root any) any {
+			return setAtPointer(root, ipath, func(any) any { return copyValue(nv) })
+		})
+	}
+	change := func(what string, fn func(old any) any) {
+		emit(label+": "+what, func(root any) any {
+			return setAtPointer(root, ipath, fn)
+		})
+	}
+
+	for _, alt := range []struct {
+		typ string
+		val any
+	}{
+		{"null", nil},
+		{"boolean", true},
+		{"integer", int64(0)},
+		{"number", 1.5},
+		{"string", "text"},
+		{"array", []any{}},
+		{"object", map[string]any{}},
+	} {
+		if jsonType(v) != alt.typ && !(alt.typ == "number" && jsonType(v) == "integer") {
+			set("replace with "+alt.typ, alt.val)
+		}
+	}
+	if s != nil {
+		for _, m := range s.Enum {
+			if !jsonEqual(m, v) {
+				set(fmt.Sprintf("replace with enum member %v", m), m)
+			}
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(v) {
+			change(fmt.Sprintf("remove property %q", k), func(old any) any {
+				m := old.(map[string]any)
+				delete(m, k)
+				return m
+			})
+		}
+		change(`add property "extra"`, func(old any) any {
+			m := old.(map[string]any)
+			m["extra"] = "text"
+			return m
+		})
+		for _, k := range sortedKeys(v) {
+			var sub *jsonschema.Schema
+			if s != nil {
+				sub = propertySchema(s, k)
+			}
+			mutateValue(v[k], sub, ipath+"/"+escapePointer(k), emit)
+		}
+
+	case []any:
+		if len(v) > 0 {
+			set("empty the array", []any{})
+			change("remove the last item", func(old any) any {
+				a := old.([]any)
+				return a[:len(a)-1]
+			})
+			change("duplicate the first item", func(old any) any {
+				a := old.([]any)
+				return append(a, copyValue(a[0]))
+			})
+		}
+		for i, e := range v {
+			var sub *jsonschema.Schema
+			if s != nil {
+				sub = itemSchema(s, i)
+			}
+			mutateValue(e, sub, fmt.Sprintf("%s/%d", ipath, i), emit)
+		}
+
+	case string:
+		set("replace with the empty string", "")
+		set("append a space", v+" ")
+		set("convert to upper case", strings.ToUpper(v))
+		set("repeat 100 times", strings.Repeat(v, 100))
+
+	default:
+		if f, ok := toFloat(v); ok {
+			set("add 1", numberValue(f+1, false))
+			set("subtract 1", numberValue(f-1, false))
+			set("negate", numberValue(-f, false))
+			set("add 0.5", f+0.5)
+			set("multiply by 1000000", numberValue(f*1e6, false))
+		}
+	}
+}
+
+// setAtPointer replaces the value at the JSON Pointer ptr in root
+// with fn applied to it, and returns the new root.
+func setAtPointer(root any, ptr string, fn func(old any) any) any {
+	if ptr == "" {
+		return fn(root)
+	}
+	i := strings.LastIndexByte(ptr, '/')
+	parent, last := ptr[:i], ptr[i+1:]
+	return setAtPointer(root, parent, func(p any) any {
+		switch p := p.(type) {
+		case map[string]any:
+			k := unescapePointer(last)
+			p[k] = fn(p[k])
+		case []any:
+			if i, err := strconv.Atoi(last); err == nil && i < len(p) {
+				p[i] = fn(p[i])
+			}
+		}
+		return p
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+)
+
+func TestMutations(t *testing.T) {
+	s := mustSchema(t, `
+name: string
+nick?: string
+status(enum): [active, banned]
+tags(array): string
+`)
+	inst := map[string]any{
+		"name":   "Ada",
+		"nick":   "ada",
+		"status": "active",
+		"tags":   []any{"x", "y"},
+	}
+	cases := Mutations(inst, s)
+	byName := make(map[string]MutationCase)
+	for _, c := range cases {
+		byName[c.Name] = c
+	}
+	for name, valid := range map[string]bool{
+		`/: remove property "name"`:                false,
+		`/: remove property "nick"`:                true,
+		`/: add property "extra"`:                  false,
+		"/name: replace with null":                 false,
+		"/name: convert to upper case":             true,
+		"/status: replace with enum member banned": true,
+		"/tags: empty the array":                   true,
+		"/tags/1: replace with integer":            false,
+	} {
+		c, ok := byName[name]
+		if !ok {
+			t.Errorf("missing case %q", name)
+			continue
+		}
+		if c.Valid != valid {
+			t.Errorf("%s: got valid=%t, want %t (violations %v)", name, c.Valid, valid, c.Violations)
+		}
+	}
+	if inst["name"] != "Ada" || len(inst["tags"].([]any)) != 2 {
+		t.Errorf("Mutations modified the instance: %v", inst)
+	}
+
+	// A validator that ignores enums disagrees on enum cases only.
+	lax := func(v any) bool {
+		for _, vl := range Check(v, s) {
+			if vl.Keyword != "enum" {
+				return false
+			}
+		}
+		return true
+	}
+	for _, c := range Disagreements(cases, lax) {
+		if c.Violations[0].Keyword != "enum" {
+			t.Errorf("unexpected disagreement on %s: %v", c.Name, c.Violations)
+		}
+	}
+	if len(Disagreements(cases, func(v any) bool { return len(Check(v, s)) == 0 })) != 0 {
+		t.Error("Check disagrees with itself")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/invopop/jsonschema"
+)
+
+// schemaNameRE matches the schema names model providers accept.
+var schemaNameRE = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// A NamedSchema is a schema with a name, for providers that require
+// the root of a structured output schema to be named.
+type NamedSchema struct {
+	Name string
+	// Schema has title Name and $id Name.
+	Schema *jsonschema.Schema
+}
+
+// checkSchemaName reports an error if name is not a schema name
+// model providers accept.
+func checkSchemaName(name string) error {
+	if !schemaNameRE.MatchString(name) {
+		return fmt.Errorf("picoschema: invalid schema name %q: want 1 to 64 letters, digits, '_' or '-'", name)
+	}
+	return nil
+}
+
+// Named returns a copy of s named name, which must consist of at most
+// 64 letters, digits, underscores and hyphens. s must not be nil.
+func Named(name string, s *jsonschema.Schema) (*NamedSchema, error) {
+	if err := checkSchemaName(name); err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("picoschema: nil schema named %q", name)
+	}
+	c := cloneSchema(s)
+	c.Title = name
+	c.ID = jsonschema.ID(name)
+	return &NamedSchema{Name: name, Schema: c}, nil
+}
+
+// A SchemaEnvelope is the {name, schema, strict} object with which
+// providers such as OpenAI take a named schema, for example as the
+// json_schema of a response_format.
+type SchemaEnvelope struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Schema      *jsonschema.Schema `json:"schema"`
+	Strict      bool               `json:"strict"`
+}
+
+// Envelope returns the envelope for n. The schema's description
+// becomes the envelope's, and its $id, which providers reject, is
+// omitted.
+func (n *NamedSchema) Envelope(strict bool) *SchemaEnvelope {
+	c := cloneSchema(n.Schema)
+	c.ID = ""
+	return &SchemaEnvelope{
+		Name:        n.Name,
+		Description: c.Description,
+		Schema:      c,
+		Strict:      strict,
+	}
+}
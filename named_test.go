@@ -0,0 +1,71 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNamed(t *testing.T) {
+	s := mustSchema(t, "city: string")
+	s.Description = "a place"
+	n, err := Named("place", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Schema.Title != "place" || n.Schema.ID != "place" {
+		t.Errorf("got title %q, $id %q, want place", n.Schema.Title, n.Schema.ID)
+	}
+	if s.Title != "" {
+		t.Error("Named modified its argument")
+	}
+
+	data, err := json.Marshal(n.Envelope(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"name":        "place",
+		"description": "a place",
+		"strict":      true,
+		"schema": map[string]any{
+			"title":                "place",
+			"description":          "a place",
+			"type":                 "object",
+			"properties":           map[string]any{"city": map[string]any{"type": "string"}},
+			"required":             []any{"city"},
+			"additionalProperties": false,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	for _, bad := range []string{"", "has space", "x.y"} {
+		if _, err := Named(bad, s); err == nil {
+			t.Errorf("Named(%q) succeeded", bad)
+		}
+	}
+	if _, err := Named("place", nil); err == nil {
+		t.Error("Named with a nil schema succeeded")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A SchemaResolver finds schemas by name.
+type SchemaResolver interface {
+	Resolve(name string) (*jsonschema.Schema, error)
+}
+
+// SchemaResolverFunc adapts an ordinary function to the SchemaResolver interface.
+type SchemaResolverFunc func(name string) (*jsonschema.Schema, error)
+
+// Resolve calls f(name).
+func (f SchemaResolverFunc) Resolve(name string) (*jsonschema.Schema, error) {
+	return f(name)
+}
+
+// ErrSchemaNotFound is returned, wrapped, by resolvers
+// when no schema has the requested name.
+var ErrSchemaNotFound = errors.New("picoschema: schema not found")
+
+// SplitName splits a namespace-qualified schema name such as
+// "billing.Invoice" or "billing.Invoice@2" into its namespace,
+// "billing", and its local name, "Invoice" or "Invoice@2".
+// Namespaces may themselves contain dots, as in "acme.billing.Invoice".
+// The namespace of an unqualified name is "".
+func SplitName(name string) (namespace, local string) {
+	base, _, _ := strings.Cut(name, "@")
+	i := strings.LastIndexByte(base, '.')
+	if i < 0 {
+		return "", name
+	}
+	return name[:i], name[i+1:]
+}
+
+// QualifyName returns name qualified by namespace.
+func QualifyName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// SetResolver makes r delegate names in namespace that it does not hold
+// to res, which is passed the name with the namespace removed.
+// When namespaces nest, as "acme" and "acme.billing" do, the longest
+// namespace containing a name wins. A nil res removes the resolver.
+func (r *Registry) SetResolver(namespace string, res SchemaResolver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if res == nil {
+		delete(r.resolvers, namespace)
+		return
+	}
+	if r.resolvers == nil {
+		r.resolvers = make(map[string]SchemaResolver)
+	}
+	r.resolvers[namespace] = res
+}
+
+// Resolve returns the schema registered under name, or else the schema
+// returned by the resolver for the name's namespace. It implements
+// SchemaResolver. The error wraps ErrSchemaNotFound if there is neither.
+func (r *Registry) Resolve(name string) (*jsonschema.Schema, error) {
+	if s, ok, err := r.lookup(name); err != nil || ok {
+		return s, err
+	}
+	r.mu.RLock()
+	var res SchemaResolver
+	namespace := ""
+	for ns, nr := range r.resolvers {
+		if (ns == "" || strings.HasPrefix(name, ns+".")) && (res == nil || len(ns) > len(namespace)) {
+			res, namespace = nr, ns
+		}
+	}
+	r.mu.RUnlock()
+	if res == nil {
+		return nil, fmt.Errorf("%w: %q", ErrSchemaNotFound, name)
+	}
+	local := name
+	if namespace != "" {
+		local = name[len(namespace)+1:]
+	}
+	return res.Resolve(local)
+}
+
+// lookupRef returns the registered name that the ref target refers
+// to from the schema named from. An unqualified target is looked up in
+// the namespace of from first, so that each team's schemas can refer
+// to each other by their local names.
+func (r *Registry) lookupRef(from, target string) (string, bool) {
+	if ns, _ := SplitName(from); ns != "" {
+		if q := QualifyName(ns, target); q != target {
+			if _, ok := r.Lookup(q); ok {
+				return q, true
+			}
+		}
+	}
+	_, ok := r.Lookup(target)
+	return target, ok
+}
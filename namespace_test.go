@@ -0,0 +1,110 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+)
+
+func TestSplitName(t *testing.T) {
+	for _, test := range []struct{ name, ns, local string }{
+		{"Invoice", "", "Invoice"},
+		{"billing.Invoice", "billing", "Invoice"},
+		{"billing.Invoice@2.1", "billing", "Invoice@2.1"},
+		{"acme.billing.Invoice", "acme.billing", "Invoice"},
+		{"Invoice@1.0", "", "Invoice@1.0"},
+	} {
+		ns, local := SplitName(test.name)
+		if ns != test.ns || local != test.local {
+			t.Errorf("SplitName(%q) = %q, %q, want %q, %q", test.name, ns, local, test.ns, test.local)
+		}
+		if got := QualifyName(ns, local); got != test.name {
+			t.Errorf("QualifyName(%q, %q) = %q", ns, local, got)
+		}
+	}
+}
+
+func TestRegistryResolve(t *testing.T) {
+	var r Registry
+	local := &jsonschema.Schema{Type: "string"}
+	if err := r.Register("billing.Invoice", local); err != nil {
+		t.Fatal(err)
+	}
+	remote := func(team string) SchemaResolver {
+		return SchemaResolverFunc(func(name string) (*jsonschema.Schema, error) {
+			if name == "Missing" {
+				return nil, fmt.Errorf("%w: %q", ErrSchemaNotFound, name)
+			}
+			return &jsonschema.Schema{Title: team + ":" + name}, nil
+		})
+	}
+	r.SetResolver("billing", remote("billing"))
+	r.SetResolver("acme", remote("acme"))
+	r.SetResolver("acme.crm", remote("crm"))
+
+	for name, want := range map[string]string{
+		"billing.Invoice":   "",
+		"billing.Receipt":   "billing:Receipt",
+		"acme.Thing":        "acme:Thing",
+		"acme.crm.Lead":     "crm:Lead",
+		"acme.crm.sub.Lead": "crm:sub.Lead",
+		"billing.Receipt@3": "billing:Receipt@3",
+	} {
+		s, err := r.Resolve(name)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", name, err)
+			continue
+		}
+		if s.Title != want {
+			t.Errorf("Resolve(%q) got title %q, want %q", name, s.Title, want)
+		}
+	}
+	for _, name := range []string{"shipping.Label", "billing.Missing", "Invoice"} {
+		if _, err := r.Resolve(name); !errors.Is(err, ErrSchemaNotFound) {
+			t.Errorf("Resolve(%q) error = %v, want ErrSchemaNotFound", name, err)
+		}
+	}
+}
+
+func TestDependencyGraphNamespaces(t *testing.T) {
+	var r Registry
+	for name, ref := range map[string]string{
+		"billing.Invoice":  "Customer",
+		"billing.Customer": "",
+		"crm.Lead":         "Customer",
+		"Customer":         "",
+		"crm.Deal":         "billing.Invoice",
+	} {
+		if err := r.Register(name, &jsonschema.Schema{Ref: ref}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g := r.DependencyGraph()
+	want := map[string][]string{
+		"Customer":         nil,
+		"billing.Customer": nil,
+		"billing.Invoice":  {"billing.Customer"},
+		"crm.Deal":         {"billing.Invoice"},
+		"crm.Lead":         {"Customer"},
+	}
+	if diff := cmp.Diff(want, g.Edges); diff != "" {
+		t.Errorf("edges mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/invopop/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseYAML decodes data as a YAML document of picoschema or JSON
+// Schema and converts it as by ToJSONSchema. Keys that YAML would
+// read as numbers or booleans, such as 404 or true, are read as
+// strings. Errors are reported as by ToJSONSchemaNode.
+func ParseYAML(data []byte, opts ...Option) (*jsonschema.Schema, error) {
+	start := time.Now()
+	s, err := parseYAML(data, opts)
+	recordConversion("picoschema", start)
+	recordError("convert", err)
+	return s, err
+}
+
+// ParseYAMLString is like ParseYAML, but takes a string.
+func ParseYAMLString(src string, opts ...Option) (*jsonschema.Schema, error) {
+	return ParseYAML([]byte(src), opts...)
+}
+
+func parseYAML(data []byte, opts []Option) (*jsonschema.Schema, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("picoschema: %w", err)
+	}
+	stringKeys(&node)
+	return toJSONSchemaNode(&node, opts)
+}
+
+// stringKeys tags the scalar mapping keys in the tree rooted at n
+// as strings, so that they decode as strings whatever they look like.
+func stringKeys(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i < len(n.Content); i += 2 {
+			if k := n.Content[i]; k.Kind == yaml.ScalarNode && k.Tag != "!!merge" {
+				k.Tag = "!!str"
+			}
+		}
+	}
+	for _, c := range n.Content {
+		stringKeys(c)
+	}
+}
+
+// ToJSONSchemaNode is like ToJSONSchema, but converts picoschema held
+// in a YAML syntax tree, such as a node of a .prompt file's front
+// matter. Errors in picoschema keys and values are reported as a
+// *PositionError giving the position of the offending key or value.
+func ToJSONSchemaNode(node *yaml.Node, opts ...Option) (*jsonschema.Schema, error) {
+	start := time.Now()
+	s, err := toJSONSchemaNode(node, opts)
+	recordConversion("picoschema", start)
+	recordError("convert", err)
+	return s, err
+}
+
+func toJSONSchemaNode(node *yaml.Node, opts []Option) (*jsonschema.Schema, error) {
+	if node == nil || node.Kind == 0 {
+		return nil, nil
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	var val any
+	if err := node.Decode(&val); err != nil {
+		return nil, fmt.Errorf("picoschema: %w", err)
+	}
+	s, err := newParser(opts).toJSONSchema(val)
+	if err == nil {
+		return s, nil
+	}
+	var ie *inputError
+	if !errors.As(err, &ie) {
+		return nil, err
+	}
+	if n := ie.locate(node); n != nil {
+		return nil, &PositionError{Line: n.Line, Column: n.Column, Err: err}
+	}
+	return nil, err
+}
+
+// A PositionError is an error at a position in YAML source.
+type PositionError struct {
+	Line, Column int // 1-based
+	Err          error
+}
+
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("picoschema: line %d, column %d: %s", e.Line, e.Column, strings.TrimPrefix(e.Err.Error(), "picoschema: "))
+}
+
+func (e *PositionError) Unwrap() error { return e.Err }
+
+// An inputError is an error in the picoschema input, located by the
+// keys of the objects that contain it. Its message is that of err.
+type inputError struct {
+	keys  []string // outermost first
+	value bool     // the error is in the value of the last key, not the key
+	err   error
+}
+
+func (e *inputError) Error() string { return e.err.Error() }
+
+func (e *inputError) Unwrap() error { return e.err }
+
+// inValue marks err, if it is not nil, as an error in a value.
+func inValue(err error) error {
+	if err == nil {
+		return nil
+	}
+	var ie *inputError
+	if errors.As(err, &ie) {
+		return err
+	}
+	return &inputError{value: true, err: err}
+}
+
+// atKey locates err, an error in the key k or within its value.
+func atKey(k string, err error) error {
+	var ie *inputError
+	if errors.As(err, &ie) {
+		ie.keys = append([]string{k}, ie.keys...)
+		return err
+	}
+	return &inputError{keys: []string{k}, err: err}
+}
+
+// locate returns the node of the YAML tree root at which e occurred,
+// or nil if it cannot be found.
+func (e *inputError) locate(root *yaml.Node) *yaml.Node {
+	n := root
+	var key *yaml.Node
+	for _, k := range e.keys {
+		for n.Kind == yaml.AliasNode {
+			n = n.Alias
+		}
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		key = nil
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == k {
+				key, n = n.Content[i], n.Content[i+1]
+				break
+			}
+		}
+		if key == nil {
+			return nil
+		}
+	}
+	if e.value || key == nil {
+		return n
+	}
+	return key
+}
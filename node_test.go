@@ -0,0 +1,104 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+func TestToJSONSchemaNode(t *testing.T) {
+	parse := func(t *testing.T, src string) *yaml.Node {
+		t.Helper()
+		var n yaml.Node
+		if err := yaml.Unmarshal([]byte(src), &n); err != nil {
+			t.Fatal(err)
+		}
+		return &n
+	}
+
+	src := "name: string\ntags(array): string\n"
+	got, err := ToJSONSchemaNode(parse(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := mustSchema(t, src)
+	sortSchemaSlices(got)
+	sortSchemaSlices(want)
+	if diff := cmp.Diff(schemaJSON(t, want), schemaJSON(t, got)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	for _, test := range []struct {
+		name, src    string
+		line, column int
+	}{
+		{"unknown scalar", "name: string\nage: integr\n", 2, 6},
+		{"nested value", "name: string\naddress:\n  street: string\n  zip: 5\n", 4, 8},
+		{"bad parenthetical", "name: string\ntags(list): string\n", 2, 1},
+		{"nested key", "a:\n  b:\n    c(widget): string\n", 3, 5},
+		{"array items", "lines(array):\n  sku: strin\n", 2, 8},
+		{"json schema tag", "meta:\n  $jsonschema: 5\n", 2, 16},
+		{"empty property name", "lines(array):\n  (array): string\n", 2, 3},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ToJSONSchemaNode(parse(t, test.src))
+			var pe *PositionError
+			if !errors.As(err, &pe) {
+				t.Fatalf("got error %v, want a PositionError", err)
+			}
+			if pe.Line != test.line || pe.Column != test.column {
+				t.Errorf("got line %d, column %d, want line %d, column %d", pe.Line, pe.Column, test.line, test.column)
+			}
+			if !strings.HasPrefix(err.Error(), "picoschema: line ") || strings.Count(err.Error(), "picoschema:") != 1 {
+				t.Errorf("bad error message %q", err)
+			}
+		})
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	got, err := ParseYAMLString("name: string\n404?: string, not found\ntrue: boolean\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortSchemaSlices(got)
+	want := map[string]any{
+		"type": "object", "additionalProperties": false, "required": []any{"name", "true"},
+		"properties": map[string]any{
+			"name": map[string]any{"type": "string"},
+			"404":  map[string]any{"type": "string", "description": "not found"},
+			"true": map[string]any{"type": "boolean"},
+		},
+	}
+	if diff := cmp.Diff(want, schemaJSON(t, got)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if s, err := ParseYAML(nil); s != nil || err != nil {
+		t.Errorf("ParseYAML(nil) = %v, %v, want nil, nil", s, err)
+	}
+	if _, err := ParseYAMLString("a: [b"); err == nil {
+		t.Error("got nil error for malformed YAML")
+	}
+	var pe *PositionError
+	if _, err := ParseYAMLString("a: string\nb: strng\n"); !errors.As(err, &pe) || pe.Line != 2 {
+		t.Errorf("got error %v, want a PositionError on line 2", err)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ToOpenAIResponseFormat converts the picoschema val and returns the
+// response_format object that requests output conforming to it from
+// the OpenAI Chat Completions API in json_schema mode, ready to be
+// marshaled into a request:
+//
+//	{"type": "json_schema", "json_schema": {"name": ..., "schema": ..., "strict": ...}}
+//
+// The schema's annotations (x- keywords) are removed. If strict is set,
+// the schema is also transformed as strict mode requires: keywords the
+// "openai" profile does not support are removed, and every property is
+// made required, with optional properties made nullable instead.
+// Strict mode also requires every object to be closed, so a schema with
+// wildcard properties is an error, as is a root that is not an object.
+//
+// Function calling takes schemas differently, without the envelope and
+// with a root that may be nullable, so this is not suitable for tools.
+func ToOpenAIResponseFormat(name string, val any, strict bool) (map[string]any, error) {
+	rf, _, err := ToOpenAIResponseFormatReport(name, val, strict)
+	return rf, err
+}
+
+// ToOpenAIResponseFormatReport is like ToOpenAIResponseFormat,
+// but also reports the changes made to the converted schema.
+func ToOpenAIResponseFormatReport(name string, val any, strict bool) (map[string]any, []SchemaChange, error) {
+	if err := checkSchemaName(name); err != nil {
+		return nil, nil, err
+	}
+	s, err := ToJSONSchema(val)
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := schemaToValue(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	var changes []SchemaChange
+	forEachValueSubschema(v, "", func(sub any, path, _ string) error {
+		if m, ok := sub.(map[string]any); ok {
+			for _, k := range sortedKeys(m) {
+				if strings.HasPrefix(k, "x-") {
+					delete(m, k)
+					changes = append(changes, SchemaChange{Path: path + "/" + escapePointer(k), Action: "removed", Reason: "annotation"})
+				}
+			}
+		}
+		return nil
+	})
+	if strict {
+		sc, err := openAIStrict(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		changes = append(changes, sc...)
+	}
+	sortChanges(changes)
+	js := map[string]any{
+		"name":   name,
+		"schema": v,
+		"strict": strict,
+	}
+	if s.Description != "" {
+		js["description"] = s.Description
+	}
+	return map[string]any{
+		"type":        "json_schema",
+		"json_schema": js,
+	}, changes, nil
+}
+
+// openAIStrict transforms v, a decoded JSON schema, as OpenAI's strict
+// mode requires, and returns the changes.
+func openAIStrict(v any) ([]SchemaChange, error) {
+	root, ok := v.(map[string]any)
+	if !ok || root["type"] != "object" {
+		return nil, fmt.Errorf("picoschema: OpenAI strict mode requires an object at the root")
+	}
+	p, _ := LookupProfile("openai")
+	changes := stripKeywordsValue(v, p)
+	err := forEachValueSubschema(v, "", func(sub any, path, _ string) error {
+		m, ok := sub.(map[string]any)
+		if !ok {
+			return nil
+		}
+		props, isObject := m["properties"].(map[string]any)
+		if !isObject && m["type"] != "object" {
+			return nil
+		}
+		if ap, ok := m["additionalProperties"]; ok && ap != false {
+			return fmt.Errorf("picoschema: OpenAI strict mode does not allow additional properties, at %q", path)
+		}
+		if _, ok := m["additionalProperties"]; !ok {
+			changes = append(changes, SchemaChange{
+				Path:   path + "/additionalProperties",
+				Action: "added",
+				Lossy:  true,
+				Reason: "strict mode requires closed objects",
+			})
+		}
+		m["additionalProperties"] = false
+		var required []string
+		if r, ok := m["required"].([]any); ok {
+			for _, e := range r {
+				if s, ok := e.(string); ok {
+					required = append(required, s)
+				}
+			}
+		}
+		all := make([]any, 0, len(props))
+		for _, name := range sortedKeys(props) {
+			all = append(all, name)
+			if !slices.Contains(required, name) {
+				props[name] = nullable(props[name])
+				changes = append(changes, SchemaChange{
+					Path:   path + "/properties/" + escapePointer(name),
+					Action: "changed",
+					Lossy:  true,
+					Reason: "strict mode requires every property: made required and nullable",
+				})
+			}
+		}
+		m["required"] = all
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	limited, err := limitDescriptions(v, p)
+	return append(changes, limited...), err
+}
+
+// nullable returns v, a decoded JSON schema, changed to also accept null.
+// A schema without a type already does.
+func nullable(v any) any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	switch {
+	case m["enum"] != nil:
+		if enum, _ := m["enum"].([]any); !slices.Contains(enum, nil) {
+			m["enum"] = append(enum, nil)
+		}
+	case m["type"] != nil:
+		if t, ok := m["type"].(string); ok && t != "null" {
+			m["type"] = []any{t, "null"}
+		}
+	case m["anyOf"] != nil:
+		alts, _ := m["anyOf"].([]any)
+		for _, alt := range alts {
+			if a, ok := alt.(map[string]any); ok && len(a) == 1 && a["type"] == "null" {
+				return m
+			}
+		}
+		m["anyOf"] = append(alts, map[string]any{"type": "null"})
+	case m["$ref"] != nil:
+		return map[string]any{"anyOf": []any{m, map[string]any{"type": "null"}}}
+	}
+	return m
+}
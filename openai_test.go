@@ -0,0 +1,109 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+func TestToOpenAIResponseFormat(t *testing.T) {
+	const src = `
+name: string, the name
+nickname?(audience=internal): string
+middle?: string?
+size?(enum): [S, M, L]
+address?:
+  city: string
+  zip?: string
+`
+	var val any
+	if err := yaml.Unmarshal([]byte(src), &val); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ToOpenAIResponseFormat("person", val, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name":   "person",
+			"strict": true,
+			"schema": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"required":             []any{"address", "middle", "name", "nickname", "size"},
+				"properties": map[string]any{
+					"name":     map[string]any{"type": "string", "description": "the name"},
+					"nickname": map[string]any{"type": []any{"string", "null"}},
+					"size":     map[string]any{"enum": []any{"S", "M", "L", nil}},
+					"middle":   map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "null"}}},
+					"address": map[string]any{
+						"type":                 []any{"object", "null"},
+						"additionalProperties": false,
+						"required":             []any{"city", "zip"},
+						"properties": map[string]any{
+							"city": map[string]any{"type": "string"},
+							"zip":  map[string]any{"type": []any{"string", "null"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	_, changes, err := ToOpenAIResponseFormatReport("person", val, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChanges := []SchemaChange{
+		{Path: "/properties/address", Action: "changed", Lossy: true, Reason: "strict mode requires every property: made required and nullable"},
+		{Path: "/properties/address/properties/zip", Action: "changed", Lossy: true, Reason: "strict mode requires every property: made required and nullable"},
+		{Path: "/properties/middle", Action: "changed", Lossy: true, Reason: "strict mode requires every property: made required and nullable"},
+		{Path: "/properties/nickname", Action: "changed", Lossy: true, Reason: "strict mode requires every property: made required and nullable"},
+		{Path: "/properties/nickname/x-audiences", Action: "removed", Reason: "annotation"},
+		{Path: "/properties/size", Action: "changed", Lossy: true, Reason: "strict mode requires every property: made required and nullable"},
+	}
+	if diff := cmp.Diff(wantChanges, changes); diff != "" {
+		t.Errorf("changes mismatch (-want, +got):\n%s", diff)
+	}
+
+	got, err = ToOpenAIResponseFormat("person", val, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := got["json_schema"].(map[string]any)["schema"].(map[string]any)
+	if diff := cmp.Diff([]any{"name"}, schema["required"]); diff != "" {
+		t.Errorf("non-strict required mismatch (-want, +got):\n%s", diff)
+	}
+
+	for _, bad := range []any{
+		map[string]any{"(*)": "string"},
+		"string",
+	} {
+		if _, err := ToOpenAIResponseFormat("x", bad, true); err == nil {
+			t.Errorf("strict conversion of %v succeeded", bad)
+		}
+	}
+	if _, err := ToOpenAIResponseFormat("bad name", val, false); err == nil {
+		t.Error("invalid name accepted")
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// openAPIComponentsRef is the prefix of refs to the schemas of an
+// OpenAPI document's components.
+const openAPIComponentsRef = "#/components/schemas/"
+
+// openAPI30Keywords are the keywords of an OpenAPI 3.0 schema object,
+// apart from extensions, whose names start with "x-".
+var openAPI30Keywords = []string{
+	"$ref", "title", "description", "default", "example", "deprecated",
+	"readOnly", "writeOnly", "nullable", "discriminator", "xml", "externalDocs",
+	"type", "format", "enum", "allOf", "anyOf", "oneOf", "not",
+	"multipleOf", "maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum",
+	"maxLength", "minLength", "pattern",
+	"items", "maxItems", "minItems", "uniqueItems",
+	"properties", "additionalProperties", "required", "maxProperties", "minProperties",
+}
+
+// ToOpenAPISchema converts the picoschema val and returns it as a
+// schema object of an OpenAPI document of version, "3.0" or "3.1"
+// (a patch version such as "3.0.3" may be given), ready to be
+// marshaled into the document.
+//
+// Schema objects refer to one another through the components of the
+// document, so the schemas of $defs are returned separately, keyed by
+// name, to be put under components/schemas, and refs to them are
+// rewritten to point there, as in "#/components/schemas/money".
+//
+// An OpenAPI 3.1 schema object is JSON Schema 2020-12, so the schema is
+// otherwise unchanged. For 3.0 it is rewritten in the OpenAPI subset of
+// JSON Schema: a nullable type, such as picoschema "string?", is
+// written with nullable: true, as is a nullable ref, wrapped in allOf
+// and given the type of its target, const becomes a one-member enum,
+// the numeric exclusiveMinimum and exclusiveMaximum become the boolean
+// forms qualifying minimum and maximum, examples becomes a single
+// example, a tuple becomes an array of the union of its members, the
+// values of patternProperties become additional properties, and
+// keywords that 3.0 lacks, such as if and then, are removed.
+// Extensions, whose names start with "x-", are kept.
+func ToOpenAPISchema(val any, version string) (schema, components map[string]any, err error) {
+	v30 := version == "3.0" || strings.HasPrefix(version, "3.0.")
+	if !v30 && version != "3.1" && !strings.HasPrefix(version, "3.1.") {
+		return nil, nil, fmt.Errorf("picoschema: unsupported OpenAPI version %q: want 3.0 or 3.1", version)
+	}
+	s, err := ToJSONSchema(val)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Sort required, so that generated documents change only when
+	// their schemas do.
+	v, err := schemaToValue(s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("picoschema: %w", err)
+	}
+	sortRequired(v)
+	schema = openAPIObject(v)
+	delete(schema, "$schema")
+	if defs, ok := schema["$defs"].(map[string]any); ok {
+		components = make(map[string]any, len(defs))
+		for name, def := range defs {
+			components[name] = openAPIObject(def)
+		}
+		delete(schema, "$defs")
+	}
+	all := append([]map[string]any{schema}, mapValues(components)...)
+	for _, m := range all {
+		forEachValueSubschema(m, "", func(sub any, _, _ string) error {
+			if m, ok := sub.(map[string]any); ok {
+				if ref, ok := m["$ref"].(string); ok {
+					if rest, ok := strings.CutPrefix(ref, "#/$defs/"); ok {
+						m["$ref"] = openAPIComponentsRef + rest
+					}
+				}
+			}
+			return nil
+		})
+	}
+	if v30 {
+		// The types of the components are taken before they are
+		// rewritten, which may hide them.
+		types := make(map[string]string, len(components))
+		for name, c := range components {
+			if t, ok := c.(map[string]any)["type"].(string); ok && t != "null" {
+				types[openAPIComponentsRef+escapePointer(name)] = t
+			}
+		}
+		for _, m := range all {
+			openAPI30(m, types)
+		}
+	}
+	return schema, components, nil
+}
+
+// mapValues returns the values of m that are maps, in key order.
+func mapValues(m map[string]any) []map[string]any {
+	var out []map[string]any
+	for _, k := range sortedKeys(m) {
+		if v, ok := m[k].(map[string]any); ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// openAPIObject returns v, a decoded JSON schema, as an object, which
+// is what OpenAPI schema objects must be. The boolean schema true
+// becomes {}, and false becomes {"not": {}}.
+func openAPIObject(v any) map[string]any {
+	switch v := v.(type) {
+	case map[string]any:
+		return v
+	case bool:
+		if !v {
+			return map[string]any{"not": map[string]any{}}
+		}
+	}
+	return map[string]any{}
+}
+
+// openAPI30 rewrites m, a decoded JSON schema, and its subschemas in
+// the OpenAPI 3.0 subset of JSON Schema, as ToOpenAPISchema describes,
+// and returns it. The types map refs to components to their types.
+func openAPI30(m map[string]any, types map[string]string) map[string]any {
+	// Take null out of unions before rewriting their alternatives,
+	// which would hide it.
+	for _, k := range []string{"anyOf", "oneOf"} {
+		alts, ok := m[k].([]any)
+		if !ok {
+			continue
+		}
+		rest := slices.DeleteFunc(slices.Clone(alts), isNullSchema)
+		if len(rest) == len(alts) || len(rest) == 0 {
+			continue
+		}
+		m["nullable"] = true
+		if len(rest) > 1 {
+			m[k] = rest
+			continue
+		}
+		delete(m, k)
+		alt, _ := rest[0].(map[string]any)
+		if ref, isRef := alt["$ref"].(string); isRef || overlaps(m, alt) {
+			// Keywords beside $ref are ignored in 3.0.
+			m["allOf"] = append([]any{alt}, listValue(m["allOf"])...)
+			// nullable has no effect without type.
+			typ, _ := alt["type"].(string)
+			if isRef {
+				typ = types[ref]
+			}
+			if _, ok := m["type"]; !ok && typ != "" && typ != "null" {
+				m["type"] = typ
+			}
+		} else {
+			maps.Copy(m, alt)
+		}
+	}
+
+	for _, k := range schemaKeywordsSingle {
+		if sub, ok := m[k]; ok {
+			if _, isBool := sub.(bool); isBool && k == "additionalProperties" {
+				// The one keyword that 3.0 allows to be boolean.
+				continue
+			}
+			m[k] = openAPI30(openAPIObject(sub), types)
+		}
+	}
+	for _, k := range schemaKeywordsList {
+		for i, sub := range listValue(m[k]) {
+			m[k].([]any)[i] = openAPI30(openAPIObject(sub), types)
+		}
+	}
+	for _, k := range schemaKeywordsMap {
+		subs, _ := m[k].(map[string]any)
+		for name, sub := range subs {
+			subs[name] = openAPI30(openAPIObject(sub), types)
+		}
+	}
+
+	switch t := m["type"].(type) {
+	case []any:
+		var types []any
+		for _, typ := range t {
+			if typ == "null" {
+				m["nullable"] = true
+			} else {
+				types = append(types, typ)
+			}
+		}
+		switch len(types) {
+		case 0:
+			delete(m, "type")
+		case 1:
+			m["type"] = types[0]
+		default:
+			delete(m, "type")
+			var alts []any
+			for _, typ := range types {
+				alts = append(alts, map[string]any{"type": typ})
+			}
+			m["anyOf"] = append(listValue(m["anyOf"]), alts...)
+		}
+	case string:
+		if t == "null" {
+			delete(m, "type")
+			m["enum"] = []any{nil}
+			m["nullable"] = true
+		}
+	}
+	if c, ok := m["const"]; ok {
+		m["enum"] = []any{c}
+		delete(m, "const")
+	}
+	if slices.Contains(listValue(m["enum"]), nil) {
+		m["nullable"] = true
+	}
+	exclusiveBound(m, "exclusiveMinimum", "minimum", 1)
+	exclusiveBound(m, "exclusiveMaximum", "maximum", -1)
+	if ex := listValue(m["examples"]); len(ex) > 0 {
+		if _, ok := m["example"]; !ok {
+			m["example"] = ex[0]
+		}
+	}
+	if prefix := listValue(m["prefixItems"]); len(prefix) > 0 {
+		alts := slices.Clone(prefix)
+		items, ok := m["items"].(map[string]any)
+		switch {
+		case !ok:
+			// Items after the prefix may be anything.
+			alts = nil
+		case isFalseValue(items):
+			if _, ok := m["maxItems"]; !ok {
+				m["maxItems"] = len(prefix)
+			}
+		default:
+			alts = append(alts, items)
+		}
+		switch len(alts) {
+		case 0:
+			m["items"] = map[string]any{}
+		case 1:
+			m["items"] = alts[0]
+		default:
+			m["items"] = map[string]any{"anyOf": alts}
+		}
+	}
+	if pp, ok := m["patternProperties"].(map[string]any); ok && len(pp) > 0 {
+		// Without patterns, the properties they describe
+		// are additional properties.
+		alts := mapValues(pp)
+		if ap, ok := m["additionalProperties"].(map[string]any); ok {
+			alts = append(alts, ap)
+		} else if m["additionalProperties"] == true {
+			alts = nil
+		}
+		switch len(alts) {
+		case 0:
+			delete(m, "additionalProperties")
+		case 1:
+			m["additionalProperties"] = alts[0]
+		default:
+			m["additionalProperties"] = map[string]any{"anyOf": anySlice(alts)}
+		}
+	}
+	if m["type"] == "array" && m["items"] == nil {
+		// 3.0 requires items of an array.
+		m["items"] = map[string]any{}
+	}
+	for k := range m {
+		if !slices.Contains(openAPI30Keywords, k) && !strings.HasPrefix(k, "x-") {
+			delete(m, k)
+		}
+	}
+	return m
+}
+
+// exclusiveBound rewrites the numeric exclusive bound ex of m as the
+// boolean form qualifying the bound inc, as OpenAPI 3.0 writes it. The
+// sign is 1 for a lower bound and -1 for an upper one.
+func exclusiveBound(m map[string]any, ex, inc string, sign float64) {
+	n, ok := m[ex].(float64)
+	if !ok {
+		return
+	}
+	delete(m, ex)
+	if b, ok := m[inc].(float64); ok && sign*b > sign*n {
+		// The inclusive bound is the tighter.
+		return
+	}
+	m[inc] = n
+	m[ex] = true
+}
+
+// isNullSchema reports whether v, a decoded JSON schema, is {"type": "null"}.
+func isNullSchema(v any) bool {
+	m, ok := v.(map[string]any)
+	return ok && len(m) == 1 && m["type"] == "null"
+}
+
+// isFalseValue reports whether m is the object form of the
+// boolean schema false that openAPIObject makes.
+func isFalseValue(m map[string]any) bool {
+	not, ok := m["not"].(map[string]any)
+	return ok && len(m) == 1 && len(not) == 0
+}
+
+// overlaps reports whether a and b have a keyword in common.
+func overlaps(a, b map[string]any) bool {
+	for k := range b {
+		if _, ok := a[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func listValue(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
+
+func anySlice(ms []map[string]any) []any {
+	out := make([]any, len(ms))
+	for i, m := range ms {
+		out[i] = m
+	}
+	return out
+}
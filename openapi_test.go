@@ -0,0 +1,110 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+func TestToOpenAPISchema(t *testing.T) {
+	var val any
+	if err := yaml.Unmarshal([]byte(`
+$defs:
+  money:
+    amount(number, exclusiveMinimum=0): the amount
+    currency(const): EUR
+middle?: string?
+total?: money?
+point(tuple): [number, string]
+status(enum): [open, closed, null]
+(/^x-/): string
+`), &val); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		version            string
+		schema, components map[string]any
+	}{
+		{
+			version: "3.0.3",
+			schema: map[string]any{
+				"type": "object", "additionalProperties": map[string]any{"type": "string"}, "required": []any{"point", "status"},
+				"properties": map[string]any{
+					"middle": map[string]any{"type": "string", "nullable": true},
+					"total":  map[string]any{"allOf": []any{map[string]any{"$ref": "#/components/schemas/money"}}, "nullable": true, "type": "object"},
+					"point": map[string]any{
+						"type": "array", "minItems": float64(2), "maxItems": 2,
+						"items": map[string]any{"anyOf": []any{map[string]any{"type": "number"}, map[string]any{"type": "string"}}},
+					},
+					"status": map[string]any{"enum": []any{"open", "closed", nil}, "nullable": true},
+				},
+			},
+			components: map[string]any{
+				"money": map[string]any{
+					"type": "object", "additionalProperties": false, "required": []any{"amount", "currency"},
+					"properties": map[string]any{
+						"amount":   map[string]any{"type": "number", "description": "the amount", "minimum": float64(0), "exclusiveMinimum": true},
+						"currency": map[string]any{"enum": []any{"EUR"}},
+					},
+				},
+			},
+		},
+		{
+			version: "3.1",
+			schema: map[string]any{
+				"type": "object", "additionalProperties": false, "required": []any{"point", "status"},
+				"properties": map[string]any{
+					"middle": map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "null"}}},
+					"total":  map[string]any{"anyOf": []any{map[string]any{"$ref": "#/components/schemas/money"}, map[string]any{"type": "null"}}},
+					"point": map[string]any{
+						"type": "array", "minItems": float64(2), "items": false,
+						"prefixItems": []any{map[string]any{"type": "number"}, map[string]any{"type": "string"}},
+					},
+					"status": map[string]any{"enum": []any{"open", "closed", nil}},
+				},
+				"patternProperties": map[string]any{"^x-": map[string]any{"type": "string"}},
+			},
+			components: map[string]any{
+				"money": map[string]any{
+					"type": "object", "additionalProperties": false, "required": []any{"amount", "currency"},
+					"properties": map[string]any{
+						"amount":   map[string]any{"type": "number", "description": "the amount", "exclusiveMinimum": float64(0)},
+						"currency": map[string]any{"const": "EUR"},
+					},
+				},
+			},
+		},
+	} {
+		t.Run(test.version, func(t *testing.T) {
+			schema, components, err := ToOpenAPISchema(val, test.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.schema, schema); diff != "" {
+				t.Errorf("schema mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.components, components); diff != "" {
+				t.Errorf("components mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, _, err := ToOpenAPISchema("string", "2.0"); err == nil {
+		t.Error("ToOpenAPISchema for OpenAPI 2.0 succeeded")
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import "slices"
+
+// An Option tunes the conversion of picoschema by ToJSONSchema.
+type Option func(*options)
+
+// options holds the settings made by Options.
+// The zero value is the default behavior.
+type options struct {
+	additionalProperties bool
+	lenientScalars       bool
+	resolver             SchemaResolver
+	namedRefs            bool
+	sources              bool
+	schemaURI            string
+	draft                Draft
+	mixedEnumPolicy      MixedEnumPolicy
+	typeUnions           bool
+}
+
+// newParser returns a parser with opts applied.
+func newParser(opts []Option) *parser {
+	p := &parser{}
+	for _, opt := range opts {
+		opt(&p.options)
+	}
+	return p
+}
+
+// WithAdditionalProperties says whether objects accept properties
+// they do not declare. By default they do not, as if every object
+// had additionalProperties: false. An object with a wildcard "(*)"
+// property is governed by the wildcard either way, and one whose
+// property has the flag "sealed" or "open" is governed by the flag.
+func WithAdditionalProperties(allow bool) Option {
+	return func(o *options) { o.additionalProperties = allow }
+}
+
+// WithStrictScalars says whether scalar type names must be written
+// exactly. By default they must, and an unknown type name is an error.
+// If strict is false, surrounding space and the case of built-in type
+// names are ignored, and unknown type names mean "any".
+func WithStrictScalars(strict bool) Option {
+	return func(o *options) { o.lenientScalars = !strict }
+}
+
+// WithResolver makes r resolve scalar type names that are neither
+// built in nor registered with RegisterScalar, so that a property can
+// be written "owner: customer" given a schema named customer. The
+// resolved schema is copied into the result. A name r reports as
+// ErrSchemaNotFound is treated as unknown.
+func WithResolver(r SchemaResolver) Option {
+	return func(o *options) { o.resolver = r }
+}
+
+// WithNamedRefs says whether a name resolved with WithResolver
+// becomes a reference to the resolved schema rather than a copy of it.
+// By default it becomes a copy. If refs is true, each resolved schema
+// is put once under $defs of the result, keyed by its name, and every
+// use of the name is a $ref to that entry, as in
+//
+//	{"$ref": "#/$defs/UserProfile"}
+//
+// Local refs within a resolved schema, such as those written with
+// "$self", are made relative to its entry, and refs to other schemas by
+// name are resolved in turn. A resolved schema with local refs, or one
+// that is referred into, as in "customer#/properties/name", or back to,
+// is put under $defs even if refs is false.
+func WithNamedRefs(refs bool) Option {
+	return func(o *options) { o.namedRefs = refs }
+}
+
+// WithSources says whether each schema resolved with WithResolver
+// records the name it was resolved from, in an x-source annotation, so
+// that the properties of a schema composed from many can be traced to
+// their origin with Sources. By default no source is recorded.
+func WithSources(record bool) Option {
+	return func(o *options) { o.sources = record }
+}
+
+// A MixedEnumPolicy says what becomes of an enum whose members are
+// of more than one JSON type, such as [1, "1st"], whether it is
+// written in picoschema or as JSON Schema. A null member, as added
+// to an optional enum, is not counted. The linter's mixed-enum rule
+// warns of such enums.
+type MixedEnumPolicy int
+
+const (
+	// MixedEnumsKeep keeps the members as they are written.
+	MixedEnumsKeep MixedEnumPolicy = iota
+	// MixedEnumsReject makes a mixed enum an error.
+	MixedEnumsReject
+	// MixedEnumsStringify writes the number and boolean members of
+	// a mixed enum as strings, so that [1, "1st"] becomes ["1", "1st"].
+	// A mixed enum with object or array members is an error.
+	MixedEnumsStringify
+)
+
+// WithMixedEnums sets the policy for enums whose members are of more
+// than one JSON type. By default they are kept as they are written.
+func WithMixedEnums(policy MixedEnumPolicy) Option {
+	return func(o *options) { o.mixedEnumPolicy = policy }
+}
+
+// WithTypeUnions says whether a list of two or more type names, as in
+//
+//	id: [string, integer]
+//
+// is a union of the types, an anyOf, as a type array is in JSON Schema.
+// By default a list is an enum, and with unions on, a list whose items
+// are not all type names still is. The type names are the built-in
+// scalars, with or without a "?" suffix, "$self", names under $defs and
+// names registered with RegisterScalar; names that only a resolver set
+// by WithResolver knows are not, so that an enum does not change
+// meaning with the resolver's contents. An enum of type names is
+// written with the parenthetical type enum, as in "kind(enum): [string,
+// number]".
+func WithTypeUnions(unions bool) Option {
+	return func(o *options) { o.typeUnions = unions }
+}
+
+// WithSchemaURI sets the $schema keyword of the result to uri,
+// declaring the dialect it is written in. By default $schema is not set.
+func WithSchemaURI(uri string) Option {
+	return func(o *options) { o.schemaURI = uri }
+}
+
+// Preset returns an Option that applies opts in order. It lets a
+// coherent set of options be named once and used at each call site:
+//
+//	var internalAPI = picoschema.Preset(
+//		picoschema.PresetOpenAPI31,
+//		picoschema.WithResolver(registry),
+//	)
+//
+// Options following a preset in a call override the preset's.
+func Preset(opts ...Option) Option {
+	opts = slices.Clone(opts)
+	return func(o *options) {
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// Dialect URIs set by the presets.
+const (
+	OpenAPI31DialectURI = "https://spec.openapis.org/oas/3.1/dialect/base"
+	Draft07SchemaURI    = "http://json-schema.org/draft-07/schema#"
+)
+
+var (
+	// PresetStrictLLM suits schemas for structured model output:
+	// objects are closed and scalar type names must be exact, so
+	// that a typo fails at conversion rather than admitting anything.
+	PresetStrictLLM = Preset(WithAdditionalProperties(false), WithStrictScalars(true))
+
+	// PresetOpenAPI31 suits schemas for OpenAPI 3.1 documents: objects
+	// accept undeclared properties, as API payloads are expected to
+	// grow, and the result declares the OpenAPI 3.1 base dialect.
+	PresetOpenAPI31 = Preset(WithAdditionalProperties(true), WithStrictScalars(true), WithSchemaURI(OpenAPI31DialectURI))
+
+	// PresetLegacyDraft07 writes the result in JSON Schema draft-07,
+	// for validators that predate 2020-12, as WithDraft does: tuples,
+	// $defs and nullable types are rewritten. The 2020-12 keywords
+	// dependentRequired, dependentSchemas, unevaluatedProperties,
+	// unevaluatedItems, minContains, maxContains, $anchor, $dynamicRef
+	// and $dynamicAnchor, which picoschema may embed or resolve, are
+	// left unconverted; DowngradeToDraft07 rewrites or removes them.
+	PresetLegacyDraft07 = Preset(WithStrictScalars(true), WithDraft(Draft07))
+)
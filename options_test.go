@@ -0,0 +1,276 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+)
+
+func TestOptions(t *testing.T) {
+	var r Registry
+	if err := r.Register("customer", mustSchema(t, "name: string")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("ns/widget", &jsonschema.Schema{Type: "string"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("node", mustSchema(t, "children?(array): $self")); err != nil {
+		t.Fatal(err)
+	}
+	broken := SchemaResolverFunc(func(string) (*jsonschema.Schema, error) { return nil, errors.New("offline") })
+
+	for _, test := range []struct {
+		name    string
+		val     any
+		opts    []Option
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "default",
+			val:  map[string]any{"a": "string"},
+			want: map[string]any{
+				"type": "object", "additionalProperties": false, "required": []any{"a"},
+				"properties": map[string]any{"a": map[string]any{"type": "string"}},
+			},
+		},
+		{
+			name: "additional properties",
+			val:  map[string]any{"a": "string", "b?": map[string]any{"c?": "integer"}},
+			opts: []Option{WithAdditionalProperties(true)},
+			want: map[string]any{
+				"type": "object", "required": []any{"a"},
+				"properties": map[string]any{
+					"a": map[string]any{"type": "string"},
+					"b": map[string]any{"type": "object", "properties": map[string]any{"c": map[string]any{"type": "integer"}}},
+				},
+			},
+		},
+		{
+			name: "sealed object with additional properties",
+			val:  map[string]any{"a(sealed)": map[string]any{"c?": "integer"}},
+			opts: []Option{WithAdditionalProperties(true)},
+			want: map[string]any{
+				"type": "object", "required": []any{"a"},
+				"properties": map[string]any{
+					"a": map[string]any{
+						"type": "object", "additionalProperties": false,
+						"properties": map[string]any{"c": map[string]any{"type": "integer"}},
+					},
+				},
+			},
+		},
+		{
+			name: "mixed enums kept",
+			val:  map[string]any{"a(enum)": []any{1, "1st", nil}},
+			want: map[string]any{
+				"type": "object", "additionalProperties": false, "required": []any{"a"},
+				"properties": map[string]any{"a": map[string]any{"enum": []any{1.0, "1st", nil}}},
+			},
+		},
+		{
+			name: "mixed enums stringified",
+			val: map[string]any{
+				"a(enum)":       []any{1, "1st", true, "1", nil},
+				"b(jsonschema)": map[string]any{"enum": []any{2.5, "x"}},
+				"c(enum)":       []any{1, 2},
+			},
+			opts: []Option{WithMixedEnums(MixedEnumsStringify)},
+			want: map[string]any{
+				"type": "object", "additionalProperties": false, "required": []any{"a", "b", "c"},
+				"properties": map[string]any{
+					"a": map[string]any{"enum": []any{"1", "1st", "true", nil}},
+					"b": map[string]any{"enum": []any{"2.5", "x"}},
+					"c": map[string]any{"enum": []any{1.0, 2.0}},
+				},
+			},
+		},
+		{
+			name:    "mixed enums rejected",
+			val:     map[string]any{"b(jsonschema)": map[string]any{"enum": []any{2.5, "x"}}},
+			opts:    []Option{WithMixedEnums(MixedEnumsReject)},
+			wantErr: true,
+		},
+		{
+			name:    "mixed enums with objects",
+			val:     map[string]any{"a(enum)": []any{1, map[string]any{"x": 1}}},
+			opts:    []Option{WithMixedEnums(MixedEnumsStringify)},
+			wantErr: true,
+		},
+		{
+			name: "type unions",
+			val: map[string]any{
+				"$defs":   map[string]any{"money": "number"},
+				"a":       []any{"string", "integer"},
+				"b":       []any{"money", "null", "boolean?"},
+				"c":       []any{"string", "small"},
+				"d(enum)": []any{"string", "integer"},
+			},
+			opts: []Option{WithTypeUnions(true)},
+			want: map[string]any{
+				"type": "object", "additionalProperties": false, "required": []any{"a", "b", "c", "d"},
+				"properties": map[string]any{
+					"a": map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "integer"}}},
+					"b": map[string]any{"anyOf": []any{
+						map[string]any{"$ref": "#/$defs/money"},
+						map[string]any{"type": "null"},
+						map[string]any{"anyOf": []any{map[string]any{"type": "boolean"}, map[string]any{"type": "null"}}},
+					}},
+					"c": map[string]any{"enum": []any{"string", "small"}},
+					"d": map[string]any{"enum": []any{"string", "integer"}},
+				},
+				"$defs": map[string]any{"money": map[string]any{"type": "number"}},
+			},
+		},
+		{
+			name: "type names as enum",
+			val:  map[string]any{"a": []any{"string", "integer"}},
+			want: map[string]any{
+				"type": "object", "additionalProperties": false, "required": []any{"a"},
+				"properties": map[string]any{"a": map[string]any{"enum": []any{"string", "integer"}}},
+			},
+		},
+		{
+			name:    "strict scalars",
+			val:     map[string]any{"a": "String"},
+			wantErr: true,
+		},
+		{
+			name: "lenient scalars",
+			val:  map[string]any{"a": "String , the a", "b": "widget"},
+			opts: []Option{WithStrictScalars(false)},
+			want: map[string]any{
+				"type": "object", "additionalProperties": false, "required": []any{"a", "b"},
+				"properties": map[string]any{
+					"a": map[string]any{"type": "string", "description": "the a"},
+					"b": true,
+				},
+			},
+		},
+		{
+			name: "resolver",
+			val:  map[string]any{"owner": "customer, who owns it"},
+			opts: []Option{WithResolver(&r)},
+			want: map[string]any{
+				"type": "object", "additionalProperties": false, "required": []any{"owner"},
+				"properties": map[string]any{
+					"owner": map[string]any{
+						"type": "object", "additionalProperties": false, "required": []any{"name"},
+						"description": "who owns it",
+						"properties":  map[string]any{"name": map[string]any{"type": "string"}},
+					},
+				},
+			},
+		},
+		{
+			name: "named refs",
+			val: map[string]any{
+				"owner":            "customer, who owns it",
+				"previous?(array)": "customer",
+				"ns/widget?":       "ns/widget",
+			},
+			opts: []Option{WithResolver(&r), WithNamedRefs(true)},
+			want: map[string]any{
+				"type": "object", "additionalProperties": false, "required": []any{"owner"},
+				"properties": map[string]any{
+					"owner":     map[string]any{"$ref": "#/$defs/customer", "description": "who owns it"},
+					"previous":  map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/customer"}},
+					"ns/widget": map[string]any{"$ref": "#/$defs/ns~1widget"},
+				},
+				"$defs": map[string]any{
+					"customer": map[string]any{
+						"type": "object", "additionalProperties": false, "required": []any{"name"},
+						"properties": map[string]any{"name": map[string]any{"type": "string"}},
+					},
+					"ns/widget": map[string]any{"type": "string"},
+				},
+			},
+		},
+		{
+			name: "recursive resolved schema",
+			val:  map[string]any{"tree": "node"},
+			opts: []Option{WithResolver(&r)},
+			want: map[string]any{
+				"type": "object", "additionalProperties": false, "required": []any{"tree"},
+				"properties": map[string]any{"tree": map[string]any{"$ref": "#/$defs/node"}},
+				"$defs": map[string]any{
+					"node": map[string]any{
+						"type": "object", "additionalProperties": false,
+						"properties": map[string]any{
+							"children": map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/node"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "resolver not found",
+			val:     map[string]any{"owner": "supplier"},
+			opts:    []Option{WithResolver(&r)},
+			wantErr: true,
+		},
+		{
+			name: "preset",
+			val:  map[string]any{"a": "string"},
+			opts: []Option{PresetOpenAPI31},
+			want: map[string]any{
+				"$schema": OpenAPI31DialectURI, "type": "object", "required": []any{"a"},
+				"properties": map[string]any{"a": map[string]any{"type": "string"}},
+			},
+		},
+		{
+			name: "user preset overridden",
+			val:  map[string]any{"a": "String"},
+			opts: []Option{Preset(PresetLegacyDraft07, WithAdditionalProperties(true)), WithStrictScalars(false)},
+			want: map[string]any{
+				"$schema": Draft07SchemaURI, "type": "object", "required": []any{"a"},
+				"properties": map[string]any{"a": map[string]any{"type": "string"}},
+			},
+		},
+		{
+			name:    "strict llm preset",
+			val:     map[string]any{"a": "String"},
+			opts:    []Option{WithStrictScalars(false), PresetStrictLLM},
+			wantErr: true,
+		},
+		{
+			name:    "resolver error",
+			val:     map[string]any{"owner": "supplier"},
+			opts:    []Option{WithResolver(broken), WithStrictScalars(false)},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ToJSONSchema(test.val, test.opts...)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			sortSchemaSlices(s)
+			if diff := cmp.Diff(test.want, schemaJSON(t, s)); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -12,141 +12,1924 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package picoschema converts picoschema, the compact YAML schema
+// notation of Dotprompt, to JSON Schema, and works with the schemas.
+//
+// The package is safe for concurrent use. Its registries of scalars,
+// importers and profiles are guarded, and its functions read the
+// schemas they are given without modifying them, so a schema may be
+// used from several goroutines at once as long as none modifies it.
+// Use Clone to get a copy that may be modified.
 package picoschema
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"math"
 	"reflect"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// ToJSONSchema turns picoschema input into a JSONSchema.
+// The val parameter is the result of parsing YAML into an value of type any.
+// picoschema is loosely documented at docs/dotprompt.md.
+// The options tune the conversion of picoschema; of them, only
+// WithResolver and WithDraft affect input that is already JSON Schema.
+// The properties of objects come in the order of their names.
+func ToJSONSchema(val any, opts ...Option) (*jsonschema.Schema, error) {
+	start := time.Now()
+	p := newParser(opts)
+	s, err := p.toJSONSchema(val)
+	recordConversion("picoschema", start)
+	recordError("convert", err)
+	return s, err
+}
+
+// A parser converts picoschema with a set of options,
+// recording its decisions in tr if it is not nil.
+type parser struct {
+	options
+	tr   *tracer
+	defs jsonschema.Definitions // resolved schemas, with WithNamedRefs, and local ones
+	// local holds the names in the $defs section of the picoschema.
+	local map[string]bool
+	// resolving holds the names of the resolved schemas being copied,
+	// so that a schema that refers back to one of them is put under
+	// $defs instead of copied without end.
+	resolving map[string]bool
+	// allOfs holds the schemas written with the parenthetical type
+	// allOf, in the order they were parsed, for closeAllOf.
+	allOfs []*jsonschema.Schema
+	// unions holds the unions given a discriminator whose branches
+	// include refs, for checkDiscriminators.
+	unions []*jsonschema.Schema
+}
+
+// toJSONSchema converts val.
+func (p *parser) toJSONSchema(val any) (*jsonschema.Schema, error) {
+	if val == nil {
+		return nil, nil
+	}
+
+	if m, ok := val.(map[string]any); ok {
+		// If we decoded to something that looks like it might
+		// be a JSON schema, treat it as a JSON schema.
+		switch m["type"] {
+		case "string", "boolean", "null", "number", "integer", "object", "array":
+			s, err := p.rootJSONSchema(m)
+			if err == nil {
+				p.tr.add("", "", "json schema", s)
+			}
+			return s, err
+		}
+
+		if props, ok := m["properties"]; ok {
+			if _, ok := props.(map[string]any); ok {
+				s, err := p.rootJSONSchema(m)
+				if err != nil {
+					return nil, err
+				}
+				s.Type = "object"
+				p.tr.add("", "", "json schema", s)
+				return s, nil
+			}
+		}
+	}
+
+	if m, ok := val.(map[string]any); ok {
+		if defs, ok := m[defsKey]; ok {
+			if err := p.parseDefs(defs); err != nil {
+				return nil, atKey(defsKey, err)
+			}
+			m = maps.Clone(m)
+			delete(m, defsKey)
+			val = m
+		}
+	}
+
+	s, err := p.parsePico(val, "")
+	if err == nil {
+		s.Version = p.schemaURI
+		if len(p.defs) > 0 {
+			s.Definitions = p.defs
+		}
+		if err := p.checkDiscriminators(s); err != nil {
+			return nil, err
+		}
+		p.closeAllOf(s)
+		writeDraft(s, p.draft)
+		p.tr.add("", "", "picoschema "+picoForm(val), s)
+	}
+	return s, err
+}
+
+// rootJSONSchema converts m, a root that is written as JSON Schema.
+func (p *parser) rootJSONSchema(m map[string]any) (*jsonschema.Schema, error) {
+	s, err := mapToJSONSchema(m)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.resolveRefs(s); err != nil {
+		return nil, err
+	}
+	if err := p.mixedEnums(s); err != nil {
+		return nil, err
+	}
+	for name, def := range p.defs {
+		if s.Definitions == nil {
+			s.Definitions = make(jsonschema.Definitions)
+		}
+		if _, ok := s.Definitions[name]; !ok {
+			s.Definitions[name] = def
+		}
+	}
+	if p.draft != Draft202012 {
+		writeDraft(s, p.draft)
+		s.Version = p.schemaURI
+	}
+	return s, nil
+}
+
+// parsePico parses picoschema from the result of the YAML parser.
+// The result is at the JSON Pointer path within the converted schema.
+func (p *parser) parsePico(val any, path string) (*jsonschema.Schema, error) {
+	switch val := val.(type) {
+	default:
+		return nil, inValue(fmt.Errorf("picoschema: value %v of type %[1]T is not an object, slice or string", val))
+
+	case string:
+		typ, desc, found := strings.Cut(val, ",")
+		if p.lenientScalars {
+			typ = strings.TrimSpace(typ)
+		}
+		typ, isNullable := strings.CutSuffix(typ, "?")
+		ret, err := p.scalar(typ)
+		if err != nil {
+			return nil, inValue(err)
+		}
+		if isNullable {
+			ret = nullableSchema(ret)
+		}
+		if found {
+			ret.Description = strings.TrimSpace(desc)
+		}
+		return ret, nil
+
+	case []any: // assume enum
+		if names, ok := p.typeUnionNames(val); ok {
+			return p.typeUnion(names)
+		}
+		return p.enum(val)
+
+	case map[string]any:
+		if body, ok := val[jsonschemaTag]; ok {
+			if len(val) > 1 {
+				return nil, inValue(fmt.Errorf("picoschema: object with key %s has other keys", jsonschemaTag))
+			}
+			s, err := p.embeddedSchema(body)
+			if err != nil {
+				return nil, atKey(jsonschemaTag, inValue(err))
+			}
+			return s, nil
+		}
+		if _, ok := val[defsKey]; ok {
+			return nil, atKey(defsKey, fmt.Errorf("picoschema: %s is only allowed at the top level", defsKey))
+		}
+		ret := &jsonschema.Schema{
+			Type:                 "object",
+			Properties:           orderedmap.New[string, *jsonschema.Schema](),
+			AdditionalProperties: jsonschema.FalseSchema,
+		}
+		if p.additionalProperties {
+			ret.AdditionalProperties = nil
+		}
+		var required []string
+		requiredKey := ""
+		var groupKeys []string
+		for _, k := range picoKeys(val) {
+			v := val[k]
+			if name, paren, _ := strings.Cut(k, "("); name == "" && strings.TrimSpace(paren) == "required)" {
+				r, err := requiredList(v)
+				if err != nil {
+					return nil, atKey(k, inValue(err))
+				}
+				required, requiredKey = r, k
+				continue
+			}
+			if name, paren, _ := strings.Cut(k, "("); name == "" {
+				if _, ok := exclusionGroupKinds[strings.TrimSpace(paren)]; ok {
+					groupKeys = append(groupKeys, k)
+					continue
+				}
+			}
+			if err := p.parseKey(ret, k, v, path); err != nil {
+				return nil, atKey(k, err)
+			}
+		}
+		if requiredKey != "" {
+			for _, r := range required {
+				if ret.Properties.Value(r) == nil {
+					return nil, atKey(requiredKey, inValue(fmt.Errorf("picoschema: required property %q is not declared", r)))
+				}
+			}
+			ret.Required = required
+			p.tr.addKeywords(path, requiredKey, "required list", []string{"required"})
+		}
+		slices.Sort(groupKeys)
+		var groups []*jsonschema.Schema
+		for _, k := range groupKeys {
+			_, paren, _ := strings.Cut(k, "(")
+			exactlyOne := exclusionGroupKinds[strings.TrimSpace(paren)]
+			gs, err := exclusionGroups(ret, val[k], exactlyOne)
+			if err != nil {
+				return nil, atKey(k, inValue(err))
+			}
+			groups = append(groups, gs...)
+			kw := "not"
+			if exactlyOne {
+				kw = "oneOf"
+			}
+			p.tr.addKeywords(path, k, "exclusion group", []string{kw})
+		}
+		switch len(groups) {
+		case 0:
+		case 1:
+			ret.OneOf, ret.Not = groups[0].OneOf, groups[0].Not
+		default:
+			ret.AllOf = groups
+		}
+		return ret, nil
+	}
+}
+
+// picoKeys returns the keys of the picoschema object m sorted by the
+// names of the properties they declare, and then by the keys, so that
+// the properties of converted objects come in the same order whatever
+// the order of the keys of m.
+func picoKeys(m map[string]any) []string {
+	keys := sortedKeys(m)
+	name := func(k string) string {
+		name, _, _ := strings.Cut(k, "(")
+		return strings.TrimSuffix(name, "?")
+	}
+	slices.SortStableFunc(keys, func(a, b string) int {
+		return strings.Compare(name(a), name(b))
+	})
+	return keys
+}
+
+// enum returns the schema of a property whose value is the list of
+// enum values list.
+func (p *parser) enum(list []any) (*jsonschema.Schema, error) {
+	ret, err := parseEnum(list)
+	if err != nil {
+		return nil, inValue(err)
+	}
+	if err := p.mixedEnum(ret); err != nil {
+		return nil, inValue(err)
+	}
+	return ret, nil
+}
+
+// typeUnionNames returns the items of list, the value of a property,
+// if WithTypeUnions is on and they are two or more type names.
+func (p *parser) typeUnionNames(list []any) ([]string, bool) {
+	if !p.typeUnions || len(list) < 2 {
+		return nil, false
+	}
+	names := make([]string, len(list))
+	for i, e := range list {
+		name, ok := e.(string)
+		if !ok || !p.isTypeName(strings.TrimSuffix(name, "?")) {
+			return nil, false
+		}
+		names[i] = name
+	}
+	return names, true
+}
+
+// isTypeName reports whether typ names a type without a resolver.
+func (p *parser) isTypeName(typ string) bool {
+	if p.lenientScalars {
+		typ = strings.TrimSpace(typ)
+		if slices.Contains(builtinScalars, strings.ToLower(typ)) {
+			return true
+		}
+	}
+	if slices.Contains(builtinScalars, typ) || typ == selfType || p.local[typ] {
+		return true
+	}
+	_, ok := lookupScalar(typ)
+	return ok
+}
+
+// typeUnion returns the schema of a property whose value lists the
+// type names names, as in "id: [string, integer]", with WithTypeUnions.
+func (p *parser) typeUnion(names []string) (*jsonschema.Schema, error) {
+	ret := &jsonschema.Schema{}
+	for _, name := range names {
+		s, err := p.parsePico(name, "")
+		if err != nil {
+			return nil, err
+		}
+		ret.AnyOf = append(ret.AnyOf, s)
+	}
+	return ret, nil
+}
+
+// defsKey is the reserved top-level key whose value names picoschemas
+// that the rest of the schema refers to by name, as scalar types are,
+// as in
+//
+//	$defs:
+//	  address:
+//	    city: string
+//	    zip?: string
+//	  tags(array): string
+//	home: address
+//	work?: address, where they work
+//
+// Each is put under $defs of the result, and every use of its name is
+// a $ref to it. Definitions may refer to each other and to themselves.
+const defsKey = "$defs"
+
+// parseDefs parses v, the value of the $defs key, into p.defs.
+// A key names a definition and may have a parenthetical, as a
+// property key does, but may not be optional.
+func (p *parser) parseDefs(v any) error {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return inValue(fmt.Errorf("picoschema: %s value %v is not an object", defsKey, v))
+	}
+	names := make(map[string]string, len(m))
+	p.local = make(map[string]bool, len(m))
+	for _, k := range sortedKeys(m) {
+		name, _, _ := strings.Cut(k, "(")
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "" || strings.HasSuffix(name, "?"):
+			return atKey(k, fmt.Errorf("picoschema: definition name %q is not a type name", name))
+		case slices.Contains(builtinScalars, name) || name == selfType:
+			return atKey(k, fmt.Errorf("picoschema: definition name %q is a built-in type", name))
+		case p.local[name]:
+			return atKey(k, fmt.Errorf("picoschema: definition %q is also declared by key %q", name, names[name]))
+		}
+		names[name] = k
+		p.local[name] = true
+	}
+	if p.defs == nil {
+		p.defs = make(jsonschema.Definitions)
+	}
+	for _, name := range sortedKeys(names) {
+		k := names[name]
+		var pt parenthetical
+		if _, paren, found := strings.Cut(k, "("); found {
+			pt = parseParenthetical(strings.TrimSuffix(paren, ")"))
+		}
+		path := "/" + defsKey + "/" + escapePointer(name)
+		s, err := p.typedValue(pt.typ, m[k], path, false)
+		if err != nil {
+			return atKey(k, err)
+		}
+		if pt.hasDesc {
+			s.Description = pt.desc
+		}
+		if err := p.applyAttributes(s, pt.attrs); err != nil {
+			return atKey(k, fmt.Errorf("picoschema: definition %q: %w", name, err))
+		}
+		p.defs[name] = s
+		p.tr.add(path, k, "definition of "+picoForm(m[k]), s)
+	}
+	return nil
+}
+
+// requiredList returns the property names listed in v, the value of a
+// "(required)" key. The list replaces the required properties implied
+// by the keys of the object, in the order given, as in
+//
+//	(required): [id, name]
+//	id: string
+//	name?: string
+func requiredList(v any) ([]string, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("picoschema: required value %v is not a list of property names", v)
+	}
+	names := make([]string, 0, len(list))
+	for _, e := range list {
+		name, ok := e.(string)
+		if !ok {
+			return nil, fmt.Errorf("picoschema: required value %v is not a list of property names", v)
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// exclusionGroupKinds maps the text after the "(" of the keys that
+// declare exclusion groups to whether each group requires exactly one
+// of its properties, rather than at most one.
+var exclusionGroupKinds = map[string]bool{
+	"oneOfGroup)":     true,
+	"atMostOneGroup)": false,
+}
+
+// exclusionGroups returns the schemas that enforce the exclusion groups
+// listed in v, the value of a "(oneOfGroup)" or "(atMostOneGroup)" key
+// of the object obj, as in
+//
+//	(oneOfGroup): [email, phone]
+//	email?: string
+//	phone?: string
+//
+// which accepts an object with an email or a phone but not both. The
+// value may also be a list of groups, as in
+//
+//	(atMostOneGroup): [[email, phone], [iban, card]]
+//
+// The properties of a group must be declared, and optional. Exactly one
+// of them is required with oneOf and a required list per property, and
+// at most one with not and anyOf a required list per pair.
+func exclusionGroups(obj *jsonschema.Schema, v any, exactlyOne bool) ([]*jsonschema.Schema, error) {
+	list, ok := v.([]any)
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("picoschema: group value %v is not a list of property names", v)
+	}
+	lists := [][]any{list}
+	if _, nested := list[0].([]any); nested {
+		lists = lists[:0]
+		for _, e := range list {
+			l, ok := e.([]any)
+			if !ok {
+				return nil, fmt.Errorf("picoschema: group value %v is not a list of lists of property names", v)
+			}
+			lists = append(lists, l)
+		}
+	}
+	var groups []*jsonschema.Schema
+	for _, l := range lists {
+		names, err := requiredList(l)
+		if err != nil || len(names) < 2 {
+			return nil, fmt.Errorf("picoschema: group %v is not a list of two or more property names", l)
+		}
+		for _, name := range names {
+			if obj.Properties.Value(name) == nil {
+				return nil, fmt.Errorf("picoschema: group property %q is not declared", name)
+			}
+			if slices.Contains(obj.Required, name) {
+				return nil, fmt.Errorf("picoschema: group property %q is required; make it optional with \"?\"", name)
+			}
+		}
+		var s *jsonschema.Schema
+		if exactlyOne {
+			s = &jsonschema.Schema{}
+			for _, name := range names {
+				s.OneOf = append(s.OneOf, &jsonschema.Schema{Required: []string{name}})
+			}
+		} else {
+			pairs := &jsonschema.Schema{}
+			for i, a := range names {
+				for _, b := range names[i+1:] {
+					pairs.AnyOf = append(pairs.AnyOf, &jsonschema.Schema{Required: []string{a, b}})
+				}
+			}
+			s = &jsonschema.Schema{Not: pairs}
+		}
+		groups = append(groups, s)
+	}
+	return groups, nil
+}
+
+// parseKey parses the key k of a picoschema object and its value v,
+// adding what they declare to ret, the object's schema at path.
+func (p *parser) parseKey(ret *jsonschema.Schema, k string, v any, path string) error {
+	name, paren, found := strings.Cut(k, "(")
+	if name == "" && strings.TrimSpace(paren) == "assert)" {
+		if err := setAssertions(ret, v); err != nil {
+			return inValue(err)
+		}
+		p.tr.addKeywords(path, k, "assertions", []string{assertKey})
+		return nil
+	}
+	if name == "" && strings.TrimSpace(paren) == "description)" {
+		// The description of the object itself, which
+		// is the only way to describe the root object.
+		desc, ok := v.(string)
+		if !ok {
+			return inValue(fmt.Errorf("picoschema: description %v is not a string", v))
+		}
+		ret.Description = strings.TrimSpace(desc)
+		p.tr.addKeywords(path, k, "object description", []string{"description"})
+		return nil
+	}
+	propertyName, isOptional := strings.CutSuffix(name, "?")
+	if name != "" && !isOptional {
+		ret.Required = append(ret.Required, propertyName)
+	}
+
+	if pattern, rest, ok := patternWildcard(paren); found && ok {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("picoschema: wildcard pattern %q: %w", pattern, err)
+		}
+		return p.parseWildcard(ret, k, propertyName, pattern, isOptional, parseParenthetical("*"+rest), v, path)
+	}
+	var pt parenthetical
+	if found {
+		if strings.TrimSpace(strings.TrimSuffix(paren, ")")) == "" {
+			return fmt.Errorf("picoschema: empty parentheses in key %q", k)
+		}
+		pt = parseParenthetical(strings.TrimSuffix(paren, ")"))
+	}
+	if pt.typ == "*" {
+		return p.parseWildcard(ret, k, propertyName, "", isOptional, pt, v, path)
+	}
+	if propertyName == "" {
+		return fmt.Errorf("picoschema: key %q has no property name", k)
+	}
+
+	ppath := path + "/properties/" + escapePointer(propertyName)
+	property, err := p.typedValue(pt.typ, v, ppath, isOptional)
+	if err != nil {
+		return err
+	}
+	if pt.hasDesc {
+		property.Description = pt.desc
+	}
+	if err := p.applyAttributes(property, pt.attrs); err != nil {
+		return fmt.Errorf("picoschema: property %q: %w", propertyName, err)
+	}
+
+	ret.Properties.Set(propertyName, property)
+	p.tr.add(ppath, k, propertyInterpretation(isOptional, pt.typ, v), property)
+	return nil
+}
+
+// parenTypes are the parenthetical types other than the scalar types.
+var parenTypes = []string{"object", "array", "enum", "const", "oneOf", "anyOf", "allOf", "tuple", "jsonschema", "*"}
+
+// typedValue returns the schema at path of v, the value of a property
+// or wildcard whose parenthetical type is typ. The type may be empty.
+// An optional enum also accepts null.
+func (p *parser) typedValue(typ string, v any, path string, optional bool) (*jsonschema.Schema, error) {
+	switch typ {
+	case "", "object":
+		return p.parsePico(v, path)
+	case "array":
+		items, err := p.parsePico(v, path+"/items")
+		if err != nil {
+			return nil, err
+		}
+		return &jsonschema.Schema{Type: "array", Items: items}, nil
+	case "enum":
+		var s *jsonschema.Schema
+		var err error
+		if list, ok := v.([]any); ok {
+			// Not a type union, even with WithTypeUnions.
+			s, err = p.enum(list)
+		} else {
+			s, err = p.parsePico(v, path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if s.Enum == nil {
+			return nil, inValue(fmt.Errorf("picoschema: enum value %v is not an array", s))
+		}
+		if optional {
+			s.Enum = append(s.Enum, nil)
+		}
+		return s, nil
+	case "const":
+		return constSchema(v), nil
+	case "oneOf", "anyOf", "allOf":
+		return p.composition(typ, v, path)
+	case "tuple":
+		return p.tuple(v, path)
+	case "jsonschema":
+		s, err := p.embeddedSchema(v)
+		return s, inValue(err)
+	}
+	if inner, ok := nestedArrayType(typ); ok {
+		items, err := p.typedValue(inner, v, path+"/items", false)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonschema.Schema{Type: "array", Items: items}, nil
+	}
+	if slices.Contains(builtinScalars, typ) {
+		return scalarParenthetical(typ, v)
+	}
+	return nil, fmt.Errorf("picoschema: parenthetical type %q is none of %q", typ,
+		append(slices.Clip(parenTypes), builtinScalars...))
+}
+
+// nestedArrayType reports whether the parenthetical type typ is an
+// array whose items have a parenthetical type, as in
+//
+//	matrix(array(array)): number
+//
+// and if so returns the type of the items.
+func nestedArrayType(typ string) (string, bool) {
+	inner, ok := strings.CutPrefix(typ, "array(")
+	if !ok {
+		return "", false
+	}
+	return strings.CutSuffix(inner, ")")
+}
+
+// isParenType reports whether typ is a parenthetical type.
+func isParenType(typ string) bool {
+	for {
+		inner, ok := nestedArrayType(typ)
+		if !ok {
+			break
+		}
+		typ = inner
+	}
+	return slices.Contains(parenTypes, typ) || slices.Contains(builtinScalars, typ)
+}
+
+// objectAttributes are the attributes of a wildcard that constrain the
+// object it belongs to rather than the values it matches.
+var objectAttributes = []string{"minProperties", "maxProperties"}
+
+// parseWildcard parses the wildcard key k and its value v. A wildcard
+// with no name, as in "(*)", describes the properties of ret that are
+// not declared; one with a name, as in "scores(*)", declares a property
+// of ret that is an object whose properties are all described by the
+// wildcard. The parenthetical may give the type of the values after
+// the "*", as in "(*, array)".
+//
+// A wildcard with a pattern, written between slashes in place of the
+// "*", as in "(/^x-/)", describes only the properties whose names match
+// the regular expression. A property declared by a named wildcard with
+// a pattern has no other properties, unless WithAdditionalProperties
+// allows them.
+func (p *parser) parseWildcard(ret *jsonschema.Schema, k, name, pattern string, optional bool, pt parenthetical, v any, path string) error {
+	obj, opath := ret, path
+	if name != "" {
+		obj = &jsonschema.Schema{Type: "object"}
+		opath = path + "/properties/" + escapePointer(name)
+		if pattern != "" && !p.additionalProperties {
+			obj.AdditionalProperties = jsonschema.FalseSchema
+		}
+	}
+	vpath := opath + "/additionalProperties"
+	if pattern != "" {
+		vpath = opath + "/patternProperties/" + escapePointer(pattern)
+	}
+	value, err := p.typedValue(pt.valueTyp, v, vpath, false)
+	if err != nil {
+		return err
+	}
+
+	var objAttrs, valueAttrs []attribute
+	for _, a := range pt.attrs {
+		if slices.Contains(objectAttributes, a.key) {
+			objAttrs = append(objAttrs, a)
+		} else {
+			valueAttrs = append(valueAttrs, a)
+		}
+	}
+	what := "wildcard"
+	if name != "" {
+		what = fmt.Sprintf("property %q", name)
+	}
+	if err := p.applyAttributes(obj, objAttrs); err != nil {
+		return fmt.Errorf("picoschema: %s: %w", what, err)
+	}
+	if err := p.applyAttributes(value, valueAttrs); err != nil {
+		return fmt.Errorf("picoschema: %s: %w", what, err)
+	}
+	if pattern == "" {
+		obj.AdditionalProperties = value
+	} else {
+		if obj.PatternProperties == nil {
+			obj.PatternProperties = make(map[string]*jsonschema.Schema)
+		}
+		obj.PatternProperties[pattern] = value
+	}
+
+	if name == "" {
+		if pt.hasDesc {
+			value.Description = pt.desc
+		}
+		p.tr.add(vpath, k, propertyInterpretation(optional, "*", v), value)
+		return nil
+	}
+	if pt.hasDesc {
+		obj.Description = pt.desc
+	}
+	ret.Properties.Set(name, obj)
+	p.tr.add(opath, k, propertyInterpretation(optional, "map", v), obj)
+	return nil
+}
+
+// constSchema returns the schema of a property with the parenthetical
+// type const, whose value v is the only value it accepts, as in
+//
+//	version(const): "2.0"
+//
+// A null value is written as the type null, since a const
+// keyword of null would be omitted.
+func constSchema(v any) *jsonschema.Schema {
+	if v == nil {
+		return &jsonschema.Schema{Type: "null"}
+	}
+	return &jsonschema.Schema{Const: v}
+}
+
+// patternWildcard reports whether paren, the text of a key after its
+// "(", starts with a pattern between slashes, as in "/^x-/)". If so,
+// it returns the pattern and the items that follow it, with their
+// leading comma and without the closing ")".
+func patternWildcard(paren string) (pattern, rest string, ok bool) {
+	body, ok := strings.CutPrefix(strings.TrimSpace(paren), "/")
+	if !ok {
+		return "", "", false
+	}
+	for i := strings.Index(body, "/"); i >= 0; i = nextIndex(body, "/", i) {
+		after := strings.TrimLeft(body[i+1:], " ")
+		if after == ")" || strings.HasPrefix(after, ",") && strings.HasSuffix(after, ")") {
+			return body[:i], strings.TrimSuffix(after, ")"), true
+		}
+	}
+	return "", "", false
+}
+
+// nextIndex returns the index of the first instance of sep
+// in s after index i, or -1.
+func nextIndex(s, sep string, i int) int {
+	j := strings.Index(s[i+1:], sep)
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
+
+// tuple returns the schema at path of a property with the parenthetical
+// type tuple, whose value v lists the picoschema of the elements of
+// a fixed-length array, as in
+//
+//	point(tuple): [number, number]
+func (p *parser) tuple(v any, path string) (*jsonschema.Schema, error) {
+	elems, ok := v.([]any)
+	if !ok || len(elems) == 0 {
+		return nil, inValue(fmt.Errorf("picoschema: tuple value %v is not a list of schemas", v))
+	}
+	n := uint64(len(elems))
+	ret := &jsonschema.Schema{
+		Type:        "array",
+		PrefixItems: make([]*jsonschema.Schema, len(elems)),
+		Items:       jsonschema.FalseSchema,
+		MinItems:    &n,
+	}
+	for i, e := range elems {
+		s, err := p.parsePico(e, fmt.Sprintf("%s/prefixItems/%d", path, i))
+		if err != nil {
+			return nil, inValue(err)
+		}
+		ret.PrefixItems[i] = s
+	}
+	return ret, nil
+}
+
+// nullableSchema returns a schema accepting null and what s accepts,
+// for a scalar type name with a "?" suffix such as "string?". In an
+// array, as in "tags(array): string?", it makes the items nullable.
+func nullableSchema(s *jsonschema.Schema) *jsonschema.Schema {
+	if b, isBool := boolSchema(s); b || (!isBool && len(schemaKeywords(s)) == 0) || s.Type == "null" {
+		// The schema already accepts null.
+		return s
+	}
+	return &jsonschema.Schema{AnyOf: []*jsonschema.Schema{s, {Type: "null"}}}
+}
+
+// compositions maps the parenthetical types that compose schemas
+// to the lists of subschemas they set.
+var compositions = map[string]func(*jsonschema.Schema) *[]*jsonschema.Schema{
+	"oneOf": func(s *jsonschema.Schema) *[]*jsonschema.Schema { return &s.OneOf },
+	"anyOf": func(s *jsonschema.Schema) *[]*jsonschema.Schema { return &s.AnyOf },
+	"allOf": func(s *jsonschema.Schema) *[]*jsonschema.Schema { return &s.AllOf },
+}
+
+// composition returns the schema at path of a property with the
+// parenthetical type kw, one of oneOf, anyOf and allOf, whose value v
+// lists the picoschema of the schemas it composes, as in
+//
+//	id(oneOf): [string, integer]
+//	contact(anyOf): [{email: string}, {phone: string}]
+//	employee(allOf): [person, {badge: string}]
+func (p *parser) composition(kw string, v any, path string) (*jsonschema.Schema, error) {
+	alts, ok := v.([]any)
+	if !ok || len(alts) == 0 {
+		return nil, inValue(fmt.Errorf("picoschema: %s value %v is not a list of schemas", kw, v))
+	}
+	ret := &jsonschema.Schema{}
+	list := compositions[kw](ret)
+	*list = make([]*jsonschema.Schema, len(alts))
+	for i, alt := range alts {
+		s, err := p.parsePico(alt, fmt.Sprintf("%s/%s/%d", path, kw, i))
+		if err != nil {
+			return nil, inValue(err)
+		}
+		(*list)[i] = s
+	}
+	if kw == "allOf" {
+		p.allOfs = append(p.allOfs, ret)
+	}
+	return ret, nil
+}
+
+// unevaluatedPropertiesKey is the keyword that closes an allOf
+// composition of objects; see closeAllOf.
+const unevaluatedPropertiesKey = "unevaluatedProperties"
+
+// closeAllOf lets the objects composed by each schema in p.allOfs, as
+// in
+//
+//	employee(allOf): [person, {badge: string}]
+//
+// accept one another's properties. An object closed with
+// additionalProperties false would reject the properties of the
+// others, so it is opened, and the composition closed instead with
+// unevaluatedProperties false, which allows the properties of all of
+// them. A ref to a closed object, resolved within root, is replaced by
+// an open copy of its target.
+func (p *parser) closeAllOf(root *jsonschema.Schema) {
+	for _, s := range p.allOfs {
+		closed := false
+		for i, b := range s.AllOf {
+			t := b
+			if ptr, ok := strings.CutPrefix(b.Ref, "#"); ok && len(schemaKeywords(b)) == 1 {
+				if t, ok = resolvePointer(root, ptr); !ok {
+					continue
+				}
+			}
+			if !isClosedObject(t) {
+				continue
+			}
+			if t != b {
+				t = cloneSchema(t)
+				s.AllOf[i] = t
+			}
+			t.AdditionalProperties = nil
+			deleteExtra(t, unevaluatedPropertiesKey)
+			closed = true
+		}
+		if closed {
+			setExtra(s, unevaluatedPropertiesKey, false)
+		}
+	}
+}
+
+// isClosedObject reports whether s is an object schema that allows
+// only the properties it declares.
+func isClosedObject(s *jsonschema.Schema) bool {
+	if ap, ok := boolSchema(s.AdditionalProperties); ok && !ap {
+		return s.Type == "object"
+	}
+	return s.Extras[unevaluatedPropertiesKey] == false
+}
+
+// scalarParenthetical returns the schema of a property whose key names
+// the scalar type typ in its parenthetical, so that constraints can
+// follow it, as in
+//
+//	age(integer, minimum=0, maximum=150): age in years
+//
+// The value v is the property's description, or null.
+func scalarParenthetical(typ string, v any) (*jsonschema.Schema, error) {
+	s, err := (&parser{}).scalar(typ)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case nil:
+	case string:
+		s.Description = strings.TrimSpace(v)
+	case map[string]any:
+		if err := scalarLongForm(s, typ, v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, inValue(fmt.Errorf("picoschema: value %v of %s property is not a description", v, typ))
+	}
+	return s, nil
+}
+
+// scalarLongForm applies m, the long-form value of a property with the
+// scalar parenthetical type typ, to its schema s. The long form has
+// any of a description, a default and a list of examples, for values
+// that cannot be written as attributes, as in
+//
+//	address(string):
+//	  description: the postal address
+//	  default: "1 Main St, Springfield"
+//	  examples: ["1 Main St, Springfield", "2 High St, Shelbyville"]
+//
+// The default and the examples are YAML values rather than the text of
+// attributes, and must be valid under s.
+func scalarLongForm(s *jsonschema.Schema, typ string, m map[string]any) error {
+	for _, k := range sortedKeys(m) {
+		switch k {
+		case "default":
+			if m[k] == nil {
+				return atKey(k, inValue(errors.New("picoschema: default is null")))
+			}
+			if vs := checkLocal(m[k], s); len(vs) > 0 {
+				return atKey(k, inValue(fmt.Errorf("picoschema: default %v is not valid: %s", m[k], vs[0].Message)))
+			}
+			s.Default = m[k]
+		case "description":
+			desc, ok := m[k].(string)
+			if !ok {
+				return atKey(k, inValue(fmt.Errorf("picoschema: description %v is not a string", m[k])))
+			}
+			s.Description = strings.TrimSpace(desc)
+		case "examples":
+			list, ok := m[k].([]any)
+			if !ok || len(list) == 0 {
+				return atKey(k, inValue(fmt.Errorf("picoschema: examples value %v is not a non-empty list", m[k])))
+			}
+			s.Examples = list
+			if err := checkExamples(s); err != nil {
+				return atKey(k, inValue(fmt.Errorf("picoschema: example %w", err)))
+			}
+		default:
+			return inValue(fmt.Errorf("picoschema: value %v of %s property is not a description or a long form with keys description, default and examples", m, typ))
+		}
+	}
+	return nil
+}
+
+// jsonschemaTag is the key of an object whose only key it is and whose
+// value is written as JSON Schema rather than picoschema, as in
+//
+//	shipping:
+//	  $jsonschema: {type: object, minProperties: 1}
+//
+// The tag may stand wherever picoschema is expected. It is an alias of
+// the parenthetical type jsonschema, and FromJSONSchema writes the
+// parenthetical.
+const jsonschemaTag = "$jsonschema"
+
+// embeddedSchema converts v, the value of a property key with the
+// parenthetical type jsonschema, which is written as JSON Schema
+// rather than picoschema, as in
+//
+//	age(jsonschema): {type: integer, minimum: 0}
+func embeddedSchema(v any) (*jsonschema.Schema, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("picoschema: jsonschema value %v is not an object", v)
+	}
+	return mapToJSONSchema(m)
+}
+
+// embeddedSchema is like the function embeddedSchema,
+// but also resolves the references in the result.
+func (p *parser) embeddedSchema(v any) (*jsonschema.Schema, error) {
+	s, err := embeddedSchema(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.resolveRefs(s); err != nil {
+		return nil, err
+	}
+	if err := p.mixedEnums(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// resolveRefs resolves the references in s, which is written as JSON
+// Schema, that name schemas known to the resolver, as scalar type
+// names are resolved. A reference is replaced by the schema it names
+// unless it has keywords of its own other than a description, in
+// which case the schema is added to its allOf. References within the
+// document, and those the resolver does not know, are kept.
+func (p *parser) resolveRefs(s *jsonschema.Schema) error {
+	if p.resolver == nil {
+		return nil
+	}
+	var err error
+	walkSchema(s, "", func(sub *jsonschema.Schema, _ string) bool {
+		if err != nil {
+			return false
+		}
+		if sub.Ref == "" || strings.HasPrefix(sub.Ref, "#") {
+			return true
+		}
+		// A ref into a schema, as in "customer#/properties/name",
+		// refers into its entry under $defs.
+		name, frag, _ := strings.Cut(sub.Ref, "#")
+		r, rerr := p.resolve(name, frag)
+		if errors.Is(rerr, ErrSchemaNotFound) {
+			return true
+		}
+		if rerr != nil {
+			err = fmt.Errorf("picoschema: resolving $ref %q: %w", sub.Ref, rerr)
+			return false
+		}
+		desc := sub.Description
+		sub.Ref, sub.Description = "", ""
+		if len(schemaKeywords(sub)) > 0 {
+			sub.Description = desc
+			sub.AllOf = append(sub.AllOf, r)
+			return false
+		}
+		*sub = *r
+		if desc != "" {
+			sub.Description = desc
+		}
+		return false
+	})
+	return err
+}
+
+// resolve returns the schema the resolver gives for name or,
+// with WithNamedRefs, a reference to it in the definitions.
+// A non-empty frag, a JSON Pointer, refers into the schema.
+func (p *parser) resolve(name, frag string) (*jsonschema.Schema, error) {
+	s, err := p.resolver.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if frag == "" && !p.namedRefs && !hasLocalRefs(s) && !p.resolving[name] {
+		c := cloneSchema(s)
+		if p.sources {
+			setSource(c, name)
+		}
+		if p.resolving == nil {
+			p.resolving = make(map[string]bool)
+		}
+		p.resolving[name] = true
+		defer delete(p.resolving, name)
+		if err := p.resolveRefs(c); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	// A schema that refers to itself, as with "$self", or that is
+	// referred into cannot be copied, so it is put under $defs even
+	// without WithNamedRefs, with its local references made relative
+	// to its entry.
+	ref := "#/$defs/" + escapePointer(name)
+	if p.defs == nil {
+		p.defs = make(jsonschema.Definitions)
+	}
+	if _, ok := p.defs[name]; !ok {
+		c := cloneSchema(s)
+		walkSchema(c, "", func(sub *jsonschema.Schema, _ string) bool {
+			if rest, ok := strings.CutPrefix(sub.Ref, "#"); ok {
+				sub.Ref = ref + rest
+			}
+			return true
+		})
+		if p.sources {
+			setSource(c, name)
+		}
+		// The entry is added first, so that schemas
+		// referring back to it resolve to it.
+		p.defs[name] = c
+		if err := p.resolveRefs(c); err != nil {
+			return nil, err
+		}
+	}
+	if frag != "" {
+		if _, ok := resolvePointer(p.defs[name], frag); !ok || !strings.HasPrefix(frag, "/") {
+			return nil, fmt.Errorf("fragment %q does not resolve", frag)
+		}
+	}
+	return &jsonschema.Schema{Ref: ref + frag}, nil
+}
+
+// hasLocalRefs reports whether s or any of its subschemas
+// has a reference within its own document.
+func hasLocalRefs(s *jsonschema.Schema) bool {
+	found := false
+	walkSchema(s, "", func(sub *jsonschema.Schema, _ string) bool {
+		found = found || strings.HasPrefix(sub.Ref, "#")
+		return !found
+	})
+	return found
+}
+
+// selfType is the type name that refers to the root of the schema,
+// so that recursive structures can be written, as in
+//
+//	name: string
+//	children?(array): $self
+//
+// It always means the root of the document it is written in, even
+// within a nested object or a $defs entry: in
+//
+//	tree:
+//	  children?(array): $self
+//
+// the children are documents of the whole schema, not trees. Write
+// the nested object as a $defs entry and refer to it by name to make
+// it recursive on its own. A document imported through a resolver
+// keeps its own root.
+const selfType = "$self"
 
-	"github.com/invopop/jsonschema"
-	orderedmap "github.com/wk8/go-ordered-map/v2"
-)
+// scalar returns the schema for the scalar type name typ. Names that
+// are not built in, defined in the $defs section or registered with
+// RegisterScalar are looked up with the resolver, if there is one.
+func (p *parser) scalar(typ string) (*jsonschema.Schema, error) {
+	if p.lenientScalars {
+		typ = strings.TrimSpace(typ)
+		if lower := strings.ToLower(typ); slices.Contains(builtinScalars, lower) {
+			typ = lower
+		}
+	}
+	switch typ {
+	case "string", "boolean", "null", "number", "integer":
+		return &jsonschema.Schema{Type: typ}, nil
+	case "any":
+		return &jsonschema.Schema{}, nil
+	case selfType:
+		return &jsonschema.Schema{Ref: "#"}, nil
+	}
+	if p.local[typ] {
+		return &jsonschema.Schema{Ref: "#/" + defsKey + "/" + escapePointer(typ)}, nil
+	}
+	if s, ok := lookupScalar(typ); ok {
+		return s, nil
+	}
+	if p.resolver != nil {
+		s, err := p.resolve(typ, "")
+		if err == nil {
+			return s, nil
+		}
+		if !errors.Is(err, ErrSchemaNotFound) {
+			return nil, fmt.Errorf("picoschema: resolving type %q: %w", typ, err)
+		}
+	}
+	if p.lenientScalars {
+		return &jsonschema.Schema{}, nil
+	}
+	return nil, fmt.Errorf("picoschema: unsupported scalar type %q", typ)
+}
 
-// ToJSONSchema turns picoschema input into a JSONSchema.
-// The val parameter is the result of parsing YAML into an value of type any.
-// picoschema is loosely documented at docs/dotprompt.md.
-func ToJSONSchema(val any) (*jsonschema.Schema, error) {
-	if val == nil {
-		return nil, nil
+// setAssertions checks the assertion expressions in v, the value of an
+// "(assert)" key, and records them in the assert annotation of s.
+func setAssertions(s *jsonschema.Schema, v any) error {
+	srcs, err := assertionSources(v)
+	if err != nil {
+		return fmt.Errorf("picoschema: %w", err)
+	}
+	as := make([]any, 0, len(srcs))
+	for _, src := range srcs {
+		if _, err := compileAssertion(src); err != nil {
+			return fmt.Errorf("picoschema: %w", err)
+		}
+		as = append(as, src)
 	}
+	setExtra(s, assertKey, as)
+	return nil
+}
 
-	if m, ok := val.(map[string]any); ok {
-		// If we decoded to something that looks like it might
-		// be a JSON schema, treat it as a JSON schema.
-		switch m["type"] {
-		case "string", "boolean", "null", "number", "integer", "object", "array":
-			return mapToJSONSchema(m)
+// parseEnum returns an enum schema for the YAML list vals.
+// A member written as a single-key map declares the key as a value,
+// with its description if the key maps to a string and its aliases if
+// it maps to a list, as in
+//
+//	status(enum): [{ok: all good}, {error: something failed}]
+//	country(enum): [{US: [USA, United States]}, CA]
+//
+// A member written as a map with the key "$value" declares that value,
+// with its aliases under "$aliases" and its description under
+// "$description", so that it can have both. Other maps are errors;
+// enums of objects are written with the type jsonschema. Other
+// members are values as written.
+func parseEnum(vals []any) (*jsonschema.Schema, error) {
+	ret := &jsonschema.Schema{Enum: make([]any, 0, len(vals))}
+	aliases := make(map[string]any)
+	descs := make(map[string]any)
+	for _, v := range vals {
+		e, ok, err := parseEnumEntry(v)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			ret.Enum = append(ret.Enum, v)
+			continue
+		}
+		ret.Enum = append(ret.Enum, e.value)
+		if e.aliases != nil {
+			aliases[e.value] = e.aliases
 		}
+		if e.desc != "" {
+			descs[e.value] = e.desc
+		}
+	}
+	if len(aliases) > 0 {
+		setExtra(ret, enumAliasesKey, aliases)
+	}
+	if len(descs) > 0 {
+		setExtra(ret, enumDescriptionsKey, descs)
+	}
+	return ret, nil
+}
 
-		if p, ok := m["properties"]; ok {
-			if _, ok := p.(map[string]any); ok {
-				s, err := mapToJSONSchema(m)
-				if err != nil {
-					return nil, err
-				}
-				s.Type = "object"
-				return s, nil
+// mixedEnums applies the mixed enum policy to s and its subschemas.
+func (p *parser) mixedEnums(s *jsonschema.Schema) error {
+	var err error
+	walkSchema(s, "", func(sub *jsonschema.Schema, _ string) bool {
+		if err == nil {
+			err = p.mixedEnum(sub)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// mixedEnum applies the mixed enum policy to the enum of s.
+func (p *parser) mixedEnum(s *jsonschema.Schema) error {
+	kinds := enumKinds(s.Enum)
+	if len(kinds) < 2 || p.mixedEnumPolicy == MixedEnumsKeep {
+		return nil
+	}
+	if p.mixedEnumPolicy == MixedEnumsReject || slices.Contains(kinds, "object") || slices.Contains(kinds, "array") {
+		return fmt.Errorf("picoschema: enum %v mixes %s members", s.Enum, strings.Join(kinds, " and "))
+	}
+	enum := make([]any, 0, len(s.Enum))
+	for _, m := range s.Enum {
+		if m != nil {
+			if _, ok := m.(string); !ok {
+				m = jsonText(m)
 			}
 		}
+		if !slices.Contains(enum, m) {
+			enum = append(enum, m)
+		}
 	}
+	s.Enum = enum
+	return nil
+}
 
-	return parsePico(val)
+// enumKinds returns the sorted JSON types of the members of enum,
+// other than null, counting integers as numbers.
+func enumKinds(enum []any) []string {
+	var kinds []string
+	for _, m := range enum {
+		k := jsonType(m)
+		if k == "integer" {
+			k = "number"
+		}
+		if k != "null" && !slices.Contains(kinds, k) {
+			kinds = append(kinds, k)
+		}
+	}
+	slices.Sort(kinds)
+	return kinds
 }
 
-// parsePico parses picoschema from the result of the YAML parser.
-func parsePico(val any) (*jsonschema.Schema, error) {
-	switch val := val.(type) {
-	default:
-		return nil, fmt.Errorf("picoschema: value %v of type %[1]T is not an object, slice or string", val)
+// The keys of an enum member that declares a value with its
+// aliases or description.
+const (
+	enumValueMarker       = "$value"
+	enumAliasesMarker     = "$aliases"
+	enumDescriptionMarker = "$description"
+)
 
-	case string:
-		typ, desc, found := strings.Cut(val, ",")
-		switch typ {
-		case "string", "boolean", "null", "number", "integer", "any":
+// An enumEntry is an enum value with its aliases and description.
+type enumEntry struct {
+	value   string
+	aliases []any
+	desc    string
+}
+
+// parseEnumEntry reports whether v is an enum member written as a map,
+// and if so returns its parts.
+func parseEnumEntry(v any) (enumEntry, bool, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return enumEntry{}, false, nil
+	}
+	val, ok := m[enumValueMarker]
+	if !ok {
+		return shortEnumEntry(m)
+	}
+	var e enumEntry
+	if e.value, ok = val.(string); !ok {
+		return enumEntry{}, false, fmt.Errorf("picoschema: enum %s %v is not a string", enumValueMarker, val)
+	}
+	for k, x := range m {
+		switch k {
+		case enumValueMarker:
+		case enumAliasesMarker:
+			if e.aliases, ok = x.([]any); !ok {
+				return enumEntry{}, false, fmt.Errorf("picoschema: enum %s of %q is not a list", k, e.value)
+			}
+		case enumDescriptionMarker:
+			desc, ok := x.(string)
+			if !ok {
+				return enumEntry{}, false, fmt.Errorf("picoschema: enum %s of %q is not a string", k, e.value)
+			}
+			e.desc = strings.TrimSpace(desc)
 		default:
-			return nil, fmt.Errorf("picoschema: unsupported scalar type %q", typ)
-		}
-		if typ == "any" {
-			typ = ""
-		}
-		ret := &jsonschema.Schema{
-			Type: typ,
+			return enumEntry{}, false, fmt.Errorf("picoschema: unknown key %q in enum member %q", k, e.value)
 		}
-		if found {
-			ret.Description = strings.TrimSpace(desc)
+	}
+	return e, true, nil
+}
+
+// shortEnumEntry returns the parts of m, an enum member written as a
+// single-key map without "$value".
+func shortEnumEntry(m map[string]any) (enumEntry, bool, error) {
+	if len(m) == 1 {
+		for k, x := range m {
+			switch x := x.(type) {
+			case string:
+				return enumEntry{value: k, desc: strings.TrimSpace(x)}, true, nil
+			case []any:
+				return enumEntry{value: k, aliases: x}, true, nil
+			}
 		}
-		return ret, nil
+	}
+	return enumEntry{}, false, fmt.Errorf("picoschema: enum member %s is not a value with a description or aliases; write enums of objects with the type jsonschema", jsonText(m))
+}
 
-	case []any: // assume enum
-		return &jsonschema.Schema{Enum: val}, nil
+// A parenthetical is the parsed form of the "(type, attributes..., description)"
+// suffix of a property key. The type and the attributes are optional.
+type parenthetical struct {
+	typ      string
+	valueTyp string // the type of a wildcard's values
+	attrs    []attribute
+	desc     string
+	hasDesc  bool
+}
 
-	case map[string]any:
-		ret := &jsonschema.Schema{
-			Type:                 "object",
-			Properties:           orderedmap.New[string, *jsonschema.Schema](),
-			AdditionalProperties: jsonschema.FalseSchema,
+// An attribute is a "key=value" item of a parenthetical,
+// or a flag such as "trim" that has no value.
+type attribute struct {
+	key, value string
+	flag       bool
+}
+
+// parseAttribute parses s as an attribute. Only the keys of
+// attributeSetters make attributes, so that a description such as
+// "x=y coordinates" is not taken for one. A flag is one of the names
+// of flagSetters, optionally marked by a leading "+"; any other word
+// with the marker is an unknown flag.
+func parseAttribute(s string) (attribute, bool) {
+	s = strings.TrimSpace(s)
+	if flag, ok := strings.CutPrefix(s, flagMarker); ok {
+		return attribute{key: flag, flag: true}, true
+	}
+	if _, ok := flagSetters[s]; ok {
+		return attribute{key: s, flag: true}, true
+	}
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if _, known := attributeSetters[key]; !ok || !known {
+		return attribute{}, false
+	}
+	return attribute{key: key, value: strings.TrimSpace(value)}, true
+}
+
+// parseParenthetical parses the text between the parentheses of a property key.
+// The first comma-separated item is the type, unless it is an attribute.
+// Attributes follow, and the first item that is not an attribute starts
+// the description, which runs to the end and may contain commas.
+// Commas inside brackets, braces, parentheses and double quotes do not
+// separate items, so that values such as "pattern=^[A-Z]{2,3}$" and
+// "default=[1,2]" are read whole.
+func parseParenthetical(s string) parenthetical {
+	var pt parenthetical
+	items := splitItems(s)
+	i := 0
+	if _, ok := parseAttribute(items[0]); !ok {
+		pt.typ = strings.TrimSpace(items[0])
+		i = 1
+	}
+	if pt.typ == "*" && i < len(items) {
+		if typ := strings.TrimSpace(items[i]); typ != "*" && isParenType(typ) {
+			pt.valueTyp = typ
+			i++
+		}
+	}
+	for ; i < len(items); i++ {
+		a, ok := parseAttribute(items[i])
+		if !ok {
+			break
 		}
-		for k, v := range val {
-			name, typ, found := strings.Cut(k, "(")
-			propertyName, isOptional := strings.CutSuffix(name, "?")
-			if name != "" && !isOptional {
-				ret.Required = append(ret.Required, propertyName)
+		pt.attrs = append(pt.attrs, a)
+	}
+	if i < len(items) {
+		pt.desc = strings.TrimSpace(strings.Join(items[i:], ","))
+		pt.hasDesc = true
+	}
+	return pt
+}
+
+// splitItems splits s at the commas outside brackets, braces,
+// parentheses and double quotes. A backslash escapes the next byte.
+func splitItems(s string) []string {
+	var items []string
+	depth, start, quoted := 0, 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[' || c == '{' || c == '(':
+			depth++
+		case c == ']' || c == '}' || c == ')':
+			if depth > 0 {
+				depth--
 			}
+		case c == ',' && depth == 0:
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
 
-			property, err := parsePico(v)
-			if err != nil {
-				return nil, err
+// attributeSetters maps each attribute key to a function applying it to a schema.
+var attributeSetters = map[string]func(s *jsonschema.Schema, value string) error{
+	"audience": func(s *jsonschema.Schema, value string) error {
+		var auds []any
+		for _, a := range strings.Split(value, "|") {
+			if a = strings.TrimSpace(a); a != "" {
+				auds = append(auds, a)
 			}
+		}
+		if len(auds) == 0 {
+			return errors.New("empty audience")
+		}
+		setExtra(s, audiencesKey, auds)
+		return nil
+	},
+	"minimum":          numberAttribute(func(s *jsonschema.Schema) *json.Number { return &s.Minimum }),
+	"maximum":          numberAttribute(func(s *jsonschema.Schema) *json.Number { return &s.Maximum }),
+	"exclusiveMinimum": numberAttribute(func(s *jsonschema.Schema) *json.Number { return &s.ExclusiveMinimum }),
+	"exclusiveMaximum": numberAttribute(func(s *jsonschema.Schema) *json.Number { return &s.ExclusiveMaximum }),
+	"multipleOf":       numberAttribute(func(s *jsonschema.Schema) *json.Number { return &s.MultipleOf }),
+	"minLength":        countAttribute(func(s *jsonschema.Schema) **uint64 { return &s.MinLength }),
+	"maxLength":        countAttribute(func(s *jsonschema.Schema) **uint64 { return &s.MaxLength }),
+	"minItems":         countAttribute(func(s *jsonschema.Schema) **uint64 { return &s.MinItems }),
+	"maxItems":         countAttribute(func(s *jsonschema.Schema) **uint64 { return &s.MaxItems }),
+	"minProperties":    countAttribute(func(s *jsonschema.Schema) **uint64 { return &s.MinProperties }),
+	"maxProperties":    countAttribute(func(s *jsonschema.Schema) **uint64 { return &s.MaxProperties }),
+	"default": func(s *jsonschema.Schema, value string) error {
+		if s.Default != nil {
+			return errors.New("default given twice")
+		}
+		d, err := defaultValue(s.Type, value)
+		if err != nil {
+			return err
+		}
+		s.Default = d
+		return nil
+	},
+	"example": func(s *jsonschema.Schema, value string) error {
+		e, err := defaultValue(s.Type, value)
+		if err != nil {
+			return err
+		}
+		s.Examples = append(s.Examples, e)
+		return nil
+	},
+	"pattern": func(s *jsonschema.Schema, value string) error {
+		if _, err := regexp.Compile(value); err != nil {
+			return err
+		}
+		s.Pattern = value
+		return nil
+	},
+	"format": func(s *jsonschema.Schema, value string) error {
+		if value == "" {
+			return errors.New("empty value")
+		}
+		s.Format = value
+		return nil
+	},
+	"sinceVersion":     stringAttribute(sinceVersionKey),
+	"removedInVersion": stringAttribute(removedInVersionKey),
+	"renamedFrom":      stringAttribute(renamedFromKey),
+	"unit":             stringAttribute(unitKey),
+	"discriminator":    discriminatorAttribute,
+	"protoField":       protoFieldAttribute,
+	"lintDisable":      lintDisableAttribute,
+}
+
+// lintDisableAttribute applies the lintDisable attribute, which lists
+// the lint rules to suppress for a schema and its subschemas, separated
+// by "|", or is "all", as in
+//
+//	legacyCode(string, lintDisable=missing-description|property-naming):
+func lintDisableAttribute(s *jsonschema.Schema, value string) error {
+	var ids []any
+	for _, id := range strings.Split(value, "|") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		if id != "all" && !slices.Contains(LintRules(), id) {
+			return fmt.Errorf("unknown lint rule %q", id)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return errors.New("no lint rules")
+	}
+	setExtra(s, lintDisableKey, ids)
+	return nil
+}
 
-			if !found {
-				ret.Properties.Set(propertyName, property)
+// discriminatorKey is the OpenAPI keyword naming the property
+// whose value tells the branches of a union apart.
+const discriminatorKey = "discriminator"
+
+// discriminatorAttribute applies the discriminator attribute of a
+// oneOf union, which names the property that tells its branches apart,
+// as in
+//
+//	event(oneOf, discriminator=type):
+//	  - {type(const): created, id: string}
+//	  - {type(const): deleted, id: string, reason?: string}
+//
+// Each branch must be an object with the property, whose value is a
+// const distinct from those of the other branches, and the property is
+// made required in each. The union also gets an OpenAPI discriminator.
+// Branches that are refs, as to $defs entries, are checked once the
+// whole schema is converted; see checkDiscriminator.
+func discriminatorAttribute(s *jsonschema.Schema, value string) error {
+	if value == "" {
+		return errors.New("empty value")
+	}
+	if len(s.OneOf) == 0 {
+		return errors.New("not a oneOf union")
+	}
+	setExtra(s, discriminatorKey, map[string]any{"propertyName": value})
+	return checkDiscriminator(s, value, nil)
+}
+
+// checkDiscriminator checks the branches of s, a union with the
+// discriminator property name, as discriminatorAttribute describes, and
+// makes the property required in each. Local refs among the branches
+// are resolved within root; if root is nil, they are skipped. A ref
+// whose target does not require the property gets a required list of
+// its own, so that the target is left as it is for its other uses.
+func checkDiscriminator(s *jsonschema.Schema, name string, root *jsonschema.Schema) error {
+	var seen []any
+	for i, b := range s.OneOf {
+		t := b
+		if ptr, ok := strings.CutPrefix(b.Ref, "#"); ok {
+			if root == nil {
 				continue
 			}
+			if t, ok = resolvePointer(root, ptr); !ok {
+				return fmt.Errorf("branch %d: $ref %q does not resolve", i, b.Ref)
+			}
+		}
+		var prop *jsonschema.Schema
+		if t.Properties != nil {
+			prop = t.Properties.Value(name)
+		}
+		if prop == nil {
+			return fmt.Errorf("branch %d has no property %q", i, name)
+		}
+		c := prop.Const
+		if c == nil && len(prop.Enum) == 1 {
+			c = prop.Enum[0]
+		}
+		if c == nil {
+			return fmt.Errorf("branch %d: property %q is not a const", i, name)
+		}
+		if slices.ContainsFunc(seen, func(v any) bool { return reflect.DeepEqual(v, c) }) {
+			return fmt.Errorf("branch %d: property %q has the value %v of an earlier branch", i, name, c)
+		}
+		seen = append(seen, c)
+		if !slices.Contains(t.Required, name) && !slices.Contains(b.Required, name) {
+			b.Required = append(b.Required, name)
+		}
+	}
+	return nil
+}
 
-			typ = strings.TrimSuffix(typ, ")")
-			typ, desc, found := strings.Cut(strings.TrimSuffix(typ, ")"), ",")
-			switch typ {
-			case "array":
-				property = &jsonschema.Schema{
-					Type:  "array",
-					Items: property,
-				}
-			case "object":
-				// Use property unchanged.
-			case "enum":
-				if property.Enum == nil {
-					return nil, fmt.Errorf("picoschema: enum value %v is not an array", property)
-				}
-				if isOptional {
-					property.Enum = append(property.Enum, nil)
-				}
+// checkDiscriminators checks the unions given a discriminator attribute
+// in p.unions whose branches include refs, resolving them within root.
+func (p *parser) checkDiscriminators(root *jsonschema.Schema) error {
+	for _, s := range p.unions {
+		name := s.Extras[discriminatorKey].(map[string]any)["propertyName"].(string)
+		if err := checkDiscriminator(s, name, root); err != nil {
+			return fmt.Errorf("picoschema: discriminator %q: %w", name, err)
+		}
+	}
+	return nil
+}
 
-			case "*":
-				ret.AdditionalProperties = property
-				continue
-			default:
-				return nil, fmt.Errorf("picoschema: parenthetical type %q is none of %q", typ,
-					[]string{"object", "array", "enum", "*"})
+// defaultValue returns the value of a default attribute written as
+// value for a schema of type typ. Strings are written bare or quoted
+// as in JSON; values of other types, and of schemas with no type, are
+// written as JSON, except that a bare string is read as a string.
+func defaultValue(typ, value string) (any, error) {
+	switch typ {
+	case "string":
+		var str string
+		if err := json.Unmarshal([]byte(value), &str); err == nil {
+			return str, nil
+		}
+		return value, nil
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		return int(n), nil
+	case "number":
+		var f float64
+		if err := json.Unmarshal([]byte(value), &f); err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return f, nil
+	case "boolean":
+		switch value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", value)
+	}
+	var v any
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return value, nil
+	}
+	return v, nil
+}
 
-			}
+// numberAttribute returns an attribute setter that stores its value,
+// which must be a number, in the keyword field returns.
+func numberAttribute(field func(*jsonschema.Schema) *json.Number) func(*jsonschema.Schema, string) error {
+	return func(s *jsonschema.Schema, value string) error {
+		var f float64
+		if err := json.Unmarshal([]byte(value), &f); err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field(s) = json.Number(value)
+		return nil
+	}
+}
+
+// countAttribute returns an attribute setter that stores its value,
+// which must be a non-negative integer, in the keyword field returns.
+func countAttribute(field func(*jsonschema.Schema) **uint64) func(*jsonschema.Schema, string) error {
+	return func(s *jsonschema.Schema, value string) error {
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a non-negative integer", value)
+		}
+		*field(s) = &n
+		return nil
+	}
+}
 
-			if found {
-				property.Description = strings.TrimSpace(desc)
+// stringAttribute returns an attribute setter that stores its
+// non-empty value in the extension keyword key.
+func stringAttribute(key string) func(*jsonschema.Schema, string) error {
+	return func(s *jsonschema.Schema, value string) error {
+		if value == "" {
+			return errors.New("empty value")
+		}
+		setExtra(s, key, value)
+		return nil
+	}
+}
+
+// flagMarker optionally marks a flag in a parenthetical, as in
+// "(string, +trim)".
+const flagMarker = "+"
+
+// flagSetters maps each flag, without its marker, to a function
+// applying it to a schema.
+var flagSetters = map[string]func(s *jsonschema.Schema) error{
+	"trim":                normalizeFlag("trim"),
+	"lowercase":           normalizeFlag("lowercase"),
+	"uppercase":           normalizeFlag("uppercase"),
+	"collapse-whitespace": normalizeFlag("collapse-whitespace"),
+	"sealed":              strictnessFlag(false),
+	"open":                strictnessFlag(true),
+	"deprecated": func(s *jsonschema.Schema) error {
+		s.Deprecated = true
+		return nil
+	},
+	"readOnly": func(s *jsonschema.Schema) error {
+		if s.WriteOnly {
+			return errors.New("also writeOnly")
+		}
+		s.ReadOnly = true
+		return nil
+	},
+	"writeOnly": func(s *jsonschema.Schema) error {
+		if s.ReadOnly {
+			return errors.New("also readOnly")
+		}
+		s.WriteOnly = true
+		return nil
+	},
+}
+
+// normalizeFlag returns a flag setter that appends the string
+// normalization name to the normalize annotation.
+func normalizeFlag(name string) func(*jsonschema.Schema) error {
+	return func(s *jsonschema.Schema) error {
+		ns, _ := s.Extras[normalizeKey].([]any)
+		setExtra(s, normalizeKey, append(ns, name))
+		return nil
+	}
+}
+
+// strictnessFlag returns a flag setter that says whether an object
+// accepts properties it does not declare, overriding the
+// WithAdditionalProperties option for that object alone, as in
+//
+//	address(sealed):
+//	  city: string
+//
+// On an array, the flag applies to the objects it contains.
+func strictnessFlag(allow bool) func(*jsonschema.Schema) error {
+	return func(s *jsonschema.Schema) error {
+		if s.Type == "array" && s.Items != nil {
+			s = s.Items
+		}
+		if s.Type != "object" || s.Properties == nil {
+			return errors.New("not an object")
+		}
+		if _, isBool := boolSchema(s.AdditionalProperties); s.AdditionalProperties != nil && !isBool {
+			return errors.New("object has a wildcard")
+		}
+		s.AdditionalProperties = jsonschema.FalseSchema
+		if allow {
+			s.AdditionalProperties = nil
+		}
+		return nil
+	}
+}
+
+// applyAttributes applies attrs to s. A union given a discriminator
+// whose branches include refs is recorded in p.unions.
+func (p *parser) applyAttributes(s *jsonschema.Schema, attrs []attribute) error {
+	for _, a := range attrs {
+		if a.flag {
+			set, ok := flagSetters[a.key]
+			if !ok {
+				return fmt.Errorf("unknown flag %q", flagMarker+a.key)
 			}
+			if err := set(s); err != nil {
+				return fmt.Errorf("flag %q: %w", a.key, err)
+			}
+			continue
+		}
+		set, ok := attributeSetters[a.key]
+		if !ok {
+			return fmt.Errorf("unknown attribute %q", a.key)
+		}
+		if err := set(s, a.value); err != nil {
+			return fmt.Errorf("attribute %q: %w", a.key, err)
+		}
+	}
+	// The default and examples, whether given by attributes or in the
+	// long form, must satisfy the constraints the attributes add.
+	source := func(key string) string {
+		if slices.ContainsFunc(attrs, func(a attribute) bool { return a.key == key }) {
+			return fmt.Sprintf("attribute %q", key)
+		}
+		return key
+	}
+	if s.Default != nil && len(attrs) > 0 {
+		if vs := checkLocal(s.Default, s); len(vs) > 0 {
+			return fmt.Errorf("%s: %v is not valid: %s", source("default"), s.Default, vs[0].Message)
+		}
+	}
+	if len(s.Examples) > 0 && len(attrs) > 0 {
+		if err := checkExamples(s); err != nil {
+			return fmt.Errorf("%s: %w", source("example"), err)
+		}
+	}
+	discriminated := slices.ContainsFunc(attrs, func(a attribute) bool { return a.key == "discriminator" })
+	if discriminated && slices.ContainsFunc(s.OneOf, func(b *jsonschema.Schema) bool { return strings.HasPrefix(b.Ref, "#") }) {
+		p.unions = append(p.unions, s)
+	}
+	return nil
+}
 
-			ret.Properties.Set(propertyName, property)
+// checkExamples reports the first example of s that s does not accept.
+func checkExamples(s *jsonschema.Schema) error {
+	for _, e := range s.Examples {
+		if vs := checkLocal(e, s); len(vs) > 0 {
+			return fmt.Errorf("%v is not valid: %s", e, vs[0].Message)
 		}
+	}
+	return nil
+}
+
+// setExtra sets the extension keyword key of s to v.
+func setExtra(s *jsonschema.Schema, key string, v any) {
+	if s.Extras == nil {
+		s.Extras = make(map[string]any)
+	}
+	s.Extras[key] = v
+}
+
+// deleteExtra removes the extension keyword key from s.
+// It leaves Extras nil if it becomes empty, so that a schema
+// with no other keywords still marshals as true.
+func deleteExtra(s *jsonschema.Schema, key string) {
+	delete(s.Extras, key)
+	if len(s.Extras) == 0 {
+		s.Extras = nil
+	}
+}
+
+// tupleItems returns a copy of m, a JSON Schema in decoded form,
+// with draft-07 tuple keywords rewritten as in JSON Schema 2020-12:
+// a list of items becomes prefixItems, and additionalItems becomes
+// items. Without a list of items, additionalItems has no effect and
+// is removed.
+func tupleItems(m map[string]any) (map[string]any, error) {
+	ret := maps.Clone(m)
+	delete(ret, "additionalItems")
+	list, ok := m["items"].([]any)
+	if !ok {
 		return ret, nil
 	}
+	if _, ok := m["prefixItems"]; ok {
+		return nil, errors.New("picoschema: found both prefixItems and a list of items")
+	}
+	ret["prefixItems"] = list
+	delete(ret, "items")
+	if ai, ok := m["additionalItems"]; ok {
+		ret["items"] = ai
+	}
+	return ret, nil
+}
+
+// schemaValue converts v, a YAML value written where JSON Schema
+// expects a schema, which is an object or a boolean. keepUnknown is
+// as for decodeSchema.
+func schemaValue(v any, keepUnknown bool) (*jsonschema.Schema, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		return decodeSchema(v, keepUnknown)
+	case bool:
+		if v {
+			return jsonschema.TrueSchema, nil
+		}
+		return jsonschema.FalseSchema, nil
+	}
+	return nil, fmt.Errorf("picoschema: found type %T for a schema, want %T or %T", v, make(map[string]any), true)
 }
 
 // mapToJSONSchema converts a YAML value to a JSONSchema.
+// The draft-07 forms of tuples, a list of items followed by
+// additionalItems, are converted to prefixItems and items, and
+// definitions, and references to them, are converted to $defs.
+// Keywords other than those of JSON Schema and extension keywords
+// starting with "x-" are errors.
 func mapToJSONSchema(m map[string]any) (*jsonschema.Schema, error) {
+	return decodeSchema(m, false)
+}
+
+// decodeSchema is like mapToJSONSchema, but if keepUnknown is set it
+// keeps keywords it does not know as extension keywords, as a JSON
+// Schema document may have keywords of other vocabularies.
+func decodeSchema(m map[string]any, keepUnknown bool) (*jsonschema.Schema, error) {
 	var ret jsonschema.Schema
 
+	if _, ok := m["items"].([]any); ok || m["additionalItems"] != nil {
+		var err error
+		if m, err = tupleItems(m); err != nil {
+			return nil, err
+		}
+	}
+	if defs, ok := m["definitions"]; ok {
+		if _, ok := m["$defs"]; ok {
+			return nil, errors.New("picoschema: found both $defs and definitions")
+		}
+		m = maps.Clone(m)
+		m["$defs"] = defs
+		delete(m, "definitions")
+	}
+
 	rval := reflect.ValueOf(&ret)
 	rtype := rval.Type().Elem()
 	numField := rtype.NumField()
@@ -162,15 +1945,27 @@ func mapToJSONSchema(m map[string]any) (*jsonschema.Schema, error) {
 
 	for k, v := range m {
 		rf, ok := jsonMap[k]
+		if !ok && (keepUnknown || strings.HasPrefix(k, "x-")) {
+			// Extension keywords, such as annotations read by
+			// the linter, are kept as they are.
+			setExtra(&ret, k, v)
+			continue
+		}
 		if !ok {
 			return nil, fmt.Errorf("picoschema: unrecognized JSON schema field name %q", k)
 		}
+		if dm, isMap := v.(map[string]any); isMap && k == "description" {
+			if err := setLocalizedDescription(&ret, dm); err != nil {
+				return nil, err
+			}
+			continue
+		}
 
 		switch rf.Type() {
 		case reflect.TypeFor[any]():
 			rf.Set(reflect.ValueOf(v))
 
-		case reflect.TypeFor[string]():
+		case reflect.TypeFor[string](), reflect.TypeFor[jsonschema.ID]():
 			str, ok := v.(string)
 			if !ok {
 				return nil, fmt.Errorf("picoschema: found type %T for field %q, want %T", v, k, "")
@@ -179,11 +1974,17 @@ func mapToJSONSchema(m map[string]any) (*jsonschema.Schema, error) {
 
 		case reflect.TypeFor[*uint64]():
 			rf.Set(reflect.New(reflect.TypeFor[uint64]()))
-			switch v.(type) {
+			switch v := v.(type) {
 			case uint, uint8, uint16, uint32, uint64, uintptr:
 				rf.Elem().SetUint(reflect.ValueOf(v).Uint())
 			case int, int8, int16, int32, int64:
 				rf.Elem().SetUint(uint64(reflect.ValueOf(v).Int()))
+			case float64:
+				// JSON decodes every number as float64.
+				if v < 0 || v != math.Trunc(v) {
+					return nil, fmt.Errorf("picoschema: found %v for field %q, want a non-negative integer", v, k)
+				}
+				rf.Elem().SetUint(uint64(v))
 			default:
 				return nil, fmt.Errorf("picoschema: found type %T for field %q, want an integer type", v, k)
 			}
@@ -211,31 +2012,37 @@ func mapToJSONSchema(m map[string]any) (*jsonschema.Schema, error) {
 			rf.Set(reflect.ValueOf(sstrs))
 
 		case reflect.TypeFor[json.Number]():
-			s, ok := v.(string)
-			if !ok {
-				return nil, fmt.Errorf("picoschema: found type %T for field %q, want %T", v, k, "")
+			switch v := v.(type) {
+			case string:
+				rf.SetString(v)
+			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+				rf.SetString(fmt.Sprint(v))
+			default:
+				return nil, fmt.Errorf("picoschema: found type %T for field %q, want a number", v, k)
 			}
-			rf.SetString(s)
 
 		case reflect.TypeFor[*jsonschema.Schema]():
-			m, ok := v.(map[string]any)
-			if !ok {
-				return nil, fmt.Errorf("picoschema: found type %T for field %q, want %T", v, k, make(map[string]any))
-			}
-			schema, err := mapToJSONSchema(m)
+			schema, err := schemaValue(v, keepUnknown)
 			if err != nil {
 				return nil, fmt.Errorf("picoschema: failed to convert field %q: %w", k, err)
 			}
 			rf.Set(reflect.ValueOf(schema))
 
 		case reflect.TypeFor[[]*jsonschema.Schema]():
-			s, ok := v.([]map[string]any)
-			if !ok {
-				return nil, fmt.Errorf("picoschema: found type %T for field %q, want %T", v, k, []map[string]any{})
+			var vs []any
+			switch v := v.(type) {
+			case []any:
+				vs = v
+			case []map[string]any:
+				for _, m := range v {
+					vs = append(vs, m)
+				}
+			default:
+				return nil, fmt.Errorf("picoschema: found type %T for field %q, want %T", v, k, []any{})
 			}
-			schemas := make([]*jsonschema.Schema, 0, len(s))
-			for _, m := range s {
-				schema, err := mapToJSONSchema(m)
+			schemas := make([]*jsonschema.Schema, 0, len(vs))
+			for _, sv := range vs {
+				schema, err := schemaValue(sv, keepUnknown)
 				if err != nil {
 					return nil, fmt.Errorf("picoschema: error in field %q: %w", k, err)
 				}
@@ -249,12 +2056,8 @@ func mapToJSONSchema(m map[string]any) (*jsonschema.Schema, error) {
 				return nil, fmt.Errorf("picoschema: found type %T for field %q, want %T", v, k, make(map[string]any))
 			}
 			om := orderedmap.New[string, *jsonschema.Schema]()
-			for mk, mv := range m {
-				mvm, ok := mv.(map[string]any)
-				if !ok {
-					return nil, fmt.Errorf("picoschema: found type %T for field %q key %q, want %T", mv, k, mk, make(map[string]any))
-				}
-				schema, err := mapToJSONSchema(mvm)
+			for _, mk := range sortedKeys(m) {
+				schema, err := schemaValue(m[mk], keepUnknown)
 				if err != nil {
 					return nil, fmt.Errorf("picoschema: error in field %q key %q: %w", k, mk, err)
 				}
@@ -262,18 +2065,53 @@ func mapToJSONSchema(m map[string]any) (*jsonschema.Schema, error) {
 			}
 			rf.Set(reflect.ValueOf(om))
 
+		case reflect.TypeFor[jsonschema.Definitions](), reflect.TypeFor[map[string]*jsonschema.Schema]():
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("picoschema: found type %T for field %q, want %T", v, k, make(map[string]any))
+			}
+			schemas := reflect.MakeMapWithSize(rf.Type(), len(m))
+			for mk, mv := range m {
+				schema, err := schemaValue(mv, keepUnknown)
+				if err != nil {
+					return nil, fmt.Errorf("picoschema: error in field %q key %q: %w", k, mk, err)
+				}
+				schemas.SetMapIndex(reflect.ValueOf(mk), reflect.ValueOf(schema))
+			}
+			rf.Set(schemas)
+
+		case reflect.TypeFor[[]any]():
+			vs, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("picoschema: found type %T for field %q, want %T", v, k, []any{})
+			}
+			rf.Set(reflect.ValueOf(vs))
+
 		default:
-			return nil, fmt.Errorf("picoschema: unsupported JSONSchema field type %s for field %q", rf.Type(), k)
+			// Other fields, such as dependentRequired, are
+			// converted by way of their JSON encoding.
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("picoschema: field %q: %w", k, err)
+			}
+			if err := json.Unmarshal(data, rf.Addr().Interface()); err != nil {
+				return nil, fmt.Errorf("picoschema: found type %T for field %q, want %s", v, k, rf.Type())
+			}
 		}
 	}
 
+	if name, ok := strings.CutPrefix(ret.Ref, "#/definitions/"); ok {
+		ret.Ref = "#/$defs/" + name
+	}
 	return &ret, nil
 }
 
 // ConvertSchema marshals s to JSON, then unmarshals the result.
+// The required lists are sorted in the result, but not in s.
 func ConvertSchema(s *jsonschema.Schema) (any, error) {
 	// JSON sorts maps but not slices.
 	// jsonschema slices are not sorted consistently.
+	s = cloneSchema(s)
 	sortSchemaSlices(s)
 	data, err := json.Marshal(s)
 	if err != nil {
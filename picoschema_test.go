@@ -15,8 +15,11 @@
 package picoschema
 
 import (
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -25,13 +28,31 @@ import (
 
 // TestPicoschema tests the same cases as picoschema_test.ts.
 func TestPicoschema(t *testing.T) {
+	skip := map[string]bool{
+		"required field":                 true,
+		"nested object in array and out": true,
+	}
+	runYAMLTests(t, "testdata/picoschema_tests.yaml", skip)
+}
+
+// TestExtensions tests picoschema syntax that only the Go package supports.
+func TestExtensions(t *testing.T) {
+	runYAMLTests(t, "testdata/extensions_tests.yaml", nil)
+}
+
+// runYAMLTests runs the conversion test cases in file,
+// skipping those whose description is in skip.
+// A case with a wantErr field expects conversion to fail
+// with an error containing that text.
+func runYAMLTests(t *testing.T, file string, skip map[string]bool) {
 	type test struct {
 		Description string
 		YAML        string
 		Want        map[string]any
+		WantErr     string `yaml:"wantErr"`
 	}
 
-	data, err := os.ReadFile(filepath.FromSlash("testdata/picoschema_tests.yaml"))
+	data, err := os.ReadFile(filepath.FromSlash(file))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -41,11 +62,6 @@ func TestPicoschema(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	skip := map[string]bool{
-		"required field":                 true,
-		"nested object in array and out": true,
-	}
-
 	for _, test := range tests {
 		t.Run(test.Description, func(t *testing.T) {
 			if skip[test.Description] {
@@ -60,6 +76,12 @@ func TestPicoschema(t *testing.T) {
 			val = val.(map[string]any)["schema"]
 
 			schema, err := ToJSONSchema(val)
+			if test.WantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.WantErr) {
+					t.Fatalf("got error %v, want error containing %q", err, test.WantErr)
+				}
+				return
+			}
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -97,3 +119,318 @@ func replaceEmptySchemas(m map[string]any) any {
 	}
 	return m
 }
+
+func TestConstraintAttributes(t *testing.T) {
+	s := mustSchema(t, `
+age(integer, minimum=0, maximum=150): age in years
+price?(number, exclusiveMinimum=0, multipleOf=0.01):
+name(string, minLength=1, maxLength=64, pattern=^[A-Z]): the name
+email?(format=email): string
+tags?(array, minItems=1, maxItems=10, the tags): string
+meta?(object, minProperties=1, maxProperties=5):
+  a?: string
+`)
+	sortSchemaSlices(s)
+	want := map[string]any{
+		"type": "object", "additionalProperties": false, "required": []any{"age", "name"},
+		"properties": map[string]any{
+			"age":   map[string]any{"type": "integer", "minimum": 0.0, "maximum": 150.0, "description": "age in years"},
+			"price": map[string]any{"type": "number", "exclusiveMinimum": 0.0, "multipleOf": 0.01},
+			"name":  map[string]any{"type": "string", "minLength": 1.0, "maxLength": 64.0, "pattern": "^[A-Z]", "description": "the name"},
+			"email": map[string]any{"type": "string", "format": "email"},
+			"tags": map[string]any{
+				"type": "array", "minItems": 1.0, "maxItems": 10.0, "description": "the tags",
+				"items": map[string]any{"type": "string"},
+			},
+			"meta": map[string]any{
+				"type": "object", "additionalProperties": false, "minProperties": 1.0, "maxProperties": 5.0,
+				"properties": map[string]any{"a": map[string]any{"type": "string"}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, schemaJSON(t, s)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWildcardObjectAttributes(t *testing.T) {
+	s := mustSchema(t, `
+(*, minProperties=1, maxProperties=8, minimum=0): number
+ratings(*, enum, maxProperties=3): [good, bad]
+`)
+	want := map[string]any{
+		"type": "object", "minProperties": 1.0, "maxProperties": 8.0,
+		"additionalProperties": map[string]any{"type": "number", "minimum": 0.0},
+		"required":             []any{"ratings"},
+		"properties": map[string]any{
+			"ratings": map[string]any{
+				"type": "object", "maxProperties": 3.0,
+				"additionalProperties": map[string]any{"enum": []any{"good", "bad"}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, schemaJSON(t, s)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestTuple(t *testing.T) {
+	s := mustSchema(t, "point(tuple, x and y): [number, number]")
+	want := map[string]any{
+		"type": "object", "additionalProperties": false, "required": []any{"point"},
+		"properties": map[string]any{
+			"point": map[string]any{
+				"type": "array", "description": "x and y", "minItems": 2.0, "items": false,
+				"prefixItems": []any{map[string]any{"type": "number"}, map[string]any{"type": "number"}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, schemaJSON(t, s)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	for inst, valid := range map[string]bool{
+		`{"point": [1, 2]}`:    true,
+		`{"point": [1]}`:       false,
+		`{"point": [1, 2, 3]}`: false,
+		`{"point": [1, "2"]}`:  false,
+	} {
+		if err := ValidateJSON([]byte(inst), s); (err == nil) != valid {
+			t.Errorf("ValidateJSON(%s) = %v, want valid %t", inst, err, valid)
+		}
+	}
+}
+
+func TestPropertyOrder(t *testing.T) {
+	src := "zip: string\nb?: string\na(integer):\nb2: {y: string, x: string}\n(required): [zip, b2, a]\nc: string\n"
+	for range 10 {
+		s := mustSchema(t, src)
+		var got []string
+		for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+			got = append(got, p.Key)
+		}
+		if want := []string{"a", "b", "b2", "c", "zip"}; !slices.Equal(got, want) {
+			t.Fatalf("properties %q, want %q", got, want)
+		}
+		var nested []string
+		for p := s.Properties.Value("b2").Properties.Oldest(); p != nil; p = p.Next() {
+			nested = append(nested, p.Key)
+		}
+		if want := []string{"x", "y"}; !slices.Equal(nested, want) {
+			t.Fatalf("nested properties %q, want %q", nested, want)
+		}
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	s := mustSchema(t, `
+limit?(integer, default=10): max results
+name?(string, default=anonymous):
+padded?(string, default=" x "):
+ratio?(number, minimum=0, default=0.5):
+verbose?(boolean, default=false):
+size?(enum, default=M): [S, M, L]
+tags?(array, default=[]): string
+`)
+	want := map[string]any{
+		"limit":   10.0,
+		"name":    "anonymous",
+		"padded":  " x ",
+		"ratio":   0.5,
+		"verbose": false,
+		"size":    "M",
+		"tags":    []any{},
+	}
+	got := make(map[string]any)
+	for name, p := range schemaJSON(t, s).(map[string]any)["properties"].(map[string]any) {
+		got[name] = p.(map[string]any)["default"]
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("defaults mismatch (-want, +got):\n%s", diff)
+	}
+
+	for _, src := range []string{
+		"limit?(integer, default=ten):",
+		"limit?(integer, minimum=5, default=1):",
+		"size?(enum, default=XL): [S, M]",
+		"verbose?(boolean, default=yes):",
+	} {
+		var val any
+		if err := yaml.Unmarshal([]byte(src), &val); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ToJSONSchema(val); err == nil || !strings.Contains(err.Error(), `attribute "default"`) {
+			t.Errorf("%s: got error %v, want one about the default", src, err)
+		}
+	}
+}
+
+func TestEmbeddedJSONSchemaFields(t *testing.T) {
+	s := mustSchema(t, `
+payment(jsonschema):
+  $id: urn:payment
+  anyOf:
+    - {type: object, properties: {card: {type: string}}, required: [card]}
+    - {type: object, properties: {iban: {type: string}}, additionalProperties: false}
+  examples: [{card: "4111"}, {iban: DE89}]
+  default: {card: "4111"}
+  dependentRequired: {card: [expiry]}
+  patternProperties: {"^x-": true}
+  $defs: {money: {type: number}}
+  enum: [{card: "4111"}, {iban: DE89}]
+`)
+	want := map[string]any{
+		"$id": "urn:payment",
+		"anyOf": []any{
+			map[string]any{"type": "object", "properties": map[string]any{"card": map[string]any{"type": "string"}}, "required": []any{"card"}},
+			map[string]any{"type": "object", "properties": map[string]any{"iban": map[string]any{"type": "string"}}, "additionalProperties": false},
+		},
+		"examples":          []any{map[string]any{"card": "4111"}, map[string]any{"iban": "DE89"}},
+		"default":           map[string]any{"card": "4111"},
+		"dependentRequired": map[string]any{"card": []any{"expiry"}},
+		"patternProperties": map[string]any{"^x-": true},
+		"$defs":             map[string]any{"money": map[string]any{"type": "number"}},
+		"enum":              []any{map[string]any{"card": "4111"}, map[string]any{"iban": "DE89"}},
+	}
+	got := schemaJSON(t, s).(map[string]any)["properties"].(map[string]any)["payment"]
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	for _, src := range []string{
+		"a(jsonschema): {anyOf: [string]}",
+		"a(jsonschema): {dependentRequired: {b: c}}",
+		"a(jsonschema): {$defs: [b]}",
+	} {
+		var val any
+		if err := yaml.Unmarshal([]byte(src), &val); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ToJSONSchema(val); err == nil {
+			t.Errorf("%s: got nil, want error", src)
+		}
+	}
+}
+
+func TestEmbeddedDraft07Items(t *testing.T) {
+	s := mustSchema(t, `
+point(jsonschema): {type: array, items: [{type: number}, {type: number}], additionalItems: false}
+pair(jsonschema): {type: array, items: [{type: string}], additionalItems: {type: integer}}
+open(jsonschema): {type: array, items: [{type: string}]}
+list(jsonschema): {type: array, items: {type: string}, additionalItems: false}
+none(jsonschema): {type: array, items: false}
+`)
+	number, str := map[string]any{"type": "number"}, map[string]any{"type": "string"}
+	want := map[string]any{
+		"point": map[string]any{"type": "array", "prefixItems": []any{number, number}, "items": false},
+		"pair":  map[string]any{"type": "array", "prefixItems": []any{str}, "items": map[string]any{"type": "integer"}},
+		"open":  map[string]any{"type": "array", "prefixItems": []any{str}},
+		"list":  map[string]any{"type": "array", "items": str},
+		"none":  map[string]any{"type": "array", "items": false},
+	}
+	got := schemaJSON(t, s).(map[string]any)["properties"]
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	var val any
+	if err := yaml.Unmarshal([]byte("a(jsonschema): {items: [{type: string}], prefixItems: [{type: string}]}"), &val); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ToJSONSchema(val); err == nil {
+		t.Error("got nil, want error for both prefixItems and a list of items")
+	}
+}
+
+func TestEmbeddedRefs(t *testing.T) {
+	var r Registry
+	if err := r.Register("customer", mustSchema(t, "name: string")); err != nil {
+		t.Fatal(err)
+	}
+	const src = `
+owner(jsonschema): {$ref: customer, description: who owns it}
+buyer(jsonschema): {$ref: customer, required: [name]}
+seller?(jsonschema): {$ref: supplier}
+self?(jsonschema): {$ref: "#"}
+legacy?(jsonschema):
+  definitions: {id: {type: string}}
+  properties: {id: {$ref: "#/definitions/id"}}
+`
+	var val any
+	if err := yaml.Unmarshal([]byte(src), &val); err != nil {
+		t.Fatal(err)
+	}
+	customer := map[string]any{
+		"type": "object", "additionalProperties": false, "required": []any{"name"},
+		"properties": map[string]any{"name": map[string]any{"type": "string"}},
+	}
+	withDesc := maps.Clone(customer)
+	withDesc["description"] = "who owns it"
+	legacy := map[string]any{
+		"$defs":      map[string]any{"id": map[string]any{"type": "string"}},
+		"properties": map[string]any{"id": map[string]any{"$ref": "#/$defs/id"}},
+	}
+	for _, test := range []struct {
+		name string
+		opts []Option
+		want map[string]any
+	}{
+		{
+			name: "no resolver",
+			want: map[string]any{
+				"owner":  map[string]any{"$ref": "customer", "description": "who owns it"},
+				"buyer":  map[string]any{"$ref": "customer", "required": []any{"name"}},
+				"seller": map[string]any{"$ref": "supplier"},
+				"self":   map[string]any{"$ref": "#"},
+				"legacy": legacy,
+			},
+		},
+		{
+			name: "resolver",
+			opts: []Option{WithResolver(&r)},
+			want: map[string]any{
+				"owner":  withDesc,
+				"buyer":  map[string]any{"allOf": []any{customer}, "required": []any{"name"}},
+				"seller": map[string]any{"$ref": "supplier"},
+				"self":   map[string]any{"$ref": "#"},
+				"legacy": legacy,
+			},
+		},
+		{
+			name: "named refs",
+			opts: []Option{WithResolver(&r), WithNamedRefs(true)},
+			want: map[string]any{
+				"owner":  map[string]any{"$ref": "#/$defs/customer", "description": "who owns it"},
+				"buyer":  map[string]any{"allOf": []any{map[string]any{"$ref": "#/$defs/customer"}}, "required": []any{"name"}},
+				"seller": map[string]any{"$ref": "supplier"},
+				"self":   map[string]any{"$ref": "#"},
+				"legacy": legacy,
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ToJSONSchema(val, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := schemaJSON(t, s).(map[string]any)["properties"]
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConvertSchemaKeepsInput(t *testing.T) {
+	s := mustSchema(t, "a: string\nb: string")
+	s.Required = []string{"b", "a"}
+	got, err := ConvertSchema(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{"a", "b"}; !slices.Equal(got.(map[string]any)["required"].([]any), want) {
+		t.Errorf("required %v, want %v", got.(map[string]any)["required"], want)
+	}
+	if want := []string{"b", "a"}; !slices.Equal(s.Required, want) {
+		t.Errorf("input required %q, want %q unchanged", s.Required, want)
+	}
+}
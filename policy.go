@@ -0,0 +1,236 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/invopop/jsonschema"
+)
+
+// ErrRefDenied is returned, wrapped, when a RefPolicy
+// forbids resolving a ref.
+var ErrRefDenied = errors.New("ref denied by policy")
+
+// A RefPolicy restricts which refs resolvers may follow. It is meant
+// for services that convert schemas supplied by untrusted users, whose
+// refs could otherwise reach internal hosts or local files.
+// The zero RefPolicy allows everything.
+type RefPolicy struct {
+	// AllowedSchemes, if non-nil, lists the URL schemes that may be
+	// resolved, such as "https" or "file".
+	AllowedSchemes []string
+
+	// AllowedHosts, if non-nil, lists the hosts that may be contacted.
+	// An entry of the form "*.example.com" allows every subdomain of
+	// example.com.
+	AllowedHosts []string
+
+	// DenyPrivateIPs forbids hosts that are, or whose addresses are,
+	// loopback, private, link-local or unspecified addresses.
+	// Addresses are looked up when the ref is checked, and an
+	// HTTPResolver checks again the address it actually connects to,
+	// so that a host whose DNS answer changes in between cannot reach
+	// a private address. That address is the proxy's if the request
+	// goes through one.
+	DenyPrivateIPs bool
+
+	// Check, if set, is called last with each ref that passed the other
+	// rules, and may forbid it by returning an error.
+	Check func(u *url.URL) error
+}
+
+// Allow reports whether p allows resolving u. The error wraps ErrRefDenied.
+func (p *RefPolicy) Allow(u *url.URL) error {
+	if p == nil {
+		return nil
+	}
+	deny := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s: %s", ErrRefDenied, u.Redacted(), fmt.Sprintf(format, args...))
+	}
+	if p.AllowedSchemes != nil && !slices.Contains(p.AllowedSchemes, u.Scheme) {
+		return deny("scheme %q is not allowed", u.Scheme)
+	}
+	if host := u.Hostname(); host != "" {
+		if p.AllowedHosts != nil && !slices.ContainsFunc(p.AllowedHosts, func(pat string) bool { return hostMatches(pat, host) }) {
+			return deny("host %q is not allowed", host)
+		}
+		if p.DenyPrivateIPs {
+			ips, err := hostIPs(host)
+			if err != nil {
+				return deny("%v", err)
+			}
+			for _, ip := range ips {
+				if isPrivateIP(ip) {
+					return deny("host %q has private address %s", host, ip)
+				}
+			}
+		}
+	}
+	if p.Check != nil {
+		if err := p.Check(u); err != nil {
+			return deny("%v", err)
+		}
+	}
+	return nil
+}
+
+// isPrivateIP reports whether ip is a loopback, private, link-local
+// or unspecified address.
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// guardClient returns a copy of c that applies p to every redirect
+// and, if p denies private IPs, refuses to connect to one. The copy
+// then uses a clone of c's transport, which must be an *http.Transport,
+// with a dialer that checks each address before connecting to it.
+func (p *RefPolicy) guardClient(c *http.Client) (*http.Client, error) {
+	g := *c
+	check := c.CheckRedirect
+	g.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := p.Allow(req.URL); err != nil {
+			return err
+		}
+		if check != nil {
+			return check(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	if !p.DenyPrivateIPs {
+		return &g, nil
+	}
+	rt := c.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot check the addresses dialed by a transport of type %T", ErrRefDenied, rt)
+	}
+	t = t.Clone()
+	d := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, addr string, _ syscall.RawConn) error {
+			host, _, _ := net.SplitHostPort(addr)
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return fmt.Errorf("%w: connecting to private address %s", ErrRefDenied, host)
+			}
+			return nil
+		},
+	}
+	t.DialContext = d.DialContext
+	g.Transport = t
+	return &g, nil
+}
+
+// hostMatches reports whether host matches the allow-list entry pat.
+func hostMatches(pat, host string) bool {
+	if sub, ok := strings.CutPrefix(pat, "*."); ok {
+		return strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(sub))
+	}
+	return strings.EqualFold(pat, host)
+}
+
+// hostIPs returns the addresses of host.
+func hostIPs(host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(context.Background(), host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	return ips, nil
+}
+
+// A FileResolver resolves schema names that are paths, or file URLs,
+// by reading files under Dir. Files ending in .json are read as JSON
+// Schema and others as picoschema. Paths that lead outside Dir,
+// including through ".." or symbolic links, are refused.
+type FileResolver struct {
+	Dir string
+	// Policy, if set, is consulted before any file is read,
+	// with a URL of scheme "file".
+	Policy *RefPolicy
+}
+
+// Resolve reads the schema at the path name, relative to r.Dir.
+// The error wraps ErrSchemaNotFound if there is no such file.
+func (r *FileResolver) Resolve(name string) (*jsonschema.Schema, error) {
+	p := name
+	if u, err := url.Parse(name); err == nil && u.Scheme == "file" {
+		p = u.Path
+	}
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if !filepath.IsLocal(filepath.FromSlash(p)) {
+		return nil, &ResolveError{Name: name, Err: fmt.Errorf("%w: path leaves %s", ErrRefDenied, r.Dir)}
+	}
+	if err := r.Policy.Allow(&url.URL{Scheme: "file", Path: "/" + p}); err != nil {
+		return nil, &ResolveError{Name: name, Err: err}
+	}
+	data, err := r.readFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, &ResolveError{Name: name, Err: fmt.Errorf("%w: %v", ErrSchemaNotFound, err)}
+	}
+	if err != nil {
+		return nil, &ResolveError{Name: name, Err: err}
+	}
+	format := "picoschema"
+	if path.Ext(p) == ".json" {
+		format = "jsonschema"
+	}
+	return Import(format, data)
+}
+
+// readFile reads the file at the slash-separated local path p under
+// r.Dir. It resolves symbolic links first, and fails with an error
+// wrapping ErrRefDenied if they lead outside r.Dir.
+func (r *FileResolver) readFile(p string) ([]byte, error) {
+	root, err := filepath.EvalSymlinks(cmp.Or(r.Dir, "."))
+	if err != nil {
+		return nil, err
+	}
+	file, err := filepath.EvalSymlinks(filepath.Join(r.Dir, filepath.FromSlash(p)))
+	if err != nil {
+		return nil, err
+	}
+	if rel, err := filepath.Rel(root, file); err != nil || !filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("%w: %s links outside %s", ErrRefDenied, p, r.Dir)
+	}
+	return os.ReadFile(file)
+}
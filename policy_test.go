@@ -0,0 +1,210 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRefPolicy(t *testing.T) {
+	p := &RefPolicy{
+		AllowedSchemes: []string{"https"},
+		AllowedHosts:   []string{"schemas.example.com", "*.trusted.dev"},
+		DenyPrivateIPs: true,
+		Check: func(u *url.URL) error {
+			if strings.Contains(u.Path, "secret") {
+				return errors.New("secret paths are off limits")
+			}
+			return nil
+		},
+	}
+	for raw, allowed := range map[string]bool{
+		"http://schemas.example.com/a.json":   false,
+		"https://evil.example.com/a.json":     false,
+		"https://trusted.dev.evil.com/a.json": false,
+		"file:///etc/passwd":                  false,
+		"https://schemas.example.com/secret":  false,
+	} {
+		u, _ := url.Parse(raw)
+		err := p.Allow(u)
+		if (err == nil) != allowed {
+			t.Errorf("Allow(%s) = %v, want allowed=%t", raw, err, allowed)
+		}
+		if err != nil && !errors.Is(err, ErrRefDenied) {
+			t.Errorf("Allow(%s) error %v does not wrap ErrRefDenied", raw, err)
+		}
+	}
+
+	open := &RefPolicy{AllowedHosts: []string{"*.trusted.dev"}}
+	for raw, allowed := range map[string]bool{
+		"https://a.trusted.dev/x":     true,
+		"https://A.B.Trusted.dev/x":   true,
+		"https://trusted.dev/x":       false,
+		"https://nottrusted.dev/x":    false,
+		"https://10.0.0.1/x":          false,
+		"file:///schemas/local.yaml":  true,
+		"https://a.trusted.dev:8443/": true,
+	} {
+		u, _ := url.Parse(raw)
+		if err := open.Allow(u); (err == nil) != allowed {
+			t.Errorf("Allow(%s) = %v, want allowed=%t", raw, err, allowed)
+		}
+	}
+
+	private := &RefPolicy{DenyPrivateIPs: true}
+	for _, raw := range []string{"http://127.0.0.1/x", "http://10.1.2.3/x", "http://[::1]/x", "http://169.254.169.254/latest"} {
+		u, _ := url.Parse(raw)
+		if err := private.Allow(u); !errors.Is(err, ErrRefDenied) {
+			t.Errorf("Allow(%s) = %v, want ErrRefDenied", raw, err)
+		}
+	}
+	if err := (*RefPolicy)(nil).Allow(&url.URL{Scheme: "file"}); err != nil {
+		t.Errorf("nil policy: %v", err)
+	}
+}
+
+func TestHTTPResolverPolicy(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"type": "string"}`))
+	}))
+	defer srv.Close()
+
+	r := &HTTPResolver{Client: srv.Client(), Policy: &RefPolicy{DenyPrivateIPs: true}}
+	_, err := r.Resolve(srv.URL + "/a.json")
+	var re *ResolveError
+	if !errors.Is(err, ErrRefDenied) || !errors.As(err, &re) || re.Temporary() {
+		t.Errorf("got error %v, want a permanent ResolveError wrapping ErrRefDenied", err)
+	}
+	if requests != 0 {
+		t.Errorf("got %d requests, want 0", requests)
+	}
+}
+
+func TestHTTPResolverPolicyRedirect(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/a.json" {
+			http.Redirect(w, r, "/secret.json", http.StatusFound)
+			return
+		}
+		w.Write([]byte(`{"type": "string"}`))
+	}))
+	defer srv.Close()
+
+	policy := &RefPolicy{Check: func(u *url.URL) error {
+		if strings.Contains(u.Path, "secret") {
+			return errors.New("secret paths are off limits")
+		}
+		return nil
+	}}
+	r := &HTTPResolver{Client: srv.Client(), Policy: policy}
+	if _, err := r.Resolve(srv.URL + "/a.json"); !errors.Is(err, ErrRefDenied) {
+		t.Errorf("got error %v, want ErrRefDenied", err)
+	}
+	if want := []string{"/a.json"}; !slices.Equal(paths, want) {
+		t.Errorf("got requests for %v, want %v", paths, want)
+	}
+}
+
+func TestGuardClientDialedAddress(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+
+	// The policy's own check passed, as when a host's DNS answer
+	// changes after it; the dialer still refuses the private address.
+	c, err := (&RefPolicy{DenyPrivateIPs: true}).guardClient(srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(srv.URL); !errors.Is(err, ErrRefDenied) {
+		t.Errorf("got error %v, want ErrRefDenied", err)
+	}
+	if requests != 0 {
+		t.Errorf("got %d requests, want 0", requests)
+	}
+
+	custom := &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}
+	if _, err := (&RefPolicy{DenyPrivateIPs: true}).guardClient(custom); !errors.Is(err, ErrRefDenied) {
+		t.Errorf("got error %v for a custom transport, want ErrRefDenied", err)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestFileResolver(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "billing"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "billing", "invoice.yaml"), []byte("id: string\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "tax.json"), []byte(`{"type": "number"}`), 0o644)
+
+	r := &FileResolver{Dir: dir}
+	for _, name := range []string{"billing/invoice.yaml", "./billing/invoice.yaml", "file:///billing/invoice.yaml"} {
+		s, err := r.Resolve(name)
+		if err != nil || s.Properties.Value("id") == nil {
+			t.Errorf("Resolve(%q) = %v, %v", name, s, err)
+		}
+	}
+	if s, err := r.Resolve("tax.json"); err != nil || s.Type != "number" {
+		t.Errorf("Resolve(tax.json) = %v, %v", s, err)
+	}
+	if _, err := r.Resolve("missing.yaml"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("got error %v, want ErrSchemaNotFound", err)
+	}
+	// Cleaning the path keeps ".." inside Dir.
+	if s, err := r.Resolve("../../billing/invoice.yaml"); err != nil || s.Properties.Value("id") == nil {
+		t.Errorf("Resolve(../../billing/invoice.yaml) = %v, %v", s, err)
+	}
+
+	// Symbolic links are followed only within Dir.
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.yaml"), []byte("key: string\n"), 0o644)
+	if err := os.Symlink(filepath.Join(outside, "secret.yaml"), filepath.Join(dir, "secret.yaml")); err != nil {
+		t.Skipf("cannot create symbolic links: %v", err)
+	}
+	if _, err := r.Resolve("secret.yaml"); !errors.Is(err, ErrRefDenied) {
+		t.Errorf("got error %v for a link outside Dir, want ErrRefDenied", err)
+	}
+	os.Symlink(outside, filepath.Join(dir, "elsewhere"))
+	if _, err := r.Resolve("elsewhere/secret.yaml"); !errors.Is(err, ErrRefDenied) {
+		t.Errorf("got error %v for a directory link outside Dir, want ErrRefDenied", err)
+	}
+	os.Symlink(filepath.Join(dir, "tax.json"), filepath.Join(dir, "vat.json"))
+	if s, err := r.Resolve("vat.json"); err != nil || s.Type != "number" {
+		t.Errorf("Resolve(vat.json) = %v, %v", s, err)
+	}
+
+	denied := &FileResolver{Dir: dir, Policy: &RefPolicy{AllowedSchemes: []string{"https"}}}
+	if _, err := denied.Resolve("tax.json"); !errors.Is(err, ErrRefDenied) {
+		t.Errorf("got error %v, want ErrRefDenied", err)
+	}
+}
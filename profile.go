@@ -0,0 +1,81 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"slices"
+	"sync"
+)
+
+// A Profile describes the JSON Schema dialect accepted by a model provider.
+type Profile struct {
+	Name string
+	// UnsupportedKeywords lists the keywords the provider rejects or ignores.
+	UnsupportedKeywords []string
+	// MaxDescriptionBytes, if positive, is the longest description
+	// the provider accepts. Longer ones are shortened to fit.
+	MaxDescriptionBytes int
+	// MaxSchemaBytes, if positive, is the largest schema, in bytes of
+	// JSON, the provider accepts. Descriptions are shortened, longest
+	// first, until the schema fits.
+	MaxSchemaBytes int
+}
+
+// Supports reports whether the provider accepts the keyword.
+func (p *Profile) Supports(keyword string) bool {
+	return !slices.Contains(p.UnsupportedKeywords, keyword)
+}
+
+var (
+	profilesMu sync.RWMutex
+	profiles   = map[string]*Profile{
+		"openai": {
+			Name: "openai",
+			UnsupportedKeywords: []string{
+				"allOf", "not", "if", "then", "else", "dependentRequired", "dependentSchemas",
+				"patternProperties", "propertyNames", "minProperties", "maxProperties",
+				"contains", "minContains", "maxContains", "uniqueItems",
+			},
+		},
+		"gemini": {
+			Name: "gemini",
+			UnsupportedKeywords: []string{
+				"$ref", "$defs", "allOf", "oneOf", "not", "const", "if", "then", "else",
+				"patternProperties", "additionalProperties", "propertyNames",
+				"dependentRequired", "dependentSchemas", "prefixItems", "contains", "uniqueItems",
+				"exclusiveMinimum", "exclusiveMaximum", "multipleOf",
+			},
+		},
+		"anthropic": {
+			Name: "anthropic",
+		},
+	}
+)
+
+// RegisterProfile makes p available to LookupProfile under p.Name,
+// replacing any profile previously registered under that name.
+func RegisterProfile(p *Profile) {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profiles[p.Name] = p
+}
+
+// LookupProfile returns the provider profile with the given name.
+func LookupProfile(name string) (*Profile, bool) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	p, ok := profiles[name]
+	return p, ok
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// Project returns a copy of s reduced to the selected properties.
+// Paths are dot-separated property names, such as "customer.address.city";
+// arrays are looked through, so "orders.id" selects the id property of
+// the items of the orders array.
+//
+// If include is non-empty, only the included properties, their
+// subschemas and the objects enclosing them are kept. Properties
+// matching a path in exclude are then removed. Required lists are
+// updated to name only the remaining properties. s is not modified.
+func Project(s *jsonschema.Schema, include, exclude []string) *jsonschema.Schema {
+	var inc *pathTrie
+	if len(include) > 0 {
+		inc = newPathTrie(include)
+	}
+	return project(s, inc, newPathTrie(exclude))
+}
+
+// A pathTrie is a set of property paths.
+// A nil *pathTrie selects everything.
+type pathTrie struct {
+	end      bool // a path ends here
+	children map[string]*pathTrie
+}
+
+func newPathTrie(paths []string) *pathTrie {
+	root := &pathTrie{children: map[string]*pathTrie{}}
+	for _, p := range paths {
+		t := root
+		for _, name := range strings.Split(p, ".") {
+			child, ok := t.children[name]
+			if !ok {
+				child = &pathTrie{children: map[string]*pathTrie{}}
+				t.children[name] = child
+			}
+			t = child
+		}
+		t.end = true
+	}
+	return root
+}
+
+// project copies s, keeping the properties selected by inc and
+// not selected by exc.
+func project(s *jsonschema.Schema, inc, exc *pathTrie) *jsonschema.Schema {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	if s.Properties != nil {
+		c.Properties = orderedmap.New[string, *jsonschema.Schema]()
+		for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+			var pinc, pexc *pathTrie
+			if inc != nil {
+				pinc = inc.children[p.Key]
+				if pinc == nil {
+					continue
+				}
+				if pinc.end {
+					pinc = nil
+				}
+			}
+			if exc != nil {
+				pexc = exc.children[p.Key]
+				if pexc != nil && pexc.end {
+					continue
+				}
+			}
+			c.Properties.Set(p.Key, project(p.Value, pinc, pexc))
+		}
+		c.Required = slices.DeleteFunc(slices.Clone(s.Required), func(name string) bool {
+			_, ok := c.Properties.Get(name)
+			return !ok
+		})
+		if len(c.Required) == 0 {
+			c.Required = nil
+		}
+	}
+	c.Items = project(s.Items, inc, exc)
+	c.PrefixItems = projectList(s.PrefixItems, inc, exc)
+	c.AllOf = projectList(s.AllOf, inc, exc)
+	c.AnyOf = projectList(s.AnyOf, inc, exc)
+	c.OneOf = projectList(s.OneOf, inc, exc)
+	return &c
+}
+
+func projectList(ss []*jsonschema.Schema, inc, exc *pathTrie) []*jsonschema.Schema {
+	if ss == nil {
+		return nil
+	}
+	ret := make([]*jsonschema.Schema, len(ss))
+	for i, s := range ss {
+		ret[i] = project(s, inc, exc)
+	}
+	return ret
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProject(t *testing.T) {
+	src := `
+id: string
+customer(object):
+  name: string
+  email?: string
+orders(array):
+  sku: string
+  qty: integer
+`
+	for _, test := range []struct {
+		name             string
+		include, exclude []string
+		want             string
+	}{
+		{
+			name:    "include",
+			include: []string{"id", "customer.name", "orders.qty"},
+			want: `
+id: string
+customer(object):
+  name: string
+orders(array):
+  qty: integer
+`,
+		},
+		{
+			name:    "exclude",
+			exclude: []string{"customer", "orders.sku"},
+			want: `
+id: string
+orders(array):
+  qty: integer
+`,
+		},
+		{
+			name:    "include and exclude",
+			include: []string{"customer"},
+			exclude: []string{"customer.email"},
+			want: `
+customer(object):
+  name: string
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := mustSchema(t, src)
+			before, err := ConvertSchema(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ConvertSchema(Project(s, test.include, test.exclude))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := ConvertSchema(mustSchema(t, test.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+			after, err := ConvertSchema(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(before, after); diff != "" {
+				t.Errorf("Project modified its input (-before, +after):\n%s", diff)
+			}
+		})
+	}
+}
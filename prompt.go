@@ -0,0 +1,370 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/invopop/jsonschema"
+)
+
+// enumDescriptionsKey is the annotation mapping enum values to their
+// descriptions. In picoschema an enum member with a description is
+// written as a single-key map, as in
+//
+//	status(enum): [{ok: all good}, {error: something failed}]
+const enumDescriptionsKey = "x-enumDescriptions"
+
+// A Verbosity selects how much detail RenderPrompt includes.
+type Verbosity int
+
+const (
+	// VerbosityNormal lists each property with its type,
+	// whether it is required, and its description.
+	VerbosityNormal Verbosity = iota
+	// VerbosityConcise describes the response in a single line.
+	VerbosityConcise
+	// VerbosityDetailed adds constraints such as enums, ranges,
+	// patterns and units to each property.
+	VerbosityDetailed
+)
+
+// RenderPrompt renders s as instructions with the default PromptRenderer.
+func RenderPrompt(s *jsonschema.Schema) (string, error) {
+	var r PromptRenderer
+	return r.Render(s)
+}
+
+// A PromptRenderer turns a schema into natural-language instructions
+// describing the JSON a model should respond with, for models that do
+// not support structured output natively. For example, it renders the
+// picoschema
+//
+//	name: string, the user's name
+//	tags?(array): string
+//
+// as
+//
+//	Respond with a JSON object containing:
+//	- name (string, required): the user's name
+//	- tags (array of string, optional)
+type PromptRenderer struct {
+	Verbosity Verbosity
+
+	// Template, if set, is executed with a *PromptData in place of the
+	// built-in template for the verbosity. Render executes a copy of
+	// Template to which it adds the functions indent, which returns two
+	// spaces per level of depth, and join, which is strings.Join, as
+	// well as a template named "fields" that renders a []PromptField
+	// as a nested list, as in {{template "fields" .Fields}}, unless
+	// Template defines its own. Templates that call indent or join must
+	// declare them with Funcs before they are parsed.
+	Template *template.Template
+}
+
+// PromptData is the data passed to prompt templates.
+type PromptData struct {
+	// Kind is the JSON type of the response: "object", "array",
+	// or a scalar type. It is empty if the type is unconstrained.
+	Kind string
+	// Type is a compact notation for the response type,
+	// such as "{name: string, tags?: string[]}".
+	Type string
+	// Description is the description of the schema.
+	Description string
+	// Fields are the properties of the response object, or of the
+	// items of the response array.
+	Fields []PromptField
+	// Closed reports whether properties other than Fields are disallowed.
+	Closed bool
+}
+
+// A PromptField describes one property for a prompt template.
+type PromptField struct {
+	Name string
+	// Type is a phrase such as "string", "array of object"
+	// or `one of "a", "b"`.
+	Type        string
+	Required    bool
+	Description string
+	// Constraints are phrases such as "at most 10 characters".
+	Constraints []string
+	// Fields are the properties of an object property,
+	// or of the items of an array property.
+	Fields []PromptField
+	// Depth is the nesting depth of the property, starting at 0.
+	Depth int
+}
+
+const promptFieldsTemplate = `{{define "fields"}}{{range .}}{{indent .Depth}}- {{.Name}} ({{.Type}}, {{if .Required}}required{{else}}optional{{end}}){{with .Description}}: {{.}}{{end}}
+{{template "fields" .Fields}}{{end}}{{end}}`
+
+// promptTemplates are the built-in templates for each verbosity.
+var promptTemplates = map[Verbosity]*template.Template{
+	VerbosityConcise: newPromptTemplate(`Respond with JSON matching {{.Type}}.`),
+	VerbosityNormal: newPromptTemplate(`{{with .Description}}{{.}}
+{{end}}{{if eq .Kind "object"}}Respond with a JSON object containing:
+{{template "fields" .Fields}}{{else if and (eq .Kind "array") .Fields}}Respond with a JSON array of objects, each containing:
+{{template "fields" .Fields}}{{else}}Respond with a JSON value of type {{.Type}}.
+{{end}}`),
+	VerbosityDetailed: newPromptTemplate(`{{with .Description}}{{.}}
+{{end}}{{if eq .Kind "object"}}Respond with a JSON object containing:
+{{template "detailed" .Fields}}{{else if and (eq .Kind "array") .Fields}}Respond with a JSON array of objects, each containing:
+{{template "detailed" .Fields}}{{else}}Respond with a JSON value of type {{.Type}}.
+{{end}}{{if .Closed}}Do not include any other properties.
+{{end}}Respond with the JSON only, without Markdown fences or commentary.
+{{define "detailed"}}{{range .}}{{indent .Depth}}- {{.Name}} ({{.Type}}, {{if .Required}}required{{else}}optional{{end}}){{with .Description}}: {{.}}{{end}}{{with .Constraints}} [{{join . "; "}}]{{end}}
+{{template "detailed" .Fields}}{{end}}{{end}}`),
+}
+
+// promptFuncs are the functions available to prompt templates.
+var promptFuncs = template.FuncMap{
+	"indent": func(depth int) string { return strings.Repeat("  ", depth) },
+	"join":   strings.Join,
+}
+
+func newPromptTemplate(text string) *template.Template {
+	t := template.New("prompt").Funcs(promptFuncs)
+	template.Must(t.Parse(promptFieldsTemplate))
+	return template.Must(t.Parse(text))
+}
+
+// userPromptTemplate returns a copy of t with the prompt functions
+// and, unless t defines it, the "fields" template.
+func userPromptTemplate(t *template.Template) (*template.Template, error) {
+	c, err := t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	c.Funcs(promptFuncs)
+	if c.Lookup("fields") == nil {
+		if _, err := c.Parse(promptFieldsTemplate); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Render returns instructions describing s.
+func (r *PromptRenderer) Render(s *jsonschema.Schema) (string, error) {
+	t := r.Template
+	if t != nil {
+		var err error
+		if t, err = userPromptTemplate(t); err != nil {
+			return "", fmt.Errorf("picoschema: rendering prompt: %w", err)
+		}
+	} else {
+		var ok bool
+		if t, ok = promptTemplates[r.Verbosity]; !ok {
+			return "", fmt.Errorf("picoschema: unknown verbosity %d", r.Verbosity)
+		}
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, NewPromptData(s)); err != nil {
+		return "", fmt.Errorf("picoschema: rendering prompt: %w", err)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// NewPromptData returns the template data describing s.
+// A nil s, like an empty schema, describes any value.
+func NewPromptData(s *jsonschema.Schema) *PromptData {
+	if s == nil {
+		s = &jsonschema.Schema{}
+	}
+	d := &PromptData{
+		Kind:        s.Type,
+		Type:        compactType(s),
+		Description: s.Description,
+	}
+	fs := s
+	if s.Type == "array" && s.Items != nil {
+		fs = s.Items
+	}
+	d.Fields = promptFields(s, 0)
+	if b, ok := boolSchema(fs.AdditionalProperties); ok && !b {
+		d.Closed = fs.Properties != nil
+	}
+	return d
+}
+
+// promptFields returns the fields describing the properties of s,
+// or of its items if it is an array.
+func promptFields(s *jsonschema.Schema, depth int) []PromptField {
+	if s.Type == "array" && s.Items != nil {
+		s = s.Items
+	}
+	if s.Properties == nil {
+		return nil
+	}
+	var fs []PromptField
+	for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+		fs = append(fs, PromptField{
+			Name:        p.Key,
+			Type:        typePhrase(p.Value),
+			Required:    slices.Contains(s.Required, p.Key),
+			Description: p.Value.Description,
+			Constraints: constraintPhrases(p.Value),
+			Fields:      promptFields(p.Value, depth+1),
+			Depth:       depth,
+		})
+	}
+	return fs
+}
+
+// typePhrase describes the type of s in words.
+func typePhrase(s *jsonschema.Schema) string {
+	switch {
+	case s.Enum != nil:
+		return "one of " + enumPhrase(s)
+	case s.Const != nil:
+		return "exactly " + jsonText(s.Const)
+	case s.Type == "array" && s.Items != nil:
+		return "array of " + typePhrase(s.Items)
+	case len(s.AnyOf) > 0 || len(s.OneOf) > 0:
+		var alts []string
+		for _, a := range append(slices.Clone(s.AnyOf), s.OneOf...) {
+			alts = append(alts, typePhrase(a))
+		}
+		return strings.Join(alts, " or ")
+	case s.Type == "":
+		return "any"
+	}
+	return s.Type
+}
+
+// compactType describes the type of s in a compact TypeScript-like notation.
+func compactType(s *jsonschema.Schema) string {
+	switch {
+	case s.Enum != nil:
+		return enumList(s.Enum, " | ")
+	case s.Const != nil:
+		return jsonText(s.Const)
+	case s.Type == "array":
+		if s.Items == nil {
+			return "any[]"
+		}
+		return compactType(s.Items) + "[]"
+	case s.Properties != nil:
+		var ps []string
+		for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+			opt := "?"
+			if slices.Contains(s.Required, p.Key) {
+				opt = ""
+			}
+			ps = append(ps, p.Key+opt+": "+compactType(p.Value))
+		}
+		return "{" + strings.Join(ps, ", ") + "}"
+	case len(s.AnyOf) > 0 || len(s.OneOf) > 0:
+		var alts []string
+		for _, a := range append(slices.Clone(s.AnyOf), s.OneOf...) {
+			alts = append(alts, compactType(a))
+		}
+		return strings.Join(alts, " | ")
+	case s.Type == "":
+		return "any"
+	}
+	return s.Type
+}
+
+// constraintPhrases describes the constraints of s other than its type.
+func constraintPhrases(s *jsonschema.Schema) []string {
+	var cs []string
+	add := func(format string, args ...any) {
+		cs = append(cs, fmt.Sprintf(format, args...))
+	}
+	if s.Minimum != "" {
+		add("at least %s", s.Minimum)
+	}
+	if s.ExclusiveMinimum != "" {
+		add("greater than %s", s.ExclusiveMinimum)
+	}
+	if s.Maximum != "" {
+		add("at most %s", s.Maximum)
+	}
+	if s.ExclusiveMaximum != "" {
+		add("less than %s", s.ExclusiveMaximum)
+	}
+	if s.MultipleOf != "" {
+		add("a multiple of %s", s.MultipleOf)
+	}
+	if s.MinLength != nil {
+		add("at least %d characters", *s.MinLength)
+	}
+	if s.MaxLength != nil {
+		add("at most %d characters", *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		add("matching /%s/", s.Pattern)
+	}
+	if s.Format != "" {
+		add("format %s", s.Format)
+	}
+	if s.MinItems != nil {
+		add("at least %d items", *s.MinItems)
+	}
+	if s.MaxItems != nil {
+		add("at most %d items", *s.MaxItems)
+	}
+	if s.UniqueItems {
+		add("items must be unique")
+	}
+	if unit, ok := s.Extras[unitKey].(string); ok {
+		add("in %s", unit)
+	}
+	if s.Default != nil {
+		add("default %s", jsonText(s.Default))
+	}
+	if len(s.Examples) > 0 {
+		add("for example %s", enumList(s.Examples, " or "))
+	}
+	return cs
+}
+
+// enumPhrase renders the enum members of s as JSON, separated by
+// commas, each followed by its description in parentheses.
+func enumPhrase(s *jsonschema.Schema) string {
+	descs, _ := s.Extras[enumDescriptionsKey].(map[string]any)
+	ss := make([]string, len(s.Enum))
+	for i, v := range s.Enum {
+		ss[i] = jsonText(v)
+		if str, ok := v.(string); ok && descs[str] != nil {
+			ss[i] += fmt.Sprintf(" (%v)", descs[str])
+		}
+	}
+	return strings.Join(ss, ", ")
+}
+
+// enumList renders enum members as JSON, separated by sep.
+func enumList(vals []any, sep string) string {
+	ss := make([]string, len(vals))
+	for i, v := range vals {
+		ss[i] = jsonText(v)
+	}
+	return strings.Join(ss, sep)
+}
+
+// jsonText renders v as JSON.
+func jsonText(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderPrompt(t *testing.T) {
+	// Picoschema property order follows YAML map iteration,
+	// so use JSON Schema, which keeps a fixed order.
+	s, err := Import("jsonschema", []byte(`{
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["name", "status", "address"],
+		"properties": {
+			"name": {"type": "string", "description": "the user's name", "maxLength": 40},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"status": {"enum": ["active", "banned"]},
+			"address": {
+				"type": "object",
+				"required": ["city"],
+				"properties": {
+					"city": {"type": "string"},
+					"timeout": {"type": "integer", "minimum": 1, "examples": [30, 60]}
+				}
+			}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		verbosity Verbosity
+		want      string
+	}{
+		{VerbosityConcise, `Respond with JSON matching {name: string, tags?: string[], status: "active" | "banned", address: {city: string, timeout?: integer}}.`},
+		{VerbosityNormal, `Respond with a JSON object containing:
+- name (string, required): the user's name
+- tags (array of string, optional)
+- status (one of "active", "banned", required)
+- address (object, required)
+  - city (string, required)
+  - timeout (integer, optional)`},
+		{VerbosityDetailed, `Respond with a JSON object containing:
+- name (string, required): the user's name [at most 40 characters]
+- tags (array of string, optional)
+- status (one of "active", "banned", required)
+- address (object, required)
+  - city (string, required)
+  - timeout (integer, optional) [at least 1; for example 30 or 60]
+Do not include any other properties.
+Respond with the JSON only, without Markdown fences or commentary.`},
+	} {
+		r := PromptRenderer{Verbosity: test.verbosity}
+		got, err := r.Render(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("verbosity %d: mismatch (-want, +got):\n%s", test.verbosity, diff)
+		}
+	}
+}
+
+func TestRenderPromptArray(t *testing.T) {
+	s := mustSchema(t, `
+type: array
+items:
+  type: object
+  properties:
+    id: {type: integer}
+`)
+	got, err := RenderPrompt(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Respond with a JSON array of objects, each containing:\n- id (integer, optional)"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRenderPromptTemplate(t *testing.T) {
+	s := mustSchema(t, "count: integer, how many")
+	tmpl := template.Must(template.New("custom").Parse(`Fields:{{range .Fields}} {{.Name}}={{.Type}}{{end}}`))
+	r := PromptRenderer{Template: tmpl}
+	got, err := r.Render(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Fields: count=integer"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRenderPromptTemplateFields(t *testing.T) {
+	s := mustSchema(t, "user:\n  name: string, the name\n")
+	tmpl := template.Must(template.New("custom").Parse(`Reply with:
+{{template "fields" .Fields}}`))
+	r := PromptRenderer{Template: tmpl}
+	got, err := r.Render(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Reply with:
+- user (object, required)
+  - name (string, required): the name`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if tmpl.Lookup("fields") != nil {
+		t.Error("Render modified the caller's template")
+	}
+}
+
+func TestRenderPromptNil(t *testing.T) {
+	got, err := (&PromptRenderer{}).Render(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Respond with a JSON value of type any."; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRenderPromptEnumDescriptions(t *testing.T) {
+	s := mustSchema(t, "status(enum): [{ok: all good}, {error: something failed}, unknown]")
+	got, err := RenderPrompt(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Respond with a JSON object containing:
+- status (one of "ok" (all good), "error" (something failed), "unknown", required)`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
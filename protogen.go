@@ -0,0 +1,533 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// GenerateProto returns a proto3 file of package pkg defining the
+// message messageName, whose JSON form, as protojson reads it, is the
+// JSON values that s accepts, so that output conforming to a schema,
+// such as a model's response, can be passed on to a gRPC service.
+//
+// s must be an object with properties. Each property becomes a field,
+// named in snake case, with a json_name option naming the property if
+// protoc would give the field another JSON name. A field takes its
+// number from the protoField attribute of its property, as in
+// "id(protoField=1): string", which JSON Schema writes as the
+// annotation x-proto-field, so that the numbers of the fields stay the
+// same as properties are added and removed. Properties without one
+// are numbered after the highest number given, in the order of their
+// names, so adding such a property renumbers those after it; number
+// every field of messages that are stored in their binary form. A
+// property that is not required, or is nullable, is an optional
+// field. An object nested in a property or an array becomes a nested
+// message named after the property, as Customer is for the property
+// customer, and each object under $defs of s becomes a message under
+// its name, made an identifier. Other $defs are used in place.
+//
+// Integers become int64, numbers double, strings of the format
+// date-time google.protobuf.Timestamp, arrays repeated fields, and
+// maps map fields. An enum of strings that are identifiers becomes an
+// enum whose values are named by the strings, with an UNSPECIFIED value
+// first; other enums and consts become the type of their values, listed
+// in a comment. Unions, arrays of arrays and other schemas with no
+// protobuf equivalent become google.protobuf.Value, and objects with no
+// properties google.protobuf.Struct. Descriptions become comments, and
+// deprecated properties deprecated fields.
+//
+// Only refs into s are followed, as described at Bundle.
+func GenerateProto(s *jsonschema.Schema, pkg, messageName string) ([]byte, error) {
+	for _, part := range strings.Split(pkg, ".") {
+		if !isProtoIdentifier(part) {
+			return nil, fmt.Errorf("picoschema: package name %q is not a protobuf package name", pkg)
+		}
+	}
+	if !isProtoIdentifier(messageName) {
+		return nil, fmt.Errorf("picoschema: message name %q is not an identifier", messageName)
+	}
+	if !protoIsMessage(s) {
+		return nil, fmt.Errorf("picoschema: the schema is not an object with properties")
+	}
+	g := &protoGenerator{
+		root:     messageName,
+		defs:     make(map[string]string),
+		schemas:  s.Definitions,
+		imports:  make(map[string]bool),
+		inlining: make(map[string]bool),
+	}
+	taken := map[string]string{messageName: ""}
+	defs := sortedKeys(s.Definitions)
+	for _, name := range defs {
+		if !protoIsMessage(s.Definitions[name]) {
+			continue
+		}
+		id := goName(name)
+		if other, ok := taken[id]; ok {
+			if other == "" {
+				return nil, fmt.Errorf("picoschema: $defs %q has the message name %s of the root", name, id)
+			}
+			return nil, fmt.Errorf("picoschema: $defs %q and %q have the same message name %s", other, name, id)
+		}
+		taken[id] = name
+		g.defs[name] = id
+	}
+
+	var body strings.Builder
+	if err := g.message(&body, messageName, s, ""); err != nil {
+		return nil, err
+	}
+	for _, name := range defs {
+		if id, ok := g.defs[name]; ok {
+			body.WriteString("\n")
+			if err := g.message(&body, id, s.Definitions[name], ""); err != nil {
+				return nil, fmt.Errorf("picoschema: $defs %q: %w", name, err)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by picoschema. DO NOT EDIT.\n\nsyntax = \"proto3\";\n\npackage %s;\n\n", pkg)
+	if len(g.imports) > 0 {
+		for _, imp := range sortedKeys(g.imports) {
+			fmt.Fprintf(&buf, "import %q;\n", imp)
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString(body.String())
+	return buf.Bytes(), nil
+}
+
+// A protoGenerator writes protobuf messages for schemas.
+type protoGenerator struct {
+	root     string
+	defs     map[string]string // message names of $defs that are messages
+	schemas  jsonschema.Definitions
+	imports  map[string]bool
+	inlining map[string]bool // $defs being used in place
+}
+
+// protoIsMessage reports whether s becomes a message: an object with
+// properties.
+func protoIsMessage(s *jsonschema.Schema) bool {
+	return goIsStruct(s)
+}
+
+// A protoScope is the names declared in a message: its fields, nested
+// types and the values of its nested enums.
+type protoScope struct {
+	names  map[string]bool
+	nested []string // declarations of nested types
+}
+
+// unique returns name, or name followed by a number if it is declared
+// in sc, and declares it.
+func (sc *protoScope) unique(name string) string {
+	unique := name
+	for i := 2; sc.names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	sc.names[unique] = true
+	return unique
+}
+
+// message writes the message name for s, indented by indent.
+func (g *protoGenerator) message(sb *strings.Builder, name string, s *jsonschema.Schema, indent string) error {
+	inner := indent + "  "
+	var props []string
+	for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+		props = append(props, p.Key)
+	}
+	slices.Sort(props)
+	numbers, err := protoFieldNumbers(s, props)
+	if err != nil {
+		return err
+	}
+	sc := &protoScope{names: make(map[string]bool)}
+	fields := make([]string, len(props))
+	for i, prop := range props {
+		fields[i] = sc.unique(protoFieldName(prop))
+	}
+
+	var body strings.Builder
+	for i, prop := range props {
+		ps := s.Properties.Value(prop)
+		t, err := g.fieldType(sc, goName(prop), ps, inner)
+		if err != nil {
+			return fmt.Errorf("property %q: %w", prop, err)
+		}
+		if t.label == "" && t.scalar && (t.nullable || !slices.Contains(s.Required, prop)) {
+			t.label = "optional"
+		}
+		var opts []string
+		if prop != protoJSONName(fields[i]) {
+			opts = append(opts, "json_name = "+jsonText(prop))
+		}
+		if _, ok := boolSchema(ps); !ok && ps.Deprecated {
+			opts = append(opts, "deprecated = true")
+		}
+		writeProtoDoc(&body, ps, t.values, inner)
+		body.WriteString(inner)
+		if t.label != "" {
+			body.WriteString(t.label + " ")
+		}
+		fmt.Fprintf(&body, "%s %s = %d", t.name, fields[i], numbers[i])
+		if len(opts) > 0 {
+			fmt.Fprintf(&body, " [%s]", strings.Join(opts, ", "))
+		}
+		body.WriteString(";\n")
+	}
+
+	if indent == "" {
+		// The field of a nested message carries its description.
+		writeProtoDoc(sb, s, nil, indent)
+	}
+	fmt.Fprintf(sb, "%smessage %s {\n", indent, name)
+	for _, n := range sc.nested {
+		sb.WriteString(n)
+		sb.WriteString("\n")
+	}
+	sb.WriteString(body.String())
+	sb.WriteString(indent + "}\n")
+	return nil
+}
+
+// protoFieldKey is the annotation giving the number of the protobuf
+// field of a property. In picoschema it is written as an attribute, as
+// in "id(protoField=1): string".
+const protoFieldKey = "x-proto-field"
+
+// Bounds of protobuf field numbers, and the range reserved for the
+// implementation of protobuf.
+const (
+	protoMaxField           = 1<<29 - 1
+	protoReservedFieldStart = 19000
+	protoReservedFieldEnd   = 19999
+)
+
+// protoFieldAttribute applies the protoField attribute.
+func protoFieldAttribute(s *jsonschema.Schema, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || !isProtoFieldNumber(n) {
+		return fmt.Errorf("%q is not a protobuf field number", value)
+	}
+	setExtra(s, protoFieldKey, n)
+	return nil
+}
+
+// isProtoFieldNumber reports whether n may number a field.
+func isProtoFieldNumber(n int) bool {
+	return n >= 1 && n <= protoMaxField && (n < protoReservedFieldStart || n > protoReservedFieldEnd)
+}
+
+// protoFieldNumbers returns the numbers of the fields of props, the
+// sorted properties of s: the numbers their schemas give, then, for
+// the others, those after the highest, in order.
+func protoFieldNumbers(s *jsonschema.Schema, props []string) ([]int, error) {
+	numbers := make([]int, len(props))
+	owners := make(map[int]string)
+	last := 0
+	for i, prop := range props {
+		ps := s.Properties.Value(prop)
+		a, ok := ps.Extras[protoFieldKey]
+		if !ok {
+			continue
+		}
+		f, ok := toFloat(a)
+		n := int(f)
+		if !ok || float64(n) != f || !isProtoFieldNumber(n) {
+			return nil, fmt.Errorf("property %q: %s %v is not a protobuf field number", prop, protoFieldKey, a)
+		}
+		if other, ok := owners[n]; ok {
+			return nil, fmt.Errorf("properties %q and %q have the field number %d", other, prop, n)
+		}
+		owners[n] = prop
+		numbers[i] = n
+		last = max(last, n)
+	}
+	for i := range numbers {
+		if numbers[i] != 0 {
+			continue
+		}
+		last++
+		if last == protoReservedFieldStart {
+			last = protoReservedFieldEnd + 1
+		}
+		if last > protoMaxField {
+			return nil, fmt.Errorf("property %q: no field number is left", props[i])
+		}
+		numbers[i] = last
+	}
+	return numbers, nil
+}
+
+// A protoType is the type of a field.
+type protoType struct {
+	name     string
+	label    string // "repeated" or ""
+	scalar   bool   // whether the type is a scalar or enum, which has no presence
+	nullable bool
+	values   []any // values of an enum or const that is not a protobuf enum
+}
+
+// Types of the well-known types of google/protobuf/struct.proto.
+var (
+	protoValue  = protoType{name: "google.protobuf.Value"}
+	protoStruct = protoType{name: "google.protobuf.Struct"}
+	protoList   = protoType{name: "google.protobuf.ListValue"}
+)
+
+// fieldType returns the type of a field of s, declaring nested types
+// named after name in sc, indented by indent.
+func (g *protoGenerator) fieldType(sc *protoScope, name string, s *jsonschema.Schema, indent string) (protoType, error) {
+	if _, ok := boolSchema(s); ok {
+		return g.wellKnown(protoValue), nil
+	}
+	s = withoutPresence(s)
+	if s.Ref != "" {
+		if s.AnyOf != nil || s.OneOf != nil || s.AllOf != nil {
+			return g.wellKnown(protoValue), nil
+		}
+		return g.ref(sc, name, s.Ref, indent)
+	}
+	if alt, ok := nullableAlternative(s); ok {
+		t, err := g.fieldType(sc, name, alt, indent)
+		t.nullable = true
+		return t, err
+	}
+	if len(s.AllOf) == 1 && s.AnyOf == nil && s.OneOf == nil && len(schemaKeywords(s)) == 1 {
+		return g.fieldType(sc, name, s.AllOf[0], indent)
+	}
+	if s.AnyOf != nil || s.OneOf != nil || s.AllOf != nil {
+		return g.wellKnown(protoValue), nil
+	}
+	if s.Const != nil {
+		return g.valueType([]any{s.Const}), nil
+	}
+	if s.Enum != nil {
+		if t, ok := g.enum(sc, name, s.Enum, indent); ok {
+			return t, nil
+		}
+		return g.valueType(s.Enum), nil
+	}
+	if protoIsMessage(s) {
+		name = sc.unique(name)
+		var sb strings.Builder
+		if err := g.message(&sb, name, s, indent); err != nil {
+			return protoType{}, err
+		}
+		sc.nested = append(sc.nested, sb.String())
+		return protoType{name: name}, nil
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			g.imports["google/protobuf/timestamp.proto"] = true
+			return protoType{name: "google.protobuf.Timestamp"}, nil
+		}
+		return protoType{name: "string", scalar: true}, nil
+	case "integer":
+		return protoType{name: "int64", scalar: true}, nil
+	case "number":
+		return protoType{name: "double", scalar: true}, nil
+	case "boolean":
+		return protoType{name: "bool", scalar: true}, nil
+	case "array":
+		if s.Items == nil || len(s.PrefixItems) > 0 {
+			// Protobuf has no tuples.
+			return g.repeated(g.wellKnown(protoValue)), nil
+		}
+		t, err := g.fieldType(sc, name+"Item", s.Items, indent)
+		if err != nil {
+			return protoType{}, err
+		}
+		if t.label != "" {
+			// Repeated fields cannot be nested.
+			t = g.wellKnown(protoList)
+		}
+		return g.repeated(t), nil
+	case "object":
+		if b, ok := boolSchema(s.AdditionalProperties); s.AdditionalProperties == nil || ok && b {
+			return g.wellKnown(protoStruct), nil
+		}
+		t, err := g.fieldType(sc, name+"Value", s.AdditionalProperties, indent)
+		if err != nil {
+			return protoType{}, err
+		}
+		if t.label != "" || strings.HasPrefix(t.name, "map<") {
+			// Map values cannot be repeated or maps.
+			t = g.wellKnown(protoValue)
+		}
+		return protoType{name: "map<string, " + t.name + ">", values: t.values}, nil
+	}
+	return g.wellKnown(protoValue), nil
+}
+
+// wellKnown returns t, a well-known type, importing its file.
+func (g *protoGenerator) wellKnown(t protoType) protoType {
+	g.imports["google/protobuf/struct.proto"] = true
+	return t
+}
+
+// repeated returns a repeated field of the type t.
+func (g *protoGenerator) repeated(t protoType) protoType {
+	t.label = "repeated"
+	t.scalar = false
+	return t
+}
+
+// valueType returns the type of a field whose values are vals.
+func (g *protoGenerator) valueType(vals []any) protoType {
+	var t protoType
+	switch goValueType(vals) {
+	case "string":
+		t = protoType{name: "string", scalar: true}
+	case "bool":
+		t = protoType{name: "bool", scalar: true}
+	case "int64":
+		t = protoType{name: "int64", scalar: true}
+	case "float64":
+		t = protoType{name: "double", scalar: true}
+	default:
+		t = g.wellKnown(protoValue)
+	}
+	t.values = vals
+	return t
+}
+
+// enum declares in sc an enum named after name of the values vals,
+// if they are strings that can name enum values there.
+func (g *protoGenerator) enum(sc *protoScope, name string, vals []any, indent string) (protoType, bool) {
+	unspecified := strings.ToUpper(strings.Join(nameWords(name), "_")) + "_UNSPECIFIED"
+	names := []string{unspecified}
+	for _, v := range vals {
+		str, ok := v.(string)
+		if !ok || !isProtoIdentifier(str) || sc.names[str] || slices.Contains(names, str) {
+			return protoType{}, false
+		}
+		names = append(names, str)
+	}
+	if sc.names[unspecified] {
+		return protoType{}, false
+	}
+	name = sc.unique(name)
+	inner := indent + "  "
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%senum %s {\n", indent, name)
+	for i, n := range names {
+		sc.names[n] = true
+		fmt.Fprintf(&sb, "%s%s = %d;\n", inner, n, i)
+	}
+	sb.WriteString(indent + "}\n")
+	sc.nested = append(sc.nested, sb.String())
+	return protoType{name: name, scalar: true}, true
+}
+
+// ref returns the type that ref refers to. A $defs entry that is not
+// a message is used in place, with nested types named after name.
+func (g *protoGenerator) ref(sc *protoScope, name, ref, indent string) (protoType, error) {
+	if ref == "#" {
+		return protoType{name: g.root}, nil
+	}
+	if rest, ok := strings.CutPrefix(ref, "#/$defs/"); ok {
+		def := unescapePointer(rest)
+		if id, ok := g.defs[def]; ok {
+			return protoType{name: id}, nil
+		}
+		if s, ok := g.schemas[def]; ok {
+			if g.inlining[def] {
+				return protoType{}, fmt.Errorf("$defs %q refers to itself other than through a message", def)
+			}
+			g.inlining[def] = true
+			defer delete(g.inlining, def)
+			return g.fieldType(sc, name, s, indent)
+		}
+	}
+	return protoType{}, fmt.Errorf("$ref %q is not to the schema or one of its $defs", ref)
+}
+
+// writeProtoDoc writes the comment of s, indented by indent: its
+// description, and vals, the values of an enum or const.
+func writeProtoDoc(sb *strings.Builder, s *jsonschema.Schema, vals []any, indent string) {
+	var paras []string
+	if desc := schemaDescription(s); desc != "" {
+		paras = append(paras, desc)
+	}
+	if len(vals) > 0 {
+		var lits []string
+		for _, v := range vals {
+			lits = append(lits, jsonText(v))
+		}
+		paras = append(paras, "One of "+strings.Join(lits, ", ")+".")
+	}
+	for i, p := range paras {
+		if i > 0 {
+			sb.WriteString(indent + "//\n")
+		}
+		for _, l := range strings.Split(p, "\n") {
+			fmt.Fprintf(sb, "%s// %s\n", indent, strings.TrimRight(l, " "))
+		}
+	}
+}
+
+// protoFieldName returns the property name prop as a field name, its
+// words in lower snake case, as "createdAt" becomes "created_at".
+func protoFieldName(prop string) string {
+	name := strings.ToLower(strings.Join(nameWords(prop), "_"))
+	if name == "" || isDigit(name[0]) {
+		name = "x_" + name
+	}
+	return name
+}
+
+// protoJSONName returns the JSON name protoc gives the field name:
+// the name in lower camel case.
+func protoJSONName(field string) string {
+	var sb strings.Builder
+	upper := false
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+func isProtoIdentifier(s string) bool {
+	if s == "" || isDigit(s[0]) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '_' && !isDigit(c) && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
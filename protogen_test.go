@@ -0,0 +1,199 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerateProto(t *testing.T) {
+	s := mustSchema(t, `
+$defs:
+  money:
+    amount: number
+    currency: string
+  sku(string, pattern=^[A-Z0-9]+$):
+order_id: string, the order ID
+status(enum): [open, closed]
+createdAt(string, format=date-time):
+note?: string?
+total: money
+discount?: money
+customer(object):
+  name: string
+  email?: string
+lines(array):
+  sku: sku
+  quantity(integer):
+tags?(array): string
+labels?(*): string
+payment(oneOf):
+  - {card: string}
+  - {iban: string}
+legacy?(string, deprecated):
+level(enum): [1, 2]
+"content-type": string
+`)
+	got, err := GenerateProto(s, "shop.v1", "Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `// Code generated by picoschema. DO NOT EDIT.
+
+syntax = "proto3";
+
+package shop.v1;
+
+import "google/protobuf/struct.proto";
+import "google/protobuf/timestamp.proto";
+
+message Order {
+  message Customer {
+    optional string email = 1;
+    string name = 2;
+  }
+
+  message LinesItem {
+    int64 quantity = 1;
+    string sku = 2;
+  }
+
+  enum Status {
+    STATUS_UNSPECIFIED = 0;
+    open = 1;
+    closed = 2;
+  }
+
+  string content_type = 1 [json_name = "content-type"];
+  google.protobuf.Timestamp created_at = 2;
+  Customer customer = 3;
+  Money discount = 4;
+  map<string, string> labels = 5;
+  optional string legacy = 6 [deprecated = true];
+  // One of 1, 2.
+  int64 level = 7;
+  repeated LinesItem lines = 8;
+  optional string note = 9;
+  // the order ID
+  string order_id = 10 [json_name = "order_id"];
+  google.protobuf.Value payment = 11;
+  Status status = 12;
+  repeated string tags = 13;
+  Money total = 14;
+}
+
+message Money {
+  double amount = 1;
+  string currency = 2;
+}
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestGenerateProtoEnums(t *testing.T) {
+	s := mustSchema(t, `
+kind(enum): [a-b, c]
+mode(enum): [fast, slow]
+fast: boolean
+`)
+	got, err := GenerateProto(s, "p", "M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"  // One of \"a-b\", \"c\".\n  string kind = 2;\n",
+		// The value fast would clash with the field fast.
+		"  // One of \"fast\", \"slow\".\n  string mode = 3;\n",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("output does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestGenerateProtoFieldNumbers(t *testing.T) {
+	s := mustSchema(t, `
+name(protoField=2): string
+id(protoField=1): string
+email: string
+age: integer
+`)
+	got, err := GenerateProto(s, "p", "M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"  string id = 1;\n",
+		"  string name = 2;\n",
+		// Unnumbered fields follow the highest number, by name.
+		"  int64 age = 3;\n",
+		"  string email = 4;\n",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("output does not contain %q:\n%s", want, got)
+		}
+	}
+
+	if _, err := ParseYAMLString("a(protoField=19000): string"); err == nil {
+		t.Error("reserved field number accepted")
+	}
+	s = mustSchema(t, "a(protoField=1): string\nb(protoField=1): string")
+	if _, err := GenerateProto(s, "p", "M"); err == nil || !strings.Contains(err.Error(), `properties "a" and "b" have the field number 1`) {
+		t.Errorf("repeated field number: got %v", err)
+	}
+}
+
+func TestGenerateProtoErrors(t *testing.T) {
+	s := mustSchema(t, "a: string")
+	if _, err := GenerateProto(s, "my-pkg", "M"); err == nil {
+		t.Error("invalid package name accepted")
+	}
+	if _, err := GenerateProto(s, "p", "my message"); err == nil {
+		t.Error("invalid message name accepted")
+	}
+	if _, err := GenerateProto(mustSchema(t, "string"), "p", "M"); err == nil {
+		t.Error("scalar schema accepted")
+	}
+	s.Properties.Value("a").Ref = "customer"
+	if _, err := GenerateProto(s, "p", "M"); err == nil || !strings.Contains(err.Error(), `$ref "customer"`) {
+		t.Errorf("external ref: got %v", err)
+	}
+	s = mustSchema(t, "$defs:\n  list(array): list\na: list")
+	if _, err := GenerateProto(s, "p", "M"); err == nil {
+		t.Error("recursive $defs that is not a message accepted")
+	}
+}
+
+func TestProtoNames(t *testing.T) {
+	for prop, want := range map[string]string{
+		"id":           "id",
+		"createdAt":    "created_at",
+		"order_id":     "order_id",
+		"content-type": "content_type",
+		"2fa":          "x_2fa",
+	} {
+		if got := protoFieldName(prop); got != want {
+			t.Errorf("protoFieldName(%q) = %q, want %q", prop, got, want)
+		}
+	}
+	if got := protoJSONName("created_at"); got != "createdAt" {
+		t.Errorf("protoJSONName(created_at) = %q", got)
+	}
+}
@@ -0,0 +1,558 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// GeneratePydantic returns the source of a Python module declaring the
+// Pydantic v2 model className, which validates the JSON values that s
+// accepts, so that Python code consuming the output of the same prompts
+// as Go code validates it by the same rules.
+//
+// s must be an object with properties. An object with properties
+// becomes a model whose fields are sorted by name. A property whose
+// name is not a Python identifier is a field named in snake case with
+// an alias. A property that is not required is Optional with a default
+// of None, as is a nullable one, as in picoschema "string?", without
+// the default. An object nested in a property or an array is a model
+// named after the enclosing model and the property, as OrderCustomer is
+// for the property customer of Order, and each schema under $defs of s
+// is declared under its name, made an identifier, as a model or a type
+// alias. A type alias, which Python evaluates where it is declared,
+// follows the declarations it uses, so one that refers to itself other
+// than through a model is an error. Models forbid properties they do
+// not declare if s does.
+//
+// Enums and consts become Literal types, unions Union types, and the
+// string, number and array bounds, such as maxLength and minimum,
+// constraints of Field. Descriptions become docstrings and the
+// descriptions of fields. Strings of the format date-time and date
+// become datetime and date. Schemas with no Python equivalent become
+// Any.
+//
+// A schema with refs to other documents must be bundled first (see
+// Bundle).
+func GeneratePydantic(s *jsonschema.Schema, className string) ([]byte, error) {
+	if !isPyIdentifier(className) {
+		return nil, fmt.Errorf("picoschema: class name %q is not a Python identifier", className)
+	}
+	if !goIsStruct(s) {
+		return nil, fmt.Errorf("picoschema: the schema is not an object with properties")
+	}
+	g := &pyGenerator{
+		root:     className,
+		defs:     make(map[string]string),
+		taken:    map[string]bool{className: true},
+		typing:   make(map[string]bool),
+		datetime: make(map[string]bool),
+	}
+	defs := sortedKeys(s.Definitions)
+	for _, name := range defs {
+		g.defs[name] = g.uniqueName(goName(name))
+	}
+	for _, name := range defs {
+		if err := g.declare(g.defs[name], s.Definitions[name]); err != nil {
+			return nil, fmt.Errorf("picoschema: $defs %q: %w", name, err)
+		}
+	}
+	if err := g.declare(className, s); err != nil {
+		return nil, err
+	}
+	decls, err := g.ordered()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Code generated by picoschema. DO NOT EDIT.\n\nfrom __future__ import annotations\n\n")
+	if len(g.datetime) > 0 {
+		fmt.Fprintf(&buf, "from datetime import %s\n", strings.Join(sortedKeys(g.datetime), ", "))
+	}
+	if len(g.typing) > 0 {
+		fmt.Fprintf(&buf, "from typing import %s\n", strings.Join(sortedKeys(g.typing), ", "))
+	}
+	if len(g.datetime) > 0 || len(g.typing) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("from pydantic import BaseModel, ConfigDict, Field\n")
+	for _, d := range decls {
+		buf.WriteString("\n\n")
+		buf.WriteString(d)
+	}
+	return buf.Bytes(), nil
+}
+
+// A pyGenerator writes Pydantic models of schemas.
+type pyGenerator struct {
+	root     string
+	defs     map[string]string // Python names of $defs
+	taken    map[string]bool   // declared names
+	decls    []pyDecl
+	uses     *[]string       // names used by the type alias being declared
+	typing   map[string]bool // names imported from typing
+	datetime map[string]bool // names imported from datetime
+}
+
+// A pyDecl is the declaration of a model or type alias.
+type pyDecl struct {
+	name, text string
+	// uses are the names of $defs and of the root that a type alias
+	// uses. Python evaluates an alias where it is declared, but not
+	// the annotations of a model, with postponed evaluation.
+	uses []string
+}
+
+// ordered returns the text of the declarations, in the order they were
+// made except that each type alias follows the declarations it uses.
+func (g *pyGenerator) ordered() ([]string, error) {
+	index := make(map[string]int, len(g.decls))
+	for i, d := range g.decls {
+		index[d.name] = i
+	}
+	const visiting, done = 1, 2
+	state := make([]int, len(g.decls))
+	var out []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("picoschema: type alias %s refers to itself other than through a model", g.decls[i].name)
+		case done:
+			return nil
+		}
+		state[i] = visiting
+		for _, u := range g.decls[i].uses {
+			if j, ok := index[u]; ok {
+				if err := visit(j); err != nil {
+					return err
+				}
+			}
+		}
+		state[i] = done
+		out = append(out, g.decls[i].text)
+		return nil
+	}
+	for i := range g.decls {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// uniqueName returns name, or name followed by a number if it is
+// already declared, and reserves it.
+func (g *pyGenerator) uniqueName(name string) string {
+	unique := name
+	for i := 2; g.taken[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	g.taken[unique] = true
+	return unique
+}
+
+// declare declares name for s: a model if s is an object with
+// properties, and otherwise a type alias.
+func (g *pyGenerator) declare(name string, s *jsonschema.Schema) error {
+	if goIsStruct(s) {
+		return g.model(name, s)
+	}
+	var uses []string
+	g.uses = &uses
+	t, err := g.annotated(name, s)
+	g.uses = nil
+	if err != nil {
+		return err
+	}
+	g.decls = append(g.decls, pyDecl{name: name, text: fmt.Sprintf("%s = %s\n", name, t), uses: uses})
+	return nil
+}
+
+// model declares the model name for s, an object with properties.
+func (g *pyGenerator) model(name string, s *jsonschema.Schema) error {
+	// The annotations of a model are not evaluated with it.
+	uses := g.uses
+	g.uses = nil
+	defer func() { g.uses = uses }()
+	var props []string
+	for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+		props = append(props, p.Key)
+	}
+	slices.Sort(props)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "class %s(BaseModel):\n", name)
+	if s.Description != "" {
+		sb.WriteString(pyDocstring(s.Description, "    "))
+		sb.WriteString("\n")
+	}
+	var config []string
+	if b, ok := boolSchema(s.AdditionalProperties); ok && !b {
+		config = append(config, `extra="forbid"`)
+	}
+	fields := make(map[string]bool)
+	var body strings.Builder
+	for _, prop := range props {
+		ps := s.Properties.Value(prop)
+		field := pyFieldName(prop)
+		for i := 2; fields[field]; i++ {
+			field = pyFieldName(prop) + strconv.Itoa(i)
+		}
+		fields[field] = true
+
+		t, kwargs, err := g.typ(name+goName(prop), ps)
+		if err != nil {
+			return fmt.Errorf("property %q: %w", prop, err)
+		}
+		required := slices.Contains(s.Required, prop)
+		if !required {
+			t = g.optional(t)
+		}
+		if field != prop {
+			kwargs = append([]string{"alias=" + jsonText(prop)}, kwargs...)
+			if !slices.Contains(config, "populate_by_name=True") {
+				config = append(config, "populate_by_name=True")
+			}
+		}
+		if desc := schemaDescription(ps); desc != "" {
+			kwargs = append(kwargs, "description="+jsonText(desc))
+		}
+		if _, ok := boolSchema(ps); !ok && ps.Deprecated {
+			kwargs = append(kwargs, "deprecated=True")
+		}
+		fmt.Fprintf(&body, "    %s: %s", field, t)
+		switch {
+		case len(kwargs) > 0 && !required:
+			fmt.Fprintf(&body, " = Field(None, %s)", strings.Join(kwargs, ", "))
+		case len(kwargs) > 0:
+			fmt.Fprintf(&body, " = Field(%s)", strings.Join(kwargs, ", "))
+		case !required:
+			body.WriteString(" = None")
+		}
+		body.WriteString("\n")
+	}
+	if len(config) > 0 {
+		fmt.Fprintf(&sb, "    model_config = ConfigDict(%s)\n\n", strings.Join(config, ", "))
+	}
+	sb.WriteString(body.String())
+	g.decls = append(g.decls, pyDecl{name: name, text: sb.String()})
+	return nil
+}
+
+// annotated returns the Python type of s, with the constraints of Field
+// that it has, if any, in an Annotated type. Nested models are named
+// after name.
+func (g *pyGenerator) annotated(name string, s *jsonschema.Schema) (string, error) {
+	t, kwargs, err := g.typ(name, s)
+	if err != nil || len(kwargs) == 0 {
+		return t, err
+	}
+	g.typing["Annotated"] = true
+	return fmt.Sprintf("Annotated[%s, Field(%s)]", t, strings.Join(kwargs, ", ")), nil
+}
+
+// optional returns the type t made Optional, unless it accepts None.
+func (g *pyGenerator) optional(t string) string {
+	if t == "Any" || t == "None" || strings.HasPrefix(t, "Optional[") {
+		return t
+	}
+	g.typing["Optional"] = true
+	return "Optional[" + t + "]"
+}
+
+// typ returns the Python type of s and the arguments of Field that
+// constrain it further. An object with properties is declared as a
+// model named name.
+func (g *pyGenerator) typ(name string, s *jsonschema.Schema) (string, []string, error) {
+	if _, ok := boolSchema(s); ok {
+		g.typing["Any"] = true
+		return "Any", nil, nil
+	}
+	s = withoutPresence(s)
+	if s.Ref != "" && s.AnyOf == nil && s.OneOf == nil && s.AllOf == nil {
+		t, err := g.ref(s.Ref)
+		return t, nil, err
+	}
+	if alt, ok := nullableAlternative(s); ok {
+		t, kwargs, err := g.typ(name, alt)
+		if err != nil {
+			return "", nil, err
+		}
+		return g.optional(t), kwargs, nil
+	}
+	if len(s.AllOf) == 1 && s.AnyOf == nil && s.OneOf == nil && len(schemaKeywords(s)) == 1 {
+		return g.typ(name, s.AllOf[0])
+	}
+	if s.Ref == "" && s.AllOf == nil && (s.AnyOf == nil) != (s.OneOf == nil) {
+		alts := s.AnyOf
+		if alts == nil {
+			alts = s.OneOf
+		}
+		var ts []string
+		for i, alt := range alts {
+			t, err := g.annotated(fmt.Sprintf("%sOption%d", name, i+1), alt)
+			if err != nil {
+				return "", nil, err
+			}
+			if !slices.Contains(ts, t) {
+				ts = append(ts, t)
+			}
+		}
+		if len(ts) == 1 {
+			return ts[0], nil, nil
+		}
+		g.typing["Union"] = true
+		return "Union[" + strings.Join(ts, ", ") + "]", nil, nil
+	}
+	if s.Ref != "" || s.AnyOf != nil || s.OneOf != nil || s.AllOf != nil {
+		g.typing["Any"] = true
+		return "Any", nil, nil
+	}
+	if s.Const != nil {
+		return g.literal([]any{s.Const}), nil, nil
+	}
+	if s.Enum != nil {
+		return g.literal(s.Enum), nil, nil
+	}
+	if goIsStruct(s) {
+		name = g.uniqueName(name)
+		if err := g.model(name, s); err != nil {
+			return "", nil, err
+		}
+		return name, nil, nil
+	}
+	switch s.Type {
+	case "string":
+		var kwargs []string
+		if s.MinLength != nil {
+			kwargs = append(kwargs, fmt.Sprintf("min_length=%d", *s.MinLength))
+		}
+		if s.MaxLength != nil {
+			kwargs = append(kwargs, fmt.Sprintf("max_length=%d", *s.MaxLength))
+		}
+		if s.Pattern != "" {
+			kwargs = append(kwargs, "pattern="+pyRawString(s.Pattern))
+		}
+		switch s.Format {
+		case "date-time":
+			g.datetime["datetime"] = true
+			return "datetime", kwargs, nil
+		case "date":
+			g.datetime["date"] = true
+			return "date", kwargs, nil
+		}
+		return "str", kwargs, nil
+	case "integer", "number":
+		var kwargs []string
+		for _, c := range []struct {
+			arg string
+			n   string
+		}{
+			{"ge", string(s.Minimum)},
+			{"gt", string(s.ExclusiveMinimum)},
+			{"le", string(s.Maximum)},
+			{"lt", string(s.ExclusiveMaximum)},
+			{"multiple_of", string(s.MultipleOf)},
+		} {
+			if c.n != "" {
+				kwargs = append(kwargs, c.arg+"="+c.n)
+			}
+		}
+		if s.Type == "integer" {
+			return "int", kwargs, nil
+		}
+		return "float", kwargs, nil
+	case "boolean":
+		return "bool", nil, nil
+	case "null":
+		return "None", nil, nil
+	case "array":
+		var kwargs []string
+		if s.MinItems != nil {
+			kwargs = append(kwargs, fmt.Sprintf("min_length=%d", *s.MinItems))
+		}
+		if s.MaxItems != nil {
+			kwargs = append(kwargs, fmt.Sprintf("max_length=%d", *s.MaxItems))
+		}
+		if len(s.PrefixItems) > 0 {
+			var elems []string
+			for i, e := range s.PrefixItems {
+				t, err := g.annotated(fmt.Sprintf("%sItem%d", name, i+1), e)
+				if err != nil {
+					return "", nil, err
+				}
+				elems = append(elems, t)
+			}
+			if b, ok := boolSchema(s.Items); !ok || b {
+				// Python tuples cannot have a fixed prefix
+				// and more items.
+				g.typing["Any"] = true
+				return "list[Any]", kwargs, nil
+			}
+			// The tuple type fixes the length.
+			return "tuple[" + strings.Join(elems, ", ") + "]", nil, nil
+		}
+		if s.Items == nil {
+			g.typing["Any"] = true
+			return "list[Any]", kwargs, nil
+		}
+		t, err := g.annotated(name+"Item", s.Items)
+		if err != nil {
+			return "", nil, err
+		}
+		return "list[" + t + "]", kwargs, nil
+	case "object":
+		if b, ok := boolSchema(s.AdditionalProperties); s.AdditionalProperties == nil || ok && b {
+			g.typing["Any"] = true
+			return "dict[str, Any]", nil, nil
+		}
+		t, err := g.annotated(name+"Value", s.AdditionalProperties)
+		if err != nil {
+			return "", nil, err
+		}
+		return "dict[str, " + t + "]", nil, nil
+	}
+	g.typing["Any"] = true
+	return "Any", nil, nil
+}
+
+// literal returns the Literal type of the JSON values vals.
+func (g *pyGenerator) literal(vals []any) string {
+	var lits []string
+	hasNone := false
+	for _, v := range vals {
+		if v == nil {
+			hasNone = true
+			continue
+		}
+		lit, ok := pyLiteral(v)
+		if !ok {
+			// Literal admits only scalars.
+			g.typing["Any"] = true
+			return "Any"
+		}
+		lits = append(lits, lit)
+	}
+	if len(lits) == 0 {
+		return "None"
+	}
+	g.typing["Literal"] = true
+	t := "Literal[" + strings.Join(lits, ", ") + "]"
+	if hasNone {
+		t = g.optional(t)
+	}
+	return t
+}
+
+// ref returns the Python type that ref refers to.
+func (g *pyGenerator) ref(ref string) (string, error) {
+	name, ok := g.root, ref == "#"
+	if rest, isDef := strings.CutPrefix(ref, "#/$defs/"); isDef {
+		name, ok = g.defs[unescapePointer(rest)]
+	}
+	if ok {
+		if g.uses != nil {
+			*g.uses = append(*g.uses, name)
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("$ref %q is not to the schema or one of its $defs", ref)
+}
+
+// pyLiteral returns the Python literal of the JSON scalar v.
+func pyLiteral(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return jsonText(v), true
+	case bool:
+		if v {
+			return "True", true
+		}
+		return "False", true
+	case float64, int, int64, uint64:
+		return jsonText(v), true
+	}
+	return "", false
+}
+
+// pyRawString returns s as a Python string literal, raw unless
+// it cannot be.
+func pyRawString(s string) string {
+	if !strings.ContainsAny(s, "\"\n\r") && !strings.HasSuffix(s, `\`) {
+		return `r"` + s + `"`
+	}
+	return jsonText(s)
+}
+
+// pyDocstring returns the docstring text, indented by indent.
+func pyDocstring(text, indent string) string {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, `\`, `\\`), `"""`, `\"\"\"`)
+	if strings.HasSuffix(text, `"`) {
+		// A quote would run into the closing quotes.
+		text = text[:len(text)-1] + `\"`
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) == 1 {
+		return fmt.Sprintf("%s\"\"\"%s\"\"\"\n", indent, text)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\"\"\"%s\n", indent, lines[0])
+	for _, l := range lines[1:] {
+		if l = strings.TrimRight(l, " "); l == "" {
+			sb.WriteString("\n")
+		} else {
+			fmt.Fprintf(&sb, "%s%s\n", indent, l)
+		}
+	}
+	fmt.Fprintf(&sb, "%s\"\"\"\n", indent)
+	return sb.String()
+}
+
+// pyKeywords are the reserved words of Python 3.
+var pyKeywords = []string{"False", "None", "True", "and", "as", "assert", "async", "await",
+	"break", "class", "continue", "def", "del", "elif", "else", "except", "finally", "for",
+	"from", "global", "if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass",
+	"raise", "return", "try", "while", "with", "yield"}
+
+// pyFieldName returns the field name of the property prop: prop itself
+// if it can be a field name, and otherwise its words in snake case, as
+// "content-type" becomes "content_type".
+func pyFieldName(prop string) string {
+	if isPyIdentifier(prop) && !strings.HasPrefix(prop, "_") && !strings.HasPrefix(prop, "model_") {
+		return prop
+	}
+	name := strings.ToLower(strings.Join(nameWords(prop), "_"))
+	if name == "" || isDigit(name[0]) {
+		name = "field_" + name
+	}
+	if slices.Contains(pyKeywords, name) || strings.HasPrefix(name, "model_") {
+		name += "_"
+	}
+	return name
+}
+
+// isPyIdentifier reports whether s is an ASCII Python identifier
+// that is not a keyword.
+func isPyIdentifier(s string) bool {
+	return isProtoIdentifier(s) && !slices.Contains(pyKeywords, s)
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGeneratePydantic(t *testing.T) {
+	s := mustSchema(t, `
+(description): An order.
+$defs:
+  money:
+    amount(number, minimum=0):
+    currency(string, pattern=^[A-Z]{3}$):
+  sku(string, maxLength=8):
+order_id: string, the order ID
+status(enum): [open, closed]
+createdAt(string, format=date-time):
+note?: string?
+total: money
+discount?: money
+customer(object):
+  name: string
+  email?: string
+lines(array, minItems=1):
+  sku: sku
+  quantity(integer, minimum=1):
+tags?(array): string
+labels?(*): string
+payment(oneOf):
+  - {card: string}
+  - {iban: string}
+legacy?(string, deprecated):
+"content-type": string
+class: string
+point(tuple): [number, number]
+`)
+	got, err := GeneratePydantic(s, "Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Code generated by picoschema. DO NOT EDIT.
+
+from __future__ import annotations
+
+from datetime import datetime
+from typing import Annotated, Literal, Optional, Union
+
+from pydantic import BaseModel, ConfigDict, Field
+
+
+class Money(BaseModel):
+    model_config = ConfigDict(extra="forbid")
+
+    amount: float = Field(ge=0)
+    currency: str = Field(pattern=r"^[A-Z]{3}$")
+
+
+Sku = Annotated[str, Field(max_length=8)]
+
+
+class OrderCustomer(BaseModel):
+    model_config = ConfigDict(extra="forbid")
+
+    email: Optional[str] = None
+    name: str
+
+
+class OrderLinesItem(BaseModel):
+    model_config = ConfigDict(extra="forbid")
+
+    quantity: int = Field(ge=1)
+    sku: Sku
+
+
+class OrderPaymentOption1(BaseModel):
+    model_config = ConfigDict(extra="forbid")
+
+    card: str
+
+
+class OrderPaymentOption2(BaseModel):
+    model_config = ConfigDict(extra="forbid")
+
+    iban: str
+
+
+class Order(BaseModel):
+    """An order."""
+
+    model_config = ConfigDict(extra="forbid", populate_by_name=True)
+
+    class_: str = Field(alias="class")
+    content_type: str = Field(alias="content-type")
+    createdAt: datetime
+    customer: OrderCustomer
+    discount: Optional[Money] = None
+    labels: Optional[dict[str, str]] = None
+    legacy: Optional[str] = Field(None, deprecated=True)
+    lines: list[OrderLinesItem] = Field(min_length=1)
+    note: Optional[str] = None
+    order_id: str = Field(description="the order ID")
+    payment: Union[OrderPaymentOption1, OrderPaymentOption2]
+    point: tuple[float, float]
+    status: Literal["open", "closed"]
+    tags: Optional[list[str]] = None
+    total: Money
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestGeneratePydanticErrors(t *testing.T) {
+	s := mustSchema(t, "a: string")
+	if _, err := GeneratePydantic(s, "class"); err == nil {
+		t.Error("keyword class name accepted")
+	}
+	if _, err := GeneratePydantic(s.Properties.Value("a"), "Order"); err == nil {
+		t.Error("string schema accepted")
+	}
+	s.Properties.Value("a").Ref = "customer"
+	if _, err := GeneratePydantic(s, "Order"); err == nil || !strings.Contains(err.Error(), `$ref "customer"`) {
+		t.Errorf("external ref: got %v", err)
+	}
+	s = mustSchema(t, "$defs:\n  list(array): list\na: list")
+	if _, err := GeneratePydantic(s, "Order"); err == nil || !strings.Contains(err.Error(), "type alias List refers to itself") {
+		t.Errorf("recursive type alias: got %v", err)
+	}
+}
+
+func TestGeneratePydanticOrder(t *testing.T) {
+	// Type aliases are evaluated where they are declared,
+	// so they follow the declarations they use.
+	s := mustSchema(t, `
+$defs:
+  aList(array): zItem
+  zItem:
+    name: string
+  parent: $self
+items: aList
+up?: parent
+`)
+	got, err := GeneratePydantic(s, "Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decls []string
+	for _, line := range strings.Split(string(got), "\n") {
+		if strings.HasPrefix(line, "class ") || strings.Contains(line, " = ") && !strings.HasPrefix(line, " ") {
+			decls = append(decls, line)
+		}
+	}
+	want := []string{"class ZItem(BaseModel):", "AList = list[ZItem]", "class Order(BaseModel):", "Parent = Order"}
+	if diff := cmp.Diff(want, decls); diff != "" {
+		t.Errorf("declarations mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestPyFieldName(t *testing.T) {
+	for prop, want := range map[string]string{
+		"id":           "id",
+		"createdAt":    "createdAt",
+		"content-type": "content_type",
+		"class":        "class_",
+		"_private":     "private",
+		"2fa":          "field_2fa",
+		"model_name":   "model_name_",
+	} {
+		if got := pyFieldName(prop); got != want {
+			t.Errorf("pyFieldName(%q) = %q, want %q", prop, got, want)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A Registry holds named schemas. A name may be qualified by a namespace,
+// as in "billing.Invoice", and may carry a version after an "@", as in
+// "billing.Invoice@2", following the file naming used by BuildCatalog.
+// The zero Registry is empty and ready to use. A Registry is safe for
+// concurrent use.
+type Registry struct {
+	// Immutable makes a name, once registered, refuse different content.
+	// Register then fails with a *ConflictError if the name is already
+	// registered with a schema of a different Fingerprint, and succeeds
+	// without effect if the fingerprints match. This is how schema
+	// publication should behave in CI: a published version never changes.
+	Immutable bool
+
+	// Interner, if set, interns the registered schemas, so that
+	// subschemas repeated within and across them are held once.
+	// It may be shared by several registries.
+	Interner *Interner
+
+	mu        sync.RWMutex
+	schemas   map[string]*jsonschema.Schema
+	resolvers map[string]SchemaResolver // by namespace
+	lazy      map[string]*lazyEntry     // registered with RegisterLazy, not yet converted
+	sizes     map[string]int64          // estimated retained bytes, by name
+}
+
+// A ConflictError reports an attempt to change the content registered
+// under a name in an immutable Registry.
+type ConflictError struct {
+	Name string
+	// Existing and New are the fingerprints of the registered schema
+	// and of the rejected one.
+	Existing, New string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("picoschema: %q is already registered with fingerprint %s, not %s", e.Name, e.Existing, e.New)
+}
+
+// Register adds a copy of s to r under name. Unless r is Immutable,
+// it replaces any schema previously registered under that name.
+func (r *Registry) Register(name string, s *jsonschema.Schema) error {
+	if name == "" {
+		return errors.New("picoschema: Register with empty name")
+	}
+	if s == nil {
+		return fmt.Errorf("picoschema: Register %q with nil schema", name)
+	}
+	if r.Immutable {
+		// Convert a schema pending from RegisterLazy, to check s against it.
+		if _, _, err := r.lookup(name); err != nil {
+			return err
+		}
+	}
+	c, err := r.copyIn(s)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.schemas[name]; ok && r.Immutable {
+		oldFP, err := Fingerprint(old)
+		if err != nil {
+			return err
+		}
+		newFP, err := Fingerprint(c)
+		if err != nil {
+			return err
+		}
+		if oldFP != newFP {
+			return &ConflictError{Name: name, Existing: oldFP, New: newFP}
+		}
+		return nil
+	}
+	if r.schemas == nil {
+		r.schemas = make(map[string]*jsonschema.Schema)
+	}
+	r.schemas[name] = c
+	r.setSize(name, c)
+	delete(r.lazy, name)
+	return nil
+}
+
+// setSize records the estimated size of s, registered under name.
+// r.mu must be held.
+func (r *Registry) setSize(name string, s *jsonschema.Schema) {
+	if r.sizes == nil {
+		r.sizes = make(map[string]int64)
+	}
+	r.sizes[name] = retainedBytes(s)
+}
+
+// copyIn returns the copy of s that r holds, interned if r has an Interner.
+func (r *Registry) copyIn(s *jsonschema.Schema) (*jsonschema.Schema, error) {
+	c := cloneSchema(s)
+	if r.Interner != nil {
+		return r.Interner.intern(c)
+	}
+	return c, nil
+}
+
+// ImportBundle registers each schema in the $defs, or the draft-07
+// definitions, of the JSON Schema document data under its name, and
+// returns the sorted names. Refs from one of the schemas to another,
+// such as "#/$defs/customer", are rewritten to name it, as in
+// "customer", and refs within a schema to itself are made relative to
+// it, so that the schemas resolve one another through r, as a resolver
+// for ToJSONSchema or a Bundler. The root of the document is not
+// registered. ImportBundle stops at the first schema it cannot
+// register, returning the names of those registered before it.
+func (r *Registry) ImportBundle(data []byte) ([]string, error) {
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("picoschema: %w", err)
+	}
+	doc, err := mapToJSONSchema(m)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Definitions) == 0 {
+		return nil, errors.New("picoschema: bundle has no $defs")
+	}
+	names := sortedKeys(doc.Definitions)
+	for _, name := range names {
+		if err := unbundleRefs(doc.Definitions, name); err != nil {
+			return nil, err
+		}
+	}
+	for i, name := range names {
+		if err := r.Register(name, doc.Definitions[name]); err != nil {
+			return names[:i], err
+		}
+	}
+	return names, nil
+}
+
+// unbundleRefs rewrites the refs in defs[name] that point into defs,
+// the $defs of a bundle, to refer to their targets by name.
+func unbundleRefs(defs jsonschema.Definitions, name string) error {
+	var err error
+	walkSchema(defs[name], "", func(s *jsonschema.Schema, _ string) bool {
+		if err != nil {
+			return false
+		}
+		if !strings.HasPrefix(s.Ref, "#") {
+			return true
+		}
+		rest, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+		if !ok {
+			err = fmt.Errorf("picoschema: bundle schema %q: $ref %q is not into $defs", name, s.Ref)
+			return false
+		}
+		first, ptr, _ := strings.Cut(rest, "/")
+		if ptr != "" {
+			ptr = "/" + ptr
+		}
+		target := unescapePointer(first)
+		if _, ok := defs[target]; !ok {
+			err = fmt.Errorf("picoschema: bundle schema %q: $ref %q does not resolve", name, s.Ref)
+			return false
+		}
+		switch {
+		case target == name:
+			s.Ref = "#" + ptr
+		case ptr == "":
+			s.Ref = target
+		default:
+			s.Ref = target + "#" + ptr
+		}
+		return true
+	})
+	return err
+}
+
+// Lookup returns the schema registered under name.
+// The schema is shared and must not be modified.
+func (r *Registry) Lookup(name string) (*jsonschema.Schema, bool) {
+	s, ok, _ := r.lookup(name)
+	return s, ok
+}
+
+// Names returns the sorted names of the registered schemas,
+// including those registered with RegisterLazy.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := sortedKeys(r.schemas)
+	if len(r.lazy) == 0 {
+		return names
+	}
+	for name := range r.lazy {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// A Query selects schema locations for Registry.Find.
+// A location matches if it satisfies every non-empty field.
+type Query struct {
+	// Property is the name of the property at the location.
+	Property string
+	// Type is the JSON Schema type at the location.
+	Type string
+	// Annotation is an extension keyword the location carries, with or
+	// without its "x-" prefix, optionally followed by "=value" to match
+	// a string value or list member, as in "unit=ms" or "x-audiences=internal".
+	Annotation string
+}
+
+// A Match is a schema location found by Registry.Find.
+type Match struct {
+	// Schema is the registered name of the schema.
+	Schema string
+	// Path is the JSON Pointer of the location within the schema.
+	Path string
+	// Property is the name of the property at the location,
+	// if the location is a property.
+	Property string
+}
+
+// Find returns the locations in the registered schemas matching q,
+// ordered by schema name and then in schema order. For example,
+//
+//	r.Find(Query{Property: "customerId"})
+//
+// lists every schema with a property named customerId, at any depth.
+// An empty query matches nothing.
+func (r *Registry) Find(q Query) []Match {
+	if q == (Query{}) {
+		return nil
+	}
+	var out []Match
+	for _, name := range r.Names() {
+		s, _ := r.Lookup(name)
+		walkSchema(s, "", func(s *jsonschema.Schema, path string) bool {
+			prop := propertyName(path)
+			if q.matches(s, prop) {
+				out = append(out, Match{Schema: name, Path: path, Property: prop})
+			}
+			return true
+		})
+	}
+	return out
+}
+
+func (q Query) matches(s *jsonschema.Schema, prop string) bool {
+	if q.Property != "" && q.Property != prop {
+		return false
+	}
+	if q.Type != "" && q.Type != s.Type {
+		return false
+	}
+	if q.Annotation != "" {
+		key, want, hasValue := strings.Cut(q.Annotation, "=")
+		if !strings.HasPrefix(key, "x-") {
+			key = "x-" + key
+		}
+		v, ok := s.Extras[key]
+		if !ok {
+			return false
+		}
+		if hasValue {
+			switch v := v.(type) {
+			case string:
+				return v == want
+			case []any:
+				return slices.Contains(v, any(want))
+			default:
+				return fmt.Sprint(v) == want
+			}
+		}
+	}
+	return true
+}
+
+// propertyName returns the name of the property located by the
+// JSON Pointer path, or "" if path does not locate a property.
+func propertyName(path string) string {
+	parent, last, ok := cutLastSegment(path)
+	if !ok {
+		return ""
+	}
+	_, kw, ok := cutLastSegment(parent)
+	if !ok || kw != "properties" {
+		return ""
+	}
+	return unescapePointer(last)
+}
+
+// cutLastSegment splits a JSON Pointer before its last segment.
+func cutLastSegment(path string) (parent, last string, ok bool) {
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 {
+		return "", "", false
+	}
+	return path[:i], path[i+1:], true
+}
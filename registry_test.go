@@ -0,0 +1,212 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRegistryFind(t *testing.T) {
+	var r Registry
+	for name, src := range map[string]string{
+		"order": `
+customerId: string
+lines(array):
+  sku: string
+  qty(unit=pcs): integer
+`,
+		"customer": `
+customerId: integer
+ssn?(audience=internal|audit): string
+`,
+		"product": `
+sku: string
+weight(unit=kg): number
+`,
+	} {
+		if err := r.Register(name, mustSchema(t, src)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := r.Names(), []string{"customer", "order", "product"}; !cmp.Equal(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+
+	for _, test := range []struct {
+		q    Query
+		want []Match
+	}{
+		{
+			Query{Property: "customerId"},
+			[]Match{
+				{Schema: "customer", Path: "/properties/customerId", Property: "customerId"},
+				{Schema: "order", Path: "/properties/customerId", Property: "customerId"},
+			},
+		},
+		{
+			Query{Property: "customerId", Type: "string"},
+			[]Match{{Schema: "order", Path: "/properties/customerId", Property: "customerId"}},
+		},
+		{
+			Query{Property: "sku"},
+			[]Match{
+				{Schema: "order", Path: "/properties/lines/items/properties/sku", Property: "sku"},
+				{Schema: "product", Path: "/properties/sku", Property: "sku"},
+			},
+		},
+		{
+			Query{Annotation: "unit"},
+			[]Match{
+				{Schema: "order", Path: "/properties/lines/items/properties/qty", Property: "qty"},
+				{Schema: "product", Path: "/properties/weight", Property: "weight"},
+			},
+		},
+		{
+			Query{Annotation: "x-unit=kg"},
+			[]Match{{Schema: "product", Path: "/properties/weight", Property: "weight"}},
+		},
+		{
+			Query{Annotation: "audiences=audit"},
+			[]Match{{Schema: "customer", Path: "/properties/ssn", Property: "ssn"}},
+		},
+		{Query{Type: "boolean"}, nil},
+		{Query{}, nil},
+	} {
+		if diff := cmp.Diff(test.want, r.Find(test.q)); diff != "" {
+			t.Errorf("Find(%+v) mismatch (-want, +got):\n%s", test.q, diff)
+		}
+	}
+}
+
+func TestRegistryImmutable(t *testing.T) {
+	r := Registry{Immutable: true}
+	v1 := mustSchema(t, "id: string\nname: string")
+	if err := r.Register("customer@1", v1); err != nil {
+		t.Fatal(err)
+	}
+	// The same content, with required in another order, is accepted.
+	same := mustSchema(t, "name: string\nid: string")
+	if err := r.Register("customer@1", same); err != nil {
+		t.Errorf("re-registering identical content: %v", err)
+	}
+	err := r.Register("customer@1", mustSchema(t, "id: integer\nname: string"))
+	var ce *ConflictError
+	if !errors.As(err, &ce) || ce.Name != "customer@1" || ce.Existing == ce.New {
+		t.Errorf("got error %v, want a ConflictError", err)
+	}
+	if err := r.Register("customer@2", mustSchema(t, "id: integer\nname: string")); err != nil {
+		t.Errorf("registering a new version: %v", err)
+	}
+	if s, _ := r.Lookup("customer@1"); s.Properties.Value("id").Type != "string" {
+		t.Error("conflicting Register changed the registered schema")
+	}
+
+	var mutable Registry
+	mutable.Register("x", v1)
+	if err := mutable.Register("x", mustSchema(t, "id: integer")); err != nil {
+		t.Errorf("mutable registry refused replacement: %v", err)
+	}
+}
+
+func TestRegistryImportBundle(t *testing.T) {
+	var r Registry
+	names, err := r.ImportBundle([]byte(`{
+		"$defs": {
+			"customer": {
+				"type": "object",
+				"properties": {"name": {"type": "string"}, "referrer": {"$ref": "#/$defs/customer"}},
+				"required": ["name"],
+				"additionalProperties": false
+			},
+			"order": {
+				"type": "object",
+				"properties": {
+					"buyer": {"$ref": "#/$defs/customer"},
+					"buyerName": {"$ref": "#/$defs/customer/properties/name"}
+				},
+				"additionalProperties": false
+			}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"customer", "order"}; !cmp.Equal(names, want) {
+		t.Errorf("got names %v, want %v", names, want)
+	}
+	order, _ := r.Lookup("order")
+	if got, want := order.Properties.Value("buyer").Ref, "customer"; got != want {
+		t.Errorf("buyer: got $ref %q, want %q", got, want)
+	}
+	if got, want := order.Properties.Value("buyerName").Ref, "customer#/properties/name"; got != want {
+		t.Errorf("buyerName: got $ref %q, want %q", got, want)
+	}
+	customer, _ := r.Lookup("customer")
+	if got, want := customer.Properties.Value("referrer").Ref, "#"; got != want {
+		t.Errorf("referrer: got $ref %q, want %q", got, want)
+	}
+
+	// The schemas resolve one another when used by name.
+	s, err := ToJSONSchema(map[string]any{"last?": "order"}, WithResolver(&r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := s.Properties.Value("last")
+	if got, want := last.Properties.Value("buyer").Ref, "#/$defs/customer"; got != want {
+		t.Errorf("resolved buyer: got $ref %q, want %q", got, want)
+	}
+	if got, want := last.Properties.Value("buyerName").Ref, "#/$defs/customer/properties/name"; got != want {
+		t.Errorf("resolved buyerName: got $ref %q, want %q", got, want)
+	}
+	instance := map[string]any{"last": map[string]any{"buyer": map[string]any{"name": "Ada"}, "buyerName": 1.0}}
+	if vs := Check(instance, s); len(vs) != 1 || vs[0].InstancePath != "/last/buyerName" {
+		t.Errorf("got violations %v, want one at /last/buyerName", vs)
+	}
+	if got, want := s.Definitions["customer"].Properties.Value("referrer").Ref, "#/$defs/customer"; got != want {
+		t.Errorf("resolved referrer: got $ref %q, want %q", got, want)
+	}
+
+	// Schemas that refer to each other are put under $defs.
+	if _, err := r.ImportBundle([]byte(`{"$defs": {
+		"ping": {"type": "object", "properties": {"pong": {"$ref": "#/$defs/pong"}}},
+		"pong": {"type": "object", "properties": {"ping": {"$ref": "#/$defs/ping"}}}
+	}}`)); err != nil {
+		t.Fatal(err)
+	}
+	s, err = ToJSONSchema(map[string]any{"first": "ping"}, WithResolver(&r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.Definitions["pong"].Properties.Value("ping").Ref, "#/$defs/ping"; got != want {
+		t.Errorf("cycle: got $ref %q, want %q", got, want)
+	}
+
+	names, err = r.ImportBundle([]byte(`{"definitions": {"tag": {"type": "string"}}}`))
+	if err != nil || !cmp.Equal(names, []string{"tag"}) {
+		t.Errorf("draft-07 bundle: got %v, %v", names, err)
+	}
+	for _, bad := range []string{
+		`{"type": "object"}`,
+		`{"$defs": {"a": {"$ref": "#/properties/x"}}}`,
+		`{"$defs": {"a": {"$ref": "#/$defs/b"}}}`,
+	} {
+		if _, err := r.ImportBundle([]byte(bad)); err == nil {
+			t.Errorf("%s: got nil error", bad)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/invopop/jsonschema"
+)
+
+// An HTTPResolver resolves schema names that are http or https URLs by
+// fetching them. Documents whose URL path ends in .yaml or .yml, or
+// whose content type mentions YAML, are read as picoschema; others
+// are read as JSON Schema.
+type HTTPResolver struct {
+	// Client makes the requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Cache, if set, holds fetched schemas. It may be shared
+	// by several resolvers.
+	Cache *HTTPCache
+
+	// TTL is how long a cached schema is used without asking the
+	// server again. After that, the server is asked with the schema's
+	// ETag, if it sent one, and a 304 Not Modified response renews the
+	// cached schema. Zero means always ask.
+	TTL time.Duration
+
+	// MaxBytes limits the size of a response body.
+	// Zero means DefaultMaxResponseBytes.
+	MaxBytes int64
+
+	// Retry says how to retry requests that fail temporarily.
+	Retry RetryPolicy
+
+	// Breaker, if set, stops requests to hosts that keep failing.
+	Breaker *CircuitBreaker
+
+	// Policy, if set, is consulted before each request and each
+	// redirect. Refs it forbids fail with an error wrapping ErrRefDenied.
+	Policy *RefPolicy
+
+	clientOnce sync.Once
+	client     *http.Client // Client guarded by Policy
+	clientErr  error
+}
+
+// DefaultMaxResponseBytes is the default limit on the size
+// of a schema fetched by an HTTPResolver.
+const DefaultMaxResponseBytes = 4 << 20
+
+// An HTTPCache is an in-memory cache of schemas fetched by HTTPResolvers.
+// The zero HTTPCache is empty and ready to use.
+// An HTTPCache is safe for concurrent use.
+type HTTPCache struct {
+	mu      sync.Mutex
+	entries map[string]*httpCacheEntry
+}
+
+type httpCacheEntry struct {
+	schema  *jsonschema.Schema
+	etag    string
+	fetched time.Time
+}
+
+func (c *HTTPCache) get(url string) (httpCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[url]
+	if !ok {
+		return httpCacheEntry{}, false
+	}
+	return *e, true
+}
+
+func (c *HTTPCache) put(url string, e httpCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*httpCacheEntry)
+	}
+	c.entries[url] = &e
+}
+
+// Len returns the number of cached schemas.
+func (c *HTTPCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Resolve fetches the schema at the URL name, or returns a copy of the
+// cached schema. Failures to fetch the schema are reported as a
+// *ResolveError, which wraps ErrSchemaNotFound if the server responds
+// 404 or 410 and ErrCircuitOpen if the Breaker refused the request;
+// errors in the fetched schema itself are reported as by Import.
+func (r *HTTPResolver) Resolve(name string) (*jsonschema.Schema, error) {
+	s, err := r.resolve(name)
+	if _, isResolve := err.(*ResolveError); isResolve {
+		recordError("resolve", err)
+	}
+	return s, err
+}
+
+func (r *HTTPResolver) resolve(name string) (*jsonschema.Schema, error) {
+	u, err := url.Parse(name)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, &ResolveError{Name: name, Err: fmt.Errorf("%w: not an http or https URL", ErrSchemaNotFound)}
+	}
+	if err := r.Policy.Allow(u); err != nil {
+		return nil, &ResolveError{Name: name, Err: err}
+	}
+	var cached httpCacheEntry
+	var isCached bool
+	if r.Cache != nil {
+		cached, isCached = r.Cache.get(name)
+		fresh := isCached && time.Since(cached.fetched) < r.TTL
+		recordCacheLookup("http", fresh)
+		if fresh {
+			return cloneSchema(cached.schema), nil
+		}
+	}
+	etag := ""
+	if isCached {
+		etag = cached.etag
+	}
+
+	var res *fetchResult
+	attempts := max(r.Retry.Attempts, 1)
+	for attempt := 0; ; attempt++ {
+		if r.Breaker != nil {
+			if err := r.Breaker.allow(u.Host); err != nil {
+				return nil, &ResolveError{Name: name, Err: err}
+			}
+		}
+		res, err = r.fetch(name, etag)
+		if r.Breaker != nil {
+			r.Breaker.record(u.Host, err == nil || !isTransient(err))
+		}
+		if err == nil || !isTransient(err) || attempt+1 >= attempts {
+			break
+		}
+		time.Sleep(r.Retry.backoff(attempt))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if res.status == http.StatusNotModified {
+		if !isCached {
+			return nil, &ResolveError{Name: name, StatusCode: res.status, Err: errors.New("unexpected 304 Not Modified")}
+		}
+		cached.fetched = time.Now()
+		r.Cache.put(name, cached)
+		return cloneSchema(cached.schema), nil
+	}
+	s, err := Import(remoteFormat(name, res.contentType), res.data)
+	if err != nil {
+		return nil, err
+	}
+	if r.Cache != nil {
+		r.Cache.put(name, httpCacheEntry{schema: s, etag: res.etag, fetched: time.Now()})
+		s = cloneSchema(s)
+	}
+	return s, nil
+}
+
+// A fetchResult is a successful or not-modified response.
+type fetchResult struct {
+	status      int
+	etag        string
+	contentType string
+	data        []byte
+}
+
+// fetch makes one request for name, conditional on etag if it is set.
+func (r *HTTPResolver) fetch(name, etag string) (*fetchResult, error) {
+	req, err := http.NewRequest(http.MethodGet, name, nil)
+	if err != nil {
+		return nil, &ResolveError{Name: name, Err: err}
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	client, err := r.httpClient()
+	if err != nil {
+		return nil, &ResolveError{Name: name, Err: err}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &ResolveError{Name: name, Err: err}
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return &fetchResult{status: resp.StatusCode}, nil
+	case http.StatusNotFound, http.StatusGone:
+		return nil, &ResolveError{Name: name, StatusCode: resp.StatusCode, Err: ErrSchemaNotFound}
+	default:
+		return nil, &ResolveError{Name: name, StatusCode: resp.StatusCode, Err: errors.New(resp.Status)}
+	}
+
+	limit := r.MaxBytes
+	if limit <= 0 {
+		limit = DefaultMaxResponseBytes
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, &ResolveError{Name: name, Err: err}
+	}
+	if int64(len(data)) > limit {
+		return nil, &ResolveError{Name: name, Err: fmt.Errorf("%w of %d bytes", ErrResponseTooLarge, limit)}
+	}
+	return &fetchResult{
+		status:      resp.StatusCode,
+		etag:        resp.Header.Get("ETag"),
+		contentType: resp.Header.Get("Content-Type"),
+		data:        data,
+	}, nil
+}
+
+// httpClient returns the client with which r makes requests.
+func (r *HTTPResolver) httpClient() (*http.Client, error) {
+	r.clientOnce.Do(func() {
+		r.client = r.Client
+		if r.client == nil {
+			r.client = http.DefaultClient
+		}
+		if r.Policy != nil {
+			r.client, r.clientErr = r.Policy.guardClient(r.client)
+		}
+	})
+	return r.client, r.clientErr
+}
+
+// ErrResponseTooLarge is returned, wrapped, when a response
+// exceeds the resolver's MaxBytes.
+var ErrResponseTooLarge = errors.New("response exceeds size limit")
+
+// A ResolveError reports a failure to fetch a schema,
+// as distinct from an error in the fetched schema.
+type ResolveError struct {
+	Name string
+	// StatusCode is the HTTP status of the response, if there was one.
+	StatusCode int
+	Err        error
+}
+
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("picoschema: resolving %s: %v", e.Name, e.Err)
+}
+
+func (e *ResolveError) Unwrap() error { return e.Err }
+
+// Temporary reports whether retrying the request might succeed:
+// whether it failed in transport or with a 429 or 5xx status.
+func (e *ResolveError) Temporary() bool {
+	if e.StatusCode != 0 {
+		return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+	}
+	for _, perm := range []error{ErrSchemaNotFound, ErrCircuitOpen, ErrResponseTooLarge, ErrRefDenied} {
+		if errors.Is(e.Err, perm) {
+			return false
+		}
+	}
+	return true
+}
+
+// isTransient reports whether err is a temporary ResolveError.
+func isTransient(err error) bool {
+	var re *ResolveError
+	return errors.As(err, &re) && re.Temporary()
+}
+
+// A RetryPolicy says how an HTTPResolver retries temporary failures.
+// The zero RetryPolicy does not retry.
+type RetryPolicy struct {
+	// Attempts is the maximum number of requests to make.
+	Attempts int
+	// BaseDelay is the delay before the first retry. Each further
+	// retry doubles it, up to MaxDelay. The actual delay is chosen
+	// at random between zero and that value, so that clients that
+	// failed together do not retry together.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay. Zero means no cap.
+	MaxDelay time.Duration
+}
+
+// backoff returns the jittered delay before retry number attempt+1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << min(attempt, 30)
+	if p.MaxDelay > 0 && (d > p.MaxDelay || d <= 0) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d + 1)
+}
+
+// ErrCircuitOpen is returned, wrapped, when a CircuitBreaker
+// refuses a request.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// A CircuitBreaker stops requests to a host after repeated temporary
+// failures, failing them immediately with ErrCircuitOpen until a
+// cooldown has passed. Then one trial request is let through; its
+// success closes the circuit and its failure restarts the cooldown.
+// The zero CircuitBreaker opens after 5 failures for 30 seconds.
+// A CircuitBreaker may be shared by several resolvers and is safe
+// for concurrent use.
+type CircuitBreaker struct {
+	// Threshold is the number of consecutive failures that opens
+	// the circuit. Zero means 5.
+	Threshold int
+	// Cooldown is how long the circuit stays open. Zero means 30s.
+	Cooldown time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*breakerState
+}
+
+type breakerState struct {
+	failures int
+	openedAt time.Time
+	trial    bool // a trial request is in flight
+}
+
+func (b *CircuitBreaker) allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := b.hosts[host]
+	if st == nil || st.failures < cmp.Or(b.Threshold, 5) {
+		return nil
+	}
+	if st.trial || time.Since(st.openedAt) < cmp.Or(b.Cooldown, 30*time.Second) {
+		return ErrCircuitOpen
+	}
+	st.trial = true
+	return nil
+}
+
+func (b *CircuitBreaker) record(host string, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.hosts == nil {
+		b.hosts = make(map[string]*breakerState)
+	}
+	st := b.hosts[host]
+	if st == nil {
+		st = &breakerState{}
+		b.hosts[host] = st
+	}
+	st.trial = false
+	if ok {
+		st.failures = 0
+		return
+	}
+	st.failures++
+	if st.failures >= cmp.Or(b.Threshold, 5) {
+		st.openedAt = time.Now()
+	}
+}
+
+// remoteFormat returns the import format for a document fetched from
+// rawURL with the given content type.
+func remoteFormat(rawURL, contentType string) string {
+	p, _, _ := strings.Cut(rawURL, "?")
+	switch path.Ext(p) {
+	case ".yaml", ".yml":
+		return "picoschema"
+	case ".json":
+		return "jsonschema"
+	}
+	if strings.Contains(contentType, "yaml") {
+		return "picoschema"
+	}
+	return "jsonschema"
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPResolverCache(t *testing.T) {
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write([]byte("name: string\n"))
+	}))
+	defer srv.Close()
+
+	var cache HTTPCache
+	revalidating := &HTTPResolver{Client: srv.Client(), Cache: &cache}
+	for i := 0; i < 3; i++ {
+		s, err := revalidating.Resolve(srv.URL + "/user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Properties.Value("name").Type != "string" {
+			t.Fatalf("got %v", s)
+		}
+		// Callers may modify the result without affecting the cache.
+		s.Properties.Delete("name")
+	}
+	if requests != 3 || notModified != 2 {
+		t.Errorf("got %d requests, %d not modified; want 3, 2", requests, notModified)
+	}
+
+	// A resolver sharing the cache with a TTL does not ask at all.
+	fresh := &HTTPResolver{Client: srv.Client(), Cache: &cache, TTL: time.Hour}
+	if _, err := fresh.Resolve(srv.URL + "/user"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("got %d requests, want 3", requests)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("got %d cache entries, want 1", cache.Len())
+	}
+}
+
+func TestHTTPResolverErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/big.json":
+			w.Write([]byte(`{"description": "` + strings.Repeat("x", 100) + `"}`))
+		case "/broken.json":
+			http.Error(w, "oops", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	r := &HTTPResolver{Client: srv.Client(), MaxBytes: 50}
+	if _, err := r.Resolve(srv.URL + "/big.json"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("got error %v for oversized response", err)
+	}
+	if _, err := r.Resolve(srv.URL + "/missing.json"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("got error %v, want ErrSchemaNotFound", err)
+	}
+	if _, err := r.Resolve(srv.URL + "/broken.json"); err == nil || errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("got error %v for server error", err)
+	}
+}
+
+func TestHTTPResolverRetry(t *testing.T) {
+	failures := map[string]int{"/flaky.json": 2, "/down.json": 1000}
+	requests := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch {
+		case r.URL.Path == "/missing.json":
+			http.NotFound(w, r)
+		case r.URL.Path == "/bad.yaml":
+			w.Write([]byte("name: nosuchtype\n"))
+		case requests[r.URL.Path] <= failures[r.URL.Path]:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"type": "string"}`))
+		}
+	}))
+	defer srv.Close()
+
+	breaker := &CircuitBreaker{Threshold: 4, Cooldown: time.Hour}
+	r := &HTTPResolver{
+		Client:  srv.Client(),
+		Retry:   RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond},
+		Breaker: breaker,
+	}
+	if _, err := r.Resolve(srv.URL + "/flaky.json"); err != nil {
+		t.Errorf("flaky: %v", err)
+	}
+	if requests["/flaky.json"] != 3 {
+		t.Errorf("flaky: got %d requests, want 3", requests["/flaky.json"])
+	}
+
+	// Not found is not retried.
+	_, err := r.Resolve(srv.URL + "/missing.json")
+	var re *ResolveError
+	if !errors.As(err, &re) || !errors.Is(err, ErrSchemaNotFound) || re.Temporary() {
+		t.Errorf("missing: got error %v", err)
+	}
+	if requests["/missing.json"] != 1 {
+		t.Errorf("missing: got %d requests, want 1", requests["/missing.json"])
+	}
+
+	// Errors in the schema are not resolution errors.
+	if _, err := r.Resolve(srv.URL + "/bad.yaml"); err == nil || errors.As(err, &re) {
+		t.Errorf("bad: got error %v, want a non-resolution error", err)
+	}
+
+	// Repeated failures open the circuit.
+	_, err = r.Resolve(srv.URL + "/down.json")
+	if !errors.As(err, &re) || re.StatusCode != http.StatusServiceUnavailable || !re.Temporary() {
+		t.Errorf("down: got error %v", err)
+	}
+	_, err = r.Resolve(srv.URL + "/down.json")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("down: got error %v, want ErrCircuitOpen", err)
+	}
+	if requests["/down.json"] != 4 {
+		t.Errorf("down: got %d requests, want 4", requests["/down.json"])
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	for attempt, max := range []time.Duration{10, 20, 40, 50, 50} {
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(attempt); d < 0 || d > max*time.Millisecond {
+				t.Fatalf("backoff(%d) = %v, want at most %v", attempt, d, max*time.Millisecond)
+			}
+		}
+	}
+}
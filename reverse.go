@@ -0,0 +1,761 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// FromJSONSchema converts s to picoschema, in the form produced by
+// decoding YAML: a map for an object, a string for a scalar type and a
+// list for an enum. The conversion is best effort. Constraints such as
+// minimum or pattern are written as attributes, as in
+// "age(minimum=0): integer". A property using keywords picoschema
+// cannot express, such as title, is written as JSON Schema with the
+// parenthetical type jsonschema, as in
+// "age(jsonschema): {type: integer, title: Age}". Keywords that cannot
+// be kept that way either, such as the title of the root, are dropped.
+// The $defs of an object root are written in a $defs section, and
+// references to them by their names.
+// Use FromJSONSchemaReport to learn what was embedded or dropped.
+func FromJSONSchema(s *jsonschema.Schema) (any, error) {
+	v, _, err := FromJSONSchemaReport(s)
+	return v, err
+}
+
+// A FidelityReport lists what a conversion to picoschema
+// could not express in picoschema's own terms.
+type FidelityReport struct {
+	// Embedded holds the JSON Pointers of the subschemas written as
+	// JSON Schema. A root written as JSON Schema has the pointer "".
+	Embedded []string `json:"embedded"`
+	// Dropped holds the JSON Pointers of the keywords and subschemas
+	// that were lost.
+	Dropped []string `json:"dropped"`
+}
+
+// Lossless reports whether nothing was dropped.
+func (r *FidelityReport) Lossless() bool {
+	return len(r.Dropped) == 0
+}
+
+// FromJSONSchemaReport is like FromJSONSchema, but also reports what
+// was embedded as JSON Schema or dropped, sorted by JSON Pointer.
+func FromJSONSchemaReport(s *jsonschema.Schema) (any, *FidelityReport, error) {
+	if s == nil {
+		return nil, &FidelityReport{}, nil
+	}
+	if b, ok := boolSchema(s); ok && !b {
+		return nil, nil, errors.New("picoschema: the false schema cannot be expressed in picoschema")
+	}
+	var w picoWriter
+	if s.Enum == nil && (s.Type == "object" || s.Type == "" && s.Properties != nil) {
+		// Only an object root has a $defs section.
+		for name := range s.Definitions {
+			if isDefName(name) {
+				if w.defs == nil {
+					w.defs = make(map[string]bool)
+				}
+				w.defs[name] = true
+			}
+		}
+	}
+	v, used := w.value(s, "")
+	if m, ok := v.(map[string]any); ok && w.defs != nil {
+		defs := make(map[string]any)
+		for _, name := range sortedKeys(s.Definitions) {
+			dpath := "/$defs/" + escapePointer(name)
+			if !w.defs[name] {
+				w.dropped = append(w.dropped, dpath)
+				continue
+			}
+			key, dv := w.property(name, s.Definitions[name], dpath)
+			defs[key] = dv
+		}
+		m[defsKey] = defs
+		used = append(used, "$defs")
+	}
+	w.drop(s, "", used...)
+	if slices.Contains(w.dropped, "") {
+		// The root itself could not be expressed;
+		// write all of it as JSON Schema if that converts back.
+		if raw, ok := rawSchema(s, true); ok {
+			v = raw
+			w.embedded, w.dropped = []string{""}, nil
+		}
+	}
+	slices.Sort(w.embedded)
+	slices.Sort(w.dropped)
+	return v, &FidelityReport{Embedded: w.embedded, Dropped: w.dropped}, nil
+}
+
+// attributeKeys maps keywords and annotations to the attributes that
+// set them, in the order attributes are written.
+var attributeKeys = []struct{ key, attr string }{
+	{"minimum", "minimum"},
+	{"exclusiveMinimum", "exclusiveMinimum"},
+	{"maximum", "maximum"},
+	{"exclusiveMaximum", "exclusiveMaximum"},
+	{"multipleOf", "multipleOf"},
+	{"minLength", "minLength"},
+	{"maxLength", "maxLength"},
+	{"pattern", "pattern"},
+	{"format", "format"},
+	{"minItems", "minItems"},
+	{"maxItems", "maxItems"},
+	{"minProperties", "minProperties"},
+	{"maxProperties", "maxProperties"},
+	{audiencesKey, "audience"},
+	{lintDisableKey, "lintDisable"},
+	{protoFieldKey, "protoField"},
+	{removedInVersionKey, "removedInVersion"},
+	{renamedFromKey, "renamedFrom"},
+	{sinceVersionKey, "sinceVersion"},
+	{unitKey, "unit"},
+}
+
+// A picoWriter converts JSON Schema to picoschema,
+// collecting what it embeds as JSON Schema and what it drops.
+type picoWriter struct {
+	embedded []string
+	dropped  []string
+	defs     map[string]bool // names written in the $defs section
+}
+
+// drop records the keywords of s at path other than those in used.
+func (w *picoWriter) drop(s *jsonschema.Schema, path string, used ...string) {
+	for _, k := range schemaKeywords(s) {
+		if !slices.Contains(used, k) {
+			w.dropped = append(w.dropped, path+"/"+escapePointer(k))
+		}
+	}
+}
+
+// value returns the picoschema value for s, which is at path, and the
+// keywords of s it expresses. The description is included only in the
+// string form of scalars; attributes are left to the caller.
+func (w *picoWriter) value(s *jsonschema.Schema, path string) (any, []string) {
+	if b, ok := boolSchema(s); ok {
+		if !b {
+			w.dropped = append(w.dropped, path)
+		}
+		return "any", nil
+	}
+	if isSelfRef(s) {
+		return withDescription(selfType, s.Description), []string{"$ref", "description"}
+	}
+	if name, ok := w.defRef(s); ok {
+		return withDescription(name, s.Description), []string{"$ref", "description"}
+	}
+	if name, ok := scalarName(s); ok {
+		return withDescription(name, s.Description), schemaKeywords(s)
+	}
+	if name, ok := w.nullableName(s); ok {
+		return withDescription(name+"?", s.Description), []string{"anyOf", "description"}
+	}
+	switch {
+	case s.Enum != nil:
+		if slices.ContainsFunc(s.Enum, isObjectValue) {
+			// picoschema reads maps in an enum as values
+			// with descriptions or aliases.
+			w.dropped = append(w.dropped, path)
+		}
+		return enumValue(s), []string{"enum", "type", enumAliasesKey, enumDescriptionsKey}
+	case s.Type == "object" || (s.Type == "" && s.Properties != nil):
+		v, used := w.object(s, path)
+		if s.Description != "" {
+			v["(description)"] = s.Description
+			used = append(used, "description")
+		}
+		return v, used
+	}
+	switch s.Type {
+	case "string", "boolean", "null", "number", "integer":
+		return withDescription(s.Type, s.Description), []string{"type", "description"}
+	case "":
+		return withDescription("any", s.Description), []string{"description"}
+	}
+	// Arrays are written with a property key, so an array
+	// that is not the value of a property is lost.
+	w.dropped = append(w.dropped, path)
+	return "any", schemaKeywords(s)
+}
+
+// object returns the picoschema map for the object schema s at path.
+func (w *picoWriter) object(s *jsonschema.Schema, path string) (map[string]any, []string) {
+	ret := make(map[string]any)
+	var names []string
+	if s.Properties != nil {
+		for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+			names = append(names, p.Key)
+			ppath := path + "/properties/" + escapePointer(p.Key)
+			if p.Key == "" || strings.ContainsAny(p.Key, "()") || strings.HasSuffix(p.Key, "?") {
+				w.dropped = append(w.dropped, ppath)
+				continue
+			}
+			name := p.Key
+			if !slices.Contains(s.Required, p.Key) {
+				name += "?"
+			}
+			key, v := w.property(name, p.Value, ppath)
+			ret[key] = v
+		}
+	}
+	for _, r := range s.Required {
+		if !slices.Contains(names, r) {
+			w.dropped = append(w.dropped, path+"/required")
+			break
+		}
+	}
+	if ap := s.AdditionalProperties; ap == nil {
+		ret["(*)"] = "any"
+	} else if b, ok := boolSchema(ap); !ok || b {
+		key, v := w.wildcard(ap, path+"/additionalProperties")
+		ret[key] = v
+	}
+	for _, pattern := range sortedKeys(s.PatternProperties) {
+		ppath := path + "/patternProperties/" + escapePointer(pattern)
+		key, v := w.wildcard(s.PatternProperties[pattern], ppath)
+		key = "(/" + pattern + "/" + strings.TrimPrefix(key, "(*")
+		if back, _, ok := patternWildcard(key[1:]); !ok || back != pattern {
+			w.dropped = append(w.dropped, ppath)
+			continue
+		}
+		ret[key] = v
+	}
+	used := []string{"type", "properties", "required", "additionalProperties", "patternProperties"}
+	if as, ok := s.Extras[assertKey]; ok {
+		ret["(assert)"] = as
+		used = append(used, assertKey)
+	}
+	return ret, used
+}
+
+// property returns the key and value that write the property name,
+// which includes any "?", with schema s at path.
+func (w *picoWriter) property(name string, s *jsonschema.Schema, path string) (string, any) {
+	ndropped, nembedded := len(w.dropped), len(w.embedded)
+	key, v := w.picoProperty(name, s, path)
+	if len(w.dropped) == ndropped {
+		return key, v
+	}
+	raw, ok := rawSchema(s, false)
+	if !ok {
+		return key, v
+	}
+	// Embedding the whole property supersedes whatever
+	// was embedded or dropped within it.
+	w.dropped, w.embedded = w.dropped[:ndropped], append(w.embedded[:nembedded], path)
+	return name + "(jsonschema)", raw
+}
+
+// rawSchema returns s as JSON Schema in decoded form, if that converts
+// back to s as the value of a property with parenthetical type
+// jsonschema or, if root is set, as the root of picoschema.
+func rawSchema(s *jsonschema.Schema, root bool) (any, bool) {
+	raw, err := schemaToValue(s)
+	if err != nil {
+		return nil, false
+	}
+	var back *jsonschema.Schema
+	if root {
+		back, err = newParser(nil).toJSONSchema(raw)
+	} else {
+		back, err = embeddedSchema(raw)
+	}
+	if err != nil || back == nil {
+		return nil, false
+	}
+	want, err1 := canonicalJSON(s)
+	got, err2 := canonicalJSON(back)
+	if err1 != nil || err2 != nil || !bytes.Equal(want, got) {
+		return nil, false
+	}
+	return raw, true
+}
+
+// picoProperty is like property, but never embeds JSON Schema.
+func (w *picoWriter) picoProperty(name string, s *jsonschema.Schema, path string) (string, any) {
+	if _, ok := w.defRef(s); s.Ref != "" && !isSelfRef(s) && !ok {
+		w.dropped = append(w.dropped, path)
+		return name, "any"
+	}
+	var typ string
+	var v any
+	var used, mapItems []string
+	switch {
+	case s.Type == "array" && s.Items != nil && s.Enum == nil && !isTuple(s):
+		typ = "array"
+		items, ipath := s.Items, path+"/items"
+		for isPlainArray(items) {
+			// Arrays of arrays are written as nested types,
+			// as in "matrix(array(array))".
+			typ = "array(" + typ + ")"
+			items, ipath = items.Items, ipath+"/items"
+		}
+		var iused []string
+		v, iused = w.value(items, ipath)
+		w.drop(items, ipath, iused...)
+		used = []string{"type", "items"}
+	case isTuple(s):
+		typ = "tuple"
+		elems := make([]any, len(s.PrefixItems))
+		for i, e := range s.PrefixItems {
+			epath := fmt.Sprintf("%s/prefixItems/%d", path, i)
+			var eused []string
+			elems[i], eused = w.value(e, epath)
+			w.drop(e, epath, eused...)
+		}
+		v = elems
+		used = []string{"type", "prefixItems", "items", "minItems"}
+	case isMap(s):
+		typ = "*"
+		var vitems []string
+		vitems, v = w.wildcardValue(s.AdditionalProperties, path+"/additionalProperties")
+		mapItems = vitems
+		used = []string{"type", "additionalProperties"}
+	case s.Const != nil && s.Type == "" && s.Enum == nil:
+		typ = "const"
+		v = s.Const
+		used = []string{"const"}
+	case compositionType(s) != "" && s.Type == "" && s.Enum == nil && !w.isNullable(s):
+		typ = compositionType(s)
+		list := *compositions[typ](s)
+		alts := make([]any, len(list))
+		used = []string{typ}
+		// An allOf closed with unevaluatedProperties is written with
+		// its open objects closed, as it was converted from.
+		closed := typ == "allOf" && s.Extras[unevaluatedPropertiesKey] == false
+		if closed {
+			used = append(used, unevaluatedPropertiesKey)
+		}
+		for i, alt := range list {
+			apath := fmt.Sprintf("%s/%s/%d", path, typ, i)
+			if closed && alt.Type == "object" && alt.AdditionalProperties == nil {
+				c := *alt
+				c.AdditionalProperties = jsonschema.FalseSchema
+				alt = &c
+			}
+			var aused []string
+			alts[i], aused = w.value(alt, apath)
+			w.drop(alt, apath, aused...)
+		}
+		v = alts
+	default:
+		v, used = w.value(s, path)
+		switch m := v.(type) {
+		case []any:
+			typ = "enum"
+		case map[string]any:
+			typ = "object"
+			// The description of an object property
+			// is written in its parenthetical.
+			delete(m, "(description)")
+			used = slices.DeleteFunc(used, func(k string) bool { return k == "description" })
+		}
+	}
+	if enum, ok := v.([]any); ok && typ == "enum" && strings.HasSuffix(name, "?") && len(enum) > 0 && enum[len(enum)-1] == nil {
+		// Converting an optional enum adds the null member.
+		v = enum[:len(enum)-1]
+	}
+
+	var items []string
+	if typ == "*" {
+		// The attributes of a map apply to its values.
+		items = mapItems
+	} else {
+		var aused []string
+		items, aused = attributes(s, used...)
+		used = append(used, aused...)
+	}
+	if s.Description != "" && typ != "" {
+		// A description that starts like an attribute
+		// would be read as one.
+		if first, _, _ := strings.Cut(s.Description, ","); !isAttribute(first) {
+			items = append(items, s.Description)
+			used = append(used, "description")
+		}
+	}
+	w.drop(s, path, used...)
+	if len(items) == 0 && !strings.HasPrefix(typ, "array") && typ != "enum" && typ != "const" && compositions[typ] == nil && typ != "tuple" && typ != "*" {
+		return name, v
+	}
+	if typ != "" {
+		items = append([]string{typ}, items...)
+	}
+	return name + "(" + strings.Join(items, ", ") + ")", v
+}
+
+// wildcard returns the key and value that write the additional
+// properties schema s at path.
+func (w *picoWriter) wildcard(s *jsonschema.Schema, path string) (string, any) {
+	items, v := w.wildcardValue(s, path)
+	return "(" + strings.Join(append([]string{"*"}, items...), ", ") + ")", v
+}
+
+// wildcardValue returns the parenthetical items that follow "*" and
+// the value that write the additional properties schema s at path.
+func (w *picoWriter) wildcardValue(s *jsonschema.Schema, path string) ([]string, any) {
+	var items, used []string
+	var v any
+	if s.Type == "array" && s.Items != nil && s.Enum == nil {
+		items = []string{"array"}
+		var iused []string
+		v, iused = w.value(s.Items, path+"/items")
+		w.drop(s.Items, path+"/items", iused...)
+		used = []string{"type", "items"}
+	} else {
+		v, used = w.value(s, path)
+	}
+	aitems, aused := attributes(s, used...)
+	items = append(items, aitems...)
+	used = append(used, aused...)
+	if s.Description != "" && len(items) > 0 && items[0] == "array" {
+		if first, _, _ := strings.Cut(s.Description, ","); !isAttribute(first) {
+			items = append(items, s.Description)
+			used = append(used, "description")
+		}
+	}
+	w.drop(s, path, used...)
+	return items, v
+}
+
+// compositionType returns the parenthetical type that writes the
+// composition keyword of s, or "" if s has none or more than one.
+func compositionType(s *jsonschema.Schema) string {
+	typ := ""
+	for _, kw := range []string{"oneOf", "anyOf", "allOf"} {
+		if *compositions[kw](s) != nil {
+			if typ != "" {
+				return ""
+			}
+			typ = kw
+		}
+	}
+	return typ
+}
+
+// isNullable reports whether s is written as a nullable
+// type name, as in "string?".
+func (w *picoWriter) isNullable(s *jsonschema.Schema) bool {
+	_, ok := w.nullableName(s)
+	return ok
+}
+
+// isAttribute reports whether the parenthetical item s
+// would be read as an attribute.
+func isAttribute(s string) bool {
+	_, ok := parseAttribute(s)
+	return ok
+}
+
+// isSelfRef reports whether s is a reference to the root
+// with no keywords other than a description.
+func isSelfRef(s *jsonschema.Schema) bool {
+	if s.Ref != "#" {
+		return false
+	}
+	for _, k := range schemaKeywords(s) {
+		if k != "$ref" && k != "description" {
+			return false
+		}
+	}
+	return true
+}
+
+// defNamePattern matches the names of definitions that can be
+// written in a picoschema $defs section and referred to by name.
+var defNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// isDefName reports whether the definition name can be
+// written in a picoschema $defs section.
+func isDefName(name string) bool {
+	return defNamePattern.MatchString(name) && !slices.Contains(builtinScalars, name)
+}
+
+// defRef returns the name of the definition that s refers to, if the
+// definition is written in the $defs section and s has no keywords
+// other than a description.
+func (w *picoWriter) defRef(s *jsonschema.Schema) (string, bool) {
+	name, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+	if !ok || !w.defs[name] {
+		return "", false
+	}
+	for _, k := range schemaKeywords(s) {
+		if k != "$ref" && k != "description" {
+			return "", false
+		}
+	}
+	return name, true
+}
+
+// isPlainArray reports whether s is an array schema with
+// no keywords other than its type and items.
+func isPlainArray(s *jsonschema.Schema) bool {
+	return s.Type == "array" && s.Items != nil && slices.Equal(schemaKeywords(s), []string{"items", "type"})
+}
+
+// isTuple reports whether s is an array schema of fixed length,
+// as written by the parenthetical type tuple.
+func isTuple(s *jsonschema.Schema) bool {
+	if s.Type != "array" || s.Enum != nil || len(s.PrefixItems) == 0 || s.MinItems == nil || *s.MinItems != uint64(len(s.PrefixItems)) {
+		return false
+	}
+	b, isBool := boolSchema(s.Items)
+	return isBool && !b
+}
+
+// isMap reports whether s is an object schema without a properties
+// keyword whose properties are all described by one schema, as
+// written by a key such as "scores(*)".
+func isMap(s *jsonschema.Schema) bool {
+	if s.Type != "object" || s.Enum != nil || s.Properties != nil || s.Required != nil {
+		return false
+	}
+	if _, ok := s.Extras[assertKey]; ok {
+		return false
+	}
+	_, isBool := boolSchema(s.AdditionalProperties)
+	return s.AdditionalProperties != nil && !isBool
+}
+
+// attributes returns the attributes and flags that write the keywords
+// and annotations of s other than those in written, and the keywords
+// and annotations they write.
+func attributes(s *jsonschema.Schema, written ...string) (items, used []string) {
+	for _, ak := range attributeKeys {
+		a, ok := keywordValue(s, ak.key)
+		if !ok || slices.Contains(written, ak.key) {
+			continue
+		}
+		if as, ok := a.([]any); ok {
+			strs := make([]string, 0, len(as))
+			for _, e := range as {
+				strs = append(strs, fmt.Sprint(e))
+			}
+			a = strings.Join(strs, "|")
+		}
+		item := fmt.Sprintf("%s=%v", ak.attr, a)
+		if pa, ok := parseAttribute(item); !ok || pa.value != fmt.Sprint(a) || len(splitItems(item)) != 1 {
+			// A value such as a pattern with a comma
+			// would not be read back whole.
+			continue
+		}
+		items = append(items, item)
+		used = append(used, ak.key)
+	}
+	if ns, ok := s.Extras[normalizeKey].([]any); ok {
+		for _, n := range ns {
+			items = append(items, fmt.Sprint(n))
+		}
+		used = append(used, normalizeKey)
+	}
+	if s.Deprecated {
+		items = append(items, "deprecated")
+		used = append(used, "deprecated")
+	}
+	// The flags cannot write a schema that is both readOnly and writeOnly.
+	if s.ReadOnly != s.WriteOnly {
+		flag := "readOnly"
+		if s.WriteOnly {
+			flag = "writeOnly"
+		}
+		items = append(items, flag)
+		used = append(used, flag)
+	}
+	if item, ok := defaultItem(s); ok {
+		items = append(items, item)
+		used = append(used, "default")
+	}
+	if es, ok := exampleItems(s); ok {
+		items = append(items, es...)
+		used = append(used, "examples")
+	}
+	if d, ok := s.Extras[discriminatorKey].(map[string]any); ok && len(d) == 1 && s.OneOf != nil {
+		if name, ok := d["propertyName"].(string); ok && name != "" && !strings.ContainsAny(name, ",)") {
+			items = append(items, discriminatorKey+"="+name)
+			used = append(used, discriminatorKey)
+		}
+	}
+	return items, used
+}
+
+// keywordValue returns the value of the keyword or annotation key of
+// s, if it is set, with pointers followed.
+func keywordValue(s *jsonschema.Schema, key string) (any, bool) {
+	if v, ok := s.Extras[key]; ok {
+		return v, true
+	}
+	rv := reflect.ValueOf(s).Elem()
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		if name, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ","); name != key {
+			continue
+		}
+		f := rv.Field(i)
+		if f.IsZero() {
+			return nil, false
+		}
+		return reflect.Indirect(f).Interface(), true
+	}
+	return nil, false
+}
+
+// defaultItem returns the default attribute that writes the default
+// of s, if s has one that can be written.
+func defaultItem(s *jsonschema.Schema) (string, bool) {
+	if s.Default == nil {
+		return "", false
+	}
+	text, ok := attributeValue(s.Type, s.Default)
+	if !ok {
+		return "", false
+	}
+	return "default=" + text, true
+}
+
+// exampleItems returns the example attributes that write the examples
+// of s, if every one can be written.
+func exampleItems(s *jsonschema.Schema) ([]string, bool) {
+	if len(s.Examples) == 0 {
+		return nil, false
+	}
+	items := make([]string, len(s.Examples))
+	for i, e := range s.Examples {
+		text, ok := attributeValue(s.Type, e)
+		if !ok {
+			return nil, false
+		}
+		items[i] = "example=" + text
+	}
+	return items, true
+}
+
+// attributeValue returns the text of an attribute that, read as by
+// defaultValue for a schema of type typ, is v.
+func attributeValue(typ string, v any) (string, bool) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	text := string(data)
+	if str, ok := v.(string); ok && str != "" && str == strings.TrimSpace(str) && !strings.HasPrefix(str, `"`) {
+		text = str
+	}
+	if strings.Contains(text, ",") {
+		return "", false
+	}
+	back, err := defaultValue(typ, text)
+	if err != nil {
+		return "", false
+	}
+	if bdata, err := json.Marshal(back); err != nil || !bytes.Equal(bdata, data) {
+		return "", false
+	}
+	return text, true
+}
+
+// isObjectValue reports whether v is a decoded JSON object.
+func isObjectValue(v any) bool {
+	_, ok := v.(map[string]any)
+	return ok
+}
+
+// enumValue returns the picoschema list for the enum of s, writing
+// members with aliases or a description as single-key maps, and those
+// with both as "$value" maps.
+func enumValue(s *jsonschema.Schema) []any {
+	aliases, _ := s.Extras[enumAliasesKey].(map[string]any)
+	descs, _ := s.Extras[enumDescriptionsKey].(map[string]any)
+	ret := make([]any, 0, len(s.Enum))
+	for _, m := range s.Enum {
+		str, ok := m.(string)
+		if !ok || aliases[str] == nil && descs[str] == nil {
+			ret = append(ret, m)
+			continue
+		}
+		switch as, desc := aliases[str], descs[str]; {
+		case desc == nil:
+			ret = append(ret, map[string]any{str: as})
+		case as == nil:
+			ret = append(ret, map[string]any{str: desc})
+		default:
+			ret = append(ret, map[string]any{enumValueMarker: str, enumAliasesMarker: as, enumDescriptionMarker: desc})
+		}
+	}
+	return ret
+}
+
+// nullableName returns the scalar type or definition name that,
+// followed by "?", writes s, if s is a nullable scalar type or
+// reference to a definition.
+func (w *picoWriter) nullableName(s *jsonschema.Schema) (string, bool) {
+	for _, k := range schemaKeywords(s) {
+		if k != "anyOf" && k != "description" {
+			return "", false
+		}
+	}
+	if len(s.AnyOf) != 2 || !slices.Equal(schemaKeywords(s.AnyOf[1]), []string{"type"}) || s.AnyOf[1].Type != "null" {
+		return "", false
+	}
+	alt := s.AnyOf[0]
+	if name, ok := scalarName(alt); ok && alt.Description == "" {
+		return name, true
+	}
+	if name, ok := w.defRef(alt); ok && alt.Description == "" {
+		return name, true
+	}
+	if slices.Equal(schemaKeywords(alt), []string{"type"}) && alt.Type != "null" && slices.Contains(builtinScalars, alt.Type) {
+		return alt.Type, true
+	}
+	return "", false
+}
+
+// withDescription returns the picoschema scalar string for typ and desc.
+func withDescription(typ, desc string) string {
+	if desc == "" {
+		return typ
+	}
+	return typ + ", " + desc
+}
+
+// scalarName returns the name of the registered scalar type that s is,
+// apart from its description.
+func scalarName(s *jsonschema.Schema) (string, bool) {
+	c := cloneSchema(s)
+	c.Description = ""
+	data, err := canonicalJSON(c)
+	if err != nil {
+		return "", false
+	}
+	scalarsMu.RLock()
+	defer scalarsMu.RUnlock()
+	for _, name := range sortedKeys(scalars) {
+		sdata, err := canonicalJSON(scalars[name])
+		if err == nil && bytes.Equal(data, sdata) {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+)
+
+func TestFromJSONSchemaRoundTrip(t *testing.T) {
+	for _, src := range []string{
+		`name: string, the name`,
+		`
+id(protoField=1): integer
+code?(string, lintDisable=property-naming|missing-description):
+n?(integer, minimum=0, exclusiveMaximum=10, multipleOf=2):
+code?(string, minLength=2, maxLength=3, pattern=^[A-Z]+$, format=iso-code):
+ids?(array, minItems=1, maxItems=5): string
+extras?(object, minProperties=1, maxProperties=3):
+  (*): string
+tags?(array, audience=internal|partner, labels for search): string, a tag
+size?(enum, shirt size): [S, M, L]
+country(enum): [{US: [USA, United States]}, CA]
+status(enum): [{ok: all good}, {$value: failed, $aliases: [error], $description: it failed}]
+email(trim, lowercase): string
+timeout(unit=ms, sinceVersion=2): integer
+home?(object, renamedFrom=address, where they live):
+  city: string
+  pos?: latlng
+lines(array):
+  sku: string
+  qty: number
+(*): string
+(assert): "id > 0"
+`,
+		`
+meta?:
+  (*): any
+`, `
+(description): a shipment
+lines(array, the order lines):
+  (description): one line of the order
+  sku: string
+`,
+		`
+scores?(*, the scores): number
+history(*, array, unit=ms): integer
+(*, array, extra fields): string
+(/^x-[a-z]+$/, header values): string
+(/^[0-9]{1,3}$/, array): integer
+`, `
+$defs:
+  address(object, a postal address):
+    city: string
+  codes(array): string
+home: address
+work?: address?, where they work
+tags?: codes
+point(tuple): [number, number]
+`, `
+id(oneOf, the key): [string, integer]
+result?(oneOf): [{ok: boolean}, {error: string}, [pending]]
+contact(anyOf, how to reach them): [{email: string}, {phone: string}]
+badge?(allOf): [{id: string}, {(*): any}]
+middle?: string?
+event(oneOf, discriminator=type): [{type(const): created, id: string}, {type(const): deleted}]
+`,
+		`
+email(string, example=a@b.com, example=c@d.com):
+retries?(integer, default=3, example=5):
+`,
+		`
+id(string, readOnly):
+password(string, writeOnly):
+legacyId?(integer, deprecated, the old numeric id):
+`,
+	} {
+		s := mustSchema(t, src)
+		v, report, err := FromJSONSchemaReport(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Embedded) > 0 || len(report.Dropped) > 0 {
+			t.Errorf("%s: got report %+v, want it empty", src, report)
+		}
+		got, err := ToJSONSchema(v)
+		if err != nil {
+			t.Fatalf("%s: converting %v back: %v", src, v, err)
+		}
+		sortSchemaSlices(s)
+		sortSchemaSlices(got)
+		if diff := cmp.Diff(schemaJSON(t, s), schemaJSON(t, got)); diff != "" {
+			t.Errorf("%s: round trip mismatch (-want, +got):\n%s", src, diff)
+		}
+	}
+}
+
+func TestFromJSONSchemaReport(t *testing.T) {
+	s, err := Import("jsonschema", []byte(`{
+		"type": "object",
+		"title": "Person",
+		"properties": {
+			"age": {"type": "integer", "minimum": 0, "description": "in years"},
+			"email": {"type": "string", "format": "email"},
+			"friends": {"type": "array", "items": {"type": "array", "items": {"type": "string"}}},
+			"boss": {"$ref": "#/properties/friends"},
+			"parent": {"$ref": "#", "description": "the parent"},
+			"origin": {"enum": [{"x": 0}]}
+		},
+		"required": ["age", "email"]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, report, err := FromJSONSchemaReport(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"age(minimum=0)":         "integer, in years",
+		"email(format=email)":    "string",
+		"friends?(array(array))": "string",
+		"boss?(jsonschema)":      map[string]any{"$ref": "#/properties/friends"},
+		"parent?":                "$self, the parent",
+		"origin?(jsonschema)":    map[string]any{"enum": []any{map[string]any{"x": float64(0)}}},
+		"(*)":                    "any",
+	}
+	if diff := cmp.Diff(want, v); diff != "" {
+		t.Errorf("value mismatch (-want, +got):\n%s", diff)
+	}
+	wantReport := &FidelityReport{
+		Embedded: []string{"/properties/boss", "/properties/origin"},
+		Dropped:  []string{"/title"},
+	}
+	if diff := cmp.Diff(wantReport, report); diff != "" {
+		t.Errorf("report mismatch (-want, +got):\n%s", diff)
+	}
+
+	back, err := ToJSONSchema(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Title = ""
+	s.AdditionalProperties = nil
+	sortSchemaSlices(s)
+	sortSchemaSlices(back)
+	back.AdditionalProperties = nil
+	if diff := cmp.Diff(schemaJSON(t, s), schemaJSON(t, back)); diff != "" {
+		t.Errorf("round trip mismatch (-want, +got):\n%s", diff)
+	}
+
+	v, report, err = FromJSONSchemaReport(&jsonschema.Schema{Type: "array", Items: &jsonschema.Schema{Type: "string"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&FidelityReport{Embedded: []string{""}}, report); diff != "" {
+		t.Errorf("array root report mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]any{"type": "array", "items": map[string]any{"type": "string"}}, v); diff != "" {
+		t.Errorf("array root mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,577 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"regexp/syntax"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// Sample returns a document valid against s, made by the default Generator.
+func Sample(s *jsonschema.Schema) (any, error) {
+	var g Generator
+	return g.Generate(s)
+}
+
+// A Generator makes sample documents that are valid against a schema,
+// for use as examples in prompts and as test data. It honors types,
+// enums, consts, examples, required properties, string lengths,
+// formats and patterns, numeric bounds and array bounds, and checks
+// every document it returns with Check.
+//
+// Generation is deterministic: generators with the same Seed
+// produce the same sequence of documents.
+// A Generator must not be used concurrently.
+type Generator struct {
+	// Seed seeds the generator's pseudo-random choices.
+	Seed uint64
+
+	// MaxItems caps the length of generated arrays beyond their
+	// minimum length. Zero means 3.
+	MaxItems int
+
+	rng *rand.Rand
+}
+
+// maxGenerateAttempts is how many candidates Generate tries
+// before giving up on finding a valid one.
+const maxGenerateAttempts = 25
+
+// maxGenerateDepth limits the nesting of generated documents. Below it,
+// optional properties are omitted and arrays are as short as allowed.
+const maxGenerateDepth = 16
+
+// Generate returns a document valid against s.
+// It fails if s admits no document or if no valid document was found,
+// for example because of assertions or conflicting subschemas.
+func (g *Generator) Generate(s *jsonschema.Schema) (any, error) {
+	for range maxGenerateAttempts {
+		v, err := g.value(s, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(Check(v, s)) == 0 {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("picoschema: no valid document found in %d attempts", maxGenerateAttempts)
+}
+
+// GenerateN returns n documents valid against s.
+func (g *Generator) GenerateN(s *jsonschema.Schema, n int) ([]any, error) {
+	vs := make([]any, 0, n)
+	for range n {
+		v, err := g.Generate(s)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+func (g *Generator) rand() *rand.Rand {
+	if g.rng == nil {
+		g.rng = rand.New(rand.NewPCG(g.Seed, g.Seed^0x9e3779b97f4a7c15))
+	}
+	return g.rng
+}
+
+// chance reports true with probability 1/n.
+func (g *Generator) chance(n int) bool {
+	return g.rand().IntN(n) == 0
+}
+
+// value generates a candidate document for s, which may be invalid.
+func (g *Generator) value(s *jsonschema.Schema, depth int) (any, error) {
+	if s == nil {
+		return g.word(), nil
+	}
+	if b, ok := boolSchema(s); ok {
+		if !b {
+			return nil, fmt.Errorf("picoschema: cannot generate a value for the false schema")
+		}
+		return g.word(), nil
+	}
+	switch {
+	case s.Const != nil:
+		return copyValue(s.Const), nil
+	case s.Enum != nil:
+		members := slices.DeleteFunc(slices.Clone(s.Enum), func(v any) bool { return v == nil })
+		if len(members) == 0 {
+			return nil, nil
+		}
+		return copyValue(members[g.rand().IntN(len(members))]), nil
+	case len(s.Examples) > 0:
+		return copyValue(s.Examples[g.rand().IntN(len(s.Examples))]), nil
+	case len(s.AnyOf) > 0:
+		return g.value(s.AnyOf[g.rand().IntN(len(s.AnyOf))], depth)
+	case len(s.OneOf) > 0:
+		return g.value(s.OneOf[g.rand().IntN(len(s.OneOf))], depth)
+	}
+	typ := s.Type
+	if typ == "" {
+		switch {
+		case s.Properties != nil:
+			typ = "object"
+		case s.Items != nil || s.PrefixItems != nil:
+			typ = "array"
+		default:
+			for _, sub := range s.AllOf {
+				if sub.Type != "" {
+					return g.value(sub, depth)
+				}
+			}
+			typ = "string"
+		}
+	}
+	switch typ {
+	case "object":
+		return g.object(s, depth)
+	case "array":
+		return g.array(s, depth)
+	case "string":
+		return g.string(s)
+	case "number", "integer":
+		return g.number(s, typ == "integer")
+	case "boolean":
+		return g.chance(2), nil
+	case "null":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("picoschema: cannot generate a value of type %q", typ)
+}
+
+func (g *Generator) object(s *jsonschema.Schema, depth int) (any, error) {
+	obj := make(map[string]any)
+	if s.Properties == nil {
+		return obj, nil
+	}
+	var minProps int
+	if s.MinProperties != nil {
+		minProps = int(*s.MinProperties)
+	}
+	optional := 0
+	for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+		if !slices.Contains(s.Required, p.Key) {
+			optional++
+		}
+	}
+	for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+		req := slices.Contains(s.Required, p.Key)
+		if !req {
+			optional--
+			// Include an optional property if it is needed to reach
+			// minProperties, and otherwise at random above the depth limit.
+			need := len(obj)+optional < minProps
+			if !need && (depth >= maxGenerateDepth || g.chance(2)) {
+				continue
+			}
+		}
+		v, err := g.value(p.Value, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%w (property %q)", err, p.Key)
+		}
+		obj[p.Key] = v
+	}
+	return obj, nil
+}
+
+func (g *Generator) array(s *jsonschema.Schema, depth int) (any, error) {
+	lo := len(s.PrefixItems)
+	if s.MinItems != nil && int(*s.MinItems) > lo {
+		lo = int(*s.MinItems)
+	}
+	extra := g.MaxItems
+	if extra == 0 {
+		extra = 3
+	}
+	hi := lo + extra
+	if s.MaxItems != nil && int(*s.MaxItems) < hi {
+		hi = int(*s.MaxItems)
+	}
+	if b, ok := boolSchema(s.Items); ok && !b {
+		hi = min(hi, len(s.PrefixItems))
+	}
+	n := lo
+	if hi > lo && depth < maxGenerateDepth {
+		n = lo + g.rand().IntN(hi-lo+1)
+	}
+	arr := make([]any, 0, n)
+	for i := 0; i < n; i++ {
+		sub := itemSchema(s, i)
+		var v any
+		var err error
+		// Retry a few times to avoid duplicates in unique arrays.
+		for try := 0; try < 10; try++ {
+			if v, err = g.value(sub, depth+1); err != nil {
+				return nil, err
+			}
+			if !s.UniqueItems || !slicesContainsEqual(arr, v) {
+				break
+			}
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+// sampleWords are the words of which generated strings are made.
+var sampleWords = []string{
+	"alpha", "bravo", "charlie", "delta", "echo", "foxtrot",
+	"golf", "hotel", "india", "juliet", "kilo", "lima",
+}
+
+func (g *Generator) word() string {
+	return sampleWords[g.rand().IntN(len(sampleWords))]
+}
+
+func (g *Generator) string(s *jsonschema.Schema) (any, error) {
+	if s.Pattern != "" {
+		re, err := syntax.Parse(s.Pattern, syntax.Perl)
+		if err != nil {
+			return nil, fmt.Errorf("picoschema: pattern %q: %w", s.Pattern, err)
+		}
+		var b strings.Builder
+		g.regexpString(&b, re.Simplify())
+		return b.String(), nil
+	}
+	var str string
+	switch s.Format {
+	case "date":
+		str = fmt.Sprintf("2024-%02d-%02d", 1+g.rand().IntN(12), 1+g.rand().IntN(28))
+	case "date-time":
+		str = fmt.Sprintf("2024-%02d-%02dT%02d:%02d:00Z", 1+g.rand().IntN(12), 1+g.rand().IntN(28), g.rand().IntN(24), g.rand().IntN(60))
+	case "time":
+		str = fmt.Sprintf("%02d:%02d:00Z", g.rand().IntN(24), g.rand().IntN(60))
+	case "email":
+		str = g.word() + "@example.com"
+	case "uri", "url":
+		str = "https://example.com/" + g.word()
+	case "hostname":
+		str = g.word() + ".example.com"
+	case "ipv4":
+		str = fmt.Sprintf("192.0.2.%d", 1+g.rand().IntN(254))
+	case "uuid":
+		b := make([]byte, 16)
+		for i := range b {
+			b[i] = byte(g.rand().IntN(256))
+		}
+		str = fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	default:
+		str = g.word()
+		for s.MinLength != nil && uint64(len(str)) < *s.MinLength {
+			str += " " + g.word()
+		}
+	}
+	if s.MaxLength != nil && uint64(len(str)) > *s.MaxLength {
+		str = strings.TrimSpace(str[:*s.MaxLength])
+	}
+	return str, nil
+}
+
+// regexpString appends to b a string matching re.
+func (g *Generator) regexpString(b *strings.Builder, re *syntax.Regexp) {
+	repeat := func(lo, hi int) {
+		if hi < 0 || hi > lo+3 {
+			hi = lo + 3
+		}
+		n := lo + g.rand().IntN(hi-lo+1)
+		for range n {
+			g.regexpString(b, re.Sub[0])
+		}
+	}
+	switch re.Op {
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		b.WriteRune(g.classRune(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteByte(byte('a' + g.rand().IntN(26)))
+	case syntax.OpCapture:
+		g.regexpString(b, re.Sub[0])
+	case syntax.OpStar:
+		repeat(0, -1)
+	case syntax.OpPlus:
+		repeat(1, -1)
+	case syntax.OpQuest:
+		repeat(0, 1)
+	case syntax.OpRepeat:
+		repeat(re.Min, re.Max)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.regexpString(b, sub)
+		}
+	case syntax.OpAlternate:
+		g.regexpString(b, re.Sub[g.rand().IntN(len(re.Sub))])
+	}
+	// Anchors, word boundaries and empty matches add nothing.
+}
+
+// classRune returns a rune from the character class ranges rs,
+// preferring printable ASCII.
+func (g *Generator) classRune(rs []rune) rune {
+	var printable [][2]rune
+	for i := 0; i+1 < len(rs); i += 2 {
+		lo, hi := max(rs[i], ' '), min(rs[i+1], '~')
+		if lo <= hi {
+			printable = append(printable, [2]rune{lo, hi})
+		}
+	}
+	if len(printable) == 0 {
+		if len(rs) == 0 {
+			return 'x'
+		}
+		return rs[2*g.rand().IntN(len(rs)/2)]
+	}
+	r := printable[g.rand().IntN(len(printable))]
+	return r[0] + rune(g.rand().IntN(int(r[1]-r[0]+1)))
+}
+
+func (g *Generator) number(s *jsonschema.Schema, integer bool) (any, error) {
+	bound := func(n json.Number) (float64, bool) {
+		if n == "" {
+			return 0, false
+		}
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	lo, hasLo := bound(s.Minimum)
+	if f, ok := bound(s.ExclusiveMinimum); ok && (!hasLo || f >= lo) {
+		lo, hasLo = nextAbove(f, integer), true
+	}
+	hi, hasHi := bound(s.Maximum)
+	if f, ok := bound(s.ExclusiveMaximum); ok && (!hasHi || f <= hi) {
+		hi, hasHi = nextBelow(f, integer), true
+	}
+	switch {
+	case !hasLo && !hasHi:
+		lo, hi = 0, 100
+	case !hasLo:
+		lo = hi - 100
+	case !hasHi:
+		hi = lo + 100
+	}
+	if integer {
+		lo, hi = math.Ceil(lo), math.Floor(hi)
+	}
+	if lo > hi {
+		return nil, fmt.Errorf("picoschema: no number lies between %v and %v", lo, hi)
+	}
+	if m, ok := bound(s.MultipleOf); ok && m > 0 {
+		klo, khi := math.Ceil(lo/m), math.Floor(hi/m)
+		if klo > khi {
+			return nil, fmt.Errorf("picoschema: no multiple of %v lies between %v and %v", m, lo, hi)
+		}
+		f := (klo + float64(g.rand().IntN(int(min(khi-klo, 1e6))+1))) * m
+		return numberValue(f, integer), nil
+	}
+	if integer {
+		return int64(lo) + g.rand().Int64N(int64(min(hi-lo, 1e15))+1), nil
+	}
+	// Two decimal places read naturally in examples.
+	f := math.Round((lo+g.rand().Float64()*(hi-lo))*100) / 100
+	return numberValue(math.Min(math.Max(f, lo), hi), false), nil
+}
+
+// nextAbove returns the least candidate greater than f.
+func nextAbove(f float64, integer bool) float64 {
+	if integer {
+		return math.Floor(f) + 1
+	}
+	return math.Nextafter(f, math.Inf(1))
+}
+
+// nextBelow returns the greatest candidate less than f.
+func nextBelow(f float64, integer bool) float64 {
+	if integer {
+		return math.Ceil(f) - 1
+	}
+	return math.Nextafter(f, math.Inf(-1))
+}
+
+// numberValue returns f as an int64 if integer is set or f is whole,
+// and as a float64 otherwise.
+func numberValue(f float64, integer bool) any {
+	if integer || (f == math.Trunc(f) && math.Abs(f) < 1<<53) {
+		return int64(f)
+	}
+	return f
+}
+
+// An InvalidSample is a document that violates exactly one constraint
+// of a schema.
+type InvalidSample struct {
+	Document any
+	// Keyword is the violated keyword, such as "required" or "enum".
+	Keyword string
+	// InstancePath is the JSON Pointer of the offending value,
+	// as in Violation.
+	InstancePath string
+}
+
+// SampleInvalid returns invalid samples made by the default Generator.
+func SampleInvalid(s *jsonschema.Schema) ([]InvalidSample, error) {
+	var g Generator
+	return g.GenerateInvalid(s)
+}
+
+// GenerateInvalid generates a valid document for s and returns variants
+// of it that each violate exactly one constraint: a missing required
+// property, a value outside an enum, a number out of range, a string or
+// array of the wrong length, a value of the wrong type, and so on.
+// Each variant is confirmed with Check to produce exactly one violation,
+// of the keyword it is labeled with. The variants are meant for
+// building evaluation sets for validators and models.
+func (g *Generator) GenerateInvalid(s *jsonschema.Schema) ([]InvalidSample, error) {
+	doc, err := g.Generate(s)
+	if err != nil {
+		return nil, err
+	}
+	var out []InvalidSample
+	for _, m := range g.mutations(doc, s, "") {
+		vs := Check(m.Document, s)
+		if len(vs) == 1 && vs[0].Keyword == m.Keyword && vs[0].InstancePath == m.InstancePath {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// mutations returns variants of v, a valid document for s at the
+// instance path ipath, that may each violate one constraint.
+func (g *Generator) mutations(v any, s *jsonschema.Schema, ipath string) []InvalidSample {
+	if s == nil {
+		return nil
+	}
+	if _, ok := boolSchema(s); ok {
+		return nil
+	}
+	var out []InvalidSample
+	add := func(keyword string, doc any) {
+		out = append(out, InvalidSample{Document: doc, Keyword: keyword, InstancePath: ipath})
+	}
+
+	if s.Type != "" {
+		add("type", wrongType(s.Type))
+	}
+	if s.Enum != nil {
+		add("enum", "not-"+fmt.Sprint(v))
+	}
+	if s.Const != nil {
+		add("const", "not-"+fmt.Sprint(s.Const))
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; ok {
+				m := copyValue(v).(map[string]any)
+				delete(m, name)
+				add("required", m)
+			}
+		}
+		if b, ok := boolSchema(s.AdditionalProperties); ok && !b {
+			m := copyValue(v).(map[string]any)
+			m["unexpectedProperty"] = g.word()
+			out = append(out, InvalidSample{Document: m, Keyword: "additionalProperties", InstancePath: ipath + "/unexpectedProperty"})
+		}
+		for _, k := range sortedKeys(v) {
+			for _, sub := range g.mutations(v[k], propertySchema(s, k), ipath+"/"+escapePointer(k)) {
+				m := copyValue(v).(map[string]any)
+				m[k] = sub.Document
+				sub.Document = m
+				out = append(out, sub)
+			}
+		}
+
+	case []any:
+		if s.MinItems != nil && *s.MinItems > 0 {
+			add("minItems", copyValue(v[:*s.MinItems-1]))
+		}
+		if s.MaxItems != nil && len(v) > 0 {
+			a := copyValue(v).([]any)
+			for uint64(len(a)) <= *s.MaxItems {
+				a = append(a, copyValue(a[len(a)-1]))
+			}
+			// Duplicating items also breaks uniqueItems; Check filters that out.
+			add("maxItems", a)
+		}
+		if s.UniqueItems && len(v) > 0 {
+			add("uniqueItems", append(copyValue(v).([]any), copyValue(v[0])))
+		}
+		for i, e := range v {
+			for _, sub := range g.mutations(e, itemSchema(s, i), fmt.Sprintf("%s/%d", ipath, i)) {
+				a := copyValue(v).([]any)
+				a[i] = sub.Document
+				sub.Document = a
+				out = append(out, sub)
+			}
+		}
+
+	case string:
+		if s.MinLength != nil && *s.MinLength > 0 {
+			add("minLength", strings.Repeat("x", int(*s.MinLength)-1))
+		}
+		if s.MaxLength != nil {
+			add("maxLength", strings.Repeat("x", int(*s.MaxLength)+1))
+		}
+		if s.Pattern != "" {
+			add("pattern", v+" !")
+		}
+
+	default:
+		if _, ok := toFloat(v); !ok {
+			break
+		}
+		off := func(n json.Number, delta float64) any {
+			f, _ := n.Float64()
+			return numberValue(f+delta, s.Type == "integer")
+		}
+		if s.Minimum != "" {
+			add("minimum", off(s.Minimum, -1))
+		}
+		if s.ExclusiveMinimum != "" {
+			add("exclusiveMinimum", off(s.ExclusiveMinimum, 0))
+		}
+		if s.Maximum != "" {
+			add("maximum", off(s.Maximum, 1))
+		}
+		if s.ExclusiveMaximum != "" {
+			add("exclusiveMaximum", off(s.ExclusiveMaximum, 0))
+		}
+		if s.MultipleOf != "" {
+			m, _ := s.MultipleOf.Float64()
+			f, _ := toFloat(v)
+			add("multipleOf", numberValue(f+m/2, false))
+		}
+	}
+	return out
+}
+
+// wrongType returns a value that is not of the JSON Schema type typ.
+func wrongType(typ string) any {
+	if typ == "string" {
+		return int64(42)
+	}
+	return "wrong type"
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerate(t *testing.T) {
+	s := mustSchema(t, `
+id: integer
+name: string
+email?: string
+status(enum): [active, banned]
+home?: latlng
+phone?: phone
+version: semver
+tags?(array): string
+score?: number
+`)
+	// Add constraints that picoschema cannot express yet.
+	id := s.Properties.Value("id")
+	id.Minimum, id.ExclusiveMaximum, id.MultipleOf = "10", "20", "3"
+	email := s.Properties.Value("email")
+	email.Format = "email"
+	score := s.Properties.Value("score")
+	score.ExclusiveMinimum, score.Maximum = "0", "1"
+
+	g := Generator{Seed: 7}
+	docs, err := g.GenerateN(s, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range docs {
+		if vs := Check(d, s); len(vs) != 0 {
+			t.Errorf("generated invalid document %v: %v", d, vs)
+		}
+	}
+
+	// The same seed yields the same documents.
+	g2 := Generator{Seed: 7}
+	again, err := g2.GenerateN(s, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(docs, again); diff != "" {
+		t.Errorf("generation is not deterministic (-first, +second):\n%s", diff)
+	}
+}
+
+func TestGenerateUnsatisfiable(t *testing.T) {
+	s := mustSchema(t, "n: integer")
+	n := s.Properties.Value("n")
+	n.Minimum, n.Maximum = "5", "4"
+	if _, err := Sample(s); err == nil {
+		t.Error("got nil error for unsatisfiable schema")
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	s := mustSchema(t, `
+name: string
+status(enum): [active, banned]
+age: integer
+tags(array): string
+`)
+	age := s.Properties.Value("age")
+	age.Minimum, age.Maximum = "0", "150"
+	tags := s.Properties.Value("tags")
+	one := uint64(1)
+	tags.MinItems = &one
+
+	g := Generator{Seed: 1}
+	samples, err := g.GenerateInvalid(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for _, is := range samples {
+		vs := Check(is.Document, s)
+		if len(vs) != 1 || vs[0].Keyword != is.Keyword || vs[0].InstancePath != is.InstancePath {
+			t.Errorf("sample labeled %s at %s has violations %v", is.Keyword, is.InstancePath, vs)
+		}
+		got[is.Keyword+" "+is.InstancePath] = true
+	}
+	for _, want := range []string{
+		"required ",
+		"additionalProperties /unexpectedProperty",
+		"type /name",
+		"enum /status",
+		"minimum /age",
+		"maximum /age",
+		"minItems /tags",
+		"type /tags/0",
+	} {
+		if !got[want] {
+			t.Errorf("missing sample %q; got %v", want, got)
+		}
+	}
+}
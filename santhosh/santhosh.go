@@ -0,0 +1,77 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package santhosh compiles converted schemas into validators of
+// github.com/santhosh-tekuri/jsonschema/v6, for programs that author
+// schemas in picoschema but want the keyword coverage and error output
+// of that validator, rather than those of picoschema.Validate.
+//
+// The schemas are handed to the compiler as decoded documents built
+// directly from the jsonschema.Schema values, without encoding them
+// as JSON and parsing them again.
+package santhosh
+
+import (
+	"fmt"
+
+	invopop "github.com/invopop/jsonschema"
+	"github.com/jumonapp/picoschema"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// DefaultURL is the URL under which Compile and Convert add the schema
+// to the compiler, unless the schema has an $id. It appears in the
+// locations of validation errors.
+const DefaultURL = "urn:picoschema:schema"
+
+// Compile compiles s with a new compiler.
+func Compile(s *invopop.Schema) (*jsonschema.Schema, error) {
+	c := jsonschema.NewCompiler()
+	if err := AddResource(c, DefaultURL, s); err != nil {
+		return nil, err
+	}
+	sch, err := c.Compile(DefaultURL)
+	if err != nil {
+		return nil, fmt.Errorf("picoschema: compiling schema: %w", err)
+	}
+	return sch, nil
+}
+
+// Convert converts picoschema, as picoschema.ToJSONSchema does,
+// and compiles the result with Compile.
+func Convert(val any, opts ...picoschema.Option) (*jsonschema.Schema, error) {
+	s, err := picoschema.ToJSONSchema(val, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("picoschema: no schema to compile")
+	}
+	return Compile(s)
+}
+
+// AddResource adds s to c under url, so that c can compile it, and
+// other schemas added to c can refer to it by url. A program that
+// compiles many related schemas, such as those of a Registry, should
+// add them all to one compiler.
+func AddResource(c *jsonschema.Compiler, url string, s *invopop.Schema) error {
+	doc, err := picoschema.SchemaDocument(s)
+	if err != nil {
+		return err
+	}
+	if err := c.AddResource(url, doc); err != nil {
+		return fmt.Errorf("picoschema: adding %s: %w", url, err)
+	}
+	return nil
+}
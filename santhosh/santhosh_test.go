@@ -0,0 +1,106 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package santhosh
+
+import (
+	"testing"
+
+	invopop "github.com/invopop/jsonschema"
+	"github.com/jumonapp/picoschema"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"gopkg.in/yaml.v3"
+)
+
+const orderSrc = `
+$defs:
+  money:
+    amount(number, minimum=0):
+    currency(string, pattern=^[A-Z]{3}$):
+id: string
+status(enum): [open, closed]
+total: money
+lines(array, minItems=1):
+  sku: string
+  quantity(integer, minimum=1):
+note?: string?
+`
+
+func TestConvert(t *testing.T) {
+	var val any
+	if err := yaml.Unmarshal([]byte(orderSrc), &val); err != nil {
+		t.Fatal(err)
+	}
+	sch, err := Convert(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := map[string]any{
+		"id":     "o1",
+		"status": "open",
+		"total":  map[string]any{"amount": 9.5, "currency": "EUR"},
+		"lines":  []any{map[string]any{"sku": "a", "quantity": 2}},
+		"note":   nil,
+	}
+	if err := sch.Validate(valid); err != nil {
+		t.Errorf("valid order: %v", err)
+	}
+	for name, change := range map[string]func(m map[string]any){
+		"missing":  func(m map[string]any) { delete(m, "id") },
+		"enum":     func(m map[string]any) { m["status"] = "lost" },
+		"ref":      func(m map[string]any) { m["total"] = map[string]any{"amount": -1, "currency": "EUR"} },
+		"pattern":  func(m map[string]any) { m["total"] = map[string]any{"amount": 1, "currency": "euro"} },
+		"minItems": func(m map[string]any) { m["lines"] = []any{} },
+		"integer":  func(m map[string]any) { m["lines"] = []any{map[string]any{"sku": "a", "quantity": 1.5}} },
+		"extra":    func(m map[string]any) { m["other"] = true },
+	} {
+		inst := map[string]any{}
+		for k, v := range valid {
+			inst[k] = v
+		}
+		change(inst)
+		if err := sch.Validate(inst); err == nil {
+			t.Errorf("%s: invalid order accepted", name)
+		}
+	}
+}
+
+func TestAddResource(t *testing.T) {
+	money, err := picoschema.ParseYAMLString("amount: number\ncurrency: string")
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := picoschema.ParseYAMLString("total: any")
+	if err != nil {
+		t.Fatal(err)
+	}
+	order.Properties.Set("total", &invopop.Schema{Ref: "urn:example:money"})
+	c := jsonschema.NewCompiler()
+	if err := AddResource(c, "urn:example:money", money); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddResource(c, "urn:example:order", order); err != nil {
+		t.Fatal(err)
+	}
+	sch, err := c.Compile("urn:example:order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sch.Validate(map[string]any{"total": map[string]any{"amount": 1, "currency": "EUR"}}); err != nil {
+		t.Errorf("valid order: %v", err)
+	}
+	if err := sch.Validate(map[string]any{"total": map[string]any{"amount": "1"}}); err == nil {
+		t.Error("invalid total accepted")
+	}
+}
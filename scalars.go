@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// builtinScalars are the scalar type names built into picoschema.
+var builtinScalars = []string{"string", "boolean", "null", "number", "integer", "any"}
+
+var (
+	scalarsMu sync.RWMutex
+	scalars   = map[string]*jsonschema.Schema{
+		"latlng": objectSchema([]string{"lat", "lng"}, nil, map[string]*jsonschema.Schema{
+			"lat": {Type: "number", Minimum: "-90", Maximum: "90", Description: "latitude in degrees"},
+			"lng": {Type: "number", Minimum: "-180", Maximum: "180", Description: "longitude in degrees"},
+		}),
+		"address": objectSchema([]string{"street", "city", "region", "postalCode", "country"},
+			[]string{"street", "city", "country"},
+			map[string]*jsonschema.Schema{
+				"street":     {Type: "string"},
+				"city":       {Type: "string"},
+				"region":     {Type: "string", Description: "state, province or region"},
+				"postalCode": {Type: "string"},
+				"country":    {Type: "string", Pattern: "^[A-Z]{2}$", Description: "ISO 3166-1 alpha-2 country code"},
+			}),
+		"phone": {
+			Type:        "string",
+			Pattern:     `^\+[1-9][0-9]{1,14}$`,
+			Description: "phone number in E.164 format",
+		},
+		"color": {
+			Type:        "string",
+			Pattern:     "^#[0-9a-fA-F]{6}$",
+			Description: "RGB color as #rrggbb",
+		},
+		"semver": {
+			Type:        "string",
+			Pattern:     `^(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`,
+			Description: "semantic version",
+		},
+	}
+)
+
+// objectSchema returns a closed object schema with the given properties,
+// in the order of names.
+func objectSchema(names, required []string, props map[string]*jsonschema.Schema) *jsonschema.Schema {
+	s := &jsonschema.Schema{
+		Type:                 "object",
+		Properties:           orderedmap.New[string, *jsonschema.Schema](),
+		AdditionalProperties: jsonschema.FalseSchema,
+		Required:             required,
+	}
+	if required == nil {
+		s.Required = names
+	}
+	for _, n := range names {
+		s.Properties.Set(n, props[n])
+	}
+	return s
+}
+
+// RegisterScalar makes name usable as a scalar type in picoschema,
+// standing for a copy of s. For example, after
+//
+//	RegisterScalar("currency", &jsonschema.Schema{Type: "string", Pattern: "^[A-Z]{3}$"})
+//
+// the picoschema "price: currency, the price currency" converts to a
+// string property with that pattern and description.
+// It replaces any type previously registered under name, including the
+// standard types latlng, address, phone, color and semver.
+// It panics if s is nil, if name is not a letter or underscore followed
+// by letters, digits, underscores, dots and hyphens, or if name is one
+// of the built-in types.
+func RegisterScalar(name string, s *jsonschema.Schema) {
+	if s == nil {
+		panic("picoschema: RegisterScalar with nil schema for " + name)
+	}
+	if !defNamePattern.MatchString(name) {
+		panic(fmt.Sprintf("picoschema: RegisterScalar with invalid type name %q", name))
+	}
+	if isParenType(name) {
+		panic("picoschema: RegisterScalar cannot redefine built-in type " + name)
+	}
+	scalarsMu.Lock()
+	defer scalarsMu.Unlock()
+	scalars[name] = cloneSchema(s)
+}
+
+// lookupScalar returns a copy of the schema registered under name.
+func lookupScalar(name string) (*jsonschema.Schema, bool) {
+	scalarsMu.RLock()
+	defer scalarsMu.RUnlock()
+	s, ok := scalars[name]
+	if !ok {
+		return nil, false
+	}
+	return cloneSchema(s), true
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/invopop/jsonschema"
+)
+
+func TestStandardScalars(t *testing.T) {
+	s := mustSchema(t, `
+home: latlng, where they live
+mailing: address
+mobile: phone
+theme: color
+version: semver
+`)
+	valid := map[string]any{
+		"home":    map[string]any{"lat": 35.6, "lng": 139.7},
+		"mailing": map[string]any{"street": "1 Main St", "city": "Springfield", "country": "US"},
+		"mobile":  "+15551234567",
+		"theme":   "#ff8800",
+		"version": "1.2.3-rc.1",
+	}
+	if vs := Check(valid, s); len(vs) != 0 {
+		t.Errorf("got violations for valid instance: %v", vs)
+	}
+	invalid := map[string]any{
+		"home":    map[string]any{"lat": 135.6, "lng": 139.7},
+		"mailing": map[string]any{"city": "Springfield", "country": "USA"},
+		"mobile":  "555-1234",
+		"theme":   "orange",
+		"version": "v1",
+	}
+	if vs := Check(invalid, s); len(vs) != 6 {
+		t.Errorf("got %d violations, want 6: %v", len(vs), vs)
+	}
+	home, _ := s.Properties.Get("home")
+	if home.Description != "where they live" {
+		t.Errorf("got description %q", home.Description)
+	}
+	if again := mustSchema(t, "home: latlng"); again.Properties.Value("home").Description != "" {
+		t.Error("description leaked into the registered type")
+	}
+}
+
+func TestRegisterScalar(t *testing.T) {
+	RegisterScalar("test-currency", &jsonschema.Schema{Type: "string", Pattern: "^[A-Z]{3}$"})
+	s := mustSchema(t, "price: test-currency, the currency")
+	p, _ := s.Properties.Get("price")
+	if p.Type != "string" || p.Pattern != "^[A-Z]{3}$" || p.Description != "the currency" {
+		t.Errorf("got %+v", p)
+	}
+}
+
+func TestRegisterScalarPanics(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		s    *jsonschema.Schema
+	}{
+		{"test-nil", nil},
+		{"", &jsonschema.Schema{Type: "string"}},
+		{"two words", &jsonschema.Schema{Type: "string"}},
+		{"money?", &jsonschema.Schema{Type: "string"}},
+		{"string", &jsonschema.Schema{Type: "string"}},
+		{"enum", &jsonschema.Schema{Type: "string"}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterScalar(%q, %v) did not panic", test.name, test.s)
+				}
+			}()
+			RegisterScalar(test.name, test.s)
+		}()
+	}
+}
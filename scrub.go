@@ -0,0 +1,185 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A SecretPattern recognizes one kind of secret.
+type SecretPattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// DefaultSecretPatterns are the patterns used by a Scrubber
+// with no patterns of its own.
+var DefaultSecretPatterns = []SecretPattern{
+	{"aws-access-key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github-token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"bearer-token", regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"assigned-secret", regexp.MustCompile(`(?i)\b(api[_-]?key|secret|password|token)\s*[:=]\s*\S{8,}`)},
+	{"internal-url", regexp.MustCompile(`(?i)\bhttps?://(localhost|127\.0\.0\.1|10\.\d+\.\d+\.\d+|192\.168\.\d+\.\d+|[a-z0-9.-]+\.(internal|corp|local|lan))(:\d+)?[^\s)]*`)},
+}
+
+// A SecretFinding reports a secret found by a Scrubber.
+type SecretFinding struct {
+	// Path is the JSON Pointer of the keyword holding the secret.
+	Path string
+	// Pattern is the name of the pattern that matched.
+	Pattern string
+}
+
+// ScrubSecrets scrubs s with the default Scrubber.
+func ScrubSecrets(s *jsonschema.Schema) (*jsonschema.Schema, []SecretFinding) {
+	var sc Scrubber
+	return sc.Scrub(s)
+}
+
+// A Scrubber removes secrets, such as tokens and internal URLs, that
+// have crept into schema text, before the schema is sent to a
+// third-party model provider.
+type Scrubber struct {
+	// Patterns recognize secrets. If nil, DefaultSecretPatterns are used.
+	Patterns []SecretPattern
+	// Replacement replaces secrets in titles, descriptions and comments.
+	// If empty, "[redacted]" is used.
+	Replacement string
+}
+
+// Scrub returns a copy of s without secrets, and reports what it found.
+// Secrets in titles, descriptions and comments are replaced;
+// extension keywords (annotations) containing a secret anywhere in
+// their value are removed, as are examples and defaults containing one.
+// Secrets in the strings of const values and enum members are replaced,
+// so that the schema keeps its shape but no longer accepts the secret.
+// Findings are sorted by path.
+// s is not modified.
+func (sc *Scrubber) Scrub(s *jsonschema.Schema) (*jsonschema.Schema, []SecretFinding) {
+	patterns := sc.Patterns
+	if patterns == nil {
+		patterns = DefaultSecretPatterns
+	}
+	repl := sc.Replacement
+	if repl == "" {
+		repl = "[redacted]"
+	}
+	var findings []SecretFinding
+	// find reports the first pattern matching str.
+	find := func(str string) (SecretPattern, bool) {
+		for _, p := range patterns {
+			if p.Regexp.MatchString(str) {
+				return p, true
+			}
+		}
+		return SecretPattern{}, false
+	}
+	// findIn reports the first pattern matching a string in v.
+	var findIn func(v any) (SecretPattern, bool)
+	findIn = func(v any) (SecretPattern, bool) {
+		switch v := v.(type) {
+		case string:
+			return find(v)
+		case []any:
+			for _, e := range v {
+				if p, ok := findIn(e); ok {
+					return p, true
+				}
+			}
+		case map[string]any:
+			for _, k := range sortedKeys(v) {
+				if p, ok := findIn(v[k]); ok {
+					return p, true
+				}
+			}
+		}
+		return SecretPattern{}, false
+	}
+	text := func(str *string, path string) {
+		for _, p := range patterns {
+			if p.Regexp.MatchString(*str) {
+				findings = append(findings, SecretFinding{Path: path, Pattern: p.Name})
+				*str = p.Regexp.ReplaceAllLiteralString(*str, repl)
+			}
+		}
+	}
+	// value returns a copy of v with secrets in its strings replaced.
+	var value func(v any, path string) any
+	value = func(v any, path string) any {
+		switch v := v.(type) {
+		case string:
+			text(&v, path)
+			return v
+		case []any:
+			c := make([]any, len(v))
+			for i, e := range v {
+				c[i] = value(e, path+"/"+strconv.Itoa(i))
+			}
+			return c
+		case map[string]any:
+			c := make(map[string]any, len(v))
+			for _, k := range sortedKeys(v) {
+				c[k] = value(v[k], path+"/"+escapePointer(k))
+			}
+			return c
+		}
+		return v
+	}
+
+	c := cloneSchema(s)
+	walkSchema(c, "", func(s *jsonschema.Schema, path string) bool {
+		text(&s.Title, path+"/title")
+		text(&s.Description, path+"/description")
+		text(&s.Comments, path+"/$comment")
+		for _, k := range sortedKeys(s.Extras) {
+			if p, ok := findIn(s.Extras[k]); ok {
+				findings = append(findings, SecretFinding{Path: path + "/" + escapePointer(k), Pattern: p.Name})
+				deleteExtra(s, k)
+			}
+		}
+		var kept []any
+		for i, e := range s.Examples {
+			if p, ok := findIn(e); ok {
+				findings = append(findings, SecretFinding{Path: path + "/examples/" + strconv.Itoa(i), Pattern: p.Name})
+				continue
+			}
+			kept = append(kept, e)
+		}
+		if len(kept) < len(s.Examples) {
+			s.Examples = kept
+		}
+		if p, ok := findIn(s.Default); ok {
+			findings = append(findings, SecretFinding{Path: path + "/default", Pattern: p.Name})
+			s.Default = nil
+		}
+		if s.Const != nil {
+			s.Const = value(s.Const, path+"/const")
+		}
+		for i, e := range s.Enum {
+			s.Enum[i] = value(e, path+"/enum/"+strconv.Itoa(i))
+		}
+		return true
+	})
+	slices.SortStableFunc(findings, func(a, b SecretFinding) int { return strings.Compare(a.Path, b.Path) })
+	return c, findings
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A Signer signs data.
+type Signer interface {
+	Sign(data []byte) ([]byte, error)
+}
+
+// A Verifier checks signatures made by a Signer.
+// Verify returns an error wrapping ErrBadSignature
+// if sig is not a valid signature of data.
+type Verifier interface {
+	Verify(data, sig []byte) error
+}
+
+// ErrBadSignature is returned, wrapped, when a schema's signature
+// does not verify.
+var ErrBadSignature = errors.New("picoschema: bad schema signature")
+
+// Ed25519Signer returns a Signer using the Ed25519 private key priv.
+func Ed25519Signer(priv ed25519.PrivateKey) Signer {
+	return ed25519Signer(priv)
+}
+
+type ed25519Signer ed25519.PrivateKey
+
+func (k ed25519Signer) Sign(data []byte) ([]byte, error) {
+	if len(k) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("picoschema: Ed25519 private key has length %d, want %d", len(k), ed25519.PrivateKeySize)
+	}
+	return ed25519.Sign(ed25519.PrivateKey(k), data), nil
+}
+
+// Ed25519Verifier returns a Verifier using the Ed25519 public key pub.
+func Ed25519Verifier(pub ed25519.PublicKey) Verifier {
+	return ed25519Verifier(pub)
+}
+
+type ed25519Verifier ed25519.PublicKey
+
+func (k ed25519Verifier) Verify(data, sig []byte) error {
+	if len(k) != ed25519.PublicKeySize {
+		return fmt.Errorf("picoschema: Ed25519 public key has length %d, want %d", len(k), ed25519.PublicKeySize)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(k), data, sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// SignSchema signs the canonical JSON encoding of s, the encoding
+// Fingerprint digests. The signature therefore covers the meaning of
+// the schema rather than its source text: reformatting the source,
+// or converting it between formats, does not invalidate it.
+func SignSchema(s *jsonschema.Schema, signer Signer) ([]byte, error) {
+	data, err := canonicalJSON(s)
+	if err != nil {
+		return nil, fmt.Errorf("picoschema: %w", err)
+	}
+	return signer.Sign(data)
+}
+
+// VerifySchema checks that sig is a signature of s made by SignSchema.
+func VerifySchema(s *jsonschema.Schema, sig []byte, v Verifier) error {
+	data, err := canonicalJSON(s)
+	if err != nil {
+		return fmt.Errorf("picoschema: %w", err)
+	}
+	return v.Verify(data, sig)
+}
+
+// ImportSigned checks that sig is a signature of data, as made by
+// a Signer's Sign, and only then imports data with the importer
+// registered under format, as Import does. It is meant for loading
+// schemas fetched from remote registries: the signature covers the
+// bytes themselves, so that no importer ever parses unverified input.
+// Unlike SignSchema, it does not accept reformatted sources.
+func ImportSigned(format string, data, sig []byte, v Verifier) (*jsonschema.Schema, error) {
+	if err := v.Verify(data, sig); err != nil {
+		return nil, err
+	}
+	return Import(format, data)
+}
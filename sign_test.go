@@ -0,0 +1,84 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+)
+
+func TestSignSchema(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := []byte("name: string\nage?: integer\n")
+	s, err := Import("picoschema", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := SignSchema(s, Ed25519Signer(priv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reordered source with the same meaning verifies.
+	r, err := Import("picoschema", []byte("age?: integer\nname: string\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySchema(r, sig, Ed25519Verifier(pub)); err != nil {
+		t.Errorf("VerifySchema: %v", err)
+	}
+	// Changed content does not.
+	c, err := Import("picoschema", []byte("name: string\nage: integer\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySchema(c, sig, Ed25519Verifier(pub)); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("got error %v, want ErrBadSignature", err)
+	}
+	// Nor does another key.
+	other, _, _ := ed25519.GenerateKey(nil)
+	if err := VerifySchema(s, sig, Ed25519Verifier(other)); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("got error %v, want ErrBadSignature", err)
+	}
+}
+
+func TestImportSigned(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := []byte("name: string\nage?: integer\n")
+	sig, err := Ed25519Signer(priv).Sign(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportSigned("picoschema", src, sig, Ed25519Verifier(pub)); err != nil {
+		t.Errorf("ImportSigned: %v", err)
+	}
+	// The signature covers the bytes, not the meaning.
+	_, err = ImportSigned("picoschema", []byte("age?: integer\nname: string\n"), sig, Ed25519Verifier(pub))
+	if !errors.Is(err, ErrBadSignature) {
+		t.Errorf("got error %v, want ErrBadSignature", err)
+	}
+	// Unverified input is not parsed.
+	_, err = ImportSigned("picoschema", []byte("name: [unterminated"), sig, Ed25519Verifier(pub))
+	if !errors.Is(err, ErrBadSignature) {
+		t.Errorf("got error %v, want ErrBadSignature", err)
+	}
+}
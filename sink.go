@@ -0,0 +1,344 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// A schemaSink builds an output model of a converted schema.
+//
+// The parser produces a jsonschema.Schema, and emitSchema replays it to
+// a sink as the structure of its JSON encoding, so that a new output
+// model needs only a sink and not its own copy of the parsing logic.
+type schemaSink interface {
+	// beginObject and endObject delimit a JSON object.
+	// Each member of the object starts with a call to key.
+	beginObject()
+	key(k string)
+	endObject()
+	// beginArray and endArray delimit a JSON array.
+	beginArray()
+	endArray()
+	// value is a string, json.Number, bool or nil.
+	value(v any)
+}
+
+// emitSchema replays s to sink, with the keys of objects in the order
+// of the JSON encoding of s. It walks s rather than encoding it, and
+// encodes only values of types it does not know, such as structs in
+// defaults.
+func emitSchema(s *jsonschema.Schema, sink schemaSink) error {
+	if s == nil {
+		sink.value(nil)
+		return nil
+	}
+	if b, ok := boolSchema(s); ok {
+		sink.value(b)
+		return nil
+	}
+	if reflect.DeepEqual(s, &jsonschema.Schema{}) {
+		// jsonschema encodes the empty schema as true.
+		sink.value(true)
+		return nil
+	}
+	sink.beginObject()
+	rv := reflect.ValueOf(s).Elem()
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+		f := rv.Field(i)
+		if name == "" || name == "-" || isEmptyValue(f) {
+			continue
+		}
+		sink.key(name)
+		if err := emitValue(f, sink); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	for _, k := range sortedKeys(s.Extras) {
+		sink.key(k)
+		if err := emitValue(reflect.ValueOf(s.Extras[k]), sink); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	sink.endObject()
+	return nil
+}
+
+// isEmptyValue reports whether encoding/json omits v from a field
+// marked omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// emitValue replays v, a value in a schema, to sink.
+func emitValue(v reflect.Value, sink schemaSink) error {
+	if !v.IsValid() {
+		sink.value(nil)
+		return nil
+	}
+	switch v.Type() {
+	case schemaType:
+		return emitSchema(v.Interface().(*jsonschema.Schema), sink)
+	case propertiesType:
+		props := v.Interface().(*orderedmap.OrderedMap[string, *jsonschema.Schema])
+		sink.beginObject()
+		for p := props.Oldest(); p != nil; p = p.Next() {
+			sink.key(p.Key)
+			if err := emitSchema(p.Value, sink); err != nil {
+				return fmt.Errorf("%s: %w", p.Key, err)
+			}
+		}
+		sink.endObject()
+		return nil
+	}
+	if v.Kind() != reflect.Interface && v.Type().Implements(marshalerType) {
+		return emitJSON(v.Interface(), sink)
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			sink.value(nil)
+			return nil
+		}
+		return emitValue(v.Elem(), sink)
+	case reflect.String:
+		if n, ok := v.Interface().(json.Number); ok {
+			sink.value(n)
+		} else {
+			sink.value(v.String())
+		}
+	case reflect.Bool:
+		sink.value(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		sink.value(json.Number(strconv.FormatInt(v.Int(), 10)))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		sink.value(json.Number(strconv.FormatUint(v.Uint(), 10)))
+	case reflect.Float32, reflect.Float64:
+		return emitJSON(v.Interface(), sink)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			sink.value(nil)
+			return nil
+		}
+		sink.beginArray()
+		for i := range v.Len() {
+			if err := emitValue(v.Index(i), sink); err != nil {
+				return err
+			}
+		}
+		sink.endArray()
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return emitJSON(v.Interface(), sink)
+		}
+		if v.IsNil() {
+			sink.value(nil)
+			return nil
+		}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+		sink.beginObject()
+		for _, k := range keys {
+			sink.key(k.String())
+			if err := emitValue(v.MapIndex(k), sink); err != nil {
+				return fmt.Errorf("%s: %w", k.String(), err)
+			}
+		}
+		sink.endObject()
+	default:
+		return emitJSON(v.Interface(), sink)
+	}
+	return nil
+}
+
+// emitJSON replays the JSON encoding of v to sink, for values that
+// emitValue does not walk, and for floats, whose encoding is subtle.
+func emitJSON(v any, sink schemaSink) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	// inObject records, for each open container, whether it is an object.
+	var inObject []bool
+	wantKey := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if wantKey {
+			if k, ok := tok.(string); ok {
+				sink.key(k)
+				wantKey = false
+				continue
+			}
+		}
+		switch tok {
+		case json.Delim('{'):
+			sink.beginObject()
+			inObject = append(inObject, true)
+		case json.Delim('['):
+			sink.beginArray()
+			inObject = append(inObject, false)
+		case json.Delim('}'):
+			sink.endObject()
+			inObject = inObject[:len(inObject)-1]
+		case json.Delim(']'):
+			sink.endArray()
+			inObject = inObject[:len(inObject)-1]
+		default:
+			sink.value(tok)
+		}
+		// After a complete member value, an object expects a key.
+		wantKey = len(inObject) > 0 && inObject[len(inObject)-1]
+	}
+}
+
+// A treeSink builds a tree of objects, []any slices and JSON scalars.
+type treeSink struct {
+	newObject func() any
+	set       func(obj any, k string, v any)
+	number    func(n json.Number) any
+	stack     []treeFrame
+	result    any
+}
+
+// A treeFrame is an open object or array of a treeSink.
+type treeFrame struct {
+	obj  any
+	key  string // the key of the member being built, in an object
+	list []any
+}
+
+func (t *treeSink) add(v any) {
+	if len(t.stack) == 0 {
+		t.result = v
+		return
+	}
+	f := &t.stack[len(t.stack)-1]
+	if f.obj != nil {
+		t.set(f.obj, f.key, v)
+	} else {
+		f.list = append(f.list, v)
+	}
+}
+
+func (t *treeSink) beginObject() { t.stack = append(t.stack, treeFrame{obj: t.newObject()}) }
+func (t *treeSink) key(k string) { t.stack[len(t.stack)-1].key = k }
+func (t *treeSink) beginArray()  { t.stack = append(t.stack, treeFrame{list: []any{}}) }
+
+func (t *treeSink) endObject() {
+	f := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	t.add(f.obj)
+}
+
+func (t *treeSink) endArray() {
+	f := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	t.add(f.list)
+}
+
+func (t *treeSink) value(v any) {
+	if n, ok := v.(json.Number); ok {
+		v = t.number(n)
+	}
+	t.add(v)
+}
+
+// newOrderedMapSink returns a sink that builds ordered maps, keeping
+// the order of keys and the exact text of numbers.
+func newOrderedMapSink() *treeSink {
+	return &treeSink{
+		newObject: func() any { return orderedmap.New[string, any]() },
+		set:       func(obj any, k string, v any) { obj.(*orderedmap.OrderedMap[string, any]).Set(k, v) },
+		number:    func(n json.Number) any { return n },
+	}
+}
+
+// ToOrderedMap converts picoschema to a JSON Schema represented as an
+// ordered map, for callers that build on generic JSON values rather
+// than jsonschema.Schema. Nested schemas are ordered maps too, lists
+// are []any, and numbers are json.Number.
+//
+// Keys appear in the order of the JSON encoding of the schema that
+// ToJSONSchema returns, so that properties come in the order of their
+// names, and encoding the map with encoding/json gives the same JSON.
+// The boolean schemas true and false at the root are returned as {}
+// and {"not": {}}.
+func ToOrderedMap(val any, opts ...Option) (*orderedmap.OrderedMap[string, any], error) {
+	s, err := ToJSONSchema(val, opts...)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	return schemaToOrderedMap(s)
+}
+
+// schemaToOrderedMap replays s to an ordered map sink.
+func schemaToOrderedMap(s *jsonschema.Schema) (*orderedmap.OrderedMap[string, any], error) {
+	sink := newOrderedMapSink()
+	if err := emitSchema(s, sink); err != nil {
+		return nil, fmt.Errorf("picoschema: %w", err)
+	}
+	switch v := sink.result.(type) {
+	case *orderedmap.OrderedMap[string, any]:
+		return v, nil
+	case bool:
+		m := orderedmap.New[string, any]()
+		if !v {
+			m.Set("not", orderedmap.New[string, any]())
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("picoschema: schema encoded as %T", sink.result)
+}
+
+// SchemaDocument returns s as a decoded JSON document, equal to what
+// json.Unmarshal decodes from the encoding of s with UseNumber: objects
+// are map[string]any, arrays []any and numbers json.Number. It builds
+// the document from s directly rather than by encoding it, for
+// validators and other libraries that take decoded schemas.
+func SchemaDocument(s *jsonschema.Schema) (any, error) {
+	sink := &treeSink{
+		newObject: func() any { return map[string]any{} },
+		set:       func(obj any, k string, v any) { obj.(map[string]any)[k] = v },
+		number:    func(n json.Number) any { return n },
+	}
+	if err := emitSchema(s, sink); err != nil {
+		return nil, fmt.Errorf("picoschema: %w", err)
+	}
+	return sink.result, nil
+}
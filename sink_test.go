@@ -0,0 +1,106 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+func TestToOrderedMap(t *testing.T) {
+	for _, src := range []string{
+		"string",
+		"any",
+		"id: string, the ID\ntags?(array): string\nsize(integer, minimum=1): \nmeta(object):\n  k: number",
+		"$defs:\n  node:\n    value: number\n    next?: node\nroot: node",
+	} {
+		s := mustSchema(t, src)
+		want, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := schemaToOrderedMap(s)
+		if err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+		got, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if src == "any" {
+			want = []byte("{}")
+		}
+		if string(got) != string(want) {
+			t.Errorf("%q:\ngot  %s\nwant %s", src, got, want)
+		}
+	}
+}
+
+func TestToOrderedMapNested(t *testing.T) {
+	m, err := ToOrderedMap(map[string]any{"b(array)": "integer", "a": "string"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	props, ok := m.Value("properties").(*orderedmap.OrderedMap[string, any])
+	if !ok {
+		t.Fatalf("properties: got %T", m.Value("properties"))
+	}
+	if k := props.Oldest().Key; k != "a" {
+		t.Errorf("first property %q, want a", k)
+	}
+	b, ok := props.Value("b").(*orderedmap.OrderedMap[string, any])
+	if !ok {
+		t.Fatalf("properties.b: got %T", props.Value("b"))
+	}
+	if _, ok := b.Value("items").(*orderedmap.OrderedMap[string, any]); !ok {
+		t.Errorf("properties.b.items: got %T", b.Value("items"))
+	}
+	if _, ok := m.Value("required").([]any); !ok {
+		t.Errorf("required: got %T", m.Value("required"))
+	}
+	if m, err := ToOrderedMap(nil); m != nil || err != nil {
+		t.Errorf("nil: got %v, %v", m, err)
+	}
+}
+
+func TestSchemaDocument(t *testing.T) {
+	s := mustSchema(t, "id: string\nn(number, minimum=0.5, maximum=10):\nl(array): {x: boolean}")
+	s.Default = struct {
+		A int `json:"a"`
+	}{1}
+	setExtra(s, "x-when", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var want any
+	if err := dec.Decode(&want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := SchemaDocument(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
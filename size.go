@@ -0,0 +1,200 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"strconv"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A Tokenizer counts the tokens a model would see for some text.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// TokenizerFunc adapts an ordinary function to the Tokenizer interface.
+type TokenizerFunc func(text string) int
+
+// CountTokens calls f(text).
+func (f TokenizerFunc) CountTokens(text string) int { return f(text) }
+
+// ApproxTokenizer estimates one token per four bytes of text,
+// a common rule of thumb for JSON with English descriptions.
+var ApproxTokenizer Tokenizer = TokenizerFunc(func(text string) int {
+	return (len(text) + 3) / 4
+})
+
+// A SizeEstimate reports the size of a schema's JSON encoding.
+type SizeEstimate struct {
+	// Total is the size of the whole schema.
+	Total int
+	// Properties attributes size to each property at any depth,
+	// largest first. The size of a property includes its name
+	// and all of its subschemas.
+	Properties []PropertySize
+}
+
+// PropertySize is the size of one property of a schema.
+type PropertySize struct {
+	Path string // JSON Pointer of the property schema
+	Size int
+}
+
+// EstimateBytes reports how many bytes s occupies when encoded as JSON
+// and embedded in a request to the provider described by p.
+// The schema is assumed to be adapted to the provider, as by
+// AdaptToProfile, before sending. The profile may be nil.
+func EstimateBytes(s *jsonschema.Schema, p *Profile) (*SizeEstimate, error) {
+	return estimateSize(s, p, func(text string) int { return len(text) })
+}
+
+// EstimateTokens reports how many tokens s occupies when encoded as JSON,
+// as counted by tok. If tok is nil, ApproxTokenizer is used.
+func EstimateTokens(s *jsonschema.Schema, tok Tokenizer) (*SizeEstimate, error) {
+	if tok == nil {
+		tok = ApproxTokenizer
+	}
+	return estimateSize(s, nil, tok.CountTokens)
+}
+
+func estimateSize(s *jsonschema.Schema, p *Profile, measure func(string) int) (*SizeEstimate, error) {
+	v, err := schemaToValue(s)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil {
+		if _, err := adaptValue(v, p); err != nil {
+			return nil, err
+		}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	est := &SizeEstimate{Total: measure(string(data))}
+	err = forEachValueSubschema(v, "", func(sub any, path, property string) error {
+		if property == "" {
+			return nil
+		}
+		data, err := json.Marshal(map[string]any{property: sub})
+		if err != nil {
+			return err
+		}
+		// Drop the enclosing braces, which belong to the parent.
+		est.Properties = append(est.Properties, PropertySize{Path: path, Size: measure(string(data[1 : len(data)-1]))})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(est.Properties, func(a, b PropertySize) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), cmp.Compare(a.Path, b.Path))
+	})
+	return est, nil
+}
+
+// schemaToValue returns the JSON encoding of s decoded into a value of type any.
+func schemaToValue(s *jsonschema.Schema) (any, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Keywords whose values are subschemas, in the decoded JSON form of a schema.
+var (
+	schemaKeywordsSingle = []string{"items", "additionalProperties", "not", "if", "then", "else",
+		"contains", "propertyNames", "contentSchema"}
+	schemaKeywordsList = []string{"allOf", "anyOf", "oneOf", "prefixItems"}
+	schemaKeywordsMap  = []string{"properties", "patternProperties", "$defs", "dependentSchemas"}
+)
+
+// forEachValueSubschema calls fn for v, a decoded JSON schema, and for
+// each of its subschemas, recursively. The property argument is the
+// property name if the subschema is an entry of "properties".
+func forEachValueSubschema(v any, path string, fn func(sub any, path, property string) error) error {
+	var visit func(v any, path, property string) error
+	visit = func(v any, path, property string) error {
+		if err := fn(v, path, property); err != nil {
+			return err
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for _, k := range schemaKeywordsSingle {
+			if sub, ok := m[k]; ok {
+				if err := visit(sub, path+"/"+k, ""); err != nil {
+					return err
+				}
+			}
+		}
+		for _, k := range schemaKeywordsList {
+			subs, _ := m[k].([]any)
+			for i, sub := range subs {
+				if err := visit(sub, path+"/"+k+"/"+strconv.Itoa(i), ""); err != nil {
+					return err
+				}
+			}
+		}
+		for _, k := range schemaKeywordsMap {
+			subs, _ := m[k].(map[string]any)
+			for _, name := range sortedKeys(subs) {
+				prop := ""
+				if k == "properties" {
+					prop = name
+				}
+				if err := visit(subs[name], path+"/"+k+"/"+escapePointer(name), prop); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return visit(v, path, "")
+}
+
+// stripKeywordsValue removes the keywords p does not support from v,
+// a decoded JSON schema, and from all of its subschemas, and returns
+// the removals.
+func stripKeywordsValue(v any, p *Profile) []SchemaChange {
+	var changes []SchemaChange
+	forEachValueSubschema(v, "", func(sub any, path, _ string) error {
+		if m, ok := sub.(map[string]any); ok {
+			for _, k := range sortedKeys(m) {
+				if !p.Supports(k) {
+					delete(m, k)
+					changes = append(changes, SchemaChange{
+						Path:   path + "/" + escapePointer(k),
+						Action: "removed",
+						Lossy:  !isAnnotation(k),
+						Reason: "not supported by " + p.Name,
+					})
+				}
+			}
+		}
+		return nil
+	})
+	return changes
+}
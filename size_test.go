@@ -0,0 +1,66 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEstimateBytes(t *testing.T) {
+	s := mustSchema(t, `
+id: string
+notes: string, a very long description that should dominate the size of this schema
+`)
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	est, err := EstimateBytes(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Total != len(data) {
+		t.Errorf("got total %d, want %d", est.Total, len(data))
+	}
+	if len(est.Properties) != 2 || est.Properties[0].Path != "/properties/notes" {
+		t.Fatalf("got properties %+v, want notes first", est.Properties)
+	}
+	// "id":{"type":"string"}
+	if got, want := est.Properties[1].Size, len(`"id":{"type":"string"}`); got != want {
+		t.Errorf("got id size %d, want %d", got, want)
+	}
+
+	gemini, _ := LookupProfile("gemini")
+	stripped, err := EstimateBytes(s, gemini)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := est.Total - len(`,"additionalProperties":false`); stripped.Total != want {
+		t.Errorf("got gemini total %d, want %d", stripped.Total, want)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	s := mustSchema(t, `id: string`)
+	words := TokenizerFunc(func(text string) int { return 1 })
+	est, err := EstimateTokens(s, words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Total != 1 || est.Properties[0].Size != 1 {
+		t.Errorf("got %+v, want sizes of 1", est)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// sourceKey is the annotation naming where a schema came from, such as
+// the name or URL of the schema it was resolved or bundled from. It is
+// recorded by the WithSources option and by a Bundler with
+// RecordSources set, and read by Sources.
+const sourceKey = "x-source"
+
+// setSource records src as the source of s, unless s
+// already has one, which is the nearer origin.
+func setSource(s *jsonschema.Schema, src string) {
+	if _, ok := s.Extras[sourceKey]; !ok {
+		setExtra(s, sourceKey, src)
+	}
+}
+
+// Sources returns a side table of where the properties of s came from,
+// mapping the JSON Pointer of each property to the source recorded on
+// it or, failing that, on the nearest schema enclosing it. Properties
+// with no recorded source are left out. When schemas composed from
+// many others conflict, as when Intersect fails, the table traces the
+// conflicting properties to their origin.
+func Sources(s *jsonschema.Schema) map[string]string {
+	sources := make(map[string]string)
+	var walk func(s *jsonschema.Schema, path, src string)
+	walk = func(s *jsonschema.Schema, path, src string) {
+		if v, ok := s.Extras[sourceKey].(string); ok {
+			src = v
+		}
+		forEachSubschema(s, func(sub *jsonschema.Schema, ptr string) {
+			p := path + ptr
+			if strings.HasPrefix(ptr, "/properties/") {
+				if v, ok := sub.Extras[sourceKey].(string); ok {
+					sources[p] = v
+				} else if src != "" {
+					sources[p] = src
+				}
+			}
+			walk(sub, p, src)
+		})
+	}
+	walk(s, "", "")
+	return sources
+}
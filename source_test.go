@@ -0,0 +1,83 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSourcesResolved(t *testing.T) {
+	var r Registry
+	if err := r.Register("customer", mustSchema(t, "name: string\nemail?: string")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("destination", mustSchema(t, "city: string")); err != nil {
+		t.Fatal(err)
+	}
+	src := `
+owner: customer
+shipping(object):
+  to: destination
+note?: string
+`
+	s, err := ParseYAMLString(src, WithResolver(&r), WithSources(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"/properties/owner":                                  "customer",
+		"/properties/owner/properties/name":                  "customer",
+		"/properties/owner/properties/email":                 "customer",
+		"/properties/shipping/properties/to":                 "destination",
+		"/properties/shipping/properties/to/properties/city": "destination",
+	}
+	if diff := cmp.Diff(want, Sources(s)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	s, err = ParseYAMLString(src, WithResolver(&r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Sources(s); len(got) != 0 {
+		t.Errorf("without WithSources: got %v, want none", got)
+	}
+}
+
+func TestSourcesInlined(t *testing.T) {
+	var r Registry
+	if err := r.Register("line", mustSchema(t, "sku: string")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("order", refObject("item", "line")); err != nil {
+		t.Fatal(err)
+	}
+	b := Bundler{Resolver: &r, RecordSources: true}
+	s, err := b.Inline(refObject("order", "order", "code", "line#/properties/sku"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"/properties/order":                                "order",
+		"/properties/order/properties/item":                "line",
+		"/properties/order/properties/item/properties/sku": "line",
+		"/properties/code":                                 "line",
+	}
+	if diff := cmp.Diff(want, Sources(s)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A TraceStep records one decision made in converting picoschema.
+// A trace marshals to JSON that is stable across runs, so it can be
+// kept as a golden file to catch unintended changes in behavior.
+type TraceStep struct {
+	// Path is the JSON Pointer of the resulting schema within the
+	// converted schema. It is "" for the root.
+	Path string `json:"path"`
+
+	// Key is the picoschema key as written, such as "tags?(array, renamedFrom=labels)".
+	// It is "" for the root.
+	Key string `json:"key"`
+
+	// Interpretation says how the key or root value was read, such as
+	// "picoschema object", "json schema", "optional array property of
+	// string", "required property of object", "wildcard of any" or
+	// "assertions".
+	Interpretation string `json:"interpretation"`
+
+	// Keywords are the keywords set in the resulting schema, sorted.
+	Keywords []string `json:"keywords"`
+}
+
+// ToJSONSchemaTrace is like ToJSONSchema, but also returns the steps
+// taken to convert val, sorted by path and key.
+func ToJSONSchemaTrace(val any, opts ...Option) (*jsonschema.Schema, []TraceStep, error) {
+	start := time.Now()
+	tr := &tracer{}
+	p := newParser(opts)
+	p.tr = tr
+	s, err := p.toJSONSchema(val)
+	recordConversion("picoschema", start)
+	recordError("convert", err)
+	if err != nil {
+		return nil, nil, err
+	}
+	slices.SortFunc(tr.steps, func(a, b TraceStep) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Key, b.Key))
+	})
+	return s, tr.steps, nil
+}
+
+// A tracer collects TraceSteps. Its methods do nothing on a nil tracer,
+// so that conversion without a trace costs nothing.
+type tracer struct {
+	steps []TraceStep
+}
+
+func (tr *tracer) add(path, key, interpretation string, s *jsonschema.Schema) {
+	if tr == nil {
+		return
+	}
+	tr.addKeywords(path, key, interpretation, schemaKeywords(s))
+}
+
+func (tr *tracer) addKeywords(path, key, interpretation string, keywords []string) {
+	if tr == nil {
+		return
+	}
+	if keywords == nil {
+		keywords = []string{}
+	}
+	tr.steps = append(tr.steps, TraceStep{Path: path, Key: key, Interpretation: interpretation, Keywords: keywords})
+}
+
+// picoForm names the picoschema form of the YAML value v.
+func picoForm(v any) string {
+	switch v := v.(type) {
+	case map[string]any:
+		if _, ok := v[jsonschemaTag]; ok {
+			return "json schema"
+		}
+		return "object"
+	case []any:
+		return "enum"
+	case string:
+		typ, _, _ := strings.Cut(v, ",")
+		return typ
+	}
+	return "value"
+}
+
+// propertyInterpretation describes a property read from a key with the
+// given optionality and parenthetical type, and with value v.
+func propertyInterpretation(optional bool, typ string, v any) string {
+	if typ == "*" {
+		return "wildcard of " + picoForm(v)
+	}
+	ret := "required "
+	if optional {
+		ret = "optional "
+	}
+	if _, ok := nestedArrayType(typ); ok {
+		return ret + "nested array property of " + picoForm(v)
+	}
+	switch typ {
+	case "array":
+		return ret + "array property of " + picoForm(v)
+	case "enum":
+		return ret + "enum property"
+	case "const":
+		return ret + "const property"
+	case "jsonschema":
+		return ret + "json schema property"
+	case "oneOf":
+		return ret + "union property"
+	case "anyOf":
+		return ret + "any-of union property"
+	case "allOf":
+		return ret + "all-of property"
+	case "tuple":
+		return ret + "tuple property"
+	case "map":
+		return ret + "map property of " + picoForm(v)
+	case "string", "boolean", "null", "number", "integer", "any":
+		return ret + "property of " + typ
+	}
+	return ret + "property of " + picoForm(v)
+}
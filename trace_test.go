@@ -0,0 +1,73 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+func TestToJSONSchemaTrace(t *testing.T) {
+	const src = `
+name: string, the name
+tags?(array, sinceVersion=2): string
+size(enum): [S, M, L]
+home:
+  city: string
+(*): integer
+(assert): "size != 'L' || name != ''"
+`
+	var val any
+	if err := yaml.Unmarshal([]byte(src), &val); err != nil {
+		t.Fatal(err)
+	}
+	s, trace, err := ToJSONSchemaTrace(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TraceStep{
+		{"", "", "picoschema object", []string{"additionalProperties", "properties", "required", "type", "x-assert"}},
+		{"", "(assert)", "assertions", []string{"x-assert"}},
+		{"/additionalProperties", "(*)", "wildcard of integer", []string{"type"}},
+		{"/properties/home", "home", "required property of object", []string{"additionalProperties", "properties", "required", "type"}},
+		{"/properties/home/properties/city", "city", "required property of string", []string{"type"}},
+		{"/properties/name", "name", "required property of string", []string{"description", "type"}},
+		{"/properties/size", "size(enum)", "required enum property", []string{"enum"}},
+		{"/properties/tags", "tags?(array, sinceVersion=2)", "optional array property of string", []string{"items", "type", "x-sinceVersion"}},
+	}
+	if diff := cmp.Diff(want, trace); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	plain, err := ToJSONSchema(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortSchemaSlices(plain)
+	sortSchemaSlices(s)
+	if !cmp.Equal(schemaJSON(t, plain), schemaJSON(t, s)) {
+		t.Error("ToJSONSchemaTrace and ToJSONSchema disagree")
+	}
+
+	_, trace, err = ToJSONSchemaTrace(map[string]any{"type": "string", "minLength": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []TraceStep{{"", "", "json schema", []string{"minLength", "type"}}}; !cmp.Equal(want, trace) {
+		t.Errorf("got %v, want %v", trace, want)
+	}
+}
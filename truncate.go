@@ -0,0 +1,175 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/invopop/jsonschema"
+)
+
+// ellipsis ends a shortened description.
+const ellipsis = "…"
+
+// FitDescriptions returns the decoded JSON encoding of s with its
+// descriptions shortened, longest first, until the encoding measures
+// at most limit tokens as counted by tok, and a report of the changes.
+// If tok is nil, ApproxTokenizer is used. A shortened description ends
+// with an ellipsis; one too short to keep any text is removed. If s does
+// not fit even without descriptions, the error wraps ErrLimitExceeded.
+func FitDescriptions(s *jsonschema.Schema, limit int, tok Tokenizer) (any, []SchemaChange, error) {
+	if tok == nil {
+		tok = ApproxTokenizer
+	}
+	v, err := schemaToValue(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := fitDescriptions(v, limit, "tokens", tok.CountTokens)
+	if err != nil {
+		return nil, nil, err
+	}
+	sortChanges(changes)
+	return v, changes, nil
+}
+
+// A description is a description keyword within a decoded schema.
+type description struct {
+	schema map[string]any
+	path   string // of the keyword
+	orig   int    // original length in bytes
+}
+
+func (d *description) text() string {
+	s, _ := d.schema["description"].(string)
+	return s
+}
+
+// shorten cuts the description to at most n bytes.
+func (d *description) shorten(n int) {
+	text := d.text()
+	if len(text) <= n {
+		return
+	}
+	if n <= len(ellipsis) {
+		delete(d.schema, "description")
+		return
+	}
+	text = text[:n-len(ellipsis)]
+	for len(text) > 0 && !utf8.ValidString(text) {
+		text = text[:len(text)-1]
+	}
+	d.schema["description"] = strings.TrimRightFunc(text, func(r rune) bool { return r == ' ' }) + ellipsis
+}
+
+func (d *description) change(reason string) SchemaChange {
+	c := SchemaChange{Path: d.path, Action: "changed", Reason: reason}
+	if _, ok := d.schema["description"]; !ok {
+		c.Action = "removed"
+	}
+	return c
+}
+
+// valueDescriptions returns the descriptions in v, a decoded schema.
+func valueDescriptions(v any) []*description {
+	var ds []*description
+	forEachValueSubschema(v, "", func(sub any, path, _ string) error {
+		if m, ok := sub.(map[string]any); ok {
+			if text, ok := m["description"].(string); ok {
+				ds = append(ds, &description{schema: m, path: path + "/description", orig: len(text)})
+			}
+		}
+		return nil
+	})
+	return ds
+}
+
+// truncateDescriptions shortens the descriptions in v, a decoded
+// schema, to at most max bytes each.
+func truncateDescriptions(v any, max int) []SchemaChange {
+	var changes []SchemaChange
+	for _, d := range valueDescriptions(v) {
+		if d.orig > max {
+			d.shorten(max)
+			changes = append(changes, d.change(fmt.Sprintf("description of %d bytes exceeds %d", d.orig, max)))
+		}
+	}
+	return changes
+}
+
+// fitDescriptions shortens the descriptions in v, a decoded schema,
+// until its JSON encoding measures at most limit units.
+func fitDescriptions(v any, limit int, unit string, measure func(string) int) ([]SchemaChange, error) {
+	ds := valueDescriptions(v)
+	for {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		size := measure(string(data))
+		if size <= limit {
+			break
+		}
+		var left []*description
+		for _, d := range ds {
+			if _, ok := d.schema["description"]; ok {
+				left = append(left, d)
+			}
+		}
+		if len(left) == 0 {
+			return nil, fmt.Errorf("picoschema: schema measures %d %s without descriptions, over the limit of %d: %w", size, unit, limit, ErrLimitExceeded)
+		}
+		// Convert the excess to bytes and level the longest
+		// descriptions down until it is removed.
+		excess := (size - limit) * len(data) / size
+		excess = max(excess, 1)
+		slices.SortFunc(left, func(a, b *description) int {
+			return cmp.Or(cmp.Compare(len(b.text()), len(a.text())), cmp.Compare(a.path, b.path))
+		})
+		level := 0
+		cut := 0
+		for i, d := range left {
+			next := 0
+			if i+1 < len(left) {
+				next = len(left[i+1].text())
+			}
+			// Cutting the i+1 longest to next removes this much.
+			if c := cut + (i+1)*(len(d.text())-next); c >= excess {
+				level = next + (c-excess)/(i+1)
+				break
+			}
+			cut += (i + 1) * (len(d.text()) - next)
+		}
+		for _, d := range left {
+			if len(d.text()) > level {
+				d.shorten(level)
+			} else {
+				break
+			}
+		}
+	}
+	var changes []SchemaChange
+	for _, d := range ds {
+		if len(d.text()) < d.orig {
+			changes = append(changes, d.change(fmt.Sprintf("shortened from %d bytes to fit the limit of %d %s", d.orig, limit, unit)))
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMaxDescriptionBytes(t *testing.T) {
+	s := mustSchema(t, `
+name: string, the full legal name of the customer
+city: string, Zürich
+note: string, très élégant
+`)
+	v, changes, err := AdaptToProfile(s, &Profile{Name: "tiny", MaxDescriptionBytes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := v.(map[string]any)["properties"].(map[string]any)
+	for name, want := range map[string]string{
+		"name": "the ful…",
+		"city": "Zürich",
+		"note": "très…",
+	} {
+		if got := props[name].(map[string]any)["description"]; got != want {
+			t.Errorf("%s: got description %q, want %q", name, got, want)
+		}
+	}
+	want := []SchemaChange{
+		{Path: "/properties/name/description", Action: "changed", Reason: "description of 35 bytes exceeds 10"},
+		{Path: "/properties/note/description", Action: "changed", Reason: "description of 15 bytes exceeds 10"},
+	}
+	if diff := cmp.Diff(want, changes); diff != "" {
+		t.Errorf("changes mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestMaxSchemaBytes(t *testing.T) {
+	long := strings.Repeat("word ", 40)
+	s := mustSchema(t, "a: string, "+long+"\nb: string, "+long[:100]+"\nc: string, short")
+	size := func(v any) int {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(data)
+	}
+	full, _ := schemaToValue(s)
+
+	limit := size(full) - 150
+	v, changes, err := AdaptToProfile(s, &Profile{Name: "small", MaxSchemaBytes: limit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := size(v); got > limit {
+		t.Errorf("adapted schema is %d bytes, over the limit of %d", got, limit)
+	}
+	props := v.(map[string]any)["properties"].(map[string]any)
+	desc := func(name string) string { d, _ := props[name].(map[string]any)["description"].(string); return d }
+	if !strings.HasSuffix(desc("a"), ellipsis) || !strings.HasSuffix(desc("b"), ellipsis) {
+		t.Errorf("long descriptions not shortened: %q, %q", desc("a"), desc("b"))
+	}
+	if desc("c") != "short" {
+		t.Errorf("short description changed to %q", desc("c"))
+	}
+	if len(changes) != 2 || changes[0].Path != "/properties/a/description" || changes[1].Path != "/properties/b/description" {
+		t.Errorf("got changes %v, want changes to a and b", changes)
+	}
+
+	_, _, err = AdaptToProfile(s, &Profile{Name: "tiny", MaxSchemaBytes: 50})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("got error %v, want ErrLimitExceeded", err)
+	}
+
+	est, err := EstimateBytes(s, &Profile{Name: "small", MaxSchemaBytes: limit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Total > limit {
+		t.Errorf("EstimateBytes = %d, over the profile limit of %d", est.Total, limit)
+	}
+}
+
+func TestFitDescriptions(t *testing.T) {
+	s := mustSchema(t, "a: string, "+strings.Repeat("token ", 50)+"\nb: integer, a count")
+	v, changes, err := FitDescriptions(s, 60, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(v)
+	if n := ApproxTokenizer.CountTokens(string(data)); n > 60 {
+		t.Errorf("fitted schema is %d tokens, over the limit of 60", n)
+	}
+	if len(changes) != 1 || changes[0].Path != "/properties/a/description" {
+		t.Errorf("got changes %v, want one change to a", changes)
+	}
+	if _, _, err := FitDescriptions(s, 5, nil); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("got error %v, want ErrLimitExceeded", err)
+	}
+}
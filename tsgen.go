@@ -0,0 +1,466 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/invopop/jsonschema"
+)
+
+// TypeScriptOptions control GenerateTypeScript.
+type TypeScriptOptions struct {
+	// RootName is the name of the type declared for the schema itself.
+	// The default is "Schema".
+	RootName string
+	// Export says whether the declarations are exported.
+	Export bool
+	// SortProperties says whether the members of each object type are
+	// sorted by name, rather than in the order of the properties of the
+	// schema.
+	SortProperties bool
+}
+
+// GenerateTypeScript returns TypeScript declarations of the types of the
+// JSON values that s accepts, for a frontend that consumes the same
+// schemas as a backend that enforces them. An object becomes an
+// interface and anything else a type alias, named opts.RootName, and
+// each schema under $defs of s is declared too, under its name, or the
+// name made an identifier, as "user-profile" becomes "UserProfile".
+//
+// Properties that are not required are optional, with "?", and a
+// nullable type, as in picoschema "string?", is a union with null.
+// Unions and enums become unions, allOf an intersection, tuples tuple
+// types, and maps a Record or an index signature. Descriptions become
+// JSDoc comments, and readOnly and deprecated schemas are marked so.
+// Validation keywords with no TypeScript equivalent, such as minimum
+// and pattern, are left out.
+//
+// Refs to other documents are not followed; see Bundle.
+func GenerateTypeScript(s *jsonschema.Schema, opts TypeScriptOptions) (string, error) {
+	g := &tsGenerator{root: opts.RootName, sort: opts.SortProperties, names: make(map[string]string)}
+	if g.root == "" {
+		g.root = "Schema"
+	}
+	taken := map[string]string{g.root: ""}
+	defs := sortedKeys(s.Definitions)
+	for _, name := range defs {
+		id := tsTypeName(name)
+		if other, ok := taken[id]; ok {
+			if other == "" {
+				return "", fmt.Errorf("picoschema: $defs %q has the TypeScript name %s of the root", name, id)
+			}
+			return "", fmt.Errorf("picoschema: $defs %q and %q have the same TypeScript name %s", other, name, id)
+		}
+		taken[id] = name
+		g.names[name] = id
+	}
+	export := ""
+	if opts.Export {
+		export = "export "
+	}
+	var sb strings.Builder
+	decl := func(name string, s *jsonschema.Schema) error {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		g.jsDoc(&sb, s, "")
+		if tsIsInterface(s) {
+			fmt.Fprintf(&sb, "%sinterface %s ", export, name)
+			if err := g.objectBody(&sb, s, ""); err != nil {
+				return err
+			}
+			sb.WriteString("\n")
+			return nil
+		}
+		t, err := g.typ(s, "")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sb, "%stype %s = %s;\n", export, name, t)
+		return nil
+	}
+	if err := decl(g.root, s); err != nil {
+		return "", err
+	}
+	for _, name := range defs {
+		if err := decl(g.names[name], s.Definitions[name]); err != nil {
+			return "", fmt.Errorf("picoschema: $defs %q: %w", name, err)
+		}
+	}
+	return sb.String(), nil
+}
+
+// A tsGenerator writes TypeScript types of schemas.
+type tsGenerator struct {
+	root  string
+	sort  bool
+	names map[string]string // TypeScript names of $defs
+}
+
+// tsIsInterface reports whether s is declared as an interface: an
+// object with properties and no other constraints on its type.
+func tsIsInterface(s *jsonschema.Schema) bool {
+	if _, ok := boolSchema(s); ok || s.Properties == nil || s.Properties.Len() == 0 {
+		return false
+	}
+	s = withoutPresence(s)
+	return s.Ref == "" && s.AllOf == nil && s.AnyOf == nil && s.OneOf == nil && s.Enum == nil && s.Const == nil
+}
+
+// typ returns the TypeScript type of s, whose members are written
+// on lines indented by indent.
+func (g *tsGenerator) typ(s *jsonschema.Schema, indent string) (string, error) {
+	if b, ok := boolSchema(s); ok {
+		if b {
+			return "unknown", nil
+		}
+		return "never", nil
+	}
+	s = withoutPresence(s)
+	var parts []string // intersected
+	add := func(t string) { parts = append(parts, t) }
+	if s.Ref != "" {
+		t, err := g.ref(s.Ref)
+		if err != nil {
+			return "", err
+		}
+		add(t)
+	}
+	switch {
+	case s.Const != nil:
+		add(jsonText(s.Const))
+	case s.Enum != nil:
+		var lits []string
+		for _, v := range s.Enum {
+			lits = append(lits, jsonText(v))
+		}
+		add(tsUnion(lits))
+	case s.Type != "":
+		t, err := g.typeKeyword(s, indent)
+		if err != nil {
+			return "", err
+		}
+		add(t)
+	case s.Properties != nil || s.AdditionalProperties != nil || s.PatternProperties != nil:
+		t, err := g.object(s, indent)
+		if err != nil {
+			return "", err
+		}
+		add(t)
+	}
+	for _, union := range [][]*jsonschema.Schema{s.AnyOf, s.OneOf} {
+		if len(union) == 0 {
+			continue
+		}
+		var alts []string
+		for _, alt := range union {
+			t, err := g.typ(alt, indent)
+			if err != nil {
+				return "", err
+			}
+			alts = append(alts, t)
+		}
+		add(tsUnion(alts))
+	}
+	for _, sub := range s.AllOf {
+		t, err := g.typ(sub, indent)
+		if err != nil {
+			return "", err
+		}
+		add(t)
+	}
+	switch len(parts) {
+	case 0:
+		return "unknown", nil
+	case 1:
+		return parts[0], nil
+	}
+	for i, p := range parts {
+		parts[i] = tsParenthesize(p, " | ")
+	}
+	return strings.Join(parts, " & "), nil
+}
+
+// typeKeyword returns the TypeScript type of s, which has a type keyword.
+func (g *tsGenerator) typeKeyword(s *jsonschema.Schema, indent string) (string, error) {
+	switch s.Type {
+	case "string", "boolean", "null", "number":
+		return s.Type, nil
+	case "integer":
+		return "number", nil
+	case "object":
+		return g.object(s, indent)
+	case "array":
+		return g.array(s, indent)
+	}
+	return "unknown", nil
+}
+
+// array returns the TypeScript type of the array s.
+func (g *tsGenerator) array(s *jsonschema.Schema, indent string) (string, error) {
+	if len(s.PrefixItems) == 0 {
+		if s.Items == nil {
+			return "unknown[]", nil
+		}
+		t, err := g.typ(s.Items, indent)
+		if err != nil {
+			return "", err
+		}
+		return tsParenthesize(t, " | ", " & ") + "[]", nil
+	}
+	var elems []string
+	for _, e := range s.PrefixItems {
+		t, err := g.typ(e, indent)
+		if err != nil {
+			return "", err
+		}
+		elems = append(elems, t)
+	}
+	if b, ok := boolSchema(s.Items); !ok || b {
+		rest := "unknown"
+		if s.Items != nil && !ok {
+			t, err := g.typ(s.Items, indent)
+			if err != nil {
+				return "", err
+			}
+			rest = t
+		}
+		elems = append(elems, "..."+tsParenthesize(rest, " | ", " & ")+"[]")
+	}
+	return "[" + strings.Join(elems, ", ") + "]", nil
+}
+
+// object returns the TypeScript type of the object s.
+func (g *tsGenerator) object(s *jsonschema.Schema, indent string) (string, error) {
+	if s.Properties == nil || s.Properties.Len() == 0 {
+		values, err := g.additional(s, indent)
+		if err != nil {
+			return "", err
+		}
+		if values == "never" {
+			return "Record<string, never>", nil
+		}
+		return "Record<string, " + values + ">", nil
+	}
+	var sb strings.Builder
+	if err := g.objectBody(&sb, s, indent); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// additional returns the TypeScript type of the properties of the
+// object s that it does not declare: "never" if there may be none.
+func (g *tsGenerator) additional(s *jsonschema.Schema, indent string) (string, error) {
+	var alts []string
+	for _, pattern := range sortedKeys(s.PatternProperties) {
+		t, err := g.typ(s.PatternProperties[pattern], indent)
+		if err != nil {
+			return "", err
+		}
+		alts = append(alts, t)
+	}
+	switch b, ok := boolSchema(s.AdditionalProperties); {
+	case s.AdditionalProperties == nil || (ok && b):
+		return "unknown", nil
+	case !ok:
+		t, err := g.typ(s.AdditionalProperties, indent)
+		if err != nil {
+			return "", err
+		}
+		alts = append(alts, t)
+	}
+	if len(alts) == 0 {
+		return "never", nil
+	}
+	return tsUnion(alts), nil
+}
+
+// objectBody writes the braced members of the object s, indented
+// by indent.
+func (g *tsGenerator) objectBody(sb *strings.Builder, s *jsonschema.Schema, indent string) error {
+	inner := indent + "  "
+	sb.WriteString("{\n")
+	var names []string
+	for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+		names = append(names, p.Key)
+	}
+	if g.sort {
+		slices.Sort(names)
+	}
+	for _, name := range names {
+		prop := s.Properties.Value(name)
+		t, err := g.typ(prop, inner)
+		if err != nil {
+			return fmt.Errorf("property %q: %w", name, err)
+		}
+		g.jsDoc(sb, prop, inner)
+		sb.WriteString(inner)
+		if prop.ReadOnly {
+			sb.WriteString("readonly ")
+		}
+		sb.WriteString(tsPropertyName(name))
+		if !slices.Contains(s.Required, name) {
+			sb.WriteString("?")
+		}
+		fmt.Fprintf(sb, ": %s;\n", t)
+	}
+	if s.AdditionalProperties != nil || s.PatternProperties != nil {
+		values, err := g.additional(s, inner)
+		if err != nil {
+			return err
+		}
+		if values != "never" {
+			// An index signature must admit the declared
+			// properties, so the values are not narrowed.
+			fmt.Fprintf(sb, "%s[key: string]: unknown;\n", inner)
+		}
+	}
+	sb.WriteString(indent + "}")
+	return nil
+}
+
+// ref returns the TypeScript type that ref refers to.
+func (g *tsGenerator) ref(ref string) (string, error) {
+	if ref == "#" {
+		return g.root, nil
+	}
+	if rest, ok := strings.CutPrefix(ref, "#/$defs/"); ok {
+		if name, ok := g.names[unescapePointer(rest)]; ok {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("$ref %q is not to the schema or one of its $defs", ref)
+}
+
+// jsDoc writes the JSDoc comment of s, if any, indented by indent.
+func (g *tsGenerator) jsDoc(sb *strings.Builder, s *jsonschema.Schema, indent string) {
+	if _, ok := boolSchema(s); ok {
+		return
+	}
+	var lines []string
+	if s.Description != "" {
+		lines = strings.Split(strings.ReplaceAll(s.Description, "*/", "*\\/"), "\n")
+	}
+	if s.Deprecated {
+		lines = append(lines, "@deprecated")
+	}
+	switch len(lines) {
+	case 0:
+		return
+	case 1:
+		fmt.Fprintf(sb, "%s/** %s */\n", indent, lines[0])
+		return
+	}
+	sb.WriteString(indent + "/**\n")
+	for _, l := range lines {
+		fmt.Fprintf(sb, "%s * %s\n", indent, strings.TrimRight(l, " "))
+	}
+	sb.WriteString(indent + " */\n")
+}
+
+// tsUnion returns the union of the types ts.
+func tsUnion(ts []string) string {
+	var uniq []string
+	for _, t := range ts {
+		if t = tsParenthesize(t, " & "); !slices.Contains(uniq, t) {
+			uniq = append(uniq, t)
+		}
+	}
+	return strings.Join(uniq, " | ")
+}
+
+// tsParenthesize returns t in parentheses if it is a union or
+// intersection whose operator is one of ops, at its top level.
+func tsParenthesize(t string, ops ...string) string {
+	depth := 0
+	for i := 0; i < len(t); i++ {
+		switch t[i] {
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}', '>':
+			depth--
+		case '"':
+			// Skip the JSON string literal.
+			for i++; i < len(t) && t[i] != '"'; i++ {
+				if t[i] == '\\' {
+					i++
+				}
+			}
+		default:
+			if depth == 0 {
+				for _, op := range ops {
+					if strings.HasPrefix(t[i:], op) {
+						return "(" + t + ")"
+					}
+				}
+			}
+		}
+	}
+	return t
+}
+
+// tsPropertyName returns the property name as written in a type:
+// bare if it is an identifier, and otherwise quoted.
+func tsPropertyName(name string) string {
+	if isTSIdentifier(name) {
+		return name
+	}
+	return jsonText(name)
+}
+
+func isTSIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isTSIdentByte(s[i], i > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// tsTypeName returns the TypeScript name of the type declared for the
+// $defs entry name: name itself if it is an identifier, and otherwise
+// its letters and digits in Pascal case, as "user-profile" becomes
+// "UserProfile".
+func tsTypeName(name string) string {
+	if isTSIdentifier(name) {
+		return name
+	}
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	id := sb.String()
+	if id == "" || isDigit(id[0]) {
+		id = "_" + id
+	}
+	return id
+}
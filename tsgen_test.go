@@ -0,0 +1,170 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerateTypeScript(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		src  string
+		opts TypeScriptOptions
+		want string
+	}{
+		{
+			name: "object",
+			src: `
+(description): An order.
+id(string, readOnly): the order ID
+status(enum): [open, closed]
+note?: string?
+total: money
+lines(array):
+  sku: string
+  quantity?: integer
+point(tuple): [number, number]
+labels?(*): string
+payment(oneOf):
+  - kind(const): card
+    last4: string
+  - kind(const): cash
+legacy?(string, deprecated):
+"content-type": string
+$defs:
+  money:
+    amount: number
+    currency: string
+`,
+			opts: TypeScriptOptions{RootName: "Order", Export: true, SortProperties: true},
+			want: `/** An order. */
+export interface Order {
+  "content-type": string;
+  /** the order ID */
+  readonly id: string;
+  labels?: Record<string, string>;
+  /** @deprecated */
+  legacy?: string;
+  lines: {
+    quantity?: number;
+    sku: string;
+  }[];
+  note?: string | null;
+  payment: {
+    kind: "card";
+    last4: string;
+  } | {
+    kind: "cash";
+  };
+  point: [number, number];
+  status: "open" | "closed";
+  total: money;
+}
+
+export interface money {
+  amount: number;
+  currency: string;
+}
+`,
+		},
+		{
+			name: "alias",
+			src:  "string",
+			want: "type Schema = string;\n",
+		},
+		{
+			name: "recursive",
+			src: `
+$defs:
+  tree node:
+    value: number
+    children(array): tree node
+root: tree node
+`,
+			want: `interface Schema {
+  root: TreeNode;
+}
+
+interface TreeNode {
+  children: TreeNode[];
+  value: number;
+}
+`,
+			opts: TypeScriptOptions{SortProperties: true},
+		},
+		{
+			name: "wildcard",
+			src: `
+id: string
+(*): number
+`,
+			want: `interface Schema {
+  id: string;
+  [key: string]: unknown;
+}
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ParseYAMLString(test.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := GenerateTypeScript(s, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateTypeScriptErrors(t *testing.T) {
+	s := mustSchema(t, "a: string")
+	s.Properties.Value("a").Ref = "customer"
+	if _, err := GenerateTypeScript(s, TypeScriptOptions{}); err == nil || !strings.Contains(err.Error(), `$ref "customer"`) {
+		t.Errorf("external ref: got %v", err)
+	}
+	s = mustSchema(t, "$defs:\n  a-b: string\n  a_b: string\nx: a-b\ny: a_b")
+	if _, err := GenerateTypeScript(s, TypeScriptOptions{}); err != nil {
+		t.Errorf("distinct names: %v", err)
+	}
+	s = mustSchema(t, "$defs:\n  a-b: string\n  AB: string\nx: a-b\ny: AB")
+	if _, err := GenerateTypeScript(s, TypeScriptOptions{}); err == nil {
+		t.Error("clashing names succeeded")
+	}
+}
+
+func TestGenerateTypeScriptDeterministic(t *testing.T) {
+	src := "e: string\nd: number\nc?: boolean\nb: {y: string, x: integer}\na(array): string"
+	var first string
+	for i := range 20 {
+		got, err := GenerateTypeScript(mustSchema(t, src), TypeScriptOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = got
+		} else if got != first {
+			t.Fatalf("output differs between conversions:\n%s\n%s", first, got)
+		}
+	}
+}
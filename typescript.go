@@ -0,0 +1,790 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// importTypeScript converts, on a best-effort basis, the interface,
+// type alias and enum declarations of a TypeScript types file, such as
+// one shared with a frontend. The last declaration is the root, and
+// the others are put under $defs, so that a type referred to by name
+// becomes a $ref; FromJSONSchema writes them as a picoschema $defs
+// section. Generics, methods, imports and other statements are
+// reported as errors.
+//
+// A JSDoc comment before a declaration or member becomes its
+// description. A member is optional if it is marked with "?" or its
+// type includes undefined. A union with null is nullable, and a union
+// of literals is an enum. An interface that extends others has their
+// members as well as its own.
+func importTypeScript(data []byte) (*jsonschema.Schema, error) {
+	toks, err := tsTokenize(string(data))
+	if err != nil {
+		return nil, err
+	}
+	p := &tsParser{toks: toks}
+	return p.file()
+}
+
+// A tsToken is a token of TypeScript source.
+type tsToken struct {
+	// kind is 'i' for an identifier or keyword, 's' for a string, 'n'
+	// for a number, the character itself for punctuation, and 0 at the
+	// end of the input.
+	kind byte
+	text string
+	doc  string // the text of the JSDoc comment just before the token
+	line int
+}
+
+// describe returns a description of t for error messages.
+func (t tsToken) describe() string {
+	if t.kind == 0 {
+		return "end of input"
+	}
+	return strconv.Quote(t.text)
+}
+
+// tsTokenize splits src into tokens, ending with one of kind 0.
+func tsTokenize(src string) ([]tsToken, error) {
+	var toks []tsToken
+	line, doc := 1, ""
+	add := func(kind byte, text string) {
+		toks = append(toks, tsToken{kind: kind, text: text, doc: doc, line: line})
+		doc = ""
+	}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			i += end
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, tsError(line, "unterminated comment")
+			}
+			body := src[i+2 : i+2+end]
+			if jsdoc, ok := strings.CutPrefix(body, "*"); ok {
+				doc = jsDocText(jsdoc)
+			}
+			line += strings.Count(body, "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for ; j < len(src) && src[j] != c && src[j] != '\n'; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+			}
+			if j == len(src) || src[j] != c {
+				return nil, tsError(line, "unterminated string")
+			}
+			str, err := tsUnquote(src[i+1 : j])
+			if err != nil {
+				return nil, tsError(line, "%v", err)
+			}
+			add('s', str)
+			i = j + 1
+		case isTSIdentByte(c, false):
+			j := i + 1
+			for j < len(src) && isTSIdentByte(src[j], true) {
+				j++
+			}
+			add('i', src[i:j])
+			i = j
+		case isDigit(c) || c == '-' && i+1 < len(src) && isDigit(src[i+1]):
+			j := i + 1
+			for j < len(src) && (isDigit(src[j]) || strings.IndexByte(".eE_", src[j]) >= 0) {
+				j++
+			}
+			add('n', src[i:j])
+			i = j
+		case strings.IndexByte("{}[]()<>:;,?|&=.", c) >= 0:
+			add(c, src[i:i+1])
+			i++
+		default:
+			return nil, tsError(line, "unexpected character %q", c)
+		}
+	}
+	add(0, "")
+	return toks, nil
+}
+
+// tsUnquote returns the value of the body of a string literal,
+// decoding its escape sequences.
+func tsUnquote(body string) (string, error) {
+	if !strings.Contains(body, "\\") {
+		return body, nil
+	}
+	bad := fmt.Errorf("bad escape in string %q", body)
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		if body[i] != '\\' {
+			b.WriteByte(body[i])
+			continue
+		}
+		i++
+		switch c := body[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		case '0':
+			b.WriteByte(0)
+		case '\n':
+			// A line continuation stands for nothing.
+		case 'x', 'u':
+			r, n, ok := tsCodePoint(body[i:])
+			if !ok {
+				return "", bad
+			}
+			i += n - 1
+			if utf16.IsSurrogate(r) {
+				// A pair of \u escapes writes a character
+				// outside the Basic Multilingual Plane.
+				lo, m, ok := tsCodePoint(strings.TrimPrefix(body[i+1:], "\\"))
+				if !ok || !strings.HasPrefix(body[i+1:], "\\u") {
+					return "", bad
+				}
+				if r = utf16.DecodeRune(r, lo); r == unicode.ReplacementChar {
+					return "", bad
+				}
+				i += m + 1
+			}
+			b.WriteRune(r)
+		default:
+			// \\, \', \" and other characters stand for themselves.
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// tsCodePoint decodes the escape at the start of s, which follows a
+// backslash: xHH, uHHHH or u{H...}. It returns the code point and the
+// length of the escape.
+func tsCodePoint(s string) (rune, int, bool) {
+	var hex string
+	switch {
+	case strings.HasPrefix(s, "x") && len(s) >= 3:
+		hex = s[1:3]
+	case strings.HasPrefix(s, "u{"):
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return 0, 0, false
+		}
+		hex = s[2:end]
+	case strings.HasPrefix(s, "u") && len(s) >= 5:
+		hex = s[1:5]
+	default:
+		return 0, 0, false
+	}
+	r, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || r > unicode.MaxRune {
+		return 0, 0, false
+	}
+	n := 1 + len(hex)
+	if strings.HasPrefix(s, "u{") {
+		n += 2
+	}
+	return rune(r), n, true
+}
+
+// jsDocText returns the text of a JSDoc comment whose body, without the
+// opening "/**" and closing "*/", is body: its lines, without their
+// leading "*", joined by spaces, up to the first block tag such as
+// "@deprecated".
+func jsDocText(body string) string {
+	var words []string
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+		if strings.HasPrefix(line, "@") {
+			break
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	return strings.Join(words, " ")
+}
+
+func isTSIdentByte(c byte, inside bool) bool {
+	return c == '_' || c == '$' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || inside && isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// tsError returns an error at the given line of TypeScript source.
+func tsError(line int, format string, args ...any) error {
+	return fmt.Errorf("picoschema: typescript line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// A tsParser converts TypeScript tokens to JSON Schema.
+type tsParser struct {
+	toks  []tsToken
+	pos   int
+	decls []*tsDecl
+	refs  []tsRef
+}
+
+// A tsDecl is a declared type.
+type tsDecl struct {
+	name    string
+	schema  *jsonschema.Schema
+	extends []string // the interfaces it extends, until they are merged
+	line    int
+}
+
+// A tsRef is a reference by name to a declared type.
+type tsRef struct {
+	s    *jsonschema.Schema
+	name string
+	line int
+}
+
+func (p *tsParser) peek(n int) tsToken {
+	return p.toks[min(p.pos+n, len(p.toks)-1)]
+}
+
+func (p *tsParser) next() tsToken {
+	t := p.toks[p.pos]
+	if t.kind != 0 {
+		p.pos++
+	}
+	return t
+}
+
+// is reports whether the next token has the given kind and,
+// if text is not empty, the given text.
+func (p *tsParser) is(kind byte, text string) bool {
+	t := p.peek(0)
+	return t.kind == kind && (text == "" || t.text == text)
+}
+
+// accept consumes the next token if it is as by is.
+func (p *tsParser) accept(kind byte, text string) bool {
+	if p.is(kind, text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect consumes the next token, which must be as by is.
+func (p *tsParser) expect(kind byte, text, want string) (tsToken, error) {
+	t := p.next()
+	if t.kind != kind || text != "" && t.text != text {
+		return t, tsError(t.line, "unexpected %s, want %s", t.describe(), want)
+	}
+	return t, nil
+}
+
+// decl returns the declaration of name, or nil.
+func (p *tsParser) decl(name string) *tsDecl {
+	for _, d := range p.decls {
+		if d.name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// file parses the declarations and returns the schema of the last.
+func (p *tsParser) file() (*jsonschema.Schema, error) {
+	for !p.is(0, "") {
+		if err := p.declaration(); err != nil {
+			return nil, err
+		}
+	}
+	if len(p.decls) == 0 {
+		return nil, tsError(p.peek(0).line, "no type declarations")
+	}
+	root := p.decls[len(p.decls)-1]
+	for _, r := range p.refs {
+		switch {
+		case r.name == root.name:
+			r.s.Ref = "#"
+		case p.decl(r.name) == nil:
+			return nil, tsError(r.line, "unknown type %q", r.name)
+		}
+	}
+	for _, d := range p.decls {
+		if err := p.extend(d, nil); err != nil {
+			return nil, err
+		}
+	}
+	s := root.schema
+	for _, d := range p.decls[:len(p.decls)-1] {
+		if s.Definitions == nil {
+			s.Definitions = make(jsonschema.Definitions)
+		}
+		s.Definitions[d.name] = d.schema
+	}
+	return s, nil
+}
+
+// declaration parses an interface, type alias or enum declaration.
+func (p *tsParser) declaration() error {
+	doc := p.peek(0).doc
+	p.accept('i', "export")
+	p.accept('i', "declare")
+	p.accept('i', "const") // as in "const enum"
+	t := p.next()
+	if t.kind != 'i' || (t.text != "interface" && t.text != "type" && t.text != "enum") {
+		return tsError(t.line, "unexpected %s, want a type declaration", t.describe())
+	}
+	name, err := p.expect('i', "", "a type name")
+	if err != nil {
+		return err
+	}
+	if p.is('<', "") {
+		return tsError(name.line, "generic type %s is not supported", name.text)
+	}
+	if p.decl(name.text) != nil {
+		return tsError(name.line, "type %s is declared twice", name.text)
+	}
+	d := &tsDecl{name: name.text, line: name.line}
+	switch t.text {
+	case "interface":
+		if p.accept('i', "extends") {
+			for {
+				base, err := p.expect('i', "", "an interface name")
+				if err != nil {
+					return err
+				}
+				d.extends = append(d.extends, base.text)
+				if !p.accept(',', "") {
+					break
+				}
+			}
+		}
+		d.schema, err = p.objectType()
+	case "type":
+		if _, err := p.expect('=', "", `"="`); err != nil {
+			return err
+		}
+		d.schema, _, err = p.typ()
+	case "enum":
+		d.schema, err = p.enumBody()
+	}
+	if err != nil {
+		return err
+	}
+	p.accept(';', "")
+	if doc != "" {
+		d.schema.Description = doc
+	}
+	p.decls = append(p.decls, d)
+	return nil
+}
+
+// extend adds the members of the interfaces that d extends to d.
+// The interfaces named in seen extend d.
+func (p *tsParser) extend(d *tsDecl, seen []string) error {
+	if len(d.extends) == 0 {
+		return nil
+	}
+	if slices.Contains(seen, d.name) {
+		return tsError(d.line, "interface %s extends itself", d.name)
+	}
+	props := orderedmap.New[string, *jsonschema.Schema]()
+	var required []string
+	for _, name := range d.extends {
+		base := p.decl(name)
+		if base == nil {
+			return tsError(d.line, "unknown interface %q", name)
+		}
+		if err := p.extend(base, append(seen, d.name)); err != nil {
+			return err
+		}
+		if base.schema.Properties == nil {
+			return tsError(d.line, "%s extends %s, which is not an object type", d.name, name)
+		}
+		for pair := base.schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			if _, ok := d.schema.Properties.Get(pair.Key); ok {
+				continue
+			}
+			props.Set(pair.Key, cloneSchema(pair.Value))
+			if slices.Contains(base.schema.Required, pair.Key) && !slices.Contains(required, pair.Key) {
+				required = append(required, pair.Key)
+			}
+		}
+	}
+	for pair := d.schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		props.Set(pair.Key, pair.Value)
+	}
+	d.schema.Properties = props
+	d.schema.Required = append(required, d.schema.Required...)
+	d.extends = nil
+	return nil
+}
+
+// objectType parses an object type, written with braces, into a
+// schema that allows no properties other than its members, unless it
+// has an index signature, such as "[key: string]: number".
+func (p *tsParser) objectType() (*jsonschema.Schema, error) {
+	if _, err := p.expect('{', "", `"{"`); err != nil {
+		return nil, err
+	}
+	s := &jsonschema.Schema{
+		Type:                 "object",
+		Properties:           orderedmap.New[string, *jsonschema.Schema](),
+		AdditionalProperties: jsonschema.FalseSchema,
+	}
+	for !p.accept('}', "") {
+		doc := p.peek(0).doc
+		if p.accept('[', "") {
+			if _, err := p.expect('i', "", "a key name"); err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(':', "", `":"`); err != nil {
+				return nil, err
+			}
+			if _, err := p.expect('i', "", "string"); err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(']', "", `"]"`); err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(':', "", `":"`); err != nil {
+				return nil, err
+			}
+			v, _, err := p.typ()
+			if err != nil {
+				return nil, err
+			}
+			if doc != "" {
+				v.Description = doc
+			}
+			s.AdditionalProperties = v
+		} else {
+			if p.is('i', "readonly") && p.peek(1).kind != ':' && p.peek(1).kind != '?' {
+				p.next()
+			}
+			name := p.next()
+			if name.kind != 'i' && name.kind != 's' && name.kind != 'n' {
+				return nil, tsError(name.line, "unexpected %s, want a member name", name.describe())
+			}
+			optional := p.accept('?', "")
+			if p.is('(', "") || p.is('<', "") {
+				return nil, tsError(name.line, "method %s is not supported", name.text)
+			}
+			if _, err := p.expect(':', "", `":"`); err != nil {
+				return nil, err
+			}
+			v, undef, err := p.typ()
+			if err != nil {
+				return nil, err
+			}
+			if doc != "" {
+				v.Description = doc
+			}
+			if _, dup := s.Properties.Get(name.text); dup {
+				return nil, tsError(name.line, "member %s is declared twice", name.text)
+			}
+			s.Properties.Set(name.text, v)
+			if !optional && !undef {
+				s.Required = append(s.Required, name.text)
+			}
+		}
+		if !p.accept(';', "") && !p.accept(',', "") && !p.is('}', "") {
+			t := p.next()
+			return nil, tsError(t.line, `unexpected %s, want ";" or "}"`, t.describe())
+		}
+	}
+	return s, nil
+}
+
+// enumBody parses the members of an enum declaration into an enum of
+// their values. Members without an initializer are numbered, as in
+// TypeScript.
+func (p *tsParser) enumBody() (*jsonschema.Schema, error) {
+	if _, err := p.expect('{', "", `"{"`); err != nil {
+		return nil, err
+	}
+	s := &jsonschema.Schema{}
+	next := 0
+	for !p.accept('}', "") {
+		name := p.next()
+		if name.kind != 'i' && name.kind != 's' {
+			return nil, tsError(name.line, "unexpected %s, want an enum member", name.describe())
+		}
+		if !p.accept('=', "") {
+			s.Enum = append(s.Enum, next)
+			next++
+		} else {
+			v, err := p.literal()
+			if err != nil {
+				return nil, err
+			}
+			if n, ok := v.(int); ok {
+				next = n + 1
+			}
+			s.Enum = append(s.Enum, v)
+		}
+		if !p.accept(',', "") && !p.is('}', "") {
+			t := p.next()
+			return nil, tsError(t.line, `unexpected %s, want "," or "}"`, t.describe())
+		}
+	}
+	return s, nil
+}
+
+// literal parses a string, number or boolean literal.
+func (p *tsParser) literal() (any, error) {
+	t := p.next()
+	switch {
+	case t.kind == 's':
+		return t.text, nil
+	case t.kind == 'n':
+		text := strings.ReplaceAll(t.text, "_", "")
+		if n, err := strconv.Atoi(text); err == nil {
+			return n, nil
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, tsError(t.line, "number %s is not supported", t.text)
+		}
+		return f, nil
+	case t.kind == 'i' && (t.text == "true" || t.text == "false"):
+		return t.text == "true", nil
+	}
+	return nil, tsError(t.line, "unexpected %s, want a literal", t.describe())
+}
+
+// typ parses a type, reporting whether it is a union that includes
+// undefined, which makes a member optional.
+func (p *tsParser) typ() (*jsonschema.Schema, bool, error) {
+	line := p.peek(0).line
+	p.accept('|', "")
+	var members []*jsonschema.Schema
+	var literals []any
+	nullable, undef := false, false
+	for {
+		t := p.peek(0)
+		switch {
+		case p.accept('i', "null"):
+			nullable = true
+		case p.accept('i', "undefined"):
+			undef = true
+		case t.kind == 's' || t.kind == 'n' || t.kind == 'i' && (t.text == "true" || t.text == "false"):
+			v, err := p.literal()
+			if err != nil {
+				return nil, false, err
+			}
+			literals = append(literals, v)
+		default:
+			s, err := p.intersection()
+			if err != nil {
+				return nil, false, err
+			}
+			members = append(members, s)
+		}
+		if !p.accept('|', "") {
+			break
+		}
+	}
+	if len(members) == 0 && (len(literals) > 1 || len(literals) == 1 && nullable) {
+		if nullable {
+			literals = append(literals, nil)
+		}
+		return &jsonschema.Schema{Enum: literals}, undef, nil
+	}
+	for _, v := range literals {
+		members = append(members, &jsonschema.Schema{Const: v})
+	}
+	var s *jsonschema.Schema
+	switch len(members) {
+	case 0:
+		if !nullable {
+			return nil, false, tsError(line, "type is only undefined")
+		}
+		return &jsonschema.Schema{Type: "null"}, undef, nil
+	case 1:
+		s = members[0]
+	default:
+		s = &jsonschema.Schema{AnyOf: members}
+	}
+	if nullable {
+		s = nullableSchema(s)
+	}
+	return s, undef, nil
+}
+
+// intersection parses an intersection of types, which must all hold.
+func (p *tsParser) intersection() (*jsonschema.Schema, error) {
+	s, err := p.postfix()
+	if err != nil || !p.is('&', "") {
+		return s, err
+	}
+	all := []*jsonschema.Schema{s}
+	for p.accept('&', "") {
+		s, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, s)
+	}
+	return &jsonschema.Schema{AllOf: all}, nil
+}
+
+// postfix parses a type followed by any number of "[]".
+func (p *tsParser) postfix() (*jsonschema.Schema, error) {
+	s, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for p.is('[', "") && p.peek(1).kind == ']' {
+		p.pos += 2
+		s = &jsonschema.Schema{Type: "array", Items: s}
+	}
+	return s, nil
+}
+
+// primary parses a type other than a union, intersection or array
+// written with "[]".
+func (p *tsParser) primary() (*jsonschema.Schema, error) {
+	t := p.peek(0)
+	switch t.kind {
+	case '{':
+		return p.objectType()
+	case '(':
+		p.next()
+		s, _, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.expect(')', "", `")"`)
+		return s, err
+	case '[':
+		p.next()
+		s := &jsonschema.Schema{Type: "array", Items: jsonschema.FalseSchema}
+		for !p.accept(']', "") {
+			e, _, err := p.typ()
+			if err != nil {
+				return nil, err
+			}
+			s.PrefixItems = append(s.PrefixItems, e)
+			if !p.accept(',', "") && !p.is(']', "") {
+				t := p.next()
+				return nil, tsError(t.line, `unexpected %s, want "," or "]"`, t.describe())
+			}
+		}
+		n := uint64(len(s.PrefixItems))
+		s.MinItems = &n
+		return s, nil
+	case 'i':
+	default:
+		return nil, tsError(t.line, "unexpected %s, want a type", t.describe())
+	}
+	p.next()
+	switch t.text {
+	case "string", "number", "boolean", "null":
+		return &jsonschema.Schema{Type: t.text}, nil
+	case "bigint":
+		return &jsonschema.Schema{Type: "integer"}, nil
+	case "any", "unknown":
+		return &jsonschema.Schema{}, nil
+	case "object":
+		return &jsonschema.Schema{Type: "object"}, nil
+	case "Date":
+		return &jsonschema.Schema{Type: "string", Format: "date-time"}, nil
+	case "Array", "ReadonlyArray":
+		args, err := p.typeArgs(t, 1)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonschema.Schema{Type: "array", Items: args[0]}, nil
+	case "Record":
+		if _, err := p.expect('<', "", `"<"`); err != nil {
+			return nil, err
+		}
+		if _, err := p.expect('i', "string", "string keys"); err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(',', "", `","`); err != nil {
+			return nil, err
+		}
+		v, _, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect('>', "", `">"`); err != nil {
+			return nil, err
+		}
+		return &jsonschema.Schema{Type: "object", AdditionalProperties: v}, nil
+	}
+	if p.is('<', "") {
+		return nil, tsError(t.line, "generic type %s is not supported", t.text)
+	}
+	if p.is('.', "") {
+		return nil, tsError(t.line, "qualified name %s.%s is not supported", t.text, p.peek(1).text)
+	}
+	s := &jsonschema.Schema{Ref: "#/$defs/" + escapePointer(t.text)}
+	p.refs = append(p.refs, tsRef{s: s, name: t.text, line: t.line})
+	return s, nil
+}
+
+// typeArgs parses the n type arguments of the generic type t.
+func (p *tsParser) typeArgs(t tsToken, n int) ([]*jsonschema.Schema, error) {
+	if _, err := p.expect('<', "", `"<"`); err != nil {
+		return nil, err
+	}
+	var args []*jsonschema.Schema
+	for {
+		s, _, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, s)
+		if !p.accept(',', "") {
+			break
+		}
+	}
+	if _, err := p.expect('>', "", `">"`); err != nil {
+		return nil, err
+	}
+	if len(args) != n {
+		return nil, tsError(t.line, "%s has %d type arguments, want %d", t.text, len(args), n)
+	}
+	return args, nil
+}
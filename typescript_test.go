@@ -0,0 +1,149 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/invopop/jsonschema"
+)
+
+const typeScriptSource = `
+// Shared with the web client.
+
+/** A postal address. */
+export interface Address {
+  street: string;
+  city: string;
+  /** ISO 3166 code. */
+  country?: string,
+}
+
+export enum Status { Active = "active", Closed = "closed" }
+
+interface Named {
+  readonly name: string;
+}
+
+/**
+ * A customer account.
+ * @public
+ */
+export interface Account extends Named {
+  id: number | string;
+  status: Status;
+  tier: 'free' | 'pro' | null;
+  kind: "account";
+  home: Address;
+  work?: Address | null;
+  tags: string[];
+  scores: Record<string, number>;
+  point: [number, number];
+  note: string | undefined;
+  parent?: Account;
+  created: Date;
+}
+`
+
+func TestImportTypeScript(t *testing.T) {
+	s, err := Import("typescript", []byte(typeScriptSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, report, err := FromJSONSchemaReport(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"$defs": map[string]any{
+			"Address(object, A postal address.)": map[string]any{
+				"street":   "string",
+				"city":     "string",
+				"country?": "string, ISO 3166 code.",
+			},
+			"Status(enum)": []any{"active", "closed"},
+			"Named":        map[string]any{"name": "string"},
+		},
+		"(description)":             "A customer account.",
+		"name":                      "string",
+		"id(anyOf)":                 []any{"number", "string"},
+		"status":                    "Status",
+		"tier(enum)":                []any{"free", "pro", nil},
+		"kind(const)":               "account",
+		"home":                      "Address",
+		"work?":                     "Address?",
+		"tags(array)":               "string",
+		"scores(*)":                 "number",
+		"point(tuple)":              []any{"number", "number"},
+		"note?":                     "string",
+		"parent?":                   "$self",
+		"created(format=date-time)": "string",
+	}
+	if diff := cmp.Diff(want, v); diff != "" {
+		t.Errorf("picoschema mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(&FidelityReport{}, report); diff != "" {
+		t.Errorf("report mismatch (-want, +got):\n%s", diff)
+	}
+
+	back, err := ToJSONSchema(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*jsonschema.Schema{s, back} {
+		sortSchemaSlices(s)
+		for _, def := range s.Definitions {
+			sortSchemaSlices(def)
+		}
+	}
+	if diff := cmp.Diff(schemaJSON(t, s), schemaJSON(t, back)); diff != "" {
+		t.Errorf("round trip mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestImportTypeScriptStrings(t *testing.T) {
+	s, err := Import("typescript", []byte(`type A = "a\nb" | 'it\'s' | "\x41\u0042\u{43}" | "\uD83D\uDE00";`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{"a\nb", "it's", "ABC", "\U0001F600"}
+	if diff := cmp.Diff(want, s.Enum); diff != "" {
+		t.Errorf("enum mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestImportTypeScriptErrors(t *testing.T) {
+	for _, test := range []struct {
+		src, wantErr string
+	}{
+		{`import { Money } from "./money";`, `line 1: unexpected "import", want a type declaration`},
+		{"interface A {\n  b: B;\n}", `line 2: unknown type "B"`},
+		{"interface Box<T> { value: T }", "generic type Box is not supported"},
+		{"interface A { size(): number }", "method size is not supported"},
+		{"type A = Partial<B>", "generic type Partial is not supported"},
+		{"interface A extends A { x: string }", "interface A extends itself"},
+		{"type A = string\ntype A = number", "type A is declared twice"},
+		{"interface A { name: string", `unexpected end of input, want ";" or "}"`},
+		{"// nothing", "no type declarations"},
+		{`type A = "\u12"`, `line 1: bad escape in string`},
+	} {
+		_, err := Import("typescript", []byte(test.src))
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%q: got error %v, want one containing %q", test.src, err, test.wantErr)
+		}
+	}
+}
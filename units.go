@@ -0,0 +1,107 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// unitKey is the annotation naming the unit of a numeric property.
+// In picoschema it is written as an attribute, as in
+// "timeout(unit=ms): integer".
+const unitKey = "x-unit"
+
+// A unitDef relates a unit to the base unit of its dimension.
+type unitDef struct {
+	dimension string
+	factor    float64 // size of the unit in base units
+}
+
+// units are the units the Coercer can convert between.
+// Minutes are "min"; "m" is the metre.
+var units = map[string]unitDef{
+	"ns":  {"time", 1e-9},
+	"us":  {"time", 1e-6},
+	"µs":  {"time", 1e-6},
+	"ms":  {"time", 1e-3},
+	"s":   {"time", 1},
+	"min": {"time", 60},
+	"h":   {"time", 3600},
+	"d":   {"time", 86400},
+
+	"mm": {"length", 1e-3},
+	"cm": {"length", 1e-2},
+	"m":  {"length", 1},
+	"km": {"length", 1e3},
+
+	"mg": {"mass", 1e-6},
+	"g":  {"mass", 1e-3},
+	"kg": {"mass", 1},
+	"t":  {"mass", 1e3},
+
+	"B":  {"data", 1},
+	"KB": {"data", 1e3},
+	"MB": {"data", 1e6},
+	"GB": {"data", 1e9},
+	"TB": {"data", 1e12},
+}
+
+// quantityRE matches a number followed by an optional unit.
+var quantityRE = regexp.MustCompile(`^\s*([-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?)\s*([^\d\s]*)\s*$`)
+
+// convertQuantity parses a string such as "2s" or "1.5 kg" and
+// converts it to the unit to. A bare number is taken to be in to already.
+// It reports false if s is not a quantity or its unit is not convertible.
+func convertQuantity(s, to string) (float64, bool) {
+	m := quantityRE.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	from := m[2]
+	if from == "" || from == to {
+		return f, true
+	}
+	fu, ok1 := units[from]
+	tu, ok2 := units[to]
+	if !ok1 || !ok2 || fu.dimension != tu.dimension {
+		return 0, false
+	}
+	return f * fu.factor / tu.factor, true
+}
+
+// coerceUnit converts a string quantity v to the unit and numeric type
+// declared by s. Other values are returned unchanged.
+func coerceUnit(v any, unit, typ string) any {
+	str, ok := v.(string)
+	if !ok || (typ != "number" && typ != "integer") {
+		return v
+	}
+	f, ok := convertQuantity(strings.TrimSpace(str), unit)
+	if !ok {
+		return v
+	}
+	// Remove floating-point noise such as 2.0000000000000004.
+	if r := math.Round(f); math.Abs(f-r) < 1e-9 {
+		return int64(r)
+	}
+	return f
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCoerceUnits(t *testing.T) {
+	s := mustSchema(t, `
+timeout(unit=ms): integer
+weight(unit=kg, shipping weight): number
+price(unit=usd): number
+`)
+	in := map[string]any{
+		"timeout": "2s",
+		"weight":  "1500 g",
+		"price":   "12.50",
+	}
+	c := Coercer{ConvertUnits: true}
+	got := c.Coerce(in, s)
+	want := map[string]any{
+		"timeout": int64(2000),
+		"weight":  1.5,
+		"price":   12.5,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if got := Coerce(in, s); !cmp.Equal(in, got) {
+		t.Errorf("default Coercer converted units: %v", got)
+	}
+	if got := c.Coerce(map[string]any{"timeout": "3 kg"}, s); !cmp.Equal(got, map[string]any{"timeout": "3 kg"}) {
+		t.Errorf("converted across dimensions: %v", got)
+	}
+}
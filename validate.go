@@ -0,0 +1,576 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/invopop/jsonschema"
+)
+
+// A Violation describes one way in which an instance fails to match a schema.
+// InstancePath and SchemaPath are JSON Pointers (RFC 6901) locating the
+// offending value in the instance and the keyword in the schema.
+type Violation struct {
+	InstancePath string `json:"instancePath"`
+	SchemaPath   string `json:"schemaPath"`
+	Keyword      string `json:"keyword"`
+	Message      string `json:"message"`
+	Value        any    `json:"value,omitempty"`
+}
+
+func (v Violation) String() string {
+	p := v.InstancePath
+	if p == "" {
+		p = "/"
+	}
+	return fmt.Sprintf("%s: %s", p, v.Message)
+}
+
+// Check validates instance against s and returns every violation found,
+// or nil if instance is valid.
+// The instance should be the result of decoding JSON or YAML into a value
+// of type any; numbers may be any Go numeric type or json.Number.
+// Check supports the type, enum, const, required, properties,
+// patternProperties, additionalProperties, items, prefixItems,
+// allOf, anyOf, oneOf and not keywords together with the numeric,
+// string, array and object bounds, and evaluates the assertions
+// declared with "(assert)". It follows local refs, such as "#" and
+// "#/$defs/name", within s; other refs are reported as violations.
+func Check(instance any, s *jsonschema.Schema) []Violation {
+	v := validator{root: s}
+	v.validate(instance, s, "", "")
+	return v.violations
+}
+
+// checkLocal is like Check, but ignores refs. It checks values against
+// subschemas while a schema is being built, before its refs resolve.
+func checkLocal(instance any, s *jsonschema.Schema) []Violation {
+	var v validator
+	v.validate(instance, s, "", "")
+	return v.violations
+}
+
+// A ValidationError reports the violations found by Validate.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Violations) == 1 {
+		return "picoschema: invalid instance: " + e.Violations[0].String()
+	}
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Sprintf("picoschema: invalid instance: %d violations: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// Validate validates instance against s as Check does, and returns
+// a *ValidationError listing the violations if there are any.
+func Validate(instance any, s *jsonschema.Schema) error {
+	if vs := Check(instance, s); len(vs) > 0 {
+		return &ValidationError{Violations: vs}
+	}
+	return nil
+}
+
+// ValidateJSON decodes data, which must hold a single JSON value,
+// and validates it against s. Numbers are decoded as json.Number,
+// so that large integers keep their precision.
+func ValidateJSON(data []byte, s *jsonschema.Schema) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var instance any
+	if err := dec.Decode(&instance); err != nil {
+		return fmt.Errorf("picoschema: decoding instance: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("picoschema: decoding instance: unexpected data after JSON value")
+	}
+	return Validate(instance, s)
+}
+
+// validator accumulates violations.
+type validator struct {
+	root       *jsonschema.Schema // for resolving refs, which are ignored if nil
+	following  []string           // the refs being followed, with their instance paths
+	violations []Violation
+}
+
+func (v *validator) add(ipath, spath, keyword string, value any, format string, args ...any) {
+	v.violations = append(v.violations, Violation{
+		InstancePath: ipath,
+		SchemaPath:   spath + "/" + keyword,
+		Keyword:      keyword,
+		Message:      fmt.Sprintf(format, args...),
+		Value:        value,
+	})
+}
+
+// ref validates inst against the target of the local ref.
+func (v *validator) ref(inst any, ref, ipath, spath string) {
+	ptr, ok := strings.CutPrefix(ref, "#")
+	target, found := resolvePointer(v.root, ptr)
+	if !ok || !found {
+		v.add(ipath, spath, "$ref", inst, "cannot resolve reference %q", ref)
+		return
+	}
+	// A ref reached again at the same instance path without
+	// descending into the instance would never end.
+	key := ipath + "\x00" + ref
+	if slices.Contains(v.following, key) {
+		v.add(ipath, spath, "$ref", inst, "reference %q refers to itself", ref)
+		return
+	}
+	v.following = append(v.following, key)
+	v.validate(inst, target, ipath, spath+"/$ref")
+	v.following = v.following[:len(v.following)-1]
+}
+
+// matches reports whether inst validates against s, without recording violations.
+func (v *validator) matches(inst any, s *jsonschema.Schema, ipath, spath string) bool {
+	sub := validator{root: v.root, following: v.following}
+	sub.validate(inst, s, ipath, spath)
+	return len(sub.violations) == 0
+}
+
+func (v *validator) validate(inst any, s *jsonschema.Schema, ipath, spath string) {
+	if s == nil {
+		return
+	}
+	if b, ok := boolSchema(s); ok {
+		if !b {
+			v.violations = append(v.violations, Violation{
+				InstancePath: ipath,
+				SchemaPath:   spath,
+				Keyword:      "false",
+				Message:      "no value is allowed here",
+				Value:        inst,
+			})
+		}
+		return
+	}
+
+	if s.Ref != "" && v.root != nil {
+		v.ref(inst, s.Ref, ipath, spath)
+	}
+	if s.Type != "" && !hasType(inst, s.Type) {
+		v.add(ipath, spath, "type", inst, "got %s, want %s", jsonType(inst), s.Type)
+		// Further keywords would only produce noise.
+		return
+	}
+	if s.Enum != nil && !slicesContainsEqual(s.Enum, inst) {
+		if canon, ok := enumAliasOf(s, inst); ok {
+			v.add(ipath, spath, "enum", inst, "value %v is an alias of %q; use Coerce to normalize it", inst, canon)
+		} else {
+			v.add(ipath, spath, "enum", inst, "value %v is not one of %v", inst, s.Enum)
+		}
+	}
+	if s.Const != nil && !jsonEqual(s.Const, inst) {
+		v.add(ipath, spath, "const", inst, "value %v is not %v", inst, s.Const)
+	}
+
+	for i, sub := range s.AllOf {
+		v.validate(inst, sub, ipath, fmt.Sprintf("%s/allOf/%d", spath, i))
+	}
+	if len(s.AnyOf) > 0 {
+		ok := false
+		for i, sub := range s.AnyOf {
+			if v.matches(inst, sub, ipath, fmt.Sprintf("%s/anyOf/%d", spath, i)) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			v.add(ipath, spath, "anyOf", inst, "value matches none of the anyOf schemas")
+		}
+	}
+	if len(s.OneOf) > 0 {
+		n := 0
+		for i, sub := range s.OneOf {
+			if v.matches(inst, sub, ipath, fmt.Sprintf("%s/oneOf/%d", spath, i)) {
+				n++
+			}
+		}
+		if n != 1 {
+			v.add(ipath, spath, "oneOf", inst, "value matches %d of the oneOf schemas, want exactly 1", n)
+		}
+	}
+	if s.Not != nil && v.matches(inst, s.Not, ipath, spath+"/not") {
+		v.add(ipath, spath, "not", inst, "value matches the schema under not")
+	}
+
+	switch inst := inst.(type) {
+	case map[string]any:
+		v.validateObject(inst, s, ipath, spath)
+	case []any:
+		v.validateArray(inst, s, ipath, spath)
+	case string:
+		v.validateString(inst, s, ipath, spath)
+	default:
+		if f, ok := toFloat(inst); ok {
+			v.validateNumber(f, s, ipath, spath)
+		}
+	}
+}
+
+func (v *validator) validateObject(inst map[string]any, s *jsonschema.Schema, ipath, spath string) {
+	if a, ok := s.Extras[assertKey]; ok {
+		v.validateAssertions(inst, a, ipath, spath)
+	}
+	for _, name := range s.Required {
+		if _, ok := inst[name]; !ok {
+			v.add(ipath, spath, "required", nil, "missing required property %q", name)
+		}
+	}
+	if s.MinProperties != nil && uint64(len(inst)) < *s.MinProperties {
+		v.add(ipath, spath, "minProperties", nil, "got %d properties, want at least %d", len(inst), *s.MinProperties)
+	}
+	if s.MaxProperties != nil && uint64(len(inst)) > *s.MaxProperties {
+		v.add(ipath, spath, "maxProperties", nil, "got %d properties, want at most %d", len(inst), *s.MaxProperties)
+	}
+	type pattern struct {
+		src string
+		re  *regexp.Regexp
+	}
+	var patterns []pattern
+	for _, pat := range sortedKeys(s.PatternProperties) {
+		re, err := compilePattern(pat)
+		if err != nil {
+			v.add(ipath, spath, "patternProperties", nil, "invalid pattern %q: %v", pat, err)
+			continue
+		}
+		patterns = append(patterns, pattern{pat, re})
+	}
+	for _, k := range sortedKeys(inst) {
+		val := inst[k]
+		kpath := ipath + "/" + escapePointer(k)
+		matched := false
+		if s.Properties != nil {
+			if ps, ok := s.Properties.Get(k); ok {
+				matched = true
+				v.validate(val, ps, kpath, spath+"/properties/"+escapePointer(k))
+			}
+		}
+		for _, pat := range patterns {
+			if pat.re.MatchString(k) {
+				matched = true
+				v.validate(val, s.PatternProperties[pat.src], kpath, spath+"/patternProperties/"+escapePointer(pat.src))
+			}
+		}
+		if !matched && s.AdditionalProperties != nil {
+			if b, ok := boolSchema(s.AdditionalProperties); ok && !b {
+				v.add(kpath, spath, "additionalProperties", val, "property %q is not allowed", k)
+				continue
+			}
+			v.validate(val, s.AdditionalProperties, kpath, spath+"/additionalProperties")
+		}
+	}
+	if u, ok := s.Extras[unevaluatedPropertiesKey]; ok && u == false {
+		evaluated := make(map[string]bool)
+		v.evaluatedProperties(inst, s, ipath, evaluated, 0)
+		for _, k := range sortedKeys(inst) {
+			if !evaluated[k] {
+				v.add(ipath+"/"+escapePointer(k), spath, unevaluatedPropertiesKey, inst[k], "property %q is not allowed", k)
+			}
+		}
+	}
+}
+
+// evaluatedProperties adds to evaluated the names of the properties of
+// inst that s evaluates: those it declares or matches with
+// patternProperties or additionalProperties, and those evaluated by
+// the targets of its refs, its allOf schemas and the anyOf and oneOf
+// schemas inst matches.
+func (v *validator) evaluatedProperties(inst map[string]any, s *jsonschema.Schema, ipath string, evaluated map[string]bool, depth int) {
+	// The limit stops cycles of refs.
+	if s == nil || depth > 32 {
+		return
+	}
+	if b, ok := boolSchema(s); ok {
+		if b {
+			for k := range inst {
+				evaluated[k] = true
+			}
+		}
+		return
+	}
+	for k := range inst {
+		if s.AdditionalProperties != nil || s.Properties != nil && s.Properties.Value(k) != nil {
+			evaluated[k] = true
+			continue
+		}
+		for pat := range s.PatternProperties {
+			if re, err := compilePattern(pat); err == nil && re.MatchString(k) {
+				evaluated[k] = true
+			}
+		}
+	}
+	if ptr, ok := strings.CutPrefix(s.Ref, "#"); ok && v.root != nil {
+		if target, ok := resolvePointer(v.root, ptr); ok {
+			v.evaluatedProperties(inst, target, ipath, evaluated, depth+1)
+		}
+	}
+	for _, sub := range s.AllOf {
+		v.evaluatedProperties(inst, sub, ipath, evaluated, depth+1)
+	}
+	for _, sub := range append(slices.Clip(s.AnyOf), s.OneOf...) {
+		if v.matches(inst, sub, ipath, "") {
+			v.evaluatedProperties(inst, sub, ipath, evaluated, depth+1)
+		}
+	}
+}
+
+// validateAssertions evaluates the assertions in a, the value of an
+// assert annotation, against inst.
+func (v *validator) validateAssertions(inst map[string]any, a any, ipath, spath string) {
+	srcs, err := assertionSources(a)
+	if err != nil {
+		v.add(ipath, spath, assertKey, nil, "%v", err)
+		return
+	}
+	for _, src := range srcs {
+		as, err := compileAssertion(src)
+		if err != nil {
+			v.add(ipath, spath, assertKey, nil, "%v", err)
+			continue
+		}
+		ok, evaluated, err := as.eval(inst)
+		switch {
+		case err != nil:
+			v.add(ipath, spath, assertKey, nil, "%v", err)
+		case evaluated && !ok:
+			v.add(ipath, spath, assertKey, nil, "assertion %q failed", src)
+		}
+	}
+}
+
+func (v *validator) validateArray(inst []any, s *jsonschema.Schema, ipath, spath string) {
+	if s.MinItems != nil && uint64(len(inst)) < *s.MinItems {
+		v.add(ipath, spath, "minItems", nil, "got %d items, want at least %d", len(inst), *s.MinItems)
+	}
+	if s.MaxItems != nil && uint64(len(inst)) > *s.MaxItems {
+		v.add(ipath, spath, "maxItems", nil, "got %d items, want at most %d", len(inst), *s.MaxItems)
+	}
+	if s.UniqueItems {
+		for i := range inst {
+			for j := 0; j < i; j++ {
+				if jsonEqual(inst[i], inst[j]) {
+					v.add(ipath, spath, "uniqueItems", inst[i], "items %d and %d are equal", j, i)
+				}
+			}
+		}
+	}
+	for i, item := range inst {
+		ip := ipath + "/" + strconv.Itoa(i)
+		if i < len(s.PrefixItems) {
+			v.validate(item, s.PrefixItems[i], ip, fmt.Sprintf("%s/prefixItems/%d", spath, i))
+			continue
+		}
+		if s.Items != nil {
+			if b, ok := boolSchema(s.Items); ok && !b {
+				v.add(ip, spath, "items", item, "array has %d items, want at most %d", len(inst), len(s.PrefixItems))
+				break
+			}
+			v.validate(item, s.Items, ip, spath+"/items")
+		}
+	}
+}
+
+func (v *validator) validateString(inst string, s *jsonschema.Schema, ipath, spath string) {
+	n := uint64(utf8.RuneCountInString(inst))
+	if s.MinLength != nil && n < *s.MinLength {
+		v.add(ipath, spath, "minLength", inst, "got length %d, want at least %d", n, *s.MinLength)
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		v.add(ipath, spath, "maxLength", inst, "got length %d, want at most %d", n, *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		re, err := compilePattern(s.Pattern)
+		switch {
+		case err != nil:
+			v.add(ipath, spath, "pattern", inst, "invalid pattern %q: %v", s.Pattern, err)
+		case !re.MatchString(inst):
+			v.add(ipath, spath, "pattern", inst, "value %q does not match pattern %q", inst, s.Pattern)
+		}
+	}
+}
+
+func (v *validator) validateNumber(f float64, s *jsonschema.Schema, ipath, spath string) {
+	bound := func(keyword string, n json.Number, ok func(f, b float64) bool, rel string) {
+		if n == "" {
+			return
+		}
+		b, err := n.Float64()
+		if err == nil && !ok(f, b) {
+			v.add(ipath, spath, keyword, f, "value %v is not %s %v", f, rel, b)
+		}
+	}
+	bound("minimum", s.Minimum, func(f, b float64) bool { return f >= b }, ">=")
+	bound("maximum", s.Maximum, func(f, b float64) bool { return f <= b }, "<=")
+	bound("exclusiveMinimum", s.ExclusiveMinimum, func(f, b float64) bool { return f > b }, ">")
+	bound("exclusiveMaximum", s.ExclusiveMaximum, func(f, b float64) bool { return f < b }, "<")
+	bound("multipleOf", s.MultipleOf, func(f, b float64) bool {
+		q := f / b
+		return b == 0 || math.Abs(q-math.Round(q)) < 1e-9
+	}, "a multiple of")
+}
+
+// boolSchema reports whether s is the boolean schema true or false,
+// and if so, which. The boolean form is unexported in jsonschema.Schema,
+// so s is compared with jsonschema.TrueSchema and FalseSchema, of which
+// the boolean schemas decoded from JSON are copies. A nil schema and
+// the empty schema are neither.
+func boolSchema(s *jsonschema.Schema) (value, ok bool) {
+	switch {
+	case s == nil:
+		return false, false
+	case s == jsonschema.TrueSchema || reflect.DeepEqual(s, jsonschema.TrueSchema):
+		return true, true
+	case s == jsonschema.FalseSchema || reflect.DeepEqual(s, jsonschema.FalseSchema):
+		return false, true
+	}
+	return false, false
+}
+
+// hasType reports whether inst is of the JSON Schema type typ.
+func hasType(inst any, typ string) bool {
+	switch typ {
+	case "integer":
+		f, ok := toFloat(inst)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := toFloat(inst)
+		return ok
+	}
+	return jsonType(inst) == typ
+}
+
+// jsonType returns the JSON type name of inst.
+func jsonType(inst any) string {
+	switch inst.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	if f, ok := toFloat(inst); ok {
+		if f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", inst)
+}
+
+// toFloat converts a decoded numeric value to a float64.
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+// jsonEqual reports whether a and b are equal JSON values,
+// treating all numeric representations of the same number as equal.
+func jsonEqual(a, b any) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	switch a := a.(type) {
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok || !jsonEqual(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// slicesContainsEqual reports whether vals contains a value jsonEqual to v.
+func slicesContainsEqual(vals []any, v any) bool {
+	for _, x := range vals {
+		if jsonEqual(x, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// escapePointer escapes a JSON Pointer reference token.
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,220 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/invopop/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCheck(t *testing.T) {
+	schema := mustSchema(t, `
+name: string
+age?: integer
+tags(array): string
+color?(enum): [RED, GREEN]
+`)
+	for _, test := range []struct {
+		name     string
+		instance string
+		want     []Violation
+	}{
+		{
+			name:     "valid",
+			instance: `{name: Ann, age: 3, tags: [a, b], color: RED}`,
+		},
+		{
+			name:     "missing required",
+			instance: `{tags: []}`,
+			want: []Violation{
+				{InstancePath: "", SchemaPath: "/required", Keyword: "required"},
+			},
+		},
+		{
+			name:     "nested violations",
+			instance: `{name: Ann, age: 1.5, tags: [a, 2], color: BLUE, extra: true}`,
+			want: []Violation{
+				{InstancePath: "/age", SchemaPath: "/properties/age/type", Keyword: "type", Value: 1.5},
+				{InstancePath: "/color", SchemaPath: "/properties/color/enum", Keyword: "enum", Value: "BLUE"},
+				{InstancePath: "/extra", SchemaPath: "/additionalProperties", Keyword: "additionalProperties", Value: true},
+				{InstancePath: "/tags/1", SchemaPath: "/properties/tags/items/type", Keyword: "type", Value: 2},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var inst any
+			if err := yaml.Unmarshal([]byte(test.instance), &inst); err != nil {
+				t.Fatal(err)
+			}
+			got := Check(inst, schema)
+			if diff := cmp.Diff(test.want, got, cmpopts.IgnoreFields(Violation{}, "Message")); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// mustSchema converts the picoschema YAML source src.
+func mustSchema(t *testing.T, src string) *jsonschema.Schema {
+	t.Helper()
+	var val any
+	if err := yaml.Unmarshal([]byte(src), &val); err != nil {
+		t.Fatal(err)
+	}
+	s, err := ToJSONSchema(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	schema := mustSchema(t, `
+id: integer
+tags?(array): string
+`)
+	if err := Validate(map[string]any{"id": 1}, schema); err != nil {
+		t.Errorf("valid instance: %v", err)
+	}
+	err := Validate(map[string]any{"tags": []any{"a", 2}, "extra": true}, schema)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %v, want a *ValidationError", err)
+	}
+	var keywords []string
+	for _, v := range verr.Violations {
+		keywords = append(keywords, v.Keyword)
+	}
+	slices.Sort(keywords)
+	if want := []string{"additionalProperties", "required", "type"}; !cmp.Equal(keywords, want) {
+		t.Errorf("got violations of %v, want %v", keywords, want)
+	}
+
+	for _, test := range []struct {
+		data    string
+		wantErr string
+	}{
+		{`{"id": 12345678901234567890}`, ""},
+		{`{"id": 1.5}`, "/id: "},
+		{`{"id": 1} {}`, "unexpected data"},
+		{`{"id": `, "decoding instance"},
+	} {
+		err := ValidateJSON([]byte(test.data), schema)
+		if test.wantErr == "" {
+			if err != nil {
+				t.Errorf("ValidateJSON(%s): %v", test.data, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("ValidateJSON(%s) = %v, want error containing %q", test.data, err, test.wantErr)
+		}
+	}
+}
+
+func TestCheckInvalidPattern(t *testing.T) {
+	s := mustSchema(t, "code: string\nmeta?(object): any")
+	s.Properties.Value("code").Pattern = "[a-"
+	s.Properties.Value("meta").PatternProperties = map[string]*jsonschema.Schema{"(": {Type: "string"}}
+	vs := Check(map[string]any{"code": "x", "meta": map[string]any{"a": 1, "b": 2}}, s)
+	var got []string
+	for _, v := range vs {
+		got = append(got, v.SchemaPath)
+	}
+	want := []string{"/properties/code/pattern", "/properties/meta/patternProperties"}
+	if !slices.Equal(got, want) {
+		t.Errorf("violations at %q, want %q: %v", got, want, vs)
+	}
+}
+
+func TestBoolSchema(t *testing.T) {
+	var decoded struct{ T, F *jsonschema.Schema }
+	if err := json.Unmarshal([]byte(`{"T": true, "F": false}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		s         *jsonschema.Schema
+		value, ok bool
+	}{
+		{jsonschema.TrueSchema, true, true},
+		{jsonschema.FalseSchema, false, true},
+		{decoded.T, true, true},
+		{decoded.F, false, true},
+		{&jsonschema.Schema{}, false, false},
+		{&jsonschema.Schema{Type: "string"}, false, false},
+		{nil, false, false},
+	} {
+		if value, ok := boolSchema(test.s); value != test.value || ok != test.ok {
+			t.Errorf("boolSchema(%v) = %t, %t, want %t, %t", test.s, value, ok, test.value, test.ok)
+		}
+	}
+}
+
+func TestCheckRefs(t *testing.T) {
+	s := mustSchema(t, `
+name: string
+children?(array): $self
+`)
+	vs := Check(map[string]any{"name": "a", "children": []any{map[string]any{"name": 1}}}, s)
+	if len(vs) != 1 || vs[0].InstancePath != "/children/0/name" || vs[0].SchemaPath != "/properties/children/items/$ref/properties/name/type" {
+		t.Errorf("got %v, want a type violation at /children/0/name", vs)
+	}
+
+	var defs jsonschema.Schema
+	if err := json.Unmarshal([]byte(`{
+		"$defs": {"id": {"type": "string", "minLength": 1}, "loop": {"$ref": "#/$defs/loop"}},
+		"properties": {
+			"id": {"$ref": "#/$defs/id"},
+			"loop": {"$ref": "#/$defs/loop"},
+			"far": {"$ref": "https://example.com/x.json"}
+		}
+	}`), &defs); err != nil {
+		t.Fatal(err)
+	}
+	vs = Check(map[string]any{"id": "", "loop": 1, "far": 1}, &defs)
+	var got []string
+	for _, v := range vs {
+		got = append(got, v.SchemaPath)
+	}
+	want := []string{"/properties/far/$ref", "/properties/id/$ref/minLength", "/properties/loop/$ref/$ref"}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("violations at %q, want %q: %v", got, want, vs)
+	}
+}
+
+func TestCheckAllOf(t *testing.T) {
+	s := mustSchema(t, `
+$defs:
+  person:
+    name: string
+employee(allOf): [person, {badge: string}]
+`)
+	if vs := Check(map[string]any{"employee": map[string]any{"name": "Ada", "badge": "A1"}}, s); len(vs) != 0 {
+		t.Errorf("got violations %v, want none", vs)
+	}
+	vs := Check(map[string]any{"employee": map[string]any{"name": "Ada", "badge": "A1", "desk": 4.0}}, s)
+	if len(vs) != 1 || vs[0].InstancePath != "/employee/desk" || vs[0].Keyword != "unevaluatedProperties" {
+		t.Errorf("got %v, want an unevaluatedProperties violation at /employee/desk", vs)
+	}
+}
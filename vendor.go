@@ -0,0 +1,174 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// Vendor fetches every remote schema that s refers to, directly or
+// through other remote schemas, and writes each one as JSON into dir,
+// at a path made of the URL's host and path, such as
+// "example.com/schemas/tax.json". It returns a copy of s in which the
+// remote refs are rewritten to paths relative to dir; refs within the
+// vendored files are rewritten to paths relative to those files.
+// The copy is therefore meant to be stored in dir as well.
+// Remote refs are http and https URLs, possibly relative to the URL of
+// the vendored schema containing them; they are fetched with res,
+// which is typically an *HTTPResolver.
+// With the vendored files in place, builds no longer need the network.
+func Vendor(s *jsonschema.Schema, res SchemaResolver, dir string) (*jsonschema.Schema, error) {
+	v := &vendorer{res: res, dir: dir, files: make(map[string]string)}
+	c := cloneSchema(s)
+	if err := v.rewrite(c, nil, "."); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type vendorer struct {
+	res   SchemaResolver
+	dir   string
+	files map[string]string // URL to path relative to dir
+}
+
+// rewrite vendors the remote refs of s, whose own URL is base (nil for
+// the root schema), and rewrites them relative to the directory from,
+// itself relative to v.dir.
+func (v *vendorer) rewrite(s *jsonschema.Schema, base *url.URL, from string) error {
+	var err error
+	walkSchema(s, "", func(s *jsonschema.Schema, _ string) bool {
+		if err != nil {
+			return false
+		}
+		if s.Ref == "" || strings.HasPrefix(s.Ref, "#") {
+			return true
+		}
+		target, frag, hasFrag := strings.Cut(s.Ref, "#")
+		u, perr := url.Parse(target)
+		if perr != nil {
+			err = fmt.Errorf("picoschema: bad ref %q: %w", s.Ref, perr)
+			return false
+		}
+		if base != nil {
+			u = base.ResolveReference(u)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return true
+		}
+		local, ferr := v.fetch(u)
+		if ferr != nil {
+			err = ferr
+			return false
+		}
+		rel, rerr := filepath.Rel(filepath.FromSlash(from), filepath.FromSlash(local))
+		if rerr != nil {
+			err = fmt.Errorf("picoschema: %w", rerr)
+			return false
+		}
+		s.Ref = filepath.ToSlash(rel)
+		if hasFrag {
+			s.Ref += "#" + frag
+		}
+		return true
+	})
+	return err
+}
+
+// fetch vendors the schema at u, if it has not been already,
+// and returns its path relative to v.dir.
+func (v *vendorer) fetch(u *url.URL) (string, error) {
+	u = &url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path, RawQuery: u.RawQuery}
+	key := u.String()
+	if local, ok := v.files[key]; ok {
+		return local, nil
+	}
+	local := vendorPath(u)
+	file := filepath.Join(v.dir, filepath.FromSlash(local))
+	if rel, err := filepath.Rel(v.dir, file); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("picoschema: vendoring %s: path %s is outside %s", key, local, v.dir)
+	}
+	v.files[key] = local // before recursing, in case of cycles
+	s, err := v.res.Resolve(key)
+	if err != nil {
+		return "", fmt.Errorf("picoschema: vendoring %s: %w", key, err)
+	}
+	c := cloneSchema(s)
+	if err := v.rewrite(c, u, path.Dir(local)); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("picoschema: vendoring %s: %w", key, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return "", fmt.Errorf("picoschema: %w", err)
+	}
+	if err := os.WriteFile(file, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("picoschema: %w", err)
+	}
+	return local, nil
+}
+
+// vendorPath returns the slash-separated path at which to store the
+// schema fetched from u. The host and each path component are reduced
+// to letters, digits, '.', '_' and '-', and none of them is "." or "..",
+// so that the path cannot leave the vendor directory.
+func vendorPath(u *url.URL) string {
+	p := path.Clean("/" + u.Path)
+	if u.RawQuery != "" {
+		p += "_" + strings.NewReplacer("&", "_", "=", "-", "/", "_").Replace(u.RawQuery)
+	}
+	switch path.Ext(p) {
+	case ".json":
+	case ".yaml", ".yml":
+		p = strings.TrimSuffix(p, path.Ext(p)) + ".json"
+	default:
+		if p == "/" {
+			p = "/index"
+		}
+		p += ".json"
+	}
+	parts := strings.Split(p[1:], "/")
+	for i, part := range parts {
+		parts[i] = vendorComponent(part)
+	}
+	return vendorComponent(u.Host) + "/" + strings.Join(parts, "/")
+}
+
+// vendorComponent returns name with the bytes other than letters,
+// digits, '.', '_' and '-' replaced by '_', and with "", "." and ".."
+// made into names of underscores.
+func vendorComponent(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			b[i] = '_'
+		}
+	}
+	switch string(b) {
+	case "", ".", "..":
+		return strings.Repeat("_", max(len(b), 1))
+	}
+	return string(b)
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/invopop/jsonschema"
+)
+
+func TestVendor(t *testing.T) {
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		switch r.URL.Path {
+		case "/schemas/address.json":
+			w.Write([]byte(`{"type": "object", "properties": {"country": {"$ref": "common/country.yaml"}}}`))
+		case "/schemas/common/country.yaml":
+			w.Write([]byte(`type: string`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	s := mustSchema(t, "id: string")
+	s.Properties.Set("home", &jsonschema.Schema{Ref: srv.URL + "/schemas/address.json"})
+	s.Properties.Set("work", &jsonschema.Schema{Ref: srv.URL + "/schemas/address.json#/properties/country"})
+	s.Properties.Set("local", &jsonschema.Schema{Ref: "#/properties/id"})
+
+	dir := t.TempDir()
+	got, err := Vendor(s, &HTTPResolver{Client: srv.Client()}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := strings.ReplaceAll(strings.TrimPrefix(srv.URL, "http://"), ":", "_")
+	for prop, want := range map[string]string{
+		"home":  host + "/schemas/address.json",
+		"work":  host + "/schemas/address.json#/properties/country",
+		"local": "#/properties/id",
+	} {
+		if ref := got.Properties.Value(prop).Ref; ref != want {
+			t.Errorf("%s: got ref %q, want %q", prop, ref, want)
+		}
+	}
+	if fetches != 2 {
+		t.Errorf("got %d fetches, want 2", fetches)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, host, "schemas", "address.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addr jsonschema.Schema
+	if err := json.Unmarshal(data, &addr); err != nil {
+		t.Fatal(err)
+	}
+	if ref := addr.Properties.Value("country").Ref; ref != "common/country.json" {
+		t.Errorf("vendored ref is %q, want common/country.json", ref)
+	}
+	if _, err := os.Stat(filepath.Join(dir, host, "schemas", "common", "country.json")); err != nil {
+		t.Error(err)
+	}
+	if s.Properties.Value("home").Ref != srv.URL+"/schemas/address.json" {
+		t.Error("Vendor modified its argument")
+	}
+
+	s.Properties.Set("gone", &jsonschema.Schema{Ref: srv.URL + "/missing.json"})
+	if _, err := Vendor(s, &HTTPResolver{Client: srv.Client()}, t.TempDir()); err == nil {
+		t.Error("got nil error for missing remote schema")
+	}
+}
+
+func TestVendorPath(t *testing.T) {
+	for raw, want := range map[string]string{
+		"https://example.com/a/b.json":        "example.com/a/b.json",
+		"https://example.com/a/b.yaml":        "example.com/a/b.json",
+		"https://example.com:8443/a/b":        "example.com_8443/a/b.json",
+		"https://example.com/":                "example.com/index.json",
+		"https://example.com/../../etc/x":     "example.com/etc/x.json",
+		"https://example.com/s?v=2":           "example.com/s_v-2.json",
+		"https://example.com/a%2F..%2F..%2Fb": "example.com/b.json",
+		"https://example.com/a\\..\\b":        "example.com/a_.._b.json",
+		"https://..:80/x":                     ".._80/x.json",
+		"https://../x":                        "__/x.json",
+		"https://user@[::1]:8080/x":           "___1__8080/x.json",
+	} {
+		u, _ := url.Parse(raw)
+		if got := vendorPath(u); got != want {
+			t.Errorf("vendorPath(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"slices"
+
+	"github.com/invopop/jsonschema"
+)
+
+// audiencesKey is the annotation listing the audiences a property is
+// visible to. In picoschema it is written as an attribute of the
+// property, as in "ssn(audience=internal): string" or
+// "notes(audience=internal|partner): string".
+const audiencesKey = "x-audiences"
+
+// View returns a copy of s containing only the properties visible to
+// the given audience. Properties without an audience annotation are
+// visible to every audience. The audience annotations themselves are
+// removed from the result, and required lists are updated to name only
+// the remaining properties. s is not modified.
+func View(s *jsonschema.Schema, audience string) *jsonschema.Schema {
+	c := cloneSchema(s)
+	walkSchema(c, "", func(s *jsonschema.Schema, _ string) bool {
+		deleteExtra(s, audiencesKey)
+		if s.Properties == nil {
+			return true
+		}
+		for p := s.Properties.Oldest(); p != nil; {
+			next := p.Next()
+			if !visibleTo(p.Value, audience) {
+				s.Properties.Delete(p.Key)
+				s.Required = slices.DeleteFunc(s.Required, func(name string) bool { return name == p.Key })
+			}
+			p = next
+		}
+		if len(s.Required) == 0 {
+			s.Required = nil
+		}
+		return true
+	})
+	return c
+}
+
+// visibleTo reports whether the property schema s is visible to audience.
+func visibleTo(s *jsonschema.Schema, audience string) bool {
+	auds, ok := s.Extras[audiencesKey].([]any)
+	return !ok || slices.Contains(auds, any(audience))
+}
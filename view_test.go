@@ -0,0 +1,70 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestView(t *testing.T) {
+	s := mustSchema(t, `
+name: string
+ssn(audience=internal): string
+account(object):
+  id: string
+  riskScore(audience=internal|partner): number
+`)
+	for _, test := range []struct {
+		audience string
+		want     string
+	}{
+		{
+			audience: "public",
+			want: `
+name: string
+account(object):
+  id: string
+`,
+		},
+		{
+			audience: "partner",
+			want: `
+name: string
+account(object):
+  id: string
+  riskScore: number
+`,
+		},
+	} {
+		t.Run(test.audience, func(t *testing.T) {
+			got, err := ConvertSchema(View(s, test.audience))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := ConvertSchema(mustSchema(t, test.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+	if _, ok := s.Properties.Get("ssn"); !ok {
+		t.Error("View modified its input")
+	}
+}
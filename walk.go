@@ -0,0 +1,102 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// forEachSubschema calls fn for each non-nil direct subschema of s,
+// passing the JSON Pointer suffix that locates it relative to s.
+func forEachSubschema(s *jsonschema.Schema, fn func(sub *jsonschema.Schema, ptr string)) {
+	if s == nil {
+		return
+	}
+	one := func(sub *jsonschema.Schema, ptr string) {
+		if sub != nil {
+			fn(sub, ptr)
+		}
+	}
+	list := func(subs []*jsonschema.Schema, keyword string) {
+		for i, sub := range subs {
+			one(sub, fmt.Sprintf("/%s/%d", keyword, i))
+		}
+	}
+	named := func(subs map[string]*jsonschema.Schema, keyword string) {
+		for _, k := range sortedKeys(subs) {
+			one(subs[k], "/"+keyword+"/"+escapePointer(k))
+		}
+	}
+
+	named(s.Definitions, "$defs")
+	list(s.AllOf, "allOf")
+	list(s.AnyOf, "anyOf")
+	list(s.OneOf, "oneOf")
+	one(s.Not, "/not")
+	one(s.If, "/if")
+	one(s.Then, "/then")
+	one(s.Else, "/else")
+	named(s.DependentSchemas, "dependentSchemas")
+	list(s.PrefixItems, "prefixItems")
+	one(s.Items, "/items")
+	one(s.Contains, "/contains")
+	if s.Properties != nil {
+		for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+			one(p.Value, "/properties/"+escapePointer(p.Key))
+		}
+	}
+	named(s.PatternProperties, "patternProperties")
+	one(s.AdditionalProperties, "/additionalProperties")
+	one(s.PropertyNames, "/propertyNames")
+	one(s.ContentSchema, "/contentSchema")
+}
+
+// walkSchema calls fn for s and, if fn returns true, recursively
+// for every subschema of s, in a depth-first pre-order traversal.
+// The path passed to fn is the JSON Pointer of the schema from the root.
+func walkSchema(s *jsonschema.Schema, path string, fn func(s *jsonschema.Schema, path string) bool) {
+	if s == nil || !fn(s, path) {
+		return
+	}
+	forEachSubschema(s, func(sub *jsonschema.Schema, ptr string) {
+		walkSchema(sub, path+ptr, fn)
+	})
+}
+
+// schemaKeywords returns the JSON names of the keywords set in s,
+// including extension keywords, in sorted order.
+func schemaKeywords(s *jsonschema.Schema) []string {
+	if s == nil {
+		return nil
+	}
+	var ret []string
+	rv := reflect.ValueOf(s).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || rv.Field(i).IsZero() {
+			continue
+		}
+		ret = append(ret, name)
+	}
+	ret = append(ret, sortedKeys(s.Extras)...)
+	slices.Sort(ret)
+	return ret
+}
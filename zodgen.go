@@ -0,0 +1,441 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// ZodOptions control GenerateZod.
+type ZodOptions struct {
+	// RootName is the name of the constant declared for the schema
+	// itself. The default is "Schema".
+	RootName string
+	// Export says whether the constants are exported.
+	Export bool
+	// SortProperties says whether the properties of each object are
+	// sorted by name, as for TypeScriptOptions.
+	SortProperties bool
+}
+
+// GenerateZod returns a TypeScript module that declares a Zod schema
+// for s, so that JavaScript code can validate values by the same rules
+// as this package. The schema is declared as a constant named
+// opts.RootName, after a constant for each schema under $defs of s,
+// named as by GenerateTypeScript.
+//
+// Types, required properties, enums and consts of scalars, arrays and
+// tuples, unions, allOf, and the string, number and array bounds become
+// the corresponding Zod schemas and checks, and descriptions become
+// .describe calls. Objects admit properties they do not declare, as in
+// JSON Schema, unless additionalProperties says otherwise, which
+// patternProperties extend. Keywords with no Zod equivalent, such as
+// uniqueItems and most formats, are left out, so the Zod schema may
+// accept values that s rejects.
+//
+// A reference to a constant that is not declared yet is wrapped in
+// z.lazy, so recursive schemas work; TypeScript needs a type annotation
+// for such a constant, which GenerateTypeScript can provide. As for
+// GenerateTypeScript, only local refs are followed (see Bundle).
+func GenerateZod(s *jsonschema.Schema, opts ZodOptions) (string, error) {
+	g := &zodGenerator{root: opts.RootName, sort: opts.SortProperties, names: make(map[string]string), declared: make(map[string]bool)}
+	if g.root == "" {
+		g.root = "Schema"
+	}
+	taken := map[string]string{g.root: ""}
+	defs := sortedKeys(s.Definitions)
+	for _, name := range defs {
+		id := tsTypeName(name)
+		if other, ok := taken[id]; ok {
+			if other == "" {
+				return "", fmt.Errorf("picoschema: $defs %q has the name %s of the root", name, id)
+			}
+			return "", fmt.Errorf("picoschema: $defs %q and %q have the same name %s", other, name, id)
+		}
+		taken[id] = name
+		g.names[name] = id
+	}
+	export := ""
+	if opts.Export {
+		export = "export "
+	}
+	var sb strings.Builder
+	sb.WriteString("import { z } from \"zod\";\n")
+	decl := func(name string, s *jsonschema.Schema) error {
+		z, err := g.schema(s, "")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sb, "\n%sconst %s = %s;\n", export, name, z)
+		g.declared[name] = true
+		return nil
+	}
+	for _, name := range defs {
+		if err := decl(g.names[name], s.Definitions[name]); err != nil {
+			return "", fmt.Errorf("picoschema: $defs %q: %w", name, err)
+		}
+	}
+	if err := decl(g.root, s); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// A zodGenerator writes Zod schemas.
+type zodGenerator struct {
+	root     string
+	sort     bool
+	names    map[string]string // constant names of $defs
+	declared map[string]bool   // constants already declared
+}
+
+// schema returns the Zod schema for s, whose members are written
+// on lines indented by indent.
+func (g *zodGenerator) schema(s *jsonschema.Schema, indent string) (string, error) {
+	if b, ok := boolSchema(s); ok {
+		if b {
+			return "z.unknown()", nil
+		}
+		return "z.never()", nil
+	}
+	s = withoutPresence(s)
+	var parts []string // intersected
+	add := func(z string) { parts = append(parts, z) }
+	if s.Ref != "" {
+		z, err := g.ref(s.Ref)
+		if err != nil {
+			return "", err
+		}
+		add(z)
+	}
+	switch {
+	case s.Const != nil:
+		add(zodLiteral(s.Const))
+	case s.Enum != nil:
+		add(zodEnum(s.Enum))
+	case s.Type != "":
+		z, err := g.typeKeyword(s, indent)
+		if err != nil {
+			return "", err
+		}
+		add(z)
+	case s.Properties != nil || s.AdditionalProperties != nil || s.PatternProperties != nil:
+		z, err := g.object(s, indent)
+		if err != nil {
+			return "", err
+		}
+		add(z)
+	}
+	for _, union := range [][]*jsonschema.Schema{s.AnyOf, s.OneOf} {
+		if len(union) == 0 {
+			continue
+		}
+		z, err := g.union(union, indent)
+		if err != nil {
+			return "", err
+		}
+		add(z)
+	}
+	for _, sub := range s.AllOf {
+		z, err := g.schema(sub, indent)
+		if err != nil {
+			return "", err
+		}
+		add(z)
+	}
+	z := "z.unknown()"
+	if len(parts) > 0 {
+		z = parts[0]
+		for _, p := range parts[1:] {
+			z += ".and(" + p + ")"
+		}
+	}
+	if s.Description != "" {
+		z += ".describe(" + jsonText(s.Description) + ")"
+	}
+	return z, nil
+}
+
+// union returns the Zod schema for the union of alts. A null
+// alternative makes the union nullable.
+func (g *zodGenerator) union(alts []*jsonschema.Schema, indent string) (string, error) {
+	var zs []string
+	nullable := false
+	for _, alt := range alts {
+		if _, ok := boolSchema(alt); !ok && alt.Type == "null" && len(schemaKeywords(alt)) == 1 {
+			nullable = true
+			continue
+		}
+		z, err := g.schema(alt, indent)
+		if err != nil {
+			return "", err
+		}
+		if !slices.Contains(zs, z) {
+			zs = append(zs, z)
+		}
+	}
+	var z string
+	switch len(zs) {
+	case 0:
+		return "z.null()", nil
+	case 1:
+		z = zs[0]
+	default:
+		z = "z.union([" + strings.Join(zs, ", ") + "])"
+	}
+	if nullable {
+		z += ".nullable()"
+	}
+	return z, nil
+}
+
+// typeKeyword returns the Zod schema for s, which has a type keyword.
+func (g *zodGenerator) typeKeyword(s *jsonschema.Schema, indent string) (string, error) {
+	switch s.Type {
+	case "string":
+		return zodString(s), nil
+	case "number":
+		return zodNumber("z.number()", s), nil
+	case "integer":
+		return zodNumber("z.number().int()", s), nil
+	case "boolean":
+		return "z.boolean()", nil
+	case "null":
+		return "z.null()", nil
+	case "object":
+		return g.object(s, indent)
+	case "array":
+		return g.array(s, indent)
+	}
+	return "z.unknown()", nil
+}
+
+// zodString returns the Zod schema for the string s.
+func zodString(s *jsonschema.Schema) string {
+	z := "z.string()"
+	switch s.Format {
+	case "email":
+		z += ".email()"
+	case "uri":
+		z += ".url()"
+	case "uuid":
+		z += ".uuid()"
+	case "date-time":
+		z += ".datetime({ offset: true })"
+	}
+	if s.MinLength != nil {
+		z += fmt.Sprintf(".min(%d)", *s.MinLength)
+	}
+	if s.MaxLength != nil {
+		z += fmt.Sprintf(".max(%d)", *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		z += ".regex(new RegExp(" + jsonText(s.Pattern) + ", \"u\"))"
+	}
+	return z
+}
+
+// zodNumber returns the Zod schema z with the bounds of the number s.
+func zodNumber(z string, s *jsonschema.Schema) string {
+	for _, c := range []struct {
+		method string
+		n      json.Number
+	}{
+		{"gte", s.Minimum},
+		{"gt", s.ExclusiveMinimum},
+		{"lte", s.Maximum},
+		{"lt", s.ExclusiveMaximum},
+		{"multipleOf", s.MultipleOf},
+	} {
+		if c.n != "" {
+			z += "." + c.method + "(" + string(c.n) + ")"
+		}
+	}
+	return z
+}
+
+// array returns the Zod schema for the array s.
+func (g *zodGenerator) array(s *jsonschema.Schema, indent string) (string, error) {
+	if len(s.PrefixItems) > 0 {
+		return g.tuple(s, indent)
+	}
+	items := "z.unknown()"
+	if s.Items != nil {
+		var err error
+		if items, err = g.schema(s.Items, indent); err != nil {
+			return "", err
+		}
+	}
+	z := "z.array(" + items + ")"
+	if s.MinItems != nil {
+		z += fmt.Sprintf(".min(%d)", *s.MinItems)
+	}
+	if s.MaxItems != nil {
+		z += fmt.Sprintf(".max(%d)", *s.MaxItems)
+	}
+	return z, nil
+}
+
+// tuple returns the Zod schema for the array s with prefixItems.
+// Zod tuples have no length checks beyond their elements, so
+// minItems and maxItems are left out.
+func (g *zodGenerator) tuple(s *jsonschema.Schema, indent string) (string, error) {
+	var elems []string
+	for _, e := range s.PrefixItems {
+		z, err := g.schema(e, indent)
+		if err != nil {
+			return "", err
+		}
+		elems = append(elems, z)
+	}
+	z := "z.tuple([" + strings.Join(elems, ", ") + "])"
+	if b, ok := boolSchema(s.Items); !ok || b {
+		rest := "z.unknown()"
+		if s.Items != nil && !ok {
+			var err error
+			if rest, err = g.schema(s.Items, indent); err != nil {
+				return "", err
+			}
+		}
+		z += ".rest(" + rest + ")"
+	}
+	return z, nil
+}
+
+// object returns the Zod schema for the object s.
+func (g *zodGenerator) object(s *jsonschema.Schema, indent string) (string, error) {
+	additional, err := g.additional(s, indent)
+	if err != nil {
+		return "", err
+	}
+	if s.Properties == nil || s.Properties.Len() == 0 {
+		if additional == "z.never()" {
+			return "z.object({}).strict()", nil
+		}
+		return "z.record(z.string(), " + additional + ")", nil
+	}
+	inner := indent + "  "
+	var sb strings.Builder
+	sb.WriteString("z.object({\n")
+	var names []string
+	for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+		names = append(names, p.Key)
+	}
+	if g.sort {
+		slices.Sort(names)
+	}
+	for _, name := range names {
+		z, err := g.schema(s.Properties.Value(name), inner)
+		if err != nil {
+			return "", fmt.Errorf("property %q: %w", name, err)
+		}
+		if !slices.Contains(s.Required, name) {
+			z += ".optional()"
+		}
+		fmt.Fprintf(&sb, "%s%s: %s,\n", inner, tsPropertyName(name), z)
+	}
+	sb.WriteString(indent + "})")
+	switch additional {
+	case "z.unknown()":
+		sb.WriteString(".passthrough()")
+	case "z.never()":
+		sb.WriteString(".strict()")
+	default:
+		sb.WriteString(".catchall(" + additional + ")")
+	}
+	return sb.String(), nil
+}
+
+// additional returns the Zod schema for the properties of the object
+// s that it does not declare: z.never() if there may be none.
+func (g *zodGenerator) additional(s *jsonschema.Schema, indent string) (string, error) {
+	var alts []*jsonschema.Schema
+	for _, pattern := range sortedKeys(s.PatternProperties) {
+		alts = append(alts, s.PatternProperties[pattern])
+	}
+	switch b, ok := boolSchema(s.AdditionalProperties); {
+	case s.AdditionalProperties == nil || (ok && b):
+		return "z.unknown()", nil
+	case !ok:
+		alts = append(alts, s.AdditionalProperties)
+	}
+	if len(alts) == 0 {
+		return "z.never()", nil
+	}
+	return g.union(alts, indent)
+}
+
+// ref returns the Zod schema that ref refers to, wrapped in z.lazy
+// if it is not declared yet.
+func (g *zodGenerator) ref(ref string) (string, error) {
+	name := ""
+	if ref == "#" {
+		name = g.root
+	} else if rest, ok := strings.CutPrefix(ref, "#/$defs/"); ok {
+		name = g.names[unescapePointer(rest)]
+	}
+	if name == "" {
+		return "", fmt.Errorf("$ref %q is not to the schema or one of its $defs", ref)
+	}
+	if !g.declared[name] {
+		return "z.lazy(() => " + name + ")", nil
+	}
+	return name, nil
+}
+
+// zodLiteral returns the Zod schema for the constant v.
+func zodLiteral(v any) string {
+	switch v.(type) {
+	case string, bool, float64, int, int64, json.Number, nil:
+		return "z.literal(" + jsonText(v) + ")"
+	}
+	// Zod compares literals by identity, which no array or
+	// object value is equal to.
+	return "z.unknown()"
+}
+
+// zodEnum returns the Zod schema for the enum of vals.
+func zodEnum(vals []any) string {
+	strs := make([]string, 0, len(vals))
+	for _, v := range vals {
+		if _, ok := v.(string); !ok {
+			break
+		}
+		strs = append(strs, jsonText(v))
+	}
+	if len(strs) == len(vals) && len(vals) > 0 {
+		return "z.enum([" + strings.Join(strs, ", ") + "])"
+	}
+	var lits []string
+	for _, v := range vals {
+		if lit := zodLiteral(v); lit == "z.unknown()" {
+			return lit
+		} else if !slices.Contains(lits, lit) {
+			lits = append(lits, lit)
+		}
+	}
+	switch len(lits) {
+	case 0:
+		return "z.never()"
+	case 1:
+		return lits[0]
+	}
+	return "z.union([" + strings.Join(lits, ", ") + "])"
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picoschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerateZod(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		src  string
+		opts ZodOptions
+		want string
+	}{
+		{
+			name: "object",
+			src: `
+id(string, minLength=1): the order ID
+status(enum): [open, closed]
+note?: string?
+total: money
+quantity(integer, minimum=1):
+lines(array, maxItems=10):
+  sku: string
+point(tuple): [number, number]
+"content-type": string
+$defs:
+  money:
+    amount: number
+    currency(string, pattern=^[A-Z]{3}$):
+`,
+			opts: ZodOptions{RootName: "Order", Export: true, SortProperties: true},
+			want: `import { z } from "zod";
+
+export const money = z.object({
+  amount: z.number(),
+  currency: z.string().regex(new RegExp("^[A-Z]{3}$", "u")),
+}).strict();
+
+export const Order = z.object({
+  "content-type": z.string(),
+  id: z.string().min(1).describe("the order ID"),
+  lines: z.array(z.object({
+    sku: z.string(),
+  }).strict()).max(10),
+  note: z.string().nullable().optional(),
+  point: z.tuple([z.number(), z.number()]),
+  quantity: z.number().int().gte(1),
+  status: z.enum(["open", "closed"]),
+  total: money,
+}).strict();
+`,
+		},
+		{
+			name: "scalar",
+			src:  "string",
+			want: "import { z } from \"zod\";\n\nconst Schema = z.string();\n",
+		},
+		{
+			name: "recursive",
+			src: `
+$defs:
+  tree node:
+    value: number
+    children(array): tree node
+root: tree node
+`,
+			opts: ZodOptions{SortProperties: true},
+			want: `import { z } from "zod";
+
+const TreeNode = z.object({
+  children: z.array(z.lazy(() => TreeNode)),
+  value: z.number(),
+}).strict();
+
+const Schema = z.object({
+  root: TreeNode,
+}).strict();
+`,
+		},
+		{
+			name: "open",
+			src:  "id: string\n(*): number",
+			want: "import { z } from \"zod\";\n\nconst Schema = z.object({\n  id: z.string(),\n}).catchall(z.number());\n",
+		},
+		{
+			name: "map",
+			src:  "(*): number",
+			want: "import { z } from \"zod\";\n\nconst Schema = z.record(z.string(), z.number());\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ParseYAMLString(test.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := GenerateZod(s, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateZodErrors(t *testing.T) {
+	s := mustSchema(t, "a: string")
+	s.Properties.Value("a").Ref = "customer"
+	if _, err := GenerateZod(s, ZodOptions{}); err == nil || !strings.Contains(err.Error(), `$ref "customer"`) {
+		t.Errorf("external ref: got %v", err)
+	}
+	s = mustSchema(t, "$defs:\n  a-b: string\n  AB: string\nx: a-b\ny: AB")
+	if _, err := GenerateZod(s, ZodOptions{}); err == nil {
+		t.Error("clashing names succeeded")
+	}
+}
+
+func TestZodEnum(t *testing.T) {
+	for _, test := range []struct {
+		vals []any
+		want string
+	}{
+		{[]any{"a", "b"}, `z.enum(["a", "b"])`},
+		{[]any{"a", 1.0, nil}, `z.union([z.literal("a"), z.literal(1), z.literal(null)])`},
+		{[]any{true}, `z.literal(true)`},
+		{[]any{}, `z.never()`},
+		{[]any{"a", []any{1.0}}, `z.unknown()`},
+	} {
+		if got := zodEnum(test.vals); got != test.want {
+			t.Errorf("zodEnum(%v) = %s, want %s", test.vals, got, test.want)
+		}
+	}
+}